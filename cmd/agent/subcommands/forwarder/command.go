@@ -0,0 +1,243 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package forwarder contains "agent forwarder" subcommands
+package forwarder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	cconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+// cliParams are the command-line arguments for the retry-queue subcommands
+type cliParams struct {
+	args []string
+
+	jsonOutput  bool
+	withPayload bool
+	output      string
+	domain      string
+	apiKey      string
+	remove      bool
+}
+
+// Commands initializes the forwarder sub-command tree.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	c := &cobra.Command{
+		Use:   "forwarder",
+		Short: "Manage the forwarder of the agent",
+	}
+
+	cliParams := &cliParams{}
+	oneShot := func(fct interface{}) func(*cobra.Command, []string) error {
+		return func(_ *cobra.Command, args []string) error {
+			cliParams.args = args
+			return fxutil.OneShot(fct,
+				fx.Supply(cliParams),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath, cconfig.WithExtraConfFiles(globalParams.ExtraConfFilePath), cconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, "off", true)}),
+				core.Bundle(),
+			)
+		}
+	}
+
+	retryQueueCmd := &cobra.Command{
+		Use:   "retry-queue",
+		Short: "Inspect and replay the transactions stored on the disk by the forwarder",
+		Long: `Inspect and replay the transactions stored on the disk by the forwarder when
+its retry queue is full, in 'forwarder_storage_path'.
+
+The Agent must be stopped before replaying transactions, otherwise they can be
+sent twice.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the files of the retry queue",
+		Args:  cobra.NoArgs,
+		RunE:  oneShot(listRetryFiles),
+	}
+	listCmd.Flags().BoolVarP(&cliParams.jsonOutput, "json", "j", false, "print out raw json")
+
+	inspectCmd := &cobra.Command{
+		Use:   "inspect <file>",
+		Short: "Print the transactions of a file of the retry queue",
+		Args:  cobra.ExactArgs(1),
+		RunE:  oneShot(inspectRetryFile),
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export [file...]",
+		Short: "Export as JSON the transactions of the files of the retry queue",
+		Long: `Export as JSON the transactions of the given files of the retry queue, or of all
+its files when no file is given. The API keys are redacted.`,
+		RunE: oneShot(exportRetryFiles),
+	}
+	exportCmd.Flags().BoolVar(&cliParams.withPayload, "payload", false, "include the payloads of the transactions, encoded in base64")
+	exportCmd.Flags().StringVarP(&cliParams.output, "output", "o", "", "write the JSON to this file instead of the standard output")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay <file>...",
+		Short: "Send the transactions of files of the retry queue to another endpoint",
+		Long: `Send the transactions of the given files of the retry queue to another endpoint,
+for instance to drain the retry queue toward another region during an incident.
+
+The API keys of the configured domains are used, unless --api-key is given.
+With --remove, the transactions accepted or rejected by the endpoint are removed
+from the files, the transactions which failed are kept to be retried.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: oneShot(replayRetryFiles),
+	}
+	replayCmd.Flags().StringVar(&cliParams.domain, "to", "", "the URL to send the transactions to, for instance https://app.datadoghq.eu")
+	replayCmd.Flags().StringVar(&cliParams.apiKey, "api-key", "", "the API key to use instead of the API keys of the transactions")
+	replayCmd.Flags().BoolVar(&cliParams.remove, "remove", false, "remove the replayed transactions from the files")
+	_ = replayCmd.MarkFlagRequired("to")
+
+	retryQueueCmd.AddCommand(listCmd, inspectCmd, exportCmd, replayCmd)
+	c.AddCommand(retryQueueCmd)
+
+	return []*cobra.Command{c}
+}
+
+func listRetryFiles(config cconfig.Component, cliParams *cliParams, log log.Component) error {
+	q := defaultforwarder.NewRetryQueue(config, log)
+	files, err := q.List()
+	if err != nil {
+		return fmt.Errorf("could not list the files of the retry queue: %v", err)
+	}
+
+	if cliParams.jsonOutput {
+		return json.NewEncoder(os.Stdout).Encode(files)
+	}
+	printRetryFiles(os.Stdout, q.StoragePath(), files)
+	return nil
+}
+
+func inspectRetryFile(config cconfig.Component, cliParams *cliParams, log log.Component) error {
+	q := defaultforwarder.NewRetryQueue(config, log)
+	transactions, err := q.Inspect(cliParams.args[0], false)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %v", cliParams.args[0], err)
+	}
+	printRetryTransactions(os.Stdout, transactions)
+	return nil
+}
+
+func exportRetryFiles(config cconfig.Component, cliParams *cliParams, log log.Component) error {
+	q := defaultforwarder.NewRetryQueue(config, log)
+	paths := cliParams.args
+	if len(paths) == 0 {
+		files, err := q.List()
+		if err != nil {
+			return fmt.Errorf("could not list the files of the retry queue: %v", err)
+		}
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+	}
+
+	exported := make(map[string][]defaultforwarder.RetryQueueTransaction, len(paths))
+	for _, path := range paths {
+		transactions, err := q.Inspect(path, cliParams.withPayload)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %v", path, err)
+		}
+		exported[path] = transactions
+	}
+
+	var w io.Writer = os.Stdout
+	if cliParams.output != "" {
+		f, err := os.OpenFile(cliParams.output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exported)
+}
+
+func replayRetryFiles(config cconfig.Component, cliParams *cliParams, log log.Component) error {
+	q := defaultforwarder.NewRetryQueue(config, log)
+	options := defaultforwarder.RetryQueueReplayOptions{
+		Domain: cliParams.domain,
+		APIKey: cliParams.apiKey,
+		Remove: cliParams.remove,
+	}
+
+	var errs []error
+	for _, path := range cliParams.args {
+		result, err := q.Replay(context.Background(), path, options)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not replay %s: %v", path, err))
+			continue
+		}
+		fmt.Printf("%s: %d sent, %d rejected, %d failed\n", path, result.Sent, result.Rejected, result.Failed)
+	}
+	return errors.Join(errs...)
+}
+
+func printRetryFiles(w io.Writer, storagePath string, files []defaultforwarder.RetryQueueFile) {
+	if len(files) == 0 {
+		fmt.Fprintf(w, "The retry queue is empty (%s).\n", storagePath)
+		return
+	}
+
+	for _, f := range files {
+		domain := f.Domain
+		if domain == "" {
+			domain = "unknown domain"
+		}
+		fmt.Fprintf(w, "%s\n", f.Path)
+		fmt.Fprintf(w, "  Domain: %s\n", domain)
+		fmt.Fprintf(w, "  Backend: %s, Size: %d bytes, Modified: %s\n", f.Backend, f.Size, f.ModTime.UTC().Format(time.RFC3339))
+		fmt.Fprintf(w, "  Transactions: %d, Points: %d\n", f.TransactionsCount, f.PointsCount)
+		if f.Error != "" {
+			fmt.Fprintf(w, "  Error: %s\n", f.Error)
+		}
+	}
+}
+
+func printRetryTransactions(w io.Writer, transactions []defaultforwarder.RetryQueueTransaction) {
+	if len(transactions) == 0 {
+		fmt.Fprintln(w, "No transaction.")
+		return
+	}
+
+	for _, tr := range transactions {
+		fmt.Fprintf(w, "%s created at %s\n", tr.Endpoint, tr.CreatedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "  Route: %s\n", tr.Route)
+		fmt.Fprintf(w, "  Payload: %d bytes, %d points\n", tr.PayloadSize, tr.PointCount)
+		fmt.Fprintf(w, "  Priority: %s, Destination: %s, Retryable: %v, Errors: %d\n", tr.Priority, tr.Destination, tr.Retryable, tr.ErrorCount)
+		if len(tr.Headers) > 0 {
+			headers := make([]string, 0, len(tr.Headers))
+			for key, values := range tr.Headers {
+				headers = append(headers, key+": "+strings.Join(values, ", "))
+			}
+			sort.Strings(headers)
+			fmt.Fprintf(w, "  Headers: %s\n", strings.Join(headers, "; "))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"forwarder", "retry-queue", "list", "--json"},
+		listRetryFiles,
+		func(p *cliParams) {
+			assert.True(t, p.jsonOutput)
+		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"forwarder", "retry-queue", "inspect", "file.retry"},
+		inspectRetryFile,
+		func(p *cliParams) {
+			assert.Equal(t, []string{"file.retry"}, p.args)
+		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"forwarder", "retry-queue", "export", "--payload", "-o", "out.json"},
+		exportRetryFiles,
+		func(p *cliParams) {
+			assert.Empty(t, p.args)
+			assert.True(t, p.withPayload)
+			assert.Equal(t, "out.json", p.output)
+		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"forwarder", "retry-queue", "replay", "file1.retry", "file2.retry", "--to", "https://app.datadoghq.eu", "--remove"},
+		replayRetryFiles,
+		func(p *cliParams) {
+			assert.Equal(t, []string{"file1.retry", "file2.retry"}, p.args)
+			assert.Equal(t, "https://app.datadoghq.eu", p.domain)
+			assert.True(t, p.remove)
+		})
+}
+
+func TestPrintRetryFiles(t *testing.T) {
+	var b strings.Builder
+	printRetryFiles(&b, "/var/run/transactions_to_retry/core", nil)
+	assert.Equal(t, "The retry queue is empty (/var/run/transactions_to_retry/core).\n", b.String())
+
+	b.Reset()
+	printRetryFiles(&b, "", []defaultforwarder.RetryQueueFile{
+		{
+			Path:              "/core/abc/2024_01_01__00_00_00_1.retry",
+			Domain:            "https://7-58-0-app.agent.datadoghq.com",
+			Backend:           "file",
+			Size:              123,
+			ModTime:           time.Unix(1700000000, 0),
+			TransactionsCount: 2,
+			PointsCount:       10,
+		},
+		{
+			Path:    "/core/def/transactions.db",
+			Backend: "kv",
+			Size:    32768,
+			ModTime: time.Unix(1700000000, 0),
+			Error:   "2 records cannot be read",
+		},
+	})
+	assert.Equal(t, `/core/abc/2024_01_01__00_00_00_1.retry
+  Domain: https://7-58-0-app.agent.datadoghq.com
+  Backend: file, Size: 123 bytes, Modified: 2023-11-14T22:13:20Z
+  Transactions: 2, Points: 10
+/core/def/transactions.db
+  Domain: unknown domain
+  Backend: kv, Size: 32768 bytes, Modified: 2023-11-14T22:13:20Z
+  Transactions: 0, Points: 0
+  Error: 2 records cannot be read
+`, b.String())
+}
@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package metrics contains "agent metrics" subcommands
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	cconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+// cliParams are the command-line arguments for the query subcommand
+type cliParams struct {
+	name            string
+	tags            []string
+	jsonOutput      bool
+	prettyPrintJSON bool
+}
+
+// Commands initializes the metrics sub-command tree.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	c := &cobra.Command{
+		Use:   "metrics",
+		Short: "Inspect the metrics sent by the agent",
+	}
+
+	cliParams := &cliParams{}
+
+	queryCmd := &cobra.Command{
+		Use:   "query <name>",
+		Short: "Print the recently flushed series and sketches of a metric",
+		Long: `Print the series and sketches of a metric, as flushed by the agent during the
+last minutes, with their tags and timestamps. Only the metrics having all the
+tags given with --tags are printed.
+
+The flush history must be enabled with 'aggregator_flush_history.enabled'.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			cliParams.name = args[0]
+			return fxutil.OneShot(queryMetrics,
+				fx.Supply(cliParams),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath, cconfig.WithExtraConfFiles(globalParams.ExtraConfFilePath), cconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, "off", true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	queryCmd.Flags().StringSliceVarP(&cliParams.tags, "tags", "t", []string{}, "only print the metrics having all these tags")
+	queryCmd.Flags().BoolVarP(&cliParams.jsonOutput, "json", "j", false, "print out raw json")
+	queryCmd.Flags().BoolVarP(&cliParams.prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
+
+	c.AddCommand(queryCmd)
+
+	return []*cobra.Command{c}
+}
+
+func queryMetrics(config cconfig.Component, cliParams *cliParams, _ log.Component) error {
+	c := util.GetClient(false)
+	addr, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("name", cliParams.name)
+	for _, tag := range cliParams.tags {
+		params.Add("tag", tag)
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/metrics-query?%s", addr, config.GetInt("cmd_port"), params.Encode())
+
+	if err := util.SetAuthToken(config); err != nil {
+		return err
+	}
+
+	r, err := util.DoGet(c, urlstr, util.LeaveConnectionOpen)
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			return errors.New(e)
+		}
+		return fmt.Errorf("could not reach agent: %v\nMake sure the agent is running before querying the metrics", err)
+	}
+
+	if cliParams.prettyPrintJSON {
+		var prettyJSON bytes.Buffer
+		json.Indent(&prettyJSON, r, "", "  ") //nolint:errcheck
+		fmt.Println(prettyJSON.String())
+		return nil
+	} else if cliParams.jsonOutput {
+		fmt.Println(string(r))
+		return nil
+	}
+
+	var flushed []aggregator.FlushedMetric
+	if err := json.Unmarshal(r, &flushed); err != nil {
+		return fmt.Errorf("could not parse the flushed metrics: %v", err)
+	}
+	printFlushedMetrics(os.Stdout, cliParams.name, flushed)
+	return nil
+}
+
+func printFlushedMetrics(w io.Writer, name string, flushed []aggregator.FlushedMetric) {
+	if len(flushed) == 0 {
+		fmt.Fprintf(w, "No series or sketch of '%s' was flushed recently.\n", name)
+		return
+	}
+
+	for _, m := range flushed {
+		fmt.Fprintf(w, "%s (%s) flushed at %s\n", m.Name, m.Type, time.Unix(m.FlushTime, 0).UTC().Format(time.RFC3339))
+		fmt.Fprintf(w, "  Host: %s\n", m.Host)
+		if m.Device != "" {
+			fmt.Fprintf(w, "  Device: %s\n", m.Device)
+		}
+		if len(m.Tags) > 0 {
+			fmt.Fprintf(w, "  Tags: %s\n", strings.Join(m.Tags, ", "))
+		}
+		if m.Interval != 0 {
+			fmt.Fprintf(w, "  Interval: %ds\n", m.Interval)
+		}
+		for _, p := range m.Points {
+			fmt.Fprintf(w, "  %d  %v\n", p.Ts, p.Value)
+		}
+		for _, p := range m.Sketches {
+			fmt.Fprintf(w, "  %d  count:%d sum:%v min:%v max:%v avg:%v\n", p.Ts, p.Count, p.Sum, p.Min, p.Max, p.Avg)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"metrics", "query", "my.metric"},
+		queryMetrics,
+		func(p *cliParams) {
+			assert.Equal(t, "my.metric", p.name)
+			assert.Empty(t, p.tags)
+		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"metrics", "query", "my.metric", "--tags", "env:prod,service:web", "-j"},
+		queryMetrics,
+		func(p *cliParams) {
+			assert.Equal(t, "my.metric", p.name)
+			assert.Equal(t, []string{"env:prod", "service:web"}, p.tags)
+			assert.True(t, p.jsonOutput)
+		})
+}
+
+func TestPrintFlushedMetrics(t *testing.T) {
+	var b strings.Builder
+	printFlushedMetrics(&b, "my.metric", nil)
+	assert.Equal(t, "No series or sketch of 'my.metric' was flushed recently.\n", b.String())
+
+	b.Reset()
+	printFlushedMetrics(&b, "my.metric", []aggregator.FlushedMetric{
+		{
+			Name:      "my.metric",
+			Type:      "gauge",
+			Tags:      []string{"env:prod", "service:web"},
+			Host:      "myhost",
+			Points:    []aggregator.FlushedPoint{{Ts: 1700000000, Value: 12.5}},
+			FlushTime: 1700000015,
+		},
+		{
+			Name:      "my.metric",
+			Type:      "sketch",
+			Host:      "myhost",
+			Interval:  10,
+			Sketches:  []aggregator.FlushedSketchPoint{{Ts: 1700000000, Count: 2, Sum: 3, Min: 1, Max: 2, Avg: 1.5}},
+			FlushTime: 1700000015,
+		},
+	})
+	assert.Equal(t, `my.metric (gauge) flushed at 2023-11-14T22:13:35Z
+  Host: myhost
+  Tags: env:prod, service:web
+  1700000000  12.5
+my.metric (sketch) flushed at 2023-11-14T22:13:35Z
+  Host: myhost
+  Interval: 10s
+  1700000000  count:2 sum:3 min:1 max:2 avg:1.5
+`, b.String())
+}
@@ -111,6 +111,11 @@ func createAgentDemultiplexerOptions(config config.Component, params Params) agg
 		options.EnableNoAggregationPipeline = config.GetBool("dogstatsd_no_aggregation_pipeline")
 	}
 
+	if config.GetBool("aggregator_flush_history.enabled") {
+		options.FlushHistoryRetention = config.GetDuration("aggregator_flush_history.retention")
+		options.FlushHistoryMaxSeries = config.GetInt("aggregator_flush_history.max_series")
+	}
+
 	// Override FlushInterval only if flushInterval is set by the user
 	if v, ok := params.flushInterval.Get(); ok {
 		options.FlushInterval = v
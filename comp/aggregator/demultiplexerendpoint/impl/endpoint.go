@@ -3,12 +3,13 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2023-present Datadog, Inc.
 
-// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump API endpoint that can register via Fx value groups.
+// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump and /metrics-query API endpoints that can register via Fx value groups.
 package demultiplexerendpointimpl
 
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"path"
@@ -19,6 +20,7 @@ import (
 	api "github.com/DataDog/datadog-agent/comp/api/api/def"
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 )
 
@@ -37,7 +39,8 @@ type demultiplexerEndpoint struct {
 
 // Provides defines the output of the demultiplexerendpoint component
 type Provides struct {
-	Endpoint api.AgentEndpointProvider
+	Endpoint      api.AgentEndpointProvider
+	QueryEndpoint api.AgentEndpointProvider
 }
 
 // NewComponent creates a new demultiplexerendpoint component
@@ -49,7 +52,8 @@ func NewComponent(reqs Requires) Provides {
 	}
 
 	return Provides{
-		Endpoint: api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		Endpoint:      api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		QueryEndpoint: api.NewAgentEndpointProvider(endpoint.queryFlushedMetrics, "/metrics-query", "GET"),
 	}
 }
 
@@ -90,3 +94,34 @@ func (demuxendpoint demultiplexerEndpoint) writeDogstatsdContexts() (string, err
 
 	return path, nil
 }
+
+// queryFlushedMetrics writes the recently flushed series and sketches matching
+// the `name` and `tag` query parameters.
+func (demuxendpoint demultiplexerEndpoint) queryFlushedMetrics(w http.ResponseWriter, r *http.Request) {
+	query := aggregator.FlushedMetricsQuery{
+		Name: r.URL.Query().Get("name"),
+		Tags: r.URL.Query()["tag"],
+	}
+	if query.Name == "" {
+		httputils.SetJSONError(w, errors.New("the 'name' parameter is required"), 400)
+		return
+	}
+
+	flushed, err := demuxendpoint.demux.QueryFlushedMetrics(query)
+	if errors.Is(err, aggregator.ErrFlushHistoryDisabled) {
+		httputils.SetJSONError(w, err, 400)
+		return
+	} else if err != nil {
+		httputils.SetJSONError(w, demuxendpoint.log.Errorf("Failed to query the flushed metrics: %v", err), 500)
+		return
+	}
+
+	resp, err := json.Marshal(flushed)
+	if err != nil {
+		httputils.SetJSONError(w, demuxendpoint.log.Errorf("Failed to serialize response: %v", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
@@ -24,13 +24,15 @@ func (m *mock) handlerFunc(w http.ResponseWriter, _ *http.Request) {
 
 // Provides is the mock component output
 type Provides struct {
-	Endpoint api.AgentEndpointProvider
+	Endpoint      api.AgentEndpointProvider
+	QueryEndpoint api.AgentEndpointProvider
 }
 
 // NewMock creates a new mock component
 func NewMock() Provides {
 	instance := &mock{}
 	return Provides{
-		Endpoint: api.NewAgentEndpointProvider(instance.handlerFunc, "/dogstatsd-contexts-dump", "POST"),
+		Endpoint:      api.NewAgentEndpointProvider(instance.handlerFunc, "/dogstatsd-contexts-dump", "POST"),
+		QueryEndpoint: api.NewAgentEndpointProvider(instance.handlerFunc, "/metrics-query", "GET"),
 	}
 }
@@ -152,6 +152,11 @@ func TestSetupHandlers(t *testing.T) {
 			method:   "POST",
 			wantCode: 200,
 		},
+		{
+			route:    "/metrics-query",
+			method:   "GET",
+			wantCode: 200,
+		},
 		{
 			route:    "/dogstatsd-stats",
 			method:   "GET",
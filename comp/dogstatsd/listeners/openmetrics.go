@@ -0,0 +1,496 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/pidmap"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/optional"
+	"github.com/DataDog/datadog-agent/pkg/util/prometheus"
+)
+
+var (
+	openMetricsExpvars        = expvar.NewMap("dogstatsd-openmetrics")
+	openMetricsRequests       = expvar.Int{}
+	openMetricsRequestErrors  = expvar.Int{}
+	openMetricsSamples        = expvar.Int{}
+	openMetricsDroppedSamples = expvar.Int{}
+)
+
+func init() {
+	openMetricsExpvars.Set("Requests", &openMetricsRequests)
+	openMetricsExpvars.Set("RequestErrors", &openMetricsRequestErrors)
+	openMetricsExpvars.Set("Samples", &openMetricsSamples)
+	openMetricsExpvars.Set("DroppedSamples", &openMetricsDroppedSamples)
+}
+
+const (
+	// openMetricsPushPath is the prefix of the pushgateway-compatible route,
+	// grouping labels are read from the rest of the path:
+	// /metrics/job/<JOB>{/<LABEL_NAME>/<LABEL_VALUE>}
+	openMetricsPushPath = "/metrics/job/"
+	// openMetricsPath accepts payloads without grouping labels
+	openMetricsPath = "/metrics"
+
+	openMetricsShutdownTimeout = 5 * time.Second
+)
+
+type openMetricsConnKey struct{}
+
+// cumulativeValue is the last value seen for a cumulative OpenMetrics series.
+type cumulativeValue struct {
+	value    float64
+	lastSeen time.Time
+}
+
+// OpenMetricsListener implements the StatsdListener interface for
+// Prometheus/OpenMetrics text payloads pushed over HTTP. It listens either on
+// a TCP port or on a Unix socket, converts every sample to the DogStatsD
+// format and sends back packets ready to be processed, so that these samples
+// go through the same mapping, enrichment and blocklist as regular traffic.
+// Origin detection is only implemented for the Unix socket transport.
+type OpenMetricsListener struct {
+	listener                net.Listener
+	server                  *http.Server
+	transport               string
+	packetsBuffer           *packets.Buffer
+	sharedPacketPoolManager *packets.PoolManager[packets.Packet]
+	originDetection         bool
+	wmeta                   optional.Option[workloadmeta.Component]
+	pidMap                  pidmap.Component
+	maxBodySize             int64
+	telemetryStore          *TelemetryStore
+
+	// OpenMetrics counters, histograms and summaries are cumulative while
+	// DogStatsD counts are deltas: the last value of every cumulative series
+	// is kept to compute the difference between two pushes.
+	cumulativeLock   sync.Mutex
+	cumulativeValues map[string]*cumulativeValue
+	cumulativeExpiry time.Duration
+
+	listenWg sync.WaitGroup
+	stop     chan struct{}
+}
+
+// NewOpenMetricsListener returns an idle OpenMetrics Statsd listener. The
+// transport is either "tcp", listening on `dogstatsd_openmetrics_port`, or
+// "unix", listening on `dogstatsd_openmetrics_socket`.
+func NewOpenMetricsListener(transport string, packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, wmeta optional.Option[workloadmeta.Component], pidMap pidmap.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*OpenMetricsListener, error) {
+	var conn net.Listener
+	var err error
+
+	switch transport {
+	case "tcp":
+		var url string
+		port := cfg.GetString("dogstatsd_openmetrics_port")
+		if port == RandomPortName {
+			port = "0"
+		}
+		if cfg.GetBool("dogstatsd_non_local_traffic") {
+			// Listen to all network interfaces
+			url = fmt.Sprintf(":%s", port)
+		} else {
+			url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+		}
+		conn, err = net.Listen("tcp", url)
+		if err != nil {
+			return nil, fmt.Errorf("can't listen: %s", err)
+		}
+	case "unix":
+		socketPath := cfg.GetString("dogstatsd_openmetrics_socket")
+		address, err := setupSocketBeforeListen(socketPath, transport)
+		if err != nil {
+			return nil, err
+		}
+		conn, err = net.ListenUnix(transport, address)
+		if err != nil {
+			return nil, fmt.Errorf("can't listen: %s", err)
+		}
+		if err = setSocketWriteOnly(socketPath); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported transport for the openmetrics listener: %q", transport)
+	}
+
+	listenerID := "openmetrics-" + transport
+	packetsBuffer := packets.NewBuffer(
+		uint(cfg.GetInt("dogstatsd_packet_buffer_size")),
+		cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout"),
+		packetOut,
+		listenerID,
+		packetsTelemetryStore,
+	)
+
+	listener := &OpenMetricsListener{
+		listener:                conn,
+		transport:               transport,
+		packetsBuffer:           packetsBuffer,
+		sharedPacketPoolManager: sharedPacketPoolManager,
+		originDetection:         transport == "unix" && cfg.GetBool("dogstatsd_origin_detection"),
+		wmeta:                   wmeta,
+		pidMap:                  pidMap,
+		maxBodySize:             int64(cfg.GetInt("dogstatsd_openmetrics_max_body_size")),
+		telemetryStore:          telemetryStore,
+		cumulativeValues:        make(map[string]*cumulativeValue),
+		cumulativeExpiry:        time.Duration(cfg.GetInt("dogstatsd_openmetrics_counter_expiry_seconds")) * time.Second,
+		stop:                    make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(openMetricsPath, listener.handlePush)
+	mux.HandleFunc(openMetricsPushPath, listener.handlePush)
+	listener.server = &http.Server{
+		Handler: mux,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, openMetricsConnKey{}, c)
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Debugf("dogstatsd-openmetrics: %s successfully initialized", conn.Addr())
+	return listener, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *OpenMetricsListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *OpenMetricsListener) Listen() {
+	l.listenWg.Add(2)
+	go func() {
+		defer l.listenWg.Done()
+		log.Infof("dogstatsd-openmetrics: starting to listen on %s", l.listener.Addr())
+		if err := l.server.Serve(l.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("dogstatsd-openmetrics: error serving: %v", err)
+		}
+	}()
+	go func() {
+		defer l.listenWg.Done()
+		l.expireLoop()
+	}()
+}
+
+// Stop closes the listener and stops serving requests
+func (l *OpenMetricsListener) Stop() {
+	close(l.stop)
+	ctx, cancel := context.WithTimeout(context.Background(), openMetricsShutdownTimeout)
+	defer cancel()
+	if err := l.server.Shutdown(ctx); err != nil {
+		log.Warnf("dogstatsd-openmetrics: error shutting down: %v", err)
+	}
+	l.listenWg.Wait()
+	l.packetsBuffer.Close()
+}
+
+func (l *OpenMetricsListener) expireLoop() {
+	if l.cumulativeExpiry <= 0 {
+		<-l.stop
+		return
+	}
+	ticker := time.NewTicker(l.cumulativeExpiry)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			l.cumulativeLock.Lock()
+			for key, v := range l.cumulativeValues {
+				if now.Sub(v.lastSeen) > l.cumulativeExpiry {
+					delete(l.cumulativeValues, key)
+				}
+			}
+			l.cumulativeLock.Unlock()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *OpenMetricsListener) handlePush(w http.ResponseWriter, r *http.Request) {
+	openMetricsRequests.Add(1)
+
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		l.requestError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	groupingTags, err := parseGroupingLabels(r.URL.Path)
+	if err != nil {
+		l.requestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, l.maxBodySize)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			l.requestError(w, http.StatusBadRequest, err)
+			return
+		}
+		defer gz.Close()
+		// read one more byte than allowed to detect the oversized decompressed bodies
+		body = io.LimitReader(gz, l.maxBodySize+1)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			l.requestError(w, http.StatusRequestEntityTooLarge, err)
+		} else {
+			l.requestError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+	if int64(len(data)) > l.maxBodySize {
+		l.requestError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("decompressed body larger than %d bytes", l.maxBodySize))
+		return
+	}
+
+	families, err := prometheus.ParseMetrics(data)
+	if err != nil {
+		l.requestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	origin := l.getOrigin(r)
+	messages, dropped := l.convert(families, groupingTags, origin, time.Now())
+	l.send(messages, origin)
+
+	openMetricsSamples.Add(int64(len(messages)))
+	openMetricsDroppedSamples.Add(int64(dropped))
+	l.telemetryStore.tlmOpenMetricsSamples.Add(float64(len(messages)), l.transport, "ok")
+	l.telemetryStore.tlmOpenMetricsSamples.Add(float64(dropped), l.transport, "dropped")
+	l.telemetryStore.tlmOpenMetricsRequests.Inc(l.transport, "ok")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (l *OpenMetricsListener) requestError(w http.ResponseWriter, status int, err error) {
+	log.Debugf("dogstatsd-openmetrics: error handling request: %v", err)
+	openMetricsRequestErrors.Add(1)
+	l.telemetryStore.tlmOpenMetricsRequests.Inc(l.transport, "error")
+	http.Error(w, err.Error(), status)
+}
+
+// getOrigin returns the origin of the request when origin detection is
+// enabled and the request was received on the Unix socket.
+func (l *OpenMetricsListener) getOrigin(r *http.Request) string {
+	if !l.originDetection {
+		return packets.NoOrigin
+	}
+	conn, ok := r.Context().Value(openMetricsConnKey{}).(*net.UnixConn)
+	if !ok {
+		return packets.NoOrigin
+	}
+	_, origin, err := processUDSPeerOrigin(conn, l.wmeta, l.pidMap)
+	if err != nil {
+		log.Warnf("dogstatsd-openmetrics: error processing origin, data will not be tagged : %v", err)
+		return packets.NoOrigin
+	}
+	return origin
+}
+
+// send packs the messages into packets from the shared pool and hands them
+// over to the packets buffer.
+func (l *OpenMetricsListener) send(messages [][]byte, origin string) {
+	packet := l.sharedPacketPoolManager.Get()
+	n := 0
+	for _, message := range messages {
+		if len(message) > len(packet.Buffer) {
+			openMetricsDroppedSamples.Add(1)
+			l.telemetryStore.tlmOpenMetricsSamples.Inc(l.transport, "dropped")
+			continue
+		}
+		if n > 0 && n+1+len(message) > len(packet.Buffer) {
+			l.appendPacket(packet, n, origin)
+			packet = l.sharedPacketPoolManager.Get()
+			n = 0
+		}
+		if n > 0 {
+			packet.Buffer[n] = '\n'
+			n++
+		}
+		n += copy(packet.Buffer[n:], message)
+	}
+	if n == 0 {
+		l.sharedPacketPoolManager.Put(packet)
+		return
+	}
+	l.appendPacket(packet, n, origin)
+}
+
+func (l *OpenMetricsListener) appendPacket(packet *packets.Packet, n int, origin string) {
+	packet.Contents = packet.Buffer[:n]
+	packet.Origin = origin
+	packet.Source = packets.OpenMetrics
+	l.packetsBuffer.Append(packet)
+}
+
+// convert turns the metric families into DogStatsD messages. It returns the
+// messages and the number of samples that could not be converted.
+func (l *OpenMetricsListener) convert(families []*prometheus.MetricFamily, groupingTags []string, origin string, now time.Time) ([][]byte, int) {
+	var messages [][]byte
+	dropped := 0
+
+	l.cumulativeLock.Lock()
+	defer l.cumulativeLock.Unlock()
+
+	for _, family := range families {
+		for _, sample := range family.Samples {
+			value := float64(sample.Value)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				dropped++
+				continue
+			}
+
+			name := sanitizeOpenMetricsName(string(sample.Metric["__name__"]))
+			tags := make([]string, 0, len(sample.Metric)-1+len(groupingTags))
+			tags = append(tags, groupingTags...)
+			for labelName, labelValue := range sample.Metric {
+				if labelName == "__name__" {
+					continue
+				}
+				tags = append(tags, sanitizeOpenMetricsTag(string(labelName))+":"+sanitizeOpenMetricsTag(string(labelValue)))
+			}
+			sort.Strings(tags)
+
+			metricType := "g"
+			if isCumulative(family, name) {
+				key := origin + "|" + name + "|" + strings.Join(tags, ",")
+				prev, found := l.cumulativeValues[key]
+				if !found {
+					// first value of the series only sets the baseline
+					l.cumulativeValues[key] = &cumulativeValue{value: value, lastSeen: now}
+					continue
+				}
+				delta := value - prev.value
+				if delta < 0 {
+					// the counter was reset, it restarted from zero
+					delta = value
+				}
+				prev.value = value
+				prev.lastSeen = now
+				value = delta
+				metricType = "c"
+			}
+
+			messages = append(messages, formatDogStatsDMessage(name, value, metricType, tags))
+		}
+	}
+	return messages, dropped
+}
+
+// isCumulative returns true if the sample is monotonic: every sample of
+// counters and histograms, and the `_sum` and `_count` samples of summaries.
+func isCumulative(family *prometheus.MetricFamily, sampleName string) bool {
+	switch family.Type {
+	case "COUNTER", "HISTOGRAM":
+		return true
+	case "SUMMARY":
+		return sampleName != sanitizeOpenMetricsName(family.Name)
+	}
+	return false
+}
+
+func formatDogStatsDMessage(name string, value float64, metricType string, tags []string) []byte {
+	message := make([]byte, 0, len(name)+32+len(tags)*16)
+	message = append(message, name...)
+	message = append(message, ':')
+	message = strconv.AppendFloat(message, value, 'f', -1, 64)
+	message = append(message, '|')
+	message = append(message, metricType...)
+	if len(tags) > 0 {
+		message = append(message, "|#"...)
+		for i, tag := range tags {
+			if i > 0 {
+				message = append(message, ',')
+			}
+			message = append(message, tag...)
+		}
+	}
+	return message
+}
+
+// sanitizeOpenMetricsName replaces the characters of a metric name which are
+// meaningful in the DogStatsD protocol.
+func sanitizeOpenMetricsName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', '\n':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// sanitizeOpenMetricsTag replaces the characters of a tag which are
+// meaningful in the DogStatsD protocol.
+func sanitizeOpenMetricsTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '\n':
+			return '_'
+		}
+		return r
+	}, tag)
+}
+
+// parseGroupingLabels returns the grouping labels of a pushgateway-style
+// path as tags. Values suffixed with `@base64` are URL-safe base64 encoded.
+func parseGroupingLabels(path string) ([]string, error) {
+	if path == openMetricsPath || path == openMetricsPath+"/" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, openMetricsPushPath) {
+		return nil, fmt.Errorf("unsupported path %q", path)
+	}
+
+	parts := strings.Split(strings.Trim(path[len(openMetricsPath):], "/"), "/")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid grouping labels in path %q", path)
+	}
+
+	tags := make([]string, 0, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		name, value := parts[i], parts[i+1]
+		if strings.HasSuffix(name, "@base64") {
+			name = strings.TrimSuffix(name, "@base64")
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 value for label %q: %v", name, err)
+			}
+			value = string(decoded)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty label name in path %q", path)
+		}
+		tags = append(tags, sanitizeOpenMetricsTag(name)+":"+sanitizeOpenMetricsTag(value))
+	}
+	return tags, nil
+}
@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/util/optional"
+	"github.com/DataDog/datadog-agent/pkg/util/prometheus"
+)
+
+func newOpenMetricsListener(t *testing.T, packetChannel chan packets.Packets) *OpenMetricsListener {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_openmetrics_port":            RandomPortName,
+		"dogstatsd_packet_buffer_flush_timeout": 10 * time.Millisecond,
+	})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	l, err := NewOpenMetricsListener("tcp", packetChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, optional.NewNoneOption[workloadmeta.Component](), deps.PidMap, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	return l
+}
+
+func TestOpenMetricsParseGroupingLabels(t *testing.T) {
+	tags, err := parseGroupingLabels("/metrics")
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+
+	tags, err = parseGroupingLabels("/metrics/job/batch/instance/host1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"job:batch", "instance:host1"}, tags)
+
+	tags, err = parseGroupingLabels("/metrics/job/batch/path@base64/L3Zhci90bXA")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"job:batch", "path:/var/tmp"}, tags)
+
+	_, err = parseGroupingLabels("/metrics/job/batch/instance")
+	assert.Error(t, err)
+
+	_, err = parseGroupingLabels("/foo")
+	assert.Error(t, err)
+}
+
+func TestOpenMetricsConvert(t *testing.T) {
+	l := &OpenMetricsListener{cumulativeValues: make(map[string]*cumulativeValue)}
+
+	payload := func(counter string) []*prometheus.MetricFamily {
+		families, err := prometheus.ParseMetrics([]byte(`# TYPE queue_size gauge
+queue_size{queue="a,b"} 12
+# TYPE jobs_total counter
+jobs_total{status="ok"} ` + counter + `
+# TYPE job:duration summary
+job:duration{quantile="0.5"} 3
+job:duration_sum 10
+job:duration_count 4
+`))
+		require.NoError(t, err)
+		return families
+	}
+
+	now := time.Now()
+	messages, dropped := l.convert(payload("5"), []string{"job:batch"}, "", now)
+	assert.Equal(t, 0, dropped)
+	assert.ElementsMatch(t, []string{
+		"queue_size:12|g|#job:batch,queue:a_b",
+		"job_duration:3|g|#job:batch,quantile:0.5",
+	}, toStrings(messages))
+
+	messages, _ = l.convert(payload("8"), []string{"job:batch"}, "", now.Add(time.Second))
+	assert.ElementsMatch(t, []string{
+		"queue_size:12|g|#job:batch,queue:a_b",
+		"jobs_total:3|c|#job:batch,status:ok",
+		"job_duration:3|g|#job:batch,quantile:0.5",
+		"job_duration_sum:0|c|#job:batch",
+		"job_duration_count:0|c|#job:batch",
+	}, toStrings(messages))
+
+	// counter reset
+	messages, _ = l.convert(payload("2"), []string{"job:batch"}, "", now.Add(2*time.Second))
+	assert.Contains(t, toStrings(messages), "jobs_total:2|c|#job:batch,status:ok")
+}
+
+func TestOpenMetricsListenerPush(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 10)
+	l := newOpenMetricsListener(t, packetChannel)
+	l.Listen()
+	defer l.Stop()
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	_, err := gz.Write([]byte("# TYPE temperature gauge\ntemperature{room=\"kitchen\"} 21.5\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req, err := http.NewRequest(http.MethodPut, "http://"+l.LocalAddr()+"/metrics/job/sensors", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case pkts := <-packetChannel:
+		require.Len(t, pkts, 1)
+		assert.Equal(t, "temperature:21.5|g|#job:sensors,room:kitchen", string(pkts[0].Contents))
+		assert.Equal(t, packets.OpenMetrics, pkts[0].Source)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+
+	resp, err = http.Get("http://" + l.LocalAddr() + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post("http://"+l.LocalAddr()+"/metrics", "text/plain", strings.NewReader("not { a valid payload"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOpenMetricsListenerPushTooLarge(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 10)
+	l := newOpenMetricsListener(t, packetChannel)
+	payload := "# TYPE temperature gauge\n" + strings.Repeat("temperature{room=\"kitchen\"} 21.5\n", 100)
+	// the compressed body fits, but not the decompressed one
+	l.maxBodySize = int64(len(payload) - 1)
+	l.Listen()
+	defer l.Stop()
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	_, err := gz.Write([]byte(payload))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.Less(t, int64(body.Len()), l.maxBodySize)
+
+	req, err := http.NewRequest(http.MethodPut, "http://"+l.LocalAddr()+"/metrics/job/sensors", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	select {
+	case pkts := <-packetChannel:
+		assert.FailNow(t, "unexpected packets", "%v", pkts)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func toStrings(messages [][]byte) []string {
+	res := make([]string, 0, len(messages))
+	for _, m := range messages {
+		res = append(res, string(m))
+	}
+	return res
+}
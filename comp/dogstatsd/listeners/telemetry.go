@@ -23,6 +23,9 @@ type TelemetryStore struct {
 	tlmUDSOriginDetectionError telemetry.Counter
 	tlmUDSPacketsBytes         telemetry.Counter
 	tlmUDSConnections          telemetry.Gauge
+	// OpenMetrics
+	tlmOpenMetricsRequests telemetry.Counter
+	tlmOpenMetricsSamples  telemetry.Counter
 
 	tlmListener telemetry.Histogram
 }
@@ -46,6 +49,10 @@ func NewTelemetryStore(buckets []float64, telemetrycomp telemetry.Component) *Te
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS packets bytes"),
 		tlmUDSConnections: telemetrycomp.NewGauge("dogstatsd", "uds_connections",
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count"),
+		tlmOpenMetricsRequests: telemetrycomp.NewCounter("dogstatsd", "openmetrics_requests",
+			[]string{"transport", "state"}, "Dogstatsd OpenMetrics requests count"),
+		tlmOpenMetricsSamples: telemetrycomp.NewCounter("dogstatsd", "openmetrics_samples",
+			[]string{"transport", "state"}, "Dogstatsd OpenMetrics samples count"),
 		tlmListener: telemetrycomp.NewHistogram(
 			"dogstatsd",
 			"listener_read_latency",
@@ -139,3 +139,37 @@ func entityForPID(pid int32, capture bool, wmeta optional.Option[workloadmeta.Co
 
 	return types.NewEntityID(types.ContainerID, cID).String(), nil
 }
+
+// processUDSPeerOrigin reads the peer credentials of a connected stream
+// socket to determine its origin. It is used by connection-oriented
+// listeners (like the OpenMetrics one) that cannot rely on ancillary data.
+func processUDSPeerOrigin(conn *net.UnixConn, wmeta optional.Option[workloadmeta.Component], state pidmap.Component) (int, string, error) {
+	rawconn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, packets.NoOrigin, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = rawconn.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, packets.NoOrigin, err
+	}
+	if credErr != nil {
+		return 0, packets.NoOrigin, credErr
+	}
+
+	if cred.Pid == 0 {
+		return 0, packets.NoOrigin, fmt.Errorf("matched PID for the process is 0, it belongs " +
+			"probably to another namespace. Is the agent in host PID mode?")
+	}
+
+	entity, err := getEntityForPID(cred.Pid, false, wmeta, state)
+	if err != nil {
+		return int(cred.Pid), packets.NoOrigin, err
+	}
+
+	return int(cred.Pid), entity, nil
+}
@@ -38,3 +38,8 @@ func enableUDSPassCred(_ *net.UnixConn) error {
 func processUDSOrigin(_ []byte, _ optional.Option[workloadmeta.Component], _ pidmap.Component) (int, string, error) {
 	return 0, packets.NoOrigin, ErrLinuxOnly
 }
+
+// processUDSPeerOrigin returns a "not implemented" error on non-linux hosts
+func processUDSPeerOrigin(_ *net.UnixConn, _ optional.Option[workloadmeta.Component], _ pidmap.Component) (int, string, error) {
+	return 0, packets.NoOrigin, ErrLinuxOnly
+}
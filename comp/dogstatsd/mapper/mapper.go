@@ -36,6 +36,7 @@ type MappingProfileConfig struct {
 	Name     string                `mapstructure:"name" json:"name" yaml:"name"`
 	Prefix   string                `mapstructure:"prefix" json:"prefix" yaml:"prefix"`
 	Mappings []MetricMappingConfig `mapstructure:"mappings" json:"mappings" yaml:"mappings"`
+	Rules    []MetricRuleConfig    `mapstructure:"rules" json:"rules" yaml:"rules"`
 }
 
 // MetricMapping represent one mapping rule
@@ -48,8 +49,9 @@ type MetricMappingConfig struct {
 
 // MetricMapper contains mappings and cache instance
 type MetricMapper struct {
-	Profiles []MappingProfile
-	cache    *mapperCache
+	Profiles   []MappingProfile
+	cache      *mapperCache
+	rulesCache *rulesCache
 }
 
 // MappingProfile represent a group of mappings
@@ -57,6 +59,7 @@ type MappingProfile struct {
 	Name     string
 	Prefix   string
 	Mappings []*MetricMapping
+	Rules    []*MetricRule
 }
 
 // MetricMapping represent one mapping rule
@@ -87,6 +90,7 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			Name:     configProfile.Name,
 			Prefix:   configProfile.Prefix,
 			Mappings: make([]*MetricMapping, 0, len(configProfile.Mappings)),
+			Rules:    make([]*MetricRule, 0, len(configProfile.Rules)),
 		}
 		for i, currentMapping := range configProfile.Mappings {
 			matchType := currentMapping.MatchType
@@ -108,13 +112,24 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			}
 			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
 		}
+		for i, currentRule := range configProfile.Rules {
+			rule, err := newMetricRule(profile.Name, i, currentRule)
+			if err != nil {
+				return nil, err
+			}
+			profile.Rules = append(profile.Rules, rule)
+		}
 		profiles = append(profiles, profile)
 	}
 	cache, err := newMapperCache(cacheSize)
 	if err != nil {
 		return nil, err
 	}
-	return &MetricMapper{Profiles: profiles, cache: cache}, nil
+	rulesCache, err := newRulesCache(cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricMapper{Profiles: profiles, cache: cache, rulesCache: rulesCache}, nil
 }
 
 func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
@@ -177,3 +192,43 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 	}
 	return nil
 }
+
+// ApplyRules applies the rules of every profile matching the metric name on
+// the tags, in order. Rules are evaluated against the name of the metric once
+// mapped. It returns the updated tags and false if the metric sample has to
+// be dropped.
+func (m *MetricMapper) ApplyRules(metricName string, tags []string) ([]string, bool) {
+	rules, cached := m.rulesCache.get(metricName)
+	if !cached {
+		for _, profile := range m.Profiles {
+			if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
+				continue
+			}
+			for _, rule := range profile.Rules {
+				if rule.regex.MatchString(metricName) {
+					rules = append(rules, rule)
+				}
+			}
+		}
+		m.rulesCache.add(metricName, rules)
+	}
+
+	keep := true
+	for _, rule := range rules {
+		tags, keep = rule.apply(tags)
+		if !keep {
+			return tags, false
+		}
+	}
+	return tags, true
+}
+
+// HasRules returns true if at least one profile defines rules.
+func (m *MetricMapper) HasRules() bool {
+	for _, profile := range m.Profiles {
+		if len(profile.Rules) > 0 {
+			return true
+		}
+	}
+	return false
+}
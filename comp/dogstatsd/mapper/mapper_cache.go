@@ -36,3 +36,27 @@ func (m *mapperCache) get(metricName string) (*MapResult, bool) {
 func (m *mapperCache) add(metricName string, mapResult *MapResult) {
 	m.cache.Add(metricName, mapResult)
 }
+
+type rulesCache struct {
+	cache *lru.Cache[string, []*MetricRule]
+}
+
+// newRulesCache creates a new rulesCache
+func newRulesCache(size int) (*rulesCache, error) {
+	cache, err := lru.New[string, []*MetricRule](size)
+	if err != nil {
+		return &rulesCache{}, err
+	}
+	return &rulesCache{cache: cache}, nil
+}
+
+// get returns the rules applying to a metric name and a boolean indicating
+// if the metric name was found in the cache
+func (m *rulesCache) get(metricName string) ([]*MetricRule, bool) {
+	return m.cache.Get(metricName)
+}
+
+// add adds the rules applying to a metric name to the cache
+func (m *rulesCache) add(metricName string, rules []*MetricRule) {
+	m.cache.Add(metricName, rules)
+}
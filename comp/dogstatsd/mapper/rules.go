@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mapper
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ruleActionDrop drops the whole metric sample
+	ruleActionDrop = "drop"
+	// ruleActionRenameTag renames the key of a tag, keeping its value
+	ruleActionRenameTag = "rename_tag"
+	// ruleActionHashTag replaces the value of a tag by its hash
+	ruleActionHashTag = "hash_tag"
+	// ruleActionTruncateTag truncates the value of a tag to a maximum length
+	ruleActionTruncateTag = "truncate_tag"
+	// ruleActionAggregateTag removes a tag so that the samples are aggregated without it
+	ruleActionAggregateTag = "aggregate_tag"
+)
+
+// MetricRuleConfig represent one rule applied on the metric samples
+type MetricRuleConfig struct {
+	Match     string            `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	Action    string            `mapstructure:"action" json:"action" yaml:"action"`
+	Tag       string            `mapstructure:"tag" json:"tag" yaml:"tag"`
+	NewTag    string            `mapstructure:"new_tag" json:"new_tag" yaml:"new_tag"`
+	Length    int               `mapstructure:"length" json:"length" yaml:"length"`
+}
+
+// MetricRule represent one rule applied on the metric samples
+type MetricRule struct {
+	action string
+	regex  *regexp.Regexp
+	// conditions on the tags of the sample, all must match for the rule to apply
+	conditions map[string]*regexp.Regexp
+	tag        string
+	newTag     string
+	length     int
+}
+
+func newMetricRule(profileName string, index int, config MetricRuleConfig) (*MetricRule, error) {
+	matchType := config.MatchType
+	if matchType == "" {
+		matchType = matchTypeWildcard
+	}
+	if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+		return nil, fmt.Errorf("profile: %s, rule num %d: invalid match type, must be `wildcard` or `regex`", profileName, index)
+	}
+	if config.Match == "" {
+		return nil, fmt.Errorf("profile: %s, rule num %d: match is required", profileName, index)
+	}
+	regex, err := buildRegex(config.Match, matchType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Action {
+	case ruleActionDrop:
+	case ruleActionRenameTag:
+		if config.Tag == "" || config.NewTag == "" {
+			return nil, fmt.Errorf("profile: %s, rule num %d: tag and new_tag are required for the `%s` action", profileName, index, config.Action)
+		}
+	case ruleActionHashTag, ruleActionAggregateTag:
+		if config.Tag == "" {
+			return nil, fmt.Errorf("profile: %s, rule num %d: tag is required for the `%s` action", profileName, index, config.Action)
+		}
+	case ruleActionTruncateTag:
+		if config.Tag == "" || config.Length <= 0 {
+			return nil, fmt.Errorf("profile: %s, rule num %d: tag and a positive length are required for the `%s` action", profileName, index, config.Action)
+		}
+	default:
+		return nil, fmt.Errorf("profile: %s, rule num %d: invalid action `%s`", profileName, index, config.Action)
+	}
+
+	conditions := make(map[string]*regexp.Regexp, len(config.Tags))
+	for tagKey, valueRe := range config.Tags {
+		re, err := regexp.Compile("^" + valueRe + "$")
+		if err != nil {
+			return nil, fmt.Errorf("profile: %s, rule num %d: invalid condition on tag `%s`: %v", profileName, index, tagKey, err)
+		}
+		conditions[tagKey] = re
+	}
+
+	return &MetricRule{
+		action:     config.Action,
+		regex:      regex,
+		conditions: conditions,
+		tag:        config.Tag,
+		newTag:     config.NewTag,
+		length:     config.Length,
+	}, nil
+}
+
+// matchTags returns true if every condition of the rule is met by the tags.
+func (r *MetricRule) matchTags(tags []string) bool {
+	for tagKey, re := range r.conditions {
+		matched := false
+		for _, tag := range tags {
+			key, value := splitTag(tag)
+			if key == tagKey && re.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// apply applies the rule on the tags, which are modified in place. It returns
+// the tags and false if the metric sample has to be dropped.
+func (r *MetricRule) apply(tags []string) ([]string, bool) {
+	if !r.matchTags(tags) {
+		return tags, true
+	}
+
+	if r.action == ruleActionDrop {
+		return tags, false
+	}
+
+	n := 0
+	for _, tag := range tags {
+		key, value := splitTag(tag)
+		if key != r.tag {
+			tags[n] = tag
+			n++
+			continue
+		}
+		switch r.action {
+		case ruleActionRenameTag:
+			tags[n] = joinTag(r.newTag, value, tag)
+		case ruleActionHashTag:
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(value))
+			tags[n] = fmt.Sprintf("%s:%016x", key, h.Sum64())
+		case ruleActionTruncateTag:
+			if len(value) > r.length {
+				value = value[:r.length]
+			}
+			tags[n] = joinTag(key, value, tag)
+		case ruleActionAggregateTag:
+			continue
+		}
+		n++
+	}
+	return tags[:n], true
+}
+
+// splitTag returns the key and the value of a tag, the value is empty if the
+// tag has none.
+func splitTag(tag string) (string, string) {
+	key, value, _ := strings.Cut(tag, ":")
+	return key, value
+}
+
+// joinTag builds a tag from a key and a value, keeping tags without value as is.
+func joinTag(key string, value string, original string) string {
+	if !strings.Contains(original, ":") {
+		return key
+	}
+	return key + ":" + value
+}
@@ -0,0 +1,264 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		config       string
+		metricName   string
+		tags         []string
+		expectedTags []string
+		expectedKeep bool
+	}{
+		{
+			name: "Drop",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.debug.*"
+        action: drop
+`,
+			metricName:   "test.debug.foo",
+			tags:         []string{"env:prod"},
+			expectedTags: []string{"env:prod"},
+			expectedKeep: false,
+		},
+		{
+			name: "Drop with tag condition not met",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: drop
+        tags:
+          env: "staging|dev"
+`,
+			metricName:   "test.foo",
+			tags:         []string{"env:prod"},
+			expectedTags: []string{"env:prod"},
+			expectedKeep: true,
+		},
+		{
+			name: "Rename tag",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: rename_tag
+        tag: svc
+        new_tag: service
+`,
+			metricName:   "test.foo",
+			tags:         []string{"svc:web", "env:prod", "svc"},
+			expectedTags: []string{"service:web", "env:prod", "service"},
+			expectedKeep: true,
+		},
+		{
+			name: "Hash tag",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: hash_tag
+        tag: user
+`,
+			metricName:   "test.foo",
+			tags:         []string{"user:john.doe@example.com", "env:prod"},
+			expectedTags: []string{"user:0d91078971584543", "env:prod"},
+			expectedKeep: true,
+		},
+		{
+			name: "Truncate tag",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: 'test\.(foo|bar)'
+        match_type: regex
+        action: truncate_tag
+        tag: path
+        length: 4
+`,
+			metricName:   "test.bar",
+			tags:         []string{"path:/api/v1/users/42", "env:prod"},
+			expectedTags: []string{"path:/api", "env:prod"},
+			expectedKeep: true,
+		},
+		{
+			name: "Aggregate tag conditionally",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: aggregate_tag
+        tag: request_id
+        tags:
+          env: prod
+`,
+			metricName:   "test.foo",
+			tags:         []string{"request_id:1", "env:prod", "request_id:2"},
+			expectedTags: []string{"env:prod"},
+			expectedKeep: true,
+		},
+		{
+			name: "Rules are applied in order across profiles",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: first
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: rename_tag
+        tag: svc
+        new_tag: service
+  - name: second
+    prefix: '*'
+    rules:
+      - match: "test.*"
+        action: drop
+        tags:
+          service: web
+`,
+			metricName:   "test.foo",
+			tags:         []string{"svc:web"},
+			expectedTags: []string{"service:web"},
+			expectedKeep: false,
+		},
+		{
+			name: "Prefix not matching",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'other.'
+    rules:
+      - match: "test.*"
+        action: drop
+`,
+			metricName:   "test.foo",
+			tags:         nil,
+			expectedTags: nil,
+			expectedKeep: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			mapper, err := getMapper(t, scenario.config)
+			require.NoError(t, err)
+			assert.True(t, mapper.HasRules())
+
+			// twice to go through the cache
+			for i := 0; i < 2; i++ {
+				tags := append([]string(nil), scenario.tags...)
+				tags, keep := mapper.ApplyRules(scenario.metricName, tags)
+				assert.Equal(t, scenario.expectedKeep, keep)
+				assert.Equal(t, scenario.expectedTags, tags)
+			}
+		})
+	}
+}
+
+func TestRulesErrors(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name: "Invalid action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: explode
+`,
+			expectedError: "invalid action",
+		},
+		{
+			name: "Missing match",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - action: drop
+`,
+			expectedError: "match is required",
+		},
+		{
+			name: "Missing new tag",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: rename_tag
+        tag: foo
+`,
+			expectedError: "tag and new_tag are required",
+		},
+		{
+			name: "Missing length",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: truncate_tag
+        tag: foo
+`,
+			expectedError: "positive length",
+		},
+		{
+			name: "Invalid tag condition",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        action: drop
+        tags:
+          env: "(prod"
+`,
+			expectedError: "invalid condition on tag `env`",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_, err := getMapper(t, scenario.config)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), scenario.expectedError)
+		})
+	}
+}
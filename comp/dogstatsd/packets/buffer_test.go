@@ -56,7 +56,7 @@ func TestBufferTelemetry(t *testing.T) {
 
 	bufferSizeBytesMetricLabel := bufferSizeBytesMetrics[0].Tags()
 	assert.Equal(t, bufferSizeBytesMetricLabel["listener_id"], "test_buffer")
-	assert.Equal(t, float64(246), bufferSizeBytesMetrics[0].Value())
+	assert.Equal(t, float64(262), bufferSizeBytesMetrics[0].Value())
 }
 
 func TestBufferTelemetryFull(t *testing.T) {
@@ -123,7 +123,7 @@ func TestBufferTelemetryFull(t *testing.T) {
 
 	channelPacketsBytesMetricLabel := channelPacketsBytesMetrics[0].Tags()
 	assert.Equal(t, channelPacketsBytesMetricLabel["listener_id"], "test_buffer")
-	assert.Equal(t, float64(123), channelPacketsBytesMetrics[0].Value())
+	assert.Equal(t, float64(131), channelPacketsBytesMetrics[0].Value())
 
 	assert.Equal(t, float64(1), channelSizeMetrics[0].Value())
 }
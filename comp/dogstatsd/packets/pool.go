@@ -65,6 +65,7 @@ func (p *Pool) Put(packet *Packet) {
 	if packet.Origin != NoOrigin {
 		packet.Origin = NoOrigin
 	}
+	packet.Timestamp = 0
 	if p.tlmEnabled {
 		p.packetsTelemetry.tlmPoolPut.Inc()
 		p.packetsTelemetry.tlmPool.Dec()
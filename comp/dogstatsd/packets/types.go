@@ -21,6 +21,8 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// OpenMetrics HTTP listener
+	OpenMetrics
 )
 
 // Packet represents a statsd packet ready to process,
@@ -35,6 +37,7 @@ type Packet struct {
 	Origin     string     // Origin container if identified
 	ListenerID string     // Listener ID
 	Source     SourceType // Type of listener that produced the packet
+	Timestamp  int64      // Reception time in nanoseconds of a packet replayed from the spill queue, 0 otherwise
 }
 
 // Packets is a slice of packet pointers
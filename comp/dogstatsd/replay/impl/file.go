@@ -6,9 +6,14 @@
 package replayimpl
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+
 	"github.com/h2non/filetype"
 	"github.com/h2non/filetype/matchers"
 )
@@ -73,3 +78,58 @@ func WriteHeader(w io.Writer) error {
 
 	return nil
 }
+
+// WriteRecord writes the byte slice argument as a record of the .dog file format:
+// its size followed by its contents.
+func WriteRecord(w io.Writer, p []byte) (int, error) {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(len(p)))
+
+	// Record size
+	if n, err := w.Write(buf); err != nil {
+		return n, err
+	}
+
+	// Record
+	n, err := w.Write(p)
+
+	return n + 4, err
+}
+
+// WriteMessage serializes the dogstatsd message to a protobuf format and writes
+// it as a record of the .dog file format.
+func WriteMessage(w io.Writer, msg *pb.UnixDogstatsdMsg) (int, error) {
+	buff, err := proto.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	return WriteRecord(w, buff)
+}
+
+// WriteState writes the tagger state terminating the records of the .dog file format.
+func WriteState(w io.Writer, pbState *pb.TaggerState) (int, error) {
+	s, err := proto.Marshal(pbState)
+	if err != nil {
+		return 0, err
+	}
+
+	// Record State Separator
+	if n, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
+		return n, err
+	}
+
+	// Record State
+	n, err := w.Write(s)
+
+	// Record size
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(len(s)))
+
+	if n, err := w.Write(buf); err != nil {
+		return n, err
+	}
+
+	// n + 4 bytes for separator + 4 bytes for state size
+	return n + 8, err
+}
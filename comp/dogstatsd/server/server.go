@@ -11,6 +11,7 @@ import (
 	"expvar"
 	"fmt"
 	"net"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -52,6 +53,7 @@ var (
 	dogstatsdMetricPackets            = expvar.Int{}
 	dogstatsdPacketsLastSec           = expvar.Int{}
 	dogstatsdUnterminatedMetricErrors = expvar.Int{}
+	dogstatsdMetricMapperDrops        = expvar.Int{}
 
 	// while we try to add the origin tag in the tlmProcessed metric, we want to
 	// avoid having it growing indefinitely, hence this safeguard to limit the
@@ -131,6 +133,7 @@ type server struct {
 	tCapture                replay.Component
 	pidMap                  pidmap.Component
 	mapper                  *mapper.MetricMapper
+	mapperRules             bool // true when the mapper has rules to apply on the samples
 	eolTerminationUDP       bool
 	eolTerminationUDS       bool
 	eolTerminationNamedPipe bool
@@ -169,6 +172,7 @@ type server struct {
 	listernersTelemetry     *listeners.TelemetryStore
 	packetsTelemetry        *packets.TelemetryStore
 	stringInternerTelemetry *stringInternerTelemetry
+	spillTelemetry          *spillTelemetry
 }
 
 func initTelemetry() {
@@ -179,6 +183,7 @@ func initTelemetry() {
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
 	dogstatsdExpvars.Set("UnterminatedMetricErrors", &dogstatsdUnterminatedMetricErrors)
+	dogstatsdExpvars.Set("MetricMapperDrops", &dogstatsdMetricMapperDrops)
 }
 
 // TODO: (components) - merge with newServerCompat once NewServerlessServer is removed
@@ -323,6 +328,7 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 
 	s.listernersTelemetry = listeners.NewTelemetryStore(getBuckets(cfg, log, "telemetry.dogstatsd.listeners_latency_buckets"), telemetrycomp)
 	s.packetsTelemetry = packets.NewTelemetryStore(getBuckets(cfg, log, "telemetry.dogstatsd.listeners_channel_latency_buckets"), telemetrycomp)
+	s.spillTelemetry = newSpillTelemetry(telemetrycomp)
 
 	return s
 }
@@ -411,6 +417,24 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_openmetrics_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_openmetrics_port") > 0 {
+		openMetricsListener, err := listeners.NewOpenMetricsListener("tcp", packetsChannel, sharedPacketPoolManager, s.config, s.wmeta, s.pidMap, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init OpenMetrics listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, openMetricsListener)
+		}
+	}
+
+	if openMetricsSocketPath := s.config.GetString("dogstatsd_openmetrics_socket"); len(openMetricsSocketPath) > 0 {
+		openMetricsListener, err := listeners.NewOpenMetricsListener("unix", packetsChannel, sharedPacketPoolManager, s.config, s.wmeta, s.pidMap, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init OpenMetrics listener on path %s: %s", openMetricsSocketPath, err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, openMetricsListener)
+		}
+	}
+
 	if len(tmpListeners) == 0 {
 		return fmt.Errorf("listening on neither udp nor socket, please check your configuration")
 	}
@@ -434,7 +458,28 @@ func (s *server) start(context.Context) error {
 			s.log.Warnf("Could not connect to statsd forward host : %s", err)
 		} else {
 			s.packetsIn = make(chan packets.Packets, s.config.GetInt("dogstatsd_queue_size"))
-			go s.forwarder(con)
+			go s.forwarder(con, s.packetsIn)
+		}
+	}
+
+	// on-disk spill queue
+	// ----------------------
+
+	if s.config.GetBool("dogstatsd_spill.enabled") {
+		spillPath := s.config.GetString("dogstatsd_spill.path")
+		if spillPath == "" {
+			spillPath = filepath.Join(s.config.GetString("run_path"), "dogstatsd-spill")
+		}
+		spillOut := make(chan packets.Packets, s.config.GetInt("dogstatsd_queue_size"))
+		queue, err := newSpillQueue(spillPath,
+			int64(s.config.GetSizeInBytes("dogstatsd_spill.max_size")),
+			int64(s.config.GetSizeInBytes("dogstatsd_spill.segment_size")),
+			s.packetsIn, spillOut, sharedPacketPoolManager, s.packetsTelemetry, s.spillTelemetry, s.eolEnabled, s.log)
+		if err != nil {
+			s.log.Errorf("Can't init the DogStatsD spill queue: %s", err)
+		} else {
+			s.packetsIn = spillOut
+			go queue.run(s.stopChan)
 		}
 	}
 
@@ -462,6 +507,7 @@ func (s *server) start(context.Context) error {
 			s.log.Warnf("Could not create metric mapper: %v", err)
 		} else {
 			s.mapper = mapperInstance
+			s.mapperRules = mapperInstance.HasRules()
 		}
 	}
 
@@ -534,7 +580,7 @@ func (s *server) UDPLocalAddr() string {
 	return s.udpLocalAddr
 }
 
-func (s *server) forwarder(fcon net.Conn) {
+func (s *server) forwarder(fcon net.Conn, packetsOut chan packets.Packets) {
 	for {
 		select {
 		case <-s.stopChan:
@@ -546,7 +592,7 @@ func (s *server) forwarder(fcon net.Conn) {
 					s.log.Warnf("Forwarding packet failed : %s", err)
 				}
 			}
-			s.packetsIn <- packets
+			packetsOut <- packets
 		}
 	}
 }
@@ -682,6 +728,11 @@ func (s *server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 					if samples[idx].Timestamp > 0.0 {
 						batcher.appendLateSample(samples[idx])
 					} else {
+						if packet.Timestamp > 0 {
+							// the packet was replayed from the spill queue, the sample is
+							// still aggregated but in the bucket of its reception time
+							samples[idx].Timestamp = float64(packet.Timestamp) / float64(time.Second)
+						}
 						batcher.appendSample(samples[idx])
 					}
 
@@ -766,6 +817,19 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 			sample.name = mapResult.Name
 			sample.tags = append(sample.tags, mapResult.Tags...)
 		}
+
+		if s.mapperRules {
+			var keep bool
+			sample.tags, keep = s.mapper.ApplyRules(sample.name, sample.tags)
+			if !keep {
+				s.log.Tracef("Dogstatsd mapper: metric %q dropped by a rule", sample.name)
+				dogstatsdMetricMapperDrops.Add(1)
+				if len(sample.values) > 0 {
+					s.sharedFloat64List.put(sample.values)
+				}
+				return metricSamples, nil
+			}
+		}
 	}
 
 	metricSamples = enrichMetricSample(metricSamples, sample, origin, listenerID, s.enrichConfig)
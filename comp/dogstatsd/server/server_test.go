@@ -870,6 +870,42 @@ dogstatsd_mapper_profiles:
 			},
 			expectedCacheSize: 1000,
 		},
+		{
+			name: "Rules",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration.*"
+        name: "test.job.duration"
+        tags:
+          job_type: "$1"
+    rules:
+      - match: "test.job.duration"
+        action: drop
+        tags:
+          job_type: "debug"
+      - match: "test.job.*"
+        action: aggregate_tag
+        tag: request_id
+      - match: "test.job.*"
+        action: rename_tag
+        tag: svc
+        new_tag: service
+`,
+			packets: []string{
+				"test.job.duration.debug:666|g",
+				"test.job.duration.batch:666|g|#request_id:1234,svc:foo",
+				"test.job.size:666|g|#request_id:1234",
+			},
+			expectedSamples: []MetricSample{
+				{Name: "test.job.duration", Tags: []string{"job_type:batch", "service:foo"}, Mtype: metrics.GaugeType, Value: 666.0},
+				{Name: "test.job.size", Tags: []string{}, Mtype: metrics.GaugeType, Value: 666.0},
+			},
+			expectedCacheSize: 1000,
+		},
 		{
 			name: "Cache size",
 			config: `
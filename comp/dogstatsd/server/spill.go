@@ -0,0 +1,438 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	replayimpl "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+const (
+	// spillListenerID is the listener ID of the packets replayed from the spill queue
+	spillListenerID = "spill"
+	// spillBatchSize is the number of packets read from disk per batch sent to the workers
+	spillBatchSize = 32
+	// spillDrainInterval is the interval at which the spill queue checks if the
+	// workers caught up, to drain the segment being written
+	spillDrainInterval = 100 * time.Millisecond
+
+	spillSegmentExt     = ".dog"
+	spillSegmentTmpExt  = ".tmp"
+	spillSegmentPattern = "dogstatsd-spill-%020d"
+)
+
+type spillTelemetry struct {
+	packets telemetry.Counter
+	bytes   telemetry.Gauge
+}
+
+func newSpillTelemetry(telemetrycomp telemetry.Component) *spillTelemetry {
+	return &spillTelemetry{
+		packets: telemetrycomp.NewCounter("dogstatsd", "spill_packets",
+			[]string{"state"}, "Count of packets spilled to disk, drained from disk or dropped because the spill queue is full"),
+		bytes: telemetrycomp.NewGauge("dogstatsd", "spill_size_bytes",
+			nil, "Size on disk of the spill queue in bytes"),
+	}
+}
+
+// spillSegment is a file of the spill queue, using the capture file format.
+// The origins of the packets are stored in the Pid field of the records, and
+// mapped back to the origin by the PidMap of the state closing the file.
+type spillSegment struct {
+	path    string
+	size    int64
+	records int
+
+	file    *os.File
+	writer  *bufio.Writer
+	origins map[string]int32
+}
+
+// spillQueue sits between the listeners and the workers. Packets are passed
+// through as long as the workers keep up, and are spilled to bounded on-disk
+// segments otherwise. The segments are drained in order as soon as the workers
+// have some room, the packets replayed from disk keep their reception time so
+// that their samples land in the right flush bucket.
+//
+// Segments left over by a previous run are drained on startup. A segment is
+// only removed once fully drained, so packets may be replayed twice if the
+// agent stops while draining it.
+type spillQueue struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	in                chan packets.Packets
+	out               chan packets.Packets
+	packetPoolManager *packets.PoolManager[packets.Packet]
+	packetsTelemetry  *packets.TelemetryStore
+	telemetry         *spillTelemetry
+	eolEnabled        func(packets.SourceType) bool
+	log               log.Component
+
+	// size is the size on disk of all the segments
+	size int64
+	// segments are the closed segments, oldest first
+	segments []*spillSegment
+	// current is the segment being written, if any
+	current *spillSegment
+	// draining is the segment being drained, if any
+	draining *spillSegment
+	reader   *replayimpl.TrafficCaptureReader
+	pidMap   map[int32]string
+	// pending is a batch of packets read from disk waiting to be sent to the workers
+	pending packets.Packets
+	seq     int64
+}
+
+func newSpillQueue(dir string, maxSize, segmentSize int64, in, out chan packets.Packets, packetPoolManager *packets.PoolManager[packets.Packet], packetsTelemetry *packets.TelemetryStore, spillTelemetry *spillTelemetry, eolEnabled func(packets.SourceType) bool, log log.Component) (*spillQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("can't create the spill directory %s: %v", dir, err)
+	}
+
+	q := &spillQueue{
+		dir:               dir,
+		maxSize:           maxSize,
+		segmentSize:       segmentSize,
+		in:                in,
+		out:               out,
+		packetPoolManager: packetPoolManager,
+		packetsTelemetry:  packetsTelemetry,
+		telemetry:         spillTelemetry,
+		eolEnabled:        eolEnabled,
+		log:               log,
+		seq:               time.Now().UnixNano(),
+	}
+
+	if err := q.loadSegments(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// loadSegments registers the segments left over by a previous run, and removes
+// the ones which were not closed properly.
+func (q *spillQueue) loadSegments() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("can't read the spill directory %s: %v", q.dir, err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(q.dir, entry.Name())
+		if strings.HasSuffix(entry.Name(), spillSegmentTmpExt) {
+			q.log.Warnf("Removing incomplete DogStatsD spill segment %s", path)
+			_ = os.Remove(path)
+			continue
+		}
+		if !strings.HasSuffix(entry.Name(), spillSegmentExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		q.segments = append(q.segments, &spillSegment{path: path, size: info.Size()})
+		q.size += info.Size()
+	}
+
+	// segment names are ordered by creation
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].path < q.segments[j].path })
+	if len(q.segments) > 0 {
+		q.log.Infof("Draining %d DogStatsD spill segments left over by a previous run", len(q.segments))
+	}
+	q.telemetry.bytes.Set(float64(q.size))
+	return nil
+}
+
+func (q *spillQueue) run(stopChan chan bool) {
+	ticker := time.NewTicker(spillDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		if q.pending == nil {
+			q.loadPending(false)
+		}
+		// a nil channel blocks the send while there is nothing to drain
+		var out chan packets.Packets
+		if q.pending != nil {
+			out = q.out
+		}
+
+		select {
+		case <-stopChan:
+			q.stop()
+			return
+		case ps := <-q.in:
+			if q.empty() {
+				select {
+				case q.out <- ps:
+					continue
+				default:
+				}
+			}
+			q.spill(ps)
+		case out <- q.pending:
+			q.telemetry.packets.Add(float64(len(q.pending)), "drained")
+			q.pending = nil
+		case <-ticker.C:
+			// the workers caught up with what is on disk, drain the segment
+			// being written as well
+			if q.pending == nil && len(q.out) == 0 {
+				q.loadPending(true)
+			}
+		}
+	}
+}
+
+// empty returns true if no packet is waiting on disk to be drained.
+func (q *spillQueue) empty() bool {
+	return q.pending == nil && q.draining == nil && len(q.segments) == 0 && q.current == nil
+}
+
+// spill writes the packets to the current segment and puts them back in the pool.
+func (q *spillQueue) spill(ps packets.Packets) {
+	q.packetsTelemetry.TelemetryUntrackPackets(ps)
+	now := time.Now().UnixNano()
+
+	for _, packet := range ps {
+		if err := q.write(packet, now); err != nil {
+			q.log.Debugf("Dropping DogStatsD packet: %v", err)
+			q.telemetry.packets.Inc("dropped")
+		} else {
+			q.telemetry.packets.Inc("spilled")
+		}
+		q.packetPoolManager.Put(packet)
+	}
+	q.telemetry.bytes.Set(float64(q.size))
+}
+
+func (q *spillQueue) write(packet *packets.Packet, timestamp int64) error {
+	contents := packet.Contents
+	if q.eolEnabled(packet.Source) {
+		// drop the unterminated message the workers would have dropped
+		contents = contents[:bytes.LastIndexByte(contents, '\n')+1]
+	} else if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+		contents = append(contents, '\n')
+	}
+	if len(contents) == 0 {
+		return nil
+	}
+
+	if q.current != nil && q.current.size >= q.segmentSize {
+		q.rotate()
+	}
+	if q.current == nil {
+		if err := q.create(); err != nil {
+			return err
+		}
+	}
+
+	origin, ok := q.current.origins[packet.Origin]
+	if !ok {
+		origin = int32(len(q.current.origins))
+		q.current.origins[packet.Origin] = origin
+	}
+
+	msg := &pb.UnixDogstatsdMsg{
+		Timestamp:   timestamp,
+		PayloadSize: int32(len(contents)),
+		Payload:     contents,
+		Pid:         origin,
+	}
+	size := int64(proto.Size(msg)) + 4
+	if q.size+size > q.maxSize {
+		return fmt.Errorf("the spill queue is full (%d bytes)", q.maxSize)
+	}
+
+	if _, err := replayimpl.WriteMessage(q.current.writer, msg); err != nil {
+		q.log.Errorf("Error writing to the DogStatsD spill segment %s: %v", q.current.path, err)
+		q.rotate()
+		return err
+	}
+	q.current.size += size
+	q.current.records++
+	q.size += size
+	return nil
+}
+
+// create creates a new segment to write the spilled packets to.
+func (q *spillQueue) create() error {
+	q.seq++
+	path := filepath.Join(q.dir, fmt.Sprintf(spillSegmentPattern, q.seq)+spillSegmentExt)
+	file, err := os.OpenFile(path+spillSegmentTmpExt, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("can't create the spill segment %s: %v", path, err)
+	}
+
+	writer := bufio.NewWriter(file)
+	if err := replayimpl.WriteHeader(writer); err != nil {
+		file.Close()
+		_ = os.Remove(path + spillSegmentTmpExt)
+		return fmt.Errorf("can't write the spill segment %s header: %v", path, err)
+	}
+
+	q.current = &spillSegment{
+		path:    path,
+		file:    file,
+		writer:  writer,
+		origins: make(map[string]int32),
+	}
+	return nil
+}
+
+// rotate closes the current segment with its state and queues it to be drained.
+func (q *spillQueue) rotate() {
+	segment := q.current
+	q.current = nil
+	if segment == nil {
+		return
+	}
+
+	pidMap := make(map[int32]string, len(segment.origins))
+	for origin, id := range segment.origins {
+		pidMap[id] = origin
+	}
+
+	_, err := replayimpl.WriteState(segment.writer, &pb.TaggerState{PidMap: pidMap})
+	if err == nil {
+		err = segment.writer.Flush()
+	}
+	if closeErr := segment.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(segment.path+spillSegmentTmpExt, segment.path)
+	}
+	if err != nil {
+		q.log.Errorf("Error closing the DogStatsD spill segment %s, %d packets are lost: %v", segment.path, segment.records, err)
+		_ = os.Remove(segment.path + spillSegmentTmpExt)
+		q.size -= segment.size
+		return
+	}
+
+	info, err := os.Stat(segment.path)
+	if err == nil {
+		q.size += info.Size() - segment.size
+		segment.size = info.Size()
+	}
+	segment.file, segment.writer, segment.origins = nil, nil, nil
+	q.segments = append(q.segments, segment)
+}
+
+// loadPending reads the next batch of packets to drain from disk. The segment
+// being written is only drained if rotateCurrent is set.
+func (q *spillQueue) loadPending(rotateCurrent bool) {
+	if q.draining == nil {
+		if len(q.segments) == 0 {
+			if !rotateCurrent || q.current == nil {
+				return
+			}
+			q.rotate()
+			if len(q.segments) == 0 {
+				return
+			}
+		}
+		if !q.open(q.segments[0]) {
+			return
+		}
+		q.segments = q.segments[1:]
+	}
+
+	batch := make(packets.Packets, 0, spillBatchSize)
+	for len(batch) < spillBatchSize {
+		msg, err := q.reader.ReadNext()
+		if err != nil {
+			if err != io.EOF {
+				q.log.Errorf("Error reading the DogStatsD spill segment %s: %v", q.draining.path, err)
+			}
+			q.finish()
+			break
+		}
+
+		packet := q.packetPoolManager.Get()
+		if cap(packet.Buffer) < len(msg.Payload) {
+			packet.Buffer = make([]byte, len(msg.Payload))
+		}
+		packet.Contents = packet.Buffer[:copy(packet.Buffer[:cap(packet.Buffer)], msg.Payload)]
+		packet.Origin = q.pidMap[msg.Pid]
+		packet.ListenerID = spillListenerID
+		// the contents are newline terminated when spilled, whatever their source
+		packet.Source = packets.UDP
+		packet.Timestamp = msg.Timestamp
+		batch = append(batch, packet)
+	}
+
+	if len(batch) > 0 {
+		q.packetsTelemetry.TelemetryTrackPackets(batch, spillListenerID)
+		q.pending = batch
+	}
+}
+
+// open opens a segment to drain it, a segment which can't be read is removed.
+func (q *spillQueue) open(segment *spillSegment) bool {
+	reader, err := replayimpl.NewTrafficCaptureReader(segment.path, 0, false)
+	if err == nil {
+		q.pidMap, _, err = reader.ReadState()
+		// skip the header
+		reader.Seek(0)
+	}
+	if err != nil {
+		q.log.Errorf("Removing unreadable DogStatsD spill segment %s: %v", segment.path, err)
+		q.segments = q.segments[1:]
+		q.remove(segment)
+		return false
+	}
+
+	q.draining = segment
+	q.reader = reader
+	return true
+}
+
+// finish removes the segment which was fully drained.
+func (q *spillQueue) finish() {
+	if err := q.reader.Close(); err != nil {
+		q.log.Debugf("Error closing the DogStatsD spill segment %s: %v", q.draining.path, err)
+	}
+	q.remove(q.draining)
+	q.draining, q.reader, q.pidMap = nil, nil, nil
+}
+
+func (q *spillQueue) remove(segment *spillSegment) {
+	if err := os.Remove(segment.path); err != nil {
+		q.log.Warnf("Error removing the DogStatsD spill segment %s: %v", segment.path, err)
+	}
+	q.size -= segment.size
+	q.telemetry.bytes.Set(float64(q.size))
+}
+
+// stop closes the segment being written so that it's drained on the next
+// startup, along with the segments which were not drained yet.
+func (q *spillQueue) stop() {
+	q.rotate()
+	for _, packet := range q.pending {
+		q.packetPoolManager.Put(packet)
+	}
+	q.pending = nil
+	if q.reader != nil {
+		_ = q.reader.Close()
+	}
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/core/telemetry/telemetryimpl"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+type testSpillQueue struct {
+	*spillQueue
+	in   chan packets.Packets
+	out  chan packets.Packets
+	pool *packets.PoolManager[packets.Packet]
+}
+
+func newTestSpillQueue(t *testing.T, dir string, maxSize int64) *testSpillQueue {
+	telemetryComp := fxutil.Test[telemetry.Mock](t, telemetryimpl.MockModule())
+	packetsTelemetry := packets.NewTelemetryStore(nil, telemetryComp)
+	pool := packets.NewPoolManager[packets.Packet](packets.NewPool(64, packetsTelemetry))
+
+	in := make(chan packets.Packets, 10)
+	out := make(chan packets.Packets, 1)
+	eolEnabled := func(source packets.SourceType) bool { return source == packets.UDS }
+	q, err := newSpillQueue(dir, maxSize, 128, in, out, pool, packetsTelemetry, newSpillTelemetry(telemetryComp), eolEnabled, logmock.New(t))
+	require.NoError(t, err)
+	return &testSpillQueue{spillQueue: q, in: in, out: out, pool: pool}
+}
+
+func (q *testSpillQueue) packet(contents string, origin string, source packets.SourceType) packets.Packets {
+	packet := q.pool.Get()
+	packet.Contents = append(packet.Buffer[:0], contents...)
+	packet.Origin = origin
+	packet.Source = source
+	return packets.Packets{packet}
+}
+
+func receivePackets(t *testing.T, out chan packets.Packets) packets.Packets {
+	select {
+	case ps := <-out:
+		return ps
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+	return nil
+}
+
+func TestSpillQueue(t *testing.T) {
+	dir := t.TempDir()
+	q := newTestSpillQueue(t, dir, 1024*1024)
+	stop := make(chan bool)
+	defer close(stop)
+
+	// the workers are stuck, the first batch fills the queue and the next ones are spilled
+	start := time.Now().UnixNano()
+	q.in <- q.packet("first:1|g", "", packets.UDP)
+	go q.run(stop)
+	require.Eventually(t, func() bool { return len(q.out) == 1 }, 2*time.Second, 10*time.Millisecond)
+	q.in <- q.packet("second:1|g\npartial:1", "container_id://abc", packets.UDS)
+	for i := 0; i < 20; i++ {
+		q.in <- q.packet("third:1|g", "", packets.UDP)
+	}
+	require.Eventually(t, func() bool { return len(q.in) == 0 }, 2*time.Second, 10*time.Millisecond)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Greater(t, len(files), 1, "the segments should have been rotated")
+
+	// once the workers recover the packets are drained in order
+	first := receivePackets(t, q.out)
+	require.Len(t, first, 1)
+	assert.Equal(t, "first:1|g", string(first[0].Contents))
+	assert.Zero(t, first[0].Timestamp)
+
+	var drained packets.Packets
+	for len(drained) < 21 {
+		drained = append(drained, receivePackets(t, q.out)...)
+	}
+	// the unterminated message is dropped as the listener requires EOL
+	assert.Equal(t, "second:1|g\n", string(drained[0].Contents))
+	assert.Equal(t, "container_id://abc", drained[0].Origin)
+	assert.Equal(t, spillListenerID, drained[0].ListenerID)
+	assert.GreaterOrEqual(t, drained[0].Timestamp, start)
+	for _, packet := range drained[1:] {
+		assert.Equal(t, "third:1|g\n", string(packet.Contents))
+		assert.Equal(t, "", packet.Origin)
+	}
+
+	// the segments are removed once drained, and the packets pass through again
+	require.Eventually(t, func() bool {
+		files, _ := os.ReadDir(dir)
+		return len(files) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+	q.in <- q.packet("fourth:1|g", "", packets.UDP)
+	last := receivePackets(t, q.out)
+	assert.Equal(t, "fourth:1|g", string(last[0].Contents))
+}
+
+func TestSpillQueueMaxSize(t *testing.T) {
+	q := newTestSpillQueue(t, t.TempDir(), 100)
+
+	q.spill(q.packet("a.very.long.metric.name:1|g", "", packets.UDP))
+	q.spill(q.packet("a.very.long.metric.name:1|g", "", packets.UDP))
+	q.spill(q.packet("a.very.long.metric.name:1|g", "", packets.UDP))
+	assert.Equal(t, 2, q.current.records)
+	assert.LessOrEqual(t, q.size, int64(100))
+}
+
+func TestSpillQueueLeftOverSegments(t *testing.T) {
+	dir := t.TempDir()
+	q := newTestSpillQueue(t, dir, 1024*1024)
+	q.spill(q.packet("left.over:1|g", "container_id://abc", packets.UDP))
+	q.stop()
+
+	// incomplete segments are removed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dogstatsd-spill-0.dog.tmp"), []byte("garbage"), 0600))
+
+	q = newTestSpillQueue(t, dir, 1024*1024)
+	require.Len(t, q.segments, 1)
+	stop := make(chan bool)
+	defer close(stop)
+	go q.run(stop)
+
+	ps := receivePackets(t, q.out)
+	require.Len(t, ps, 1)
+	assert.Equal(t, "left.over:1|g\n", string(ps[0].Contents))
+	assert.Equal(t, "container_id://abc", ps[0].Origin)
+	assert.NoFileExists(t, filepath.Join(dir, "dogstatsd-spill-0.dog.tmp"))
+}
+
+func TestSpilledPacketsTimestamp(t *testing.T) {
+	deps := fulfillDepsWithConfigOverride(t, map[string]interface{}{
+		"dogstatsd_port":          listeners.RandomPortName,
+		"dogstatsd_spill.enabled": true,
+		"dogstatsd_spill.path":    t.TempDir(),
+	})
+	s := deps.Server.(*server)
+	demux := deps.Demultiplexer
+	require.True(t, s.IsRunning())
+
+	// packets pass through the spill queue
+	conn, err := net.Dial("udp", s.UDPLocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("live:1|g"))
+	require.NoError(t, err)
+	samples, _ := demux.WaitForSamples(2 * time.Second)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "live", samples[0].Name)
+	assert.Zero(t, samples[0].Timestamp)
+	demux.Reset()
+
+	// samples of packets drained from disk are aggregated at their reception time
+	packet := s.sharedPacketPoolManager.Get()
+	packet.Contents = append(packet.Buffer[:0], "spilled:1|g\n"...)
+	packet.Timestamp = time.Unix(1000, 0).UnixNano()
+	s.packetsIn <- packets.Packets{packet}
+	samples, timedSamples := demux.WaitForSamples(2 * time.Second)
+	require.Len(t, samples, 1)
+	assert.Len(t, timedSamples, 0)
+	assert.Equal(t, "spilled", samples[0].Name)
+	assert.Equal(t, float64(1000), samples[0].Timestamp)
+}
@@ -248,14 +248,10 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	if storageMaxSize == 0 {
 		log.Infof("Retry queue storage on disk is disabled")
 	} else if agentName != "" {
-		storagePath := config.GetString("forwarder_storage_path")
-		if storagePath == "" {
-			storagePath = path.Join(config.GetString("run_path"), "transactions_to_retry")
-		}
+		storagePath := getStoragePath(config, agentName)
 		outdatedFileInDays := config.GetInt("forwarder_outdated_file_in_days")
 		var err error
 
-		storagePath = path.Join(storagePath, agentName)
 		optionalRemovalPolicy, err = retry.NewFileRemovalPolicy(storagePath, outdatedFileInDays, retry.FileRemovalPolicyTelemetry{})
 		if err != nil {
 			log.Errorf("Error when initializing the removal policy: %v", err)
@@ -275,6 +271,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	}
 
 	flushToDiskMemRatio := config.GetFloat64("forwarder_flush_to_disk_mem_ratio")
+	storageBackend := config.GetString("forwarder_storage_backend")
 	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 
@@ -313,6 +310,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 				flushToDiskMemRatio,
 				domainFolderPath,
 				diskUsageLimit,
+				storageBackend,
 				transactionContainerSort,
 				resolver,
 				pointCountTelemetry)
@@ -378,6 +376,16 @@ func getAgentName(options *Options) string {
 	return ""
 }
 
+// getStoragePath returns the folder where the transactions of the retry queue
+// of an Agent are stored.
+func getStoragePath(config config.Component, agentName string) string {
+	storagePath := config.GetString("forwarder_storage_path")
+	if storagePath == "" {
+		storagePath = path.Join(config.GetString("run_path"), "transactions_to_retry")
+	}
+	return path.Join(storagePath, agentName)
+}
+
 // Start initialize and runs the forwarder.
 func (f *DefaultForwarder) Start() error {
 	// Lock so we can't stop a Forwarder while is starting
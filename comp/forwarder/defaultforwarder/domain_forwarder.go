@@ -252,6 +252,9 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 	if err := f.retryQueue.FlushToDisk(); err != nil {
 		f.log.Errorf("Error when flushing the retry queue to disk: %v", err)
 	}
+	if err := f.retryQueue.Close(); err != nil {
+		f.log.Errorf("Error when closing the retry queue storage: %v", err)
+	}
 
 	f.log.Info("domainForwarder stopped")
 	f.internalState = Stopped
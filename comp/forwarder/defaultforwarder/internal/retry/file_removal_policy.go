@@ -108,14 +108,21 @@ func (p *FileRemovalPolicy) forEachDomainPath(callback func(folderPath string) (
 }
 
 func (p *FileRemovalPolicy) getFolderPathForDomain(domainName string) (string, error) {
+	folder, err := DomainFolderName(domainName)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(p.rootPath, folder), nil
+}
+
+// DomainFolderName returns the name of the folder storing the transactions of a domain.
+func DomainFolderName(domainName string) (string, error) {
 	// Use md5 for the folder name as the domainName is an url which can contain invalid charaters for a file path.
 	h := md5.New()
 	if _, err := io.WriteString(h, domainName); err != nil {
 		return "", err
 	}
-	folder := fmt.Sprintf("%x", h.Sum(nil))
-
-	return path.Join(p.rootPath, folder), nil
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
 func (p *FileRemovalPolicy) removeUnknownDomain(folderPath string) ([]string, error) {
@@ -137,7 +144,7 @@ func (p *FileRemovalPolicy) removeOutdatedRetryFiles(folderPath string) ([]strin
 }
 
 func (p *FileRemovalPolicy) removeRetryFiles(folderPath string, shouldRemove func(string) bool) ([]string, error) {
-	files, err := p.getRetryFiles(folderPath)
+	files, err := getRetryFiles(folderPath)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +163,7 @@ func (p *FileRemovalPolicy) removeRetryFiles(folderPath string, shouldRemove fun
 	return filesRemoved, errs
 }
 
-func (p *FileRemovalPolicy) getRetryFiles(folder string) ([]string, error) {
+func getRetryFiles(folder string) ([]string, error) {
 	entries, err := os.ReadDir(folder)
 	if err != nil {
 		return nil, err
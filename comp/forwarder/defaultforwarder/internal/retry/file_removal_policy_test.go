@@ -30,11 +30,12 @@ func TestFileRemovalPolicyUnknownDomain(t *testing.T) {
 	file2 := createRetryFile(a, domain2, "file2")
 	file3 := createRetryFile(a, path.Join(root, "unknownDomain"), "file3")
 	file4 := createFile(a, path.Join(root, "unknownDomain"), "notRetryFileMustNotBeRemoved")
+	file5 := createFile(a, path.Join(root, "unknownDomain"), kvStorageFileName)
 
 	pathsRemoved, err := p.RemoveUnknownDomains()
 	a.NoError(err)
 	assertFilenamesEqual(a, []string{file3}, pathsRemoved)
-	assertFilenamesEqual(a, []string{file1, file2, file4}, getRemainingFiles(a, root))
+	assertFilenamesEqual(a, []string{file1, file2, file4, file5}, getRemainingFiles(a, root))
 }
 
 func TestFileRemovalPolicyOutdatedFiles(t *testing.T) {
@@ -50,9 +51,11 @@ func TestFileRemovalPolicyOutdatedFiles(t *testing.T) {
 	file1 := createRetryFile(a, domain, "file1")
 	file2 := createRetryFile(a, domain, "file2")
 	file3 := createRetryFile(a, domain, "file3")
+	file4 := createFile(a, domain, kvStorageFileName)
 
 	modTime := time.Now().Add(time.Duration(-3*24) * time.Hour)
 	a.NoError(os.Chtimes(file2, modTime, modTime))
+	a.NoError(os.Chtimes(file4, modTime, modTime))
 
 	modTime = time.Now().Add(time.Duration(-1*24) * time.Hour)
 	a.NoError(os.Chtimes(file3, modTime, modTime))
@@ -60,7 +63,7 @@ func TestFileRemovalPolicyOutdatedFiles(t *testing.T) {
 	pathsRemoved, err := p.RemoveOutdatedFiles()
 	a.NoError(err)
 	assertFilenamesEqual(a, []string{file2}, pathsRemoved)
-	assertFilenamesEqual(a, []string{file1, file3}, getRemainingFiles(a, root))
+	assertFilenamesEqual(a, []string{file1, file3, file4}, getRemainingFiles(a, root))
 }
 
 func TestFileRemovalPolicyExistingDomain(t *testing.T) {
@@ -120,46 +120,55 @@ func (s *HTTPTransactionsSerializer) Deserialize(bytes []byte) ([]transaction.Tr
 	var httpTransactions []transaction.Transaction
 	errorCount := 0
 	for _, tr := range collection.Values {
-		var route string
-		var proto http.Header
-		var destination transaction.Destination
-		e := tr.Endpoint
-
-		priority, err := fromTransactionPriorityProto(tr.Priority)
-		if err == nil {
-			route, err = s.restoreAPIKeys(e.Route)
-			if err == nil {
-				proto, err = s.fromHeaderProto(tr.Headers)
-				if err == nil { // TODO: the reason for this nesting pattern is unclear to me
-					destination, err = fromTransactionDestinationProto(tr.Destination)
-				}
-			}
-		}
-
+		httpTransaction, err := s.FromProto(tr)
 		if err != nil {
 			s.log.Errorf("Error when deserializing a transaction: %v", err)
 			errorCount++
 			continue
 		}
+		httpTransactions = append(httpTransactions, httpTransaction)
+	}
+	return httpTransactions, errorCount, nil
+}
+
+// FromProto converts a serialized transaction to an HTTPTransaction, restoring its API keys.
+func (s *HTTPTransactionsSerializer) FromProto(tr *HttpTransactionProto) (*transaction.HTTPTransaction, error) {
+	var route string
+	var proto http.Header
+	var destination transaction.Destination
+	e := tr.Endpoint
 
-		endpoint := transaction.Endpoint{Route: route, Name: e.Name}
-		domain, _ := s.resolver.Resolve(endpoint)
-		tr := transaction.HTTPTransaction{
-			Domain:         domain,
-			Endpoint:       endpoint,
-			Headers:        proto,
-			Payload:        transaction.NewBytesPayload(tr.Payload, int(tr.GetPointCount())),
-			ErrorCount:     int(tr.ErrorCount),
-			CreatedAt:      time.Unix(tr.CreatedAt, 0),
-			Retryable:      tr.Retryable,
-			StorableOnDisk: true,
-			Priority:       priority,
-			Destination:    destination,
+	priority, err := fromTransactionPriorityProto(tr.Priority)
+	if err == nil {
+		route, err = s.restoreAPIKeys(e.Route)
+		if err == nil {
+			proto, err = s.fromHeaderProto(tr.Headers)
+			if err == nil { // TODO: the reason for this nesting pattern is unclear to me
+				destination, err = fromTransactionDestinationProto(tr.Destination)
+			}
 		}
-		tr.SetDefaultHandlers()
-		httpTransactions = append(httpTransactions, &tr)
 	}
-	return httpTransactions, errorCount, nil
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := transaction.Endpoint{Route: route, Name: e.Name}
+	domain, _ := s.resolver.Resolve(endpoint)
+	httpTransaction := transaction.HTTPTransaction{
+		Domain:         domain,
+		Endpoint:       endpoint,
+		Headers:        proto,
+		Payload:        transaction.NewBytesPayload(tr.Payload, int(tr.GetPointCount())),
+		ErrorCount:     int(tr.ErrorCount),
+		CreatedAt:      time.Unix(tr.CreatedAt, 0),
+		Retryable:      tr.Retryable,
+		StorableOnDisk: true,
+		Priority:       priority,
+		Destination:    destination,
+	}
+	httpTransaction.SetDefaultHandlers()
+	return &httpTransaction, nil
 }
 
 func (s *HTTPTransactionsSerializer) replaceAPIKeys(str string) string {
@@ -248,6 +257,28 @@ func toTransactionDestinationProto(destination transaction.Destination) (Transac
 	}
 }
 
+// ReplaceAPIKeyPlaceholders replaces the placeholders of the API keys of a
+// serialized transaction: `replace` is called with the index of the API key.
+func ReplaceAPIKeyPlaceholders(str string, replace func(index string) string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(str, placeHolderPrefix)
+		if start < 0 {
+			break
+		}
+		rest := str[start+len(placeHolderPrefix):]
+		end := strings.Index(rest, squareChar)
+		if end < 0 {
+			break
+		}
+		b.WriteString(str[:start])
+		b.WriteString(replace(rest[:end]))
+		str = rest[end+len(squareChar):]
+	}
+	b.WriteString(str)
+	return b.String()
+}
+
 func createReplacers(apiKeys []string) (*strings.Replacer, *strings.Replacer) {
 	// Copy to not modify apiKeys order
 	keys := make([]string, len(apiKeys))
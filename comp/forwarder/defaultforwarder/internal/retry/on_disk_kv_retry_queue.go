@@ -0,0 +1,370 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+)
+
+const kvStorageFileName = "transactions.db"
+const kvStorageLockTimeout = time.Second
+
+// the size of the header storing the point count of a transaction before its serialized bytes
+const kvRecordHeaderSize = 4
+
+var kvSequenceBucket = []byte("sequence")
+
+// kvPriorityBuckets are the buckets of each priority, in the order in which the
+// transactions are extracted. The transactions are evicted in the reverse order.
+var kvPriorityBuckets = []struct {
+	priority transaction.Priority
+	name     []byte
+}{
+	{transaction.TransactionPriorityHigh, []byte("high")},
+	{transaction.TransactionPriorityNormal, []byte("normal")},
+}
+
+// onDiskKVRetryQueue stores the transactions in an embedded indexed key-value store.
+//
+// Each transaction is stored in its own record, indexed by priority, endpoint and
+// insertion order:
+//   - the transactions are extracted by priority, from the oldest to the most recent,
+//     so that the order of the transactions of an endpoint is preserved.
+//   - when the disk usage limit is reached, the oldest transactions with a normal
+//     priority are evicted before the ones with a high priority.
+//   - every store and extraction is an atomic transaction of the key-value store, so
+//     a crash never leaves a partially written batch and a corrupted record doesn't
+//     prevent reading the others.
+//
+// The disk space used is the size of the records: the database file itself is
+// not shrunk when records are removed, its free pages are reused.
+type onDiskKVRetryQueue struct {
+	log                   log.Component
+	serializer            *HTTPTransactionsSerializer
+	db                    *bbolt.DB
+	diskUsageLimit        *DiskUsageLimit
+	maxExtractSizeInBytes int
+	currentSizeInBytes    int64
+	transactionsCount     int
+	telemetry             onDiskRetryQueueTelemetry
+	pointCountTelemetry   *PointCountTelemetry
+}
+
+func newOnDiskKVRetryQueue(
+	log log.Component,
+	serializer *HTTPTransactionsSerializer,
+	storagePath string,
+	diskUsageLimit *DiskUsageLimit,
+	maxExtractSizeInBytes int,
+	telemetry onDiskRetryQueueTelemetry,
+	pointCountTelemetry *PointCountTelemetry) (*onDiskKVRetryQueue, error) {
+
+	if err := os.MkdirAll(storagePath, 0700); err != nil {
+		return nil, err
+	}
+
+	db, err := bbolt.Open(path.Join(storagePath, kvStorageFileName), 0600, &bbolt.Options{
+		Timeout: kvStorageLockTimeout,
+	})
+	if err != nil {
+		if errors.Is(err, bbolt.ErrTimeout) {
+			return nil, fmt.Errorf("the transactions database is locked, check if another instance of the agent is using the same `forwarder_storage_path`")
+		}
+		return nil, err
+	}
+
+	storage := &onDiskKVRetryQueue{
+		log:                   log,
+		serializer:            serializer,
+		db:                    db,
+		diskUsageLimit:        diskUsageLimit,
+		maxExtractSizeInBytes: maxExtractSizeInBytes,
+		telemetry:             telemetry,
+		pointCountTelemetry:   pointCountTelemetry,
+	}
+
+	if err := storage.reloadExistingTransactions(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	// Check if there is an error when computing the available space
+	// in this function to warn the user sooner (and not when there is an outage)
+	_, err = diskUsageLimit.computeAvailableSpace(0)
+
+	return storage, err
+}
+
+// Store stores transactions in the key-value store.
+func (s *onDiskKVRetryQueue) Store(transactions []transaction.Transaction) error {
+	s.telemetry.addSerializeCount()
+
+	// Reset the serializer in case some transactions were serialized
+	// but `GetBytesAndReset` was not called because of an error.
+	_, _ = s.serializer.GetBytesAndReset()
+
+	type record struct {
+		priority transaction.Priority
+		endpoint string
+		value    []byte
+	}
+	records := make([]record, 0, len(transactions))
+	bufferSize := int64(0)
+	for _, t := range transactions {
+		if err := t.SerializeTo(s.log, s.serializer); err != nil {
+			return err
+		}
+		bytes, err := s.serializer.GetBytesAndReset()
+		if err != nil {
+			return err
+		}
+		value := make([]byte, kvRecordHeaderSize, kvRecordHeaderSize+len(bytes))
+		binary.BigEndian.PutUint32(value, uint32(t.GetPointCount()))
+		value = append(value, bytes...)
+		records = append(records, record{priority: t.GetPriority(), endpoint: t.GetEndpointName(), value: value})
+		bufferSize += int64(len(value))
+	}
+
+	maxSizeInBytes := s.diskUsageLimit.getMaxSizeInBytes()
+	if bufferSize > maxSizeInBytes {
+		return fmt.Errorf("The payload is too big. Current:%v Maximum:%v", bufferSize, maxSizeInBytes)
+	}
+	maxStorageInBytes, err := s.diskUsageLimit.computeAvailableSpace(s.currentSizeInBytes)
+	if err != nil {
+		return err
+	}
+
+	var evicted kvExtraction
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		evicted = kvExtraction{}
+		sizeToFree := s.currentSizeInBytes + bufferSize - maxStorageInBytes
+		for i := len(kvPriorityBuckets) - 1; i >= 0 && evicted.size < sizeToFree; i-- {
+			err := extractOldest(tx.Bucket(kvPriorityBuckets[i].name), func(_, value []byte) bool {
+				evicted.add(value)
+				if len(value) >= kvRecordHeaderSize {
+					evicted.pointCount += int(binary.BigEndian.Uint32(value))
+				}
+				return evicted.size < sizeToFree
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		sequence := tx.Bucket(kvSequenceBucket)
+		for _, r := range records {
+			bucket, err := tx.Bucket(priorityBucketName(r.priority)).CreateBucketIfNotExists(endpointBucketName(r.endpoint))
+			if err != nil {
+				return err
+			}
+			seq, err := sequence.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(kvKey(seq), r.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if evicted.count > 0 {
+		s.log.Errorf("Maximum disk space for retry transactions is reached. Removed %d transactions", evicted.count)
+		s.onPointDropped(evicted.pointCount)
+		s.telemetry.addFilesRemovedCount()
+	}
+	s.currentSizeInBytes += bufferSize - evicted.size
+	s.transactionsCount += len(records) - evicted.count
+	s.telemetry.setFileSize(bufferSize)
+	s.telemetry.setCurrentSizeInBytes(s.GetDiskSpaceUsed())
+	s.telemetry.setFilesCount(s.transactionsCount)
+	return nil
+}
+
+// ExtractLast extracts the transactions with the highest priority, from the
+// oldest to the most recent, up to `maxExtractSizeInBytes`.
+// The records that cannot be deserialized are removed and skipped.
+func (s *onDiskKVRetryQueue) ExtractLast() ([]transaction.Transaction, error) {
+	if s.transactionsCount == 0 {
+		return nil, nil
+	}
+	s.telemetry.addDeserializeCount()
+
+	var values [][]byte
+	var extracted kvExtraction
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		values = nil
+		extracted = kvExtraction{}
+		for _, p := range kvPriorityBuckets {
+			err := extractOldest(tx.Bucket(p.name), func(_, value []byte) bool {
+				// the value is only valid during the transaction
+				values = append(values, bytes.Clone(value))
+				extracted.add(value)
+				return extracted.size < int64(s.maxExtractSizeInBytes)
+			})
+			if err != nil {
+				return err
+			}
+			if extracted.size >= int64(s.maxExtractSizeInBytes) {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.currentSizeInBytes -= extracted.size
+	s.transactionsCount -= extracted.count
+
+	var transactions []transaction.Transaction
+	errorsCount := 0
+	for _, value := range values {
+		if len(value) < kvRecordHeaderSize {
+			errorsCount++
+			continue
+		}
+		trs, deserializeErrorsCount, err := s.serializer.Deserialize(value[kvRecordHeaderSize:])
+		if err != nil {
+			s.log.Errorf("Cannot deserialize a transaction: %v", err)
+			errorsCount++
+			continue
+		}
+		errorsCount += deserializeErrorsCount
+		transactions = append(transactions, trs...)
+	}
+
+	s.telemetry.addDeserializeErrorsCount(errorsCount)
+	s.telemetry.addDeserializeTransactionsCount(len(transactions))
+	s.telemetry.setCurrentSizeInBytes(s.GetDiskSpaceUsed())
+	s.telemetry.setFilesCount(s.transactionsCount)
+	return transactions, nil
+}
+
+// GetDiskSpaceUsed returns the current disk space used.
+func (s *onDiskKVRetryQueue) GetDiskSpaceUsed() int64 {
+	return s.currentSizeInBytes
+}
+
+func (s *onDiskKVRetryQueue) getTransactionsCount() int {
+	return s.transactionsCount
+}
+
+// Close closes the database and releases its file lock.
+func (s *onDiskKVRetryQueue) Close() error {
+	return s.db.Close()
+}
+
+func (s *onDiskKVRetryQueue) onPointDropped(count int) {
+	s.telemetry.addPointDroppedCount(count)
+	s.pointCountTelemetry.OnPointDropped(count)
+}
+
+func (s *onDiskKVRetryQueue) reloadExistingTransactions() error {
+	var reloaded kvExtraction
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(kvSequenceBucket); err != nil {
+			return err
+		}
+		for _, p := range kvPriorityBuckets {
+			bucket, err := tx.CreateBucketIfNotExists(p.name)
+			if err != nil {
+				return err
+			}
+			err = bucket.ForEachBucket(func(name []byte) error {
+				return bucket.Bucket(name).ForEach(func(_, value []byte) error {
+					reloaded.add(value)
+					return nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.currentSizeInBytes = reloaded.size
+	s.transactionsCount = reloaded.count
+	s.telemetry.setReloadedRetryFilesCount(reloaded.count)
+	return nil
+}
+
+// kvExtraction accounts for the records extracted from the key-value store
+type kvExtraction struct {
+	count      int
+	size       int64
+	pointCount int
+}
+
+func (e *kvExtraction) add(value []byte) {
+	e.count++
+	e.size += int64(len(value))
+}
+
+// extractOldest removes the records of the priority bucket from the oldest to
+// the most recent, across all its endpoints, as long as `callback` returns true.
+func extractOldest(priorityBucket *bbolt.Bucket, callback func(key, value []byte) bool) error {
+	for {
+		var oldestBucket *bbolt.Bucket
+		var oldestKey, oldestValue []byte
+		err := priorityBucket.ForEachBucket(func(name []byte) error {
+			bucket := priorityBucket.Bucket(name)
+			if key, value := bucket.Cursor().First(); key != nil && (oldestKey == nil || bytes.Compare(key, oldestKey) < 0) {
+				oldestBucket, oldestKey, oldestValue = bucket, key, value
+			}
+			return nil
+		})
+		if err != nil || oldestBucket == nil {
+			return err
+		}
+
+		next := callback(oldestKey, oldestValue)
+		if err := oldestBucket.Delete(oldestKey); err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+}
+
+func priorityBucketName(priority transaction.Priority) []byte {
+	for _, p := range kvPriorityBuckets {
+		if p.priority == priority {
+			return p.name
+		}
+	}
+	return kvPriorityBuckets[len(kvPriorityBuckets)-1].name
+}
+
+func endpointBucketName(endpoint string) []byte {
+	// bucket names cannot be empty
+	return []byte("endpoint:" + endpoint)
+}
+
+func kvKey(sequence uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return key
+}
@@ -0,0 +1,205 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package retry
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+)
+
+func TestOnDiskKVRetryQueue(t *testing.T) {
+	a := assert.New(t)
+	q := newTestOnDiskKVRetryQueue(t, a, t.TempDir(), 1000, 1000)
+
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+	a.NoError(q.Store(withPriority(transaction.TransactionPriorityHigh, createHTTPTransactionCollectionTests("endpoint3"))))
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint4")))
+	a.Equal(5, q.getTransactionsCount())
+	a.Greater(q.GetDiskSpaceUsed(), int64(0))
+
+	// The transactions with a high priority are extracted first, then the others
+	// from the oldest to the most recent.
+	transactions, err := q.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint3", "endpoint1", "endpoint2", "endpoint1", "endpoint4"}, getEndpointsFromTransactions(transactions))
+	a.Equal(transaction.TransactionPriorityHigh, transactions[0].GetPriority())
+	a.Equal(0, q.getTransactionsCount())
+	a.Equal(int64(0), q.GetDiskSpaceUsed())
+
+	transactions, err = q.ExtractLast()
+	a.NoError(err)
+	a.Empty(transactions)
+}
+
+func TestOnDiskKVRetryQueueMaxExtractSize(t *testing.T) {
+	a := assert.New(t)
+	q := newTestOnDiskKVRetryQueue(t, a, t.TempDir(), 1000, 1)
+
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+
+	// At least one transaction is extracted, even when it is bigger than the maximum size.
+	for _, endpoint := range []string{"endpoint1", "endpoint2"} {
+		transactions, err := q.ExtractLast()
+		a.NoError(err)
+		a.Equal([]string{endpoint}, getEndpointsFromTransactions(transactions))
+	}
+	a.Equal(0, q.getTransactionsCount())
+}
+
+func TestOnDiskKVRetryQueueMaxSize(t *testing.T) {
+	a := assert.New(t)
+	maxSizeInBytes := int64(200)
+	pointDropped := fileStoragePointDroppedCountTelemetry.expvar.Value()
+	q := newTestOnDiskKVRetryQueue(t, a, t.TempDir(), maxSizeInBytes, 1000)
+
+	a.NoError(q.Store(withPriority(transaction.TransactionPriorityHigh, createHTTPTransactionCollectionTests("0"))))
+	maxTransactionsCount := int(maxSizeInBytes / q.GetDiskSpaceUsed())
+	a.Greaterf(maxTransactionsCount, 3, "Not enough transactions for this test, increase maxSizeInBytes")
+
+	// The transactions with a normal priority are evicted before the ones with a high priority
+	transactionsToDrop := 2
+	for i := 1; i < maxTransactionsCount+transactionsToDrop; i++ {
+		a.NoError(q.Store(createHTTPTransactionCollectionTests(strconv.Itoa(i))))
+	}
+	a.LessOrEqual(q.GetDiskSpaceUsed(), maxSizeInBytes)
+	a.Equal(maxTransactionsCount, q.getTransactionsCount())
+	a.Equal(pointDropped+int64(transactionsToDrop), fileStoragePointDroppedCountTelemetry.expvar.Value())
+
+	transactions, err := q.ExtractLast()
+	a.NoError(err)
+	expected := []string{"0"}
+	for i := 1 + transactionsToDrop; i < maxTransactionsCount+transactionsToDrop; i++ {
+		expected = append(expected, strconv.Itoa(i))
+	}
+	a.Equal(expected, getEndpointsFromTransactions(transactions))
+
+	tooManyEndpoints := make([]string, maxTransactionsCount+1)
+	for i := range tooManyEndpoints {
+		tooManyEndpoints[i] = strconv.Itoa(i)
+	}
+	err = q.Store(createHTTPTransactionCollectionTests(tooManyEndpoints...))
+	a.ErrorContains(err, "The payload is too big")
+}
+
+func TestOnDiskKVRetryQueueReloadExistingTransactions(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	q := newTestOnDiskKVRetryQueue(t, a, path, 1000, 1000)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+	diskSpaceUsed := q.GetDiskSpaceUsed()
+	a.NoError(q.Close())
+
+	q = newTestOnDiskKVRetryQueue(t, a, path, 1000, 1000)
+	a.Equal(diskSpaceUsed, q.GetDiskSpaceUsed())
+	a.Equal(2, q.getTransactionsCount())
+
+	// The sequence is persisted: the new transactions are extracted after the reloaded ones
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint3")))
+	transactions, err := q.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1", "endpoint2", "endpoint3"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestOnDiskKVRetryQueueCorruptedRecord(t *testing.T) {
+	a := assert.New(t)
+	q := newTestOnDiskKVRetryQueue(t, a, t.TempDir(), 1000, 1000)
+
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+
+	// Corrupt the record of the first transaction
+	err := q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(priorityBucketName(transaction.TransactionPriorityNormal)).Bucket(endpointBucketName("endpoint1"))
+		key, _ := bucket.Cursor().First()
+		return bucket.Put(key, []byte{0, 0, 0, 1, 0xff, 0xff})
+	})
+	a.NoError(err)
+
+	transactions, err := q.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint2"}, getEndpointsFromTransactions(transactions))
+	a.Equal(0, q.getTransactionsCount())
+}
+
+func TestBuildTransactionRetryQueueStorageBackend(t *testing.T) {
+	a := assert.New(t)
+	log := logmock.New(t)
+	resolver := resolver.NewSingleDomainResolver(domainName, nil)
+	diskUsageLimit := NewDiskUsageLimit("", diskUsageRetrieverMock{
+		diskUsage: &filesystem.DiskUsage{
+			Available: 10000,
+			Total:     10000,
+		}}, 1000, 1)
+	build := func(storageBackend string) *TransactionRetryQueue {
+		return BuildTransactionRetryQueue(log, 100, 0.5, t.TempDir(), diskUsageLimit, storageBackend, createDropPrioritySorter(), resolver, NewPointCountTelemetryMock())
+	}
+
+	a.IsType(&onDiskRetryQueue{}, build("").optionalStorage)
+	a.IsType(&onDiskRetryQueue{}, build(StorageBackendFile).optionalStorage)
+
+	kvStorage, ok := build(StorageBackendKV).optionalStorage.(*onDiskKVRetryQueue)
+	a.True(ok)
+	a.NoError(kvStorage.Close())
+
+	// The retry queue is still used in memory when the storage backend is unknown
+	a.Nil(build("unknown").optionalStorage)
+}
+
+func TestTransactionRetryQueueCloseReleasesKVStorage(t *testing.T) {
+	a := assert.New(t)
+	log := logmock.New(t)
+	folder := t.TempDir()
+	resolver := resolver.NewSingleDomainResolver(domainName, nil)
+	diskUsageLimit := NewDiskUsageLimit("", diskUsageRetrieverMock{
+		diskUsage: &filesystem.DiskUsage{
+			Available: 10000,
+			Total:     10000,
+		}}, 1000, 1)
+	build := func() *TransactionRetryQueue {
+		return BuildTransactionRetryQueue(log, 100, 0.5, folder, diskUsageLimit, StorageBackendKV, createDropPrioritySorter(), resolver, NewPointCountTelemetryMock())
+	}
+
+	q := build()
+	a.NotNil(q.optionalStorage)
+	a.NoError(q.Close())
+
+	// The database is no longer locked once the queue is closed
+	q = build()
+	a.NotNil(q.optionalStorage)
+	a.NoError(q.Close())
+}
+
+func withPriority(priority transaction.Priority, transactions []transaction.Transaction) []transaction.Transaction {
+	for _, t := range transactions {
+		t.(*transaction.HTTPTransaction).Priority = priority
+	}
+	return transactions
+}
+
+func newTestOnDiskKVRetryQueue(t *testing.T, a *assert.Assertions, path string, maxSizeInBytes int64, maxExtractSizeInBytes int) *onDiskKVRetryQueue {
+	telemetry := newOnDiskRetryQueueTelemetry("domain")
+	disk := diskUsageRetrieverMock{
+		diskUsage: &filesystem.DiskUsage{
+			Available: 10000,
+			Total:     10000,
+		}}
+	diskUsageLimit := NewDiskUsageLimit("", disk, maxSizeInBytes, 1)
+	log := logmock.New(t)
+	storage, err := newOnDiskKVRetryQueue(log, NewHTTPTransactionsSerializer(log, resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, maxExtractSizeInBytes, telemetry, NewPointCountTelemetryMock())
+	a.NoError(err)
+	t.Cleanup(func() { _ = storage.Close() })
+	return storage
+}
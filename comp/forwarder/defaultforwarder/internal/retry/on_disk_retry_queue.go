@@ -151,6 +151,11 @@ func (s *onDiskRetryQueue) GetDiskSpaceUsed() int64 {
 	return s.currentSizeInBytes
 }
 
+// Close is a no-op as the retry files are not kept open.
+func (s *onDiskRetryQueue) Close() error {
+	return nil
+}
+
 func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 	maxSizeInBytes := s.diskUsageLimit.getMaxSizeInBytes()
 	if bufferSize > maxSizeInBytes {
@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	"go.etcd.io/bbolt"
+)
+
+// RetryFile is a file storing transactions of the retry queue: either a `.retry`
+// file or the database of the key-value storage.
+type RetryFile struct {
+	Path         string
+	DomainFolder string
+	Backend      string
+	Size         int64
+	ModTime      time.Time
+}
+
+// StoredTransaction is a transaction read from a retry file. Its API keys are
+// replaced by placeholders.
+type StoredTransaction struct {
+	*HttpTransactionProto
+
+	// the position of the transaction in a `.retry` file
+	index int
+	// the buckets and the key of the record in the key-value storage
+	bucketPath [][]byte
+	key        []byte
+}
+
+// ListRetryFiles lists the retry files of all the domain folders of `storagePath`,
+// from the oldest to the most recent in each domain folder.
+func ListRetryFiles(storagePath string) ([]RetryFile, error) {
+	entries, err := os.ReadDir(storagePath)
+	if os.IsNotExist(err) {
+		// Nothing was stored on the disk yet
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var retryFiles []RetryFile
+	for _, domain := range entries {
+		if !domain.IsDir() {
+			continue
+		}
+		folder := path.Join(storagePath, domain.Name())
+		files, err := getRetryFiles(folder)
+		if err != nil {
+			return nil, err
+		}
+		// The name of the `.retry` files starts with their creation time
+		sort.Strings(files)
+		// The database of the key-value storage is not a `.retry` file
+		kvFile := path.Join(folder, kvStorageFileName)
+		if info, err := os.Stat(kvFile); err == nil && info.Mode().IsRegular() {
+			files = append(files, kvFile)
+		}
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				return nil, err
+			}
+			backend := StorageBackendFile
+			if filepath.Base(file) == kvStorageFileName {
+				backend = StorageBackendKV
+			}
+			retryFiles = append(retryFiles, RetryFile{
+				Path:         file,
+				DomainFolder: domain.Name(),
+				Backend:      backend,
+				Size:         info.Size(),
+				ModTime:      info.ModTime(),
+			})
+		}
+	}
+	return retryFiles, nil
+}
+
+// ReadRetryFile reads the transactions of a retry file, from the oldest to the
+// most recent, and returns the number of records which cannot be read.
+// The database of the key-value storage cannot be read while an Agent uses it.
+func ReadRetryFile(filePath string) ([]StoredTransaction, int, error) {
+	if filepath.Base(filePath) == kvStorageFileName {
+		return readKVRetryFile(filePath)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	collection := HttpTransactionProtoCollection{}
+	if err := proto.Unmarshal(content, &collection); err != nil {
+		return nil, 0, fmt.Errorf("cannot read the transactions of %s: %v", filePath, err)
+	}
+	transactions := make([]StoredTransaction, 0, len(collection.Values))
+	for i, tr := range collection.Values {
+		transactions = append(transactions, StoredTransaction{HttpTransactionProto: tr, index: i})
+	}
+	return transactions, 0, nil
+}
+
+// RemoveFromRetryFile removes transactions read by `ReadRetryFile` from a
+// retry file. A `.retry` file is removed when no transaction is left.
+func RemoveFromRetryFile(filePath string, transactions []StoredTransaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	if filepath.Base(filePath) == kvStorageFileName {
+		return removeFromKVRetryFile(filePath, transactions)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	collection := HttpTransactionProtoCollection{}
+	if err := proto.Unmarshal(content, &collection); err != nil {
+		return err
+	}
+	removed := make(map[int]struct{}, len(transactions))
+	for _, tr := range transactions {
+		removed[tr.index] = struct{}{}
+	}
+	var values []*HttpTransactionProto
+	for i, tr := range collection.Values {
+		if _, found := removed[i]; !found {
+			values = append(values, tr)
+		}
+	}
+	if len(values) == 0 {
+		return os.Remove(filePath)
+	}
+
+	collection.Values = values
+	content, err = proto.Marshal(&collection)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first to not lose the remaining transactions on failure
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+func openKVRetryFile(filePath string, readOnly bool) (*bbolt.DB, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, err
+	}
+	db, err := bbolt.Open(filePath, 0600, &bbolt.Options{
+		Timeout:  kvStorageLockTimeout,
+		ReadOnly: readOnly,
+	})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is locked, stop the Agent before using it", filePath)
+	}
+	return db, err
+}
+
+func readKVRetryFile(filePath string) ([]StoredTransaction, int, error) {
+	db, err := openKVRetryFile(filePath, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer db.Close()
+
+	var transactions []StoredTransaction
+	errorsCount := 0
+	err = db.View(func(tx *bbolt.Tx) error {
+		for _, p := range kvPriorityBuckets {
+			priorityBucket := tx.Bucket(p.name)
+			if priorityBucket == nil {
+				continue
+			}
+			err := priorityBucket.ForEachBucket(func(name []byte) error {
+				return priorityBucket.Bucket(name).ForEach(func(key, value []byte) error {
+					collection := HttpTransactionProtoCollection{}
+					if len(value) < kvRecordHeaderSize || proto.Unmarshal(value[kvRecordHeaderSize:], &collection) != nil {
+						errorsCount++
+						return nil
+					}
+					// the keys and the values are only valid during the transaction
+					bucketPath := [][]byte{p.name, bytes.Clone(name)}
+					for _, tr := range collection.Values {
+						transactions = append(transactions, StoredTransaction{HttpTransactionProto: tr, bucketPath: bucketPath, key: bytes.Clone(key)})
+					}
+					return nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The keys are the insertion order of the transactions
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return bytes.Compare(transactions[i].key, transactions[j].key) < 0
+	})
+	return transactions, errorsCount, nil
+}
+
+func removeFromKVRetryFile(filePath string, transactions []StoredTransaction) error {
+	db, err := openKVRetryFile(filePath, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		for _, tr := range transactions {
+			if len(tr.bucketPath) != 2 {
+				return fmt.Errorf("the transaction was not read from %s", filePath)
+			}
+			priorityBucket := tx.Bucket(tr.bucketPath[0])
+			if priorityBucket == nil {
+				continue
+			}
+			if bucket := priorityBucket.Bucket(tr.bucketPath[1]); bucket != nil {
+				if err := bucket.Delete(tr.key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package retry
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAndRemoveRetryFile(t *testing.T) {
+	a := assert.New(t)
+	root := t.TempDir()
+	q := newTestOnDiskRetryQueue(t, a, path.Join(root, "domain"), 1000)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint2", "endpoint3")))
+
+	files, err := ListRetryFiles(root)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	a.Equal("domain", files[0].DomainFolder)
+	a.Equal(StorageBackendFile, files[0].Backend)
+	a.Equal(q.GetDiskSpaceUsed(), files[0].Size)
+
+	transactions, errorsCount, err := ReadRetryFile(files[0].Path)
+	require.NoError(t, err)
+	a.Equal(0, errorsCount)
+	a.Equal([]string{"endpoint1", "endpoint2", "endpoint3"}, getStoredEndpoints(transactions))
+
+	// The remaining transactions are kept in the file
+	a.NoError(RemoveFromRetryFile(files[0].Path, []StoredTransaction{transactions[0], transactions[2]}))
+	transactions, _, err = ReadRetryFile(files[0].Path)
+	require.NoError(t, err)
+	a.Equal([]string{"endpoint2"}, getStoredEndpoints(transactions))
+
+	a.NoError(RemoveFromRetryFile(files[0].Path, transactions))
+	_, err = os.Stat(files[0].Path)
+	a.True(os.IsNotExist(err))
+}
+
+func TestReadAndRemoveKVRetryFile(t *testing.T) {
+	a := assert.New(t)
+	root := t.TempDir()
+	q := newTestOnDiskKVRetryQueue(t, a, path.Join(root, "domain"), 1000, 1000)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1")))
+
+	// The database cannot be read while it is used
+	files, err := ListRetryFiles(root)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	a.Equal(StorageBackendKV, files[0].Backend)
+	_, _, err = ReadRetryFile(files[0].Path)
+	a.ErrorContains(err, "stop the Agent")
+	a.NoError(q.Close())
+
+	transactions, errorsCount, err := ReadRetryFile(files[0].Path)
+	require.NoError(t, err)
+	a.Equal(0, errorsCount)
+	a.Equal([]string{"endpoint1", "endpoint2", "endpoint1"}, getStoredEndpoints(transactions))
+
+	a.NoError(RemoveFromRetryFile(files[0].Path, transactions[:2]))
+	transactions, _, err = ReadRetryFile(files[0].Path)
+	require.NoError(t, err)
+	a.Equal([]string{"endpoint1"}, getStoredEndpoints(transactions))
+}
+
+func TestReplaceAPIKeyPlaceholders(t *testing.T) {
+	apiKeyToPlaceholder, _ := createReplacers([]string{"key1", "key2"})
+	str := apiKeyToPlaceholder.Replace("/api/v1/series?api_key=key2&other=key1")
+	assert.Equal(t, "/api/v1/series?api_key=<1>&other=<0>", ReplaceAPIKeyPlaceholders(str, func(index string) string {
+		return "<" + index + ">"
+	}))
+}
+
+func getStoredEndpoints(transactions []StoredTransaction) []string {
+	var endpoints []string
+	for _, t := range transactions {
+		endpoints = append(endpoints, t.Endpoint.Name)
+	}
+	return endpoints
+}
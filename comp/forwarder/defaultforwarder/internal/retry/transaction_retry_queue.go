@@ -16,11 +16,19 @@ import (
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 )
 
+const (
+	// StorageBackendFile stores each batch of transactions flushed to the disk in its own `.retry` file.
+	StorageBackendFile = "file"
+	// StorageBackendKV stores the transactions in an embedded indexed key-value store.
+	StorageBackendKV = "kv"
+)
+
 // TransactionDiskStorage is an interface to store and load transactions from disk
 type TransactionDiskStorage interface {
 	Store([]transaction.Transaction) error
 	ExtractLast() ([]transaction.Transaction, error)
 	GetDiskSpaceUsed() int64
+	Close() error
 }
 
 // TransactionPrioritySorter is an interface to sort transactions.
@@ -49,6 +57,7 @@ func BuildTransactionRetryQueue(
 	flushToStorageRatio float64,
 	optionalDomainFolderPath string,
 	optionalDiskUsageLimit *DiskUsageLimit,
+	storageBackend string,
 	dropPrioritySorter TransactionPrioritySorter,
 	resolver resolver.DomainResolver,
 	pointCountTelemetry *PointCountTelemetry) *TransactionRetryQueue {
@@ -58,7 +67,20 @@ func BuildTransactionRetryQueue(
 
 	if optionalDomainFolderPath != "" && optionalDiskUsageLimit != nil {
 		serializer := NewHTTPTransactionsSerializer(log, resolver)
-		storage, err = newOnDiskRetryQueue(log, serializer, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()), pointCountTelemetry)
+		telemetry := newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain())
+		switch storageBackend {
+		case StorageBackendKV:
+			// extract from the disk as many transactions as flushed to it at once
+			maxExtractSizeInBytes := int(float64(maxMemSizeInBytes) * flushToStorageRatio)
+			var kvStorage *onDiskKVRetryQueue
+			if kvStorage, err = newOnDiskKVRetryQueue(log, serializer, optionalDomainFolderPath, optionalDiskUsageLimit, maxExtractSizeInBytes, telemetry, pointCountTelemetry); kvStorage != nil {
+				storage = kvStorage
+			}
+		case StorageBackendFile, "":
+			storage, err = newOnDiskRetryQueue(log, serializer, optionalDomainFolderPath, optionalDiskUsageLimit, telemetry, pointCountTelemetry)
+		default:
+			err = fmt.Errorf("unknown storage backend '%s', must be '%s' or '%s'", storageBackend, StorageBackendFile, StorageBackendKV)
+		}
 
 		// If the storage on disk cannot be used, log the error and continue.
 		// Returning `nil, err` would mean not using `TransactionRetryQueue` and so not using `forwarder_retry_queue_payloads_max_size` config.
@@ -222,6 +244,18 @@ func (tc *TransactionRetryQueue) FlushToDisk() error {
 	return tc.optionalStorage.Store(transactions)
 }
 
+// Close releases the resources held by the storage on disk, if any. It must be called
+// once the queue is no longer used, after FlushToDisk.
+func (tc *TransactionRetryQueue) Close() error {
+	if tc.optionalStorage == nil {
+		return nil
+	}
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	return tc.optionalStorage.Close()
+}
+
 func (tc *TransactionRetryQueue) extractTransactionsForDisk(payloadSize int) [][]transaction.Transaction {
 	sizeInBytesToFlush := int(float64(tc.maxMemSizeInBytes) * tc.flushToStorageRatio)
 	var payloadsGroupToFlush [][]transaction.Transaction
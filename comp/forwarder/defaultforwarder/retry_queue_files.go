@@ -0,0 +1,271 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config/utils"
+)
+
+// RetryQueueFile is a file of the retry queue stored on the disk by the forwarder.
+type RetryQueueFile struct {
+	Path              string    `json:"path"`
+	Domain            string    `json:"domain,omitempty"`
+	Backend           string    `json:"backend"`
+	Size              int64     `json:"size"`
+	ModTime           time.Time `json:"mod_time"`
+	TransactionsCount int       `json:"transactions_count"`
+	PointsCount       int       `json:"points_count"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// RetryQueueTransaction is a transaction of the retry queue stored on the disk.
+// The API keys of its route and of its headers are redacted.
+type RetryQueueTransaction struct {
+	Endpoint    string              `json:"endpoint"`
+	Route       string              `json:"route"`
+	Headers     map[string][]string `json:"headers"`
+	PayloadSize int                 `json:"payload_size"`
+	Payload     []byte              `json:"payload,omitempty"`
+	PointCount  int                 `json:"point_count"`
+	ErrorCount  int                 `json:"error_count"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Retryable   bool                `json:"retryable"`
+	Priority    string              `json:"priority"`
+	Destination string              `json:"destination"`
+}
+
+// RetryQueueReplayOptions defines how the transactions of a retry file are replayed.
+type RetryQueueReplayOptions struct {
+	// Domain is the URL the transactions are sent to, for instance `https://app.datadoghq.eu`.
+	Domain string
+	// APIKey replaces the API keys of the transactions when set. It is required
+	// when the domain of the retry file is no longer configured.
+	APIKey string
+	// Remove removes from the retry file the transactions which don't need to be retried.
+	Remove bool
+}
+
+// RetryQueueReplayResult counts the transactions of a retry file by outcome.
+type RetryQueueReplayResult struct {
+	// Sent is the number of transactions accepted by the intake.
+	Sent int
+	// Rejected is the number of transactions rejected by the intake which would be dropped by the forwarder.
+	Rejected int
+	// Failed is the number of transactions that the forwarder would retry.
+	Failed int
+}
+
+// RetryQueue gives access to the transactions stored on the disk by the forwarder
+// of the core Agent, to inspect and replay them while the Agent is stopped.
+type RetryQueue struct {
+	config       config.Component
+	log          log.Component
+	storagePath  string
+	domainByPath map[string]resolver.DomainResolver
+}
+
+// NewRetryQueue creates a new RetryQueue from the configuration of the forwarder.
+func NewRetryQueue(config config.Component, log log.Component) *RetryQueue {
+	q := &RetryQueue{
+		config:       config,
+		log:          log,
+		storagePath:  getStoragePath(config, "core"),
+		domainByPath: make(map[string]resolver.DomainResolver),
+	}
+	for domain, resolver := range resolver.NewSingleDomainResolvers(getMultipleEndpoints(config, log)) {
+		// The folders are named after the domain with the version of the Agent, as in `NewDefaultForwarder`
+		domain, _ := utils.AddAgentVersionToDomain(domain, "app")
+		resolver.SetBaseDomain(domain)
+		if folder, err := retry.DomainFolderName(domain); err == nil {
+			q.domainByPath[folder] = resolver
+		}
+	}
+	return q
+}
+
+// StoragePath returns the folder where the retry files are stored.
+func (q *RetryQueue) StoragePath() string {
+	return q.storagePath
+}
+
+// List lists the retry files, from the oldest to the most recent of each domain.
+// A file which cannot be read is listed with its error.
+func (q *RetryQueue) List() ([]RetryQueueFile, error) {
+	retryFiles, err := retry.ListRetryFiles(q.storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]RetryQueueFile, 0, len(retryFiles))
+	for _, f := range retryFiles {
+		file := RetryQueueFile{
+			Path:    f.Path,
+			Backend: f.Backend,
+			Size:    f.Size,
+			ModTime: f.ModTime,
+		}
+		if r, found := q.domainByPath[f.DomainFolder]; found {
+			file.Domain = r.GetBaseDomain()
+		}
+		transactions, errorsCount, err := retry.ReadRetryFile(f.Path)
+		if err != nil {
+			file.Error = err.Error()
+		} else if errorsCount > 0 {
+			file.Error = fmt.Sprintf("%d records cannot be read", errorsCount)
+		}
+		file.TransactionsCount = len(transactions)
+		for _, tr := range transactions {
+			file.PointsCount += int(tr.PointCount)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Inspect returns the transactions of a retry file, from the oldest to the most recent.
+func (q *RetryQueue) Inspect(filePath string, withPayload bool) ([]RetryQueueTransaction, error) {
+	stored, errorsCount, err := retry.ReadRetryFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if errorsCount > 0 {
+		q.log.Warnf("%d records of %s cannot be read", errorsCount, filePath)
+	}
+
+	transactions := make([]RetryQueueTransaction, 0, len(stored))
+	for _, tr := range stored {
+		transactions = append(transactions, newRetryQueueTransaction(tr.HttpTransactionProto, withPayload))
+	}
+	return transactions, nil
+}
+
+// Replay sends the transactions of a retry file to another domain.
+func (q *RetryQueue) Replay(ctx context.Context, filePath string, options RetryQueueReplayOptions) (RetryQueueReplayResult, error) {
+	var result RetryQueueReplayResult
+	if options.Domain == "" {
+		return result, fmt.Errorf("the domain to replay the transactions to is required")
+	}
+
+	apiKeys := []string{options.APIKey}
+	if options.APIKey == "" {
+		r, found := q.domainByPath[filepath.Base(filepath.Dir(filePath))]
+		if !found {
+			return result, fmt.Errorf("the domain of %s is not configured, an API key is required to replay its transactions", filePath)
+		}
+		apiKeys = r.GetAPIKeys()
+	}
+	serializer := retry.NewHTTPTransactionsSerializer(q.log, resolver.NewSingleDomainResolver(strings.TrimSuffix(options.Domain, "/"), apiKeys))
+
+	stored, errorsCount, err := retry.ReadRetryFile(filePath)
+	if err != nil {
+		return result, err
+	}
+	if errorsCount > 0 {
+		q.log.Warnf("%d records of %s cannot be read", errorsCount, filePath)
+	}
+
+	client := NewHTTPClient(q.config)
+	var done []retry.StoredTransaction
+	for _, tr := range stored {
+		if options.APIKey != "" {
+			tr.HttpTransactionProto = withAPIKey(tr.HttpTransactionProto, options.APIKey)
+		}
+		httpTransaction, err := serializer.FromProto(tr.HttpTransactionProto)
+		if err != nil {
+			q.log.Errorf("Cannot replay a transaction of %s: %v", filePath, err)
+			result.Failed++
+			continue
+		}
+
+		statusCode := 0
+		var sendErr error
+		httpTransaction.CompletionHandler = func(_ *transaction.HTTPTransaction, code int, _ []byte, err error) {
+			statusCode, sendErr = code, err
+		}
+		// The transactions which are not retryable don't return their error
+		if err := httpTransaction.Process(ctx, q.config, q.log, client); err != nil || sendErr != nil || ctx.Err() != nil {
+			result.Failed++
+			continue
+		}
+		if statusCode >= http.StatusBadRequest {
+			result.Rejected++
+		} else {
+			result.Sent++
+		}
+		done = append(done, tr)
+	}
+
+	if options.Remove {
+		if err := retry.RemoveFromRetryFile(filePath, done); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func newRetryQueueTransaction(tr *retry.HttpTransactionProto, withPayload bool) RetryQueueTransaction {
+	t := RetryQueueTransaction{
+		Headers:     make(map[string][]string, len(tr.Headers)),
+		PayloadSize: len(tr.Payload),
+		PointCount:  int(tr.PointCount),
+		ErrorCount:  int(tr.ErrorCount),
+		CreatedAt:   time.Unix(tr.CreatedAt, 0).UTC(),
+		Retryable:   tr.Retryable,
+		Priority:    strings.ToLower(tr.Priority.String()),
+		Destination: strings.ToLower(tr.Destination.String()),
+	}
+	if tr.Endpoint != nil {
+		t.Endpoint = tr.Endpoint.Name
+		t.Route = redactAPIKeys(tr.Endpoint.Route)
+	}
+	for key, values := range tr.Headers {
+		for _, v := range values.GetValues() {
+			t.Headers[key] = append(t.Headers[key], redactAPIKeys(v))
+		}
+	}
+	if withPayload {
+		t.Payload = tr.Payload
+	}
+	return t
+}
+
+func redactAPIKeys(str string) string {
+	return retry.ReplaceAPIKeyPlaceholders(str, func(index string) string {
+		return "<api_key_" + index + ">"
+	})
+}
+
+// withAPIKey returns a copy of the transaction using `apiKey` for all its API keys
+func withAPIKey(tr *retry.HttpTransactionProto, apiKey string) *retry.HttpTransactionProto {
+	replace := func(str string) string {
+		return retry.ReplaceAPIKeyPlaceholders(str, func(string) string { return apiKey })
+	}
+	copied := *tr
+	if tr.Endpoint != nil {
+		copied.Endpoint = &retry.EndpointProto{Route: replace(tr.Endpoint.Route), Name: tr.Endpoint.Name}
+	}
+	copied.Headers = make(map[string]*retry.HeaderValuesProto, len(tr.Headers))
+	for key, values := range tr.Headers {
+		headerValues := &retry.HeaderValuesProto{}
+		for _, v := range values.GetValues() {
+			headerValues.Values = append(headerValues.Values, replace(v))
+		}
+		copied.Headers[key] = headerValues
+	}
+	return &copied
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+func newTestRetryQueue(t *testing.T, routes ...string) (*RetryQueue, string) {
+	mockConfig := config.NewMock(t)
+	mockConfig.Set("api_key", "api_key1", pkgconfigmodel.SourceAgentRuntime)
+	mockConfig.Set("forwarder_storage_path", t.TempDir(), pkgconfigmodel.SourceAgentRuntime)
+	log := logmock.New(t)
+	q := NewRetryQueue(mockConfig, log)
+	require.Len(t, q.domainByPath, 1)
+
+	// Store the transactions as the forwarder does
+	for folder, r := range q.domainByPath {
+		serializer := retry.NewHTTPTransactionsSerializer(log, r)
+		for _, route := range routes {
+			tr := transaction.NewHTTPTransaction()
+			tr.Domain = r.GetBaseDomain()
+			tr.Endpoint = transaction.Endpoint{Route: route + "?api_key=api_key1", Name: route}
+			tr.Headers.Set("DD-Api-Key", "api_key1")
+			tr.Payload = transaction.NewBytesPayload([]byte("payload"), 2)
+			tr.Retryable = true
+			require.NoError(t, serializer.Add(tr))
+		}
+		content, err := serializer.GetBytesAndReset()
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(path.Join(q.StoragePath(), folder), 0700))
+		filePath := path.Join(q.StoragePath(), folder, "2024_01_01__00_00_00_1.retry")
+		require.NoError(t, os.WriteFile(filePath, content, 0600))
+		return q, filePath
+	}
+	return nil, ""
+}
+
+func TestRetryQueueListAndInspect(t *testing.T) {
+	q, filePath := newTestRetryQueue(t, "/api/v1/series", "/api/v1/check_run")
+
+	files, err := q.List()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, filePath, files[0].Path)
+	assert.NotEmpty(t, files[0].Domain)
+	assert.Equal(t, "file", files[0].Backend)
+	assert.Equal(t, 2, files[0].TransactionsCount)
+	assert.Equal(t, 4, files[0].PointsCount)
+	assert.Empty(t, files[0].Error)
+
+	transactions, err := q.Inspect(filePath, false)
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "/api/v1/series", transactions[0].Endpoint)
+	assert.Equal(t, "/api/v1/series?api_key=<api_key_0>", transactions[0].Route)
+	assert.Equal(t, []string{"<api_key_0>"}, transactions[0].Headers["Dd-Api-Key"])
+	assert.Equal(t, len("payload"), transactions[0].PayloadSize)
+	assert.Nil(t, transactions[0].Payload)
+	assert.Equal(t, "normal", transactions[0].Priority)
+	assert.Equal(t, "all_regions", transactions[0].Destination)
+
+	transactions, err = q.Inspect(filePath, true)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("payload"), transactions[1].Payload)
+}
+
+func TestRetryQueueReplay(t *testing.T) {
+	q, filePath := newTestRetryQueue(t, "/accepted", "/rejected", "/failed")
+
+	var apiKeys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys = append(apiKeys, r.URL.Query().Get("api_key"), r.Header.Get("DD-Api-Key"))
+		switch r.URL.Path {
+		case "/accepted":
+			w.WriteHeader(http.StatusAccepted)
+		case "/rejected":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	_, err := q.Replay(context.Background(), filePath, RetryQueueReplayOptions{})
+	assert.Error(t, err)
+
+	result, err := q.Replay(context.Background(), filePath, RetryQueueReplayOptions{Domain: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, RetryQueueReplayResult{Sent: 1, Rejected: 1, Failed: 1}, result)
+	assert.Equal(t, []string{"api_key1", "api_key1", "api_key1", "api_key1", "api_key1", "api_key1"}, apiKeys)
+
+	// The transactions which don't need to be retried are removed
+	apiKeys = nil
+	result, err = q.Replay(context.Background(), filePath, RetryQueueReplayOptions{Domain: ts.URL, APIKey: "api_key2", Remove: true})
+	require.NoError(t, err)
+	assert.Equal(t, RetryQueueReplayResult{Sent: 1, Rejected: 1, Failed: 1}, result)
+	assert.Equal(t, "api_key2", apiKeys[0])
+	assert.Equal(t, "api_key2", apiKeys[1])
+
+	transactions, err := q.Inspect(filePath, false)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "/failed", transactions[0].Endpoint)
+
+	// The API keys of an unknown domain cannot be restored
+	unknownDomainPath := path.Join(q.StoragePath(), "unknown", path.Base(filePath))
+	require.NoError(t, os.MkdirAll(path.Dir(unknownDomainPath), 0700))
+	require.NoError(t, os.Rename(filePath, unknownDomainPath))
+	_, err = q.Replay(context.Background(), unknownDomainPath, RetryQueueReplayOptions{Domain: ts.URL})
+	assert.ErrorContains(t, err, "an API key is required")
+}
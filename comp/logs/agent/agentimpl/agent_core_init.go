@@ -26,6 +26,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers/listener"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers/windowsevent"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util/optional"
@@ -44,7 +45,7 @@ func (a *logAgent) SetupPipeline(processingRules []*config.ProcessingRule, wmeta
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver(nil, a.hostname)
 
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, a.endpoints, destinationsCtx, NewStatusProvider(), a.hostname, a.config)
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, a.endpoints, destinationsCtx, NewStatusProvider(), a.hostname, a.config, a.metricSender())
 
 	// setup the launchers
 	lnchrs := launchers.NewLaunchers(a.sources, pipelineProvider, auditor, a.tracker)
@@ -52,12 +53,14 @@ func (a *logAgent) SetupPipeline(processingRules []*config.ProcessingRule, wmeta
 	fileLimits := a.config.GetInt("logs_config.open_files_limit")
 	fileValidatePodContainer := a.config.GetBool("logs_config.validate_pod_container_id")
 	fileScanPeriod := time.Duration(a.config.GetFloat64("logs_config.file_scan_period") * float64(time.Second))
+	fileWatchEvents := a.config.GetBool("logs_config.file_watcher_enabled")
 	fileWildcardSelectionMode := a.config.GetString("logs_config.file_wildcard_selection_mode")
 	lnchrs.AddLauncher(filelauncher.NewLauncher(
 		fileLimits,
 		filelauncher.DefaultSleepDuration,
 		fileValidatePodContainer,
 		fileScanPeriod,
+		fileWatchEvents,
 		fileWildcardSelectionMode,
 		a.flarecontroller,
 		a.tagger))
@@ -86,3 +89,17 @@ func buildEndpoints(coreConfig model.Reader) (*config.Endpoints, error) {
 	}
 	return config.BuildEndpointsWithVectorOverride(coreConfig, httpConnectivity, intakeTrackType, config.AgentJSONIntakeProtocol, config.DefaultIntakeOrigin)
 }
+
+// metricSender returns the sender of the metrics generated from logs by the
+// processing rules, nil if there is no aggregator.
+func (a *logAgent) metricSender() processor.MetricSender {
+	if a.senderManager == nil {
+		return nil
+	}
+	sender, err := a.senderManager.GetDefaultSender()
+	if err != nil {
+		a.log.Warnf("Metrics cannot be generated from logs: %v", err)
+		return nil
+	}
+	return sender
+}
@@ -63,6 +63,7 @@ func (a *logAgent) SetupPipeline(
 		filelauncher.DefaultSleepDuration,
 		fileValidatePodContainer,
 		fileScanPeriod,
+		false,
 		fileWildcardSelectionMode,
 		a.flarecontroller,
 		a.tagger))
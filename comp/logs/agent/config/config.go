@@ -115,7 +115,10 @@ func BuildEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *Logs
 	}
 
 	mrfEnabled := coreConfig.GetBool("multi_region_failover.enabled")
-	if logsConfig.isForceHTTPUse() || logsConfig.obsPipelineWorkerEnabled() || mrfEnabled || (bool(httpConnectivity) && !(logsConfig.isForceTCPUse() || logsConfig.isSocks5ProxySet() || logsConfig.hasAdditionalEndpoints())) {
+	// the file endpoints write the logs encoded in JSON even when the intake
+	// cannot be reached, e.g. on air-gapped hosts
+	fileEndpoints := logsConfig.hasFileEndpoints() && !(logsConfig.isForceTCPUse() || logsConfig.isSocks5ProxySet())
+	if logsConfig.isForceHTTPUse() || logsConfig.obsPipelineWorkerEnabled() || mrfEnabled || fileEndpoints || (bool(httpConnectivity) && !(logsConfig.isForceTCPUse() || logsConfig.isSocks5ProxySet() || logsConfig.hasAdditionalEndpoints())) {
 		return BuildHTTPEndpointsWithConfig(coreConfig, logsConfig, endpointPrefix, intakeTrackType, intakeProtocol, intakeOrigin)
 	}
 	log.Warnf("You are currently sending Logs to Datadog through TCP (either because %s or %s is set or the HTTP connectivity test has failed) "+
@@ -172,6 +175,9 @@ func buildTCPEndpoints(coreConfig pkgconfigmodel.Reader, logsConfig *LogsConfigK
 	}
 
 	additionals := loadTCPAdditionalEndpoints(main, logsConfig)
+	if len(logsConfig.getDestinationGroups()) > 0 {
+		log.Warn("Ignoring the destination groups, they are only supported over HTTP")
+	}
 	return NewEndpoints(main, additionals, useProto, false), nil
 }
 
@@ -247,6 +253,7 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 		e.IsMRF = true
 		e.UseCompression = main.UseCompression
 		e.CompressionLevel = main.CompressionLevel
+		e.CompressionKind = main.CompressionKind
 		e.BackoffBase = main.BackoffBase
 		e.BackoffMax = main.BackoffMax
 		e.BackoffFactor = main.BackoffFactor
@@ -266,7 +273,9 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 	batchMaxContentSize := logsConfig.batchMaxContentSize()
 	inputChanSize := logsConfig.inputChanSize()
 
-	return NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize), nil
+	endpoints := NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize)
+	endpoints.DestinationGroups = loadDestinationGroups(endpoints, logsConfig, intakeTrackType, intakeProtocol, intakeOrigin)
+	return endpoints, nil
 }
 
 type defaultParseAddressFunc func(string) (host string, port int, err error)
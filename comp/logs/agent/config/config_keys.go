@@ -113,6 +113,10 @@ func (l *LogsConfigKeys) compressionLevel() int {
 	return l.getConfig().GetInt(l.getConfigKey("compression_level"))
 }
 
+func (l *LogsConfigKeys) compressionKind() string {
+	return l.getConfig().GetString(l.getConfigKey("compression_kind"))
+}
+
 func (l *LogsConfigKeys) useCompression() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_compression"))
 }
@@ -121,6 +125,17 @@ func (l *LogsConfigKeys) hasAdditionalEndpoints() bool {
 	return len(l.getAdditionalEndpoints()) > 0
 }
 
+// hasFileEndpoints returns true if logs are written to a local file, which
+// requires the JSON encoding of the HTTP pipeline.
+func (l *LogsConfigKeys) hasFileEndpoints() bool {
+	for _, e := range l.getAdditionalEndpoints() {
+		if e.IsFile() {
+			return true
+		}
+	}
+	return false
+}
+
 // getAPIKeyGetter returns a getter function to retrieve the API key from the configuration. The getter will refetch the
 // value from the configuration upon each call to ensure the latest version is used. This ensure that the logs agent is
 // compatible with rotating the API key at runtime.
@@ -161,6 +176,25 @@ func (l *LogsConfigKeys) getAdditionalEndpoints() []unmarshalEndpoint {
 	return endpoints
 }
 
+func (l *LogsConfigKeys) getDestinationGroups() []unmarshalDestinationGroup {
+	var groups []unmarshalDestinationGroup
+	var err error
+	configKey := l.getConfigKey("destination_groups")
+	raw := l.getConfig().Get(configKey)
+	if raw == nil {
+		return nil
+	}
+	if s, ok := raw.(string); ok && s != "" {
+		err = json.Unmarshal([]byte(s), &groups)
+	} else {
+		err = structure.UnmarshalKey(l.getConfig(), configKey, &groups, structure.EnableSquash)
+	}
+	if err != nil {
+		log.Warnf("Could not parse destination_groups for logs: %v", err)
+	}
+	return groups
+}
+
 func (l *LogsConfigKeys) expectedTagsDuration() time.Duration {
 	return l.getConfig().GetDuration(l.getConfigKey("expected_tags_duration"))
 }
@@ -245,6 +245,140 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 	suite.compareEndpoints(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestAdditionalHTTPEndpointsFormats() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.logs_dd_url", "agent-http-intake.logs.datadoghq.com:443")
+	suite.config.SetWithoutSource("logs_config.use_compression", true)
+	suite.config.SetWithoutSource("logs_config.compression_kind", "zstd")
+	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[
+		{"host": "elasticsearch.internal", "port": 9200, "format": "elasticsearch", "path": "/_bulk", "index": "logs",
+		 "headers": {"Authorization": "ApiKey secret"}, "use_compression": true, "compression_level": 3},
+		{"host": "loki.internal", "port": 3100, "use_ssl": false, "format": "loki_protobuf", "path": "/loki/api/v1/push", "is_reliable": false},
+		{"host": "unknown.internal", "format": "splunk"},
+		{"host": "webhook.internal", "format": "ndjson", "template": "{\"text\":{{json .Message}}}"},
+		{"host": "invalid.internal", "format": "ndjson", "template": "{{.Unknown}}"}]`)
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.True(endpoints.Main.IsDatadog())
+	suite.Require().Len(endpoints.Endpoints, 4)
+
+	elasticsearch := endpoints.Endpoints[1]
+	suite.Equal(ElasticsearchFormat, elasticsearch.Format)
+	suite.False(elasticsearch.IsDatadog())
+	suite.Equal("elasticsearch.internal", elasticsearch.Host)
+	suite.Equal(9200, elasticsearch.Port)
+	suite.Equal("/_bulk", elasticsearch.Path)
+	suite.Equal("logs", elasticsearch.Index)
+	suite.Equal(map[string]string{"Authorization": "ApiKey secret"}, elasticsearch.Headers)
+	suite.True(elasticsearch.UseSSL())
+	suite.True(elasticsearch.IsReliable())
+	// the payloads are compressed for this endpoint
+	suite.True(elasticsearch.UseCompression)
+	suite.Equal(3, elasticsearch.CompressionLevel)
+	suite.Equal("", elasticsearch.CompressionKind)
+	suite.Equal(endpoints.Main.BackoffMax, elasticsearch.BackoffMax)
+
+	loki := endpoints.Endpoints[2]
+	suite.Equal(LokiProtobufFormat, loki.Format)
+	suite.Equal("/loki/api/v1/push", loki.Path)
+	suite.False(loki.UseSSL())
+	suite.False(loki.IsReliable())
+	suite.False(loki.UseCompression)
+	suite.Contains(loki.GetStatus("", true), "in loki_protobuf format in HTTP to loki.internal on port 3100")
+
+	// the endpoints with an invalid template are ignored
+	webhook := endpoints.Endpoints[3]
+	suite.Equal("webhook.internal", webhook.Host)
+	suite.Equal(`{"text":{{json .Message}}}`, webhook.Template)
+	suite.Equal("", elasticsearch.Template)
+
+	// the formats are not supported over TCP
+	endpoints, err = buildTCPEndpoints(suite.config, defaultLogsConfigKeys(suite.config))
+	suite.Nil(err)
+	suite.Len(endpoints.Endpoints, 1)
+}
+
+func (suite *ConfigTestSuite) TestFileEndpoints() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.logs_dd_url", "agent-http-intake.logs.datadoghq.com:443")
+	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[
+		{"format": "file", "path": "/var/log/datadog/archive.log", "max_file_size": 1024, "rotation_interval": 3600, "max_files": 3},
+		{"format": "file", "path": "/var/log/datadog/debug.log", "is_reliable": false},
+		{"format": "file"}]`)
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Require().Len(endpoints.Endpoints, 3)
+
+	archive := endpoints.Endpoints[1]
+	suite.True(archive.IsFile())
+	suite.Equal("/var/log/datadog/archive.log", archive.Path)
+	suite.Equal(int64(1024), archive.MaxFileSize)
+	suite.Equal(3600, archive.RotationInterval)
+	suite.Equal(3, archive.MaxFiles)
+	suite.True(archive.IsReliable())
+	suite.Equal("Writing logs to the file /var/log/datadog/archive.log", archive.GetStatus("", true))
+
+	debug := endpoints.Endpoints[2]
+	suite.True(debug.IsFile())
+	suite.Equal(int64(DefaultFileMaxSize), debug.MaxFileSize)
+	suite.Equal(0, debug.RotationInterval)
+	suite.Equal(DefaultFileMaxFiles, debug.MaxFiles)
+	suite.False(debug.IsReliable())
+
+	// the logs are sent over HTTP, and encoded in JSON, even when the intake
+	// cannot be reached
+	endpoints, err = BuildEndpoints(suite.config, HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+	suite.Len(endpoints.Endpoints, 3)
+
+	// the file endpoints are ignored over TCP
+	suite.config.SetWithoutSource("logs_config.force_use_tcp", true)
+	endpoints, err = BuildEndpoints(suite.config, HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.False(endpoints.UseHTTP)
+	suite.Len(endpoints.Endpoints, 1)
+}
+
+func (suite *ConfigTestSuite) TestDestinationGroups() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.logs_dd_url", "agent-http-intake.logs.datadoghq.com:443")
+	suite.config.SetWithoutSource("logs_config.batch_wait", 5)
+	suite.config.SetWithoutSource("logs_config.destination_groups", `[
+		{"name": "security", "batch_wait": 1, "input_chan_size": 10, "endpoints": [
+			{"api_key": "456", "host": "http-intake.logs.datadoghq.eu", "port": 443},
+			{"host": "loki.internal", "port": 3100, "format": "loki", "is_reliable": false}]},
+		{"name": "default", "endpoints": [{"host": "default.internal"}]},
+		{"name": "unreliable", "endpoints": [{"host": "unreliable.internal", "is_reliable": false}]},
+		{"name": "security", "endpoints": [{"host": "duplicate.internal"}]}]`)
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Len(endpoints.Endpoints, 1)
+	suite.Require().Len(endpoints.DestinationGroups, 1)
+
+	security := endpoints.GetDestinationGroup("security")
+	suite.Require().NotNil(security)
+	suite.Equal("http-intake.logs.datadoghq.eu", security.Endpoints.Main.Host)
+	suite.Equal("456", security.Endpoints.Main.GetAPIKey())
+	suite.Require().Len(security.Endpoints.Endpoints, 2)
+	suite.Equal(LokiFormat, security.Endpoints.Endpoints[1].Format)
+	suite.Len(security.Endpoints.GetReliableEndpoints(), 1)
+	suite.Equal(1*time.Second, security.Endpoints.BatchWait)
+	suite.Equal(10, security.Endpoints.InputChanSize)
+	suite.Equal(endpoints.BatchMaxSize, security.Endpoints.BatchMaxSize)
+	suite.True(security.Endpoints.UseHTTP)
+	suite.Nil(endpoints.GetDestinationGroup("unreliable"))
+	suite.Contains(endpoints.GetStatus(), "Destination group security: Reliable: Sending compressed logs in HTTPS to http-intake.logs.datadoghq.eu on port 443")
+
+	// the destination groups are not supported over TCP
+	endpoints, err = buildTCPEndpoints(suite.config, defaultLogsConfigKeys(suite.config))
+	suite.Nil(err)
+	suite.Empty(endpoints.DestinationGroups)
+}
+
 func (suite *ConfigTestSuite) TestMultipleTCPEndpointsEnvVar() {
 	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[{"api_key": "456      \n", "host": "additional.endpoint", "port": 1234}]`)
 
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// DefaultDestinationGroup is the name routing the logs to the main endpoints.
+const DefaultDestinationGroup = "default"
+
+// DestinationGroup is a named set of endpoints the logs of some sources, or
+// matching routing rules, are sent to instead of the main endpoints. The logs
+// of a group are batched and sent independently of the other logs.
+type DestinationGroup struct {
+	Name      string
+	Endpoints *Endpoints
+}
+
+// unmarshalDestinationGroup is used to load the destination groups from
+// 'logs_config.destination_groups', the endpoints have the same settings as
+// the additional endpoints, the batching settings default to the ones of the
+// main endpoints.
+type unmarshalDestinationGroup struct {
+	Name                string              `mapstructure:"name" json:"name"`
+	Endpoints           []unmarshalEndpoint `mapstructure:"endpoints" json:"endpoints"`
+	BatchWait           int                 `mapstructure:"batch_wait" json:"batch_wait"`
+	BatchMaxSize        int                 `mapstructure:"batch_max_size" json:"batch_max_size"`
+	BatchMaxContentSize int                 `mapstructure:"batch_max_content_size" json:"batch_max_content_size"`
+	InputChanSize       int                 `mapstructure:"input_chan_size" json:"input_chan_size"`
+}
+
+// loadDestinationGroups returns the destination groups of the configuration,
+// the misconfigured groups are ignored.
+func loadDestinationGroups(main *Endpoints, l *LogsConfigKeys, intakeTrackType IntakeTrackType, intakeProtocol IntakeProtocol, intakeOrigin IntakeOrigin) []*DestinationGroup {
+	var groups []*DestinationGroup
+	names := make(map[string]bool)
+	for _, g := range l.getDestinationGroups() {
+		if g.Name == "" || g.Name == DefaultDestinationGroup {
+			log.Warnf("Ignoring a destination group, its name must be set and differ from %q", DefaultDestinationGroup)
+			continue
+		}
+		if names[g.Name] {
+			log.Warnf("Ignoring the destination group %s, its name is already used", g.Name)
+			continue
+		}
+		endpoints := newHTTPAdditionalEndpoints(main.Main, g.Endpoints, intakeTrackType, intakeProtocol, intakeOrigin)
+		if !hasReliableEndpoint(endpoints) {
+			// the logs of the group would not be sent anywhere while its
+			// unreliable endpoints are down
+			log.Warnf("Ignoring the destination group %s, it has no reliable endpoint", g.Name)
+			continue
+		}
+		names[g.Name] = true
+
+		batchWait := main.BatchWait
+		if g.BatchWait > 0 {
+			batchWait = time.Duration(g.BatchWait) * time.Second
+		}
+		batchMaxSize := main.BatchMaxSize
+		if g.BatchMaxSize > 0 {
+			batchMaxSize = g.BatchMaxSize
+		}
+		batchMaxContentSize := main.BatchMaxContentSize
+		if g.BatchMaxContentSize > 0 {
+			batchMaxContentSize = g.BatchMaxContentSize
+		}
+		inputChanSize := main.InputChanSize
+		if g.InputChanSize > 0 {
+			inputChanSize = g.InputChanSize
+		}
+		groups = append(groups, &DestinationGroup{
+			Name:      g.Name,
+			Endpoints: NewEndpointsWithBatchSettings(endpoints[0], endpoints[1:], false, true, batchWait, main.BatchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize),
+		})
+	}
+	return groups
+}
+
+func hasReliableEndpoint(endpoints []Endpoint) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.IsReliable() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDestinationGroup returns the destination group with the given name, or
+// nil if there is none.
+func (e *Endpoints) GetDestinationGroup(name string) *DestinationGroup {
+	for _, group := range e.DestinationGroups {
+		if group.Name == name {
+			return group
+		}
+	}
+	return nil
+}
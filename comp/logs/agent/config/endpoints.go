@@ -11,6 +11,7 @@ import (
 
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	pkgconfigutils "github.com/DataDog/datadog-agent/pkg/config/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // EPIntakeVersion is the events platform intake API version
@@ -25,6 +26,27 @@ type IntakeProtocol string
 // IntakeOrigin indicates the log source to use for an endpoint intake.
 type IntakeOrigin string
 
+// EndpointFormat indicates the format of the payloads sent to an HTTP endpoint.
+type EndpointFormat string
+
+// Endpoint formats, the payloads of the Datadog format are sent to a Datadog
+// intake, the other formats let additional endpoints ship logs to other stores,
+// or write them to a local file.
+const (
+	DatadogFormat       EndpointFormat = ""
+	ElasticsearchFormat EndpointFormat = "elasticsearch"
+	LokiFormat          EndpointFormat = "loki"
+	LokiProtobufFormat  EndpointFormat = "loki_protobuf"
+	NDJSONFormat        EndpointFormat = "ndjson"
+	FileFormat          EndpointFormat = "file"
+)
+
+// Default rotation settings of the file endpoints.
+const (
+	DefaultFileMaxSize  = 100 * 1024 * 1024
+	DefaultFileMaxFiles = 10
+)
+
 const (
 	_ EPIntakeVersion = iota
 	// EPIntakeVersion1 is version 1 of the envets platform intake API
@@ -41,8 +63,9 @@ type Endpoint struct {
 
 	Host                    string `mapstructure:"host" json:"host"`
 	Port                    int
-	UseCompression          bool `mapstructure:"use_compression" json:"use_compression"`
-	CompressionLevel        int  `mapstructure:"compression_level" json:"compression_level"`
+	UseCompression          bool   `mapstructure:"use_compression" json:"use_compression"`
+	CompressionLevel        int    `mapstructure:"compression_level" json:"compression_level"`
+	CompressionKind         string `mapstructure:"compression_kind" json:"compression_kind"`
 	ProxyAddress            string
 	IsMRF                   bool `mapstructure:"-" json:"-"`
 	ConnectionResetInterval time.Duration
@@ -57,6 +80,25 @@ type Endpoint struct {
 	TrackType IntakeTrackType
 	Protocol  IntakeProtocol
 	Origin    IntakeOrigin
+
+	// Format is the format of the payloads, the following fields are only
+	// used by the endpoints which are not Datadog intakes.
+	Format EndpointFormat `mapstructure:"format" json:"format"`
+	// Path is the path of the URL the payloads are posted to, or the path of
+	// the file the logs are written to by file endpoints.
+	Path string `mapstructure:"path" json:"path"`
+	// Headers are added to the requests, e.g. for authentication.
+	Headers map[string]string `mapstructure:"headers" json:"headers"`
+	// Index is the Elasticsearch index the logs are written to.
+	Index string `mapstructure:"index" json:"index"`
+	// Template renders each log of the payloads, instead of its JSON document.
+	Template string `mapstructure:"template" json:"template"`
+	// The file of a file endpoint is rotated when it would exceed MaxFileSize
+	// bytes, or every RotationInterval seconds if it is set, and MaxFiles
+	// rotated files are kept.
+	MaxFileSize      int64 `mapstructure:"max_file_size" json:"max_file_size"`
+	RotationInterval int   `mapstructure:"rotation_interval" json:"rotation_interval"`
+	MaxFiles         int   `mapstructure:"max_files" json:"max_files"`
 }
 
 // unmarshalEndpoint is used to load additional endpoints from the configuration which stored as JSON/mapstructure.
@@ -100,6 +142,7 @@ func NewHTTPEndpoint(logsConfig *LogsConfigKeys) Endpoint {
 		apiKeyGetter:            logsConfig.getAPIKeyGetter(),
 		UseCompression:          logsConfig.useCompression(),
 		CompressionLevel:        logsConfig.compressionLevel(),
+		CompressionKind:         logsConfig.compressionKind(),
 		ConnectionResetInterval: logsConfig.connectionResetInterval(),
 		BackoffBase:             logsConfig.senderBackoffBase(),
 		BackoffMax:              logsConfig.senderBackoffMax(),
@@ -120,10 +163,19 @@ func loadTCPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys) []Endpoint {
 
 	newEndpoints := make([]Endpoint, 0, len(additionals))
 	for _, e := range additionals {
+		if e.IsFile() {
+			log.Warnf("Ignoring the file endpoint %s, the file format is only supported over HTTP", e.Path)
+			continue
+		}
+		if !e.IsDatadog() {
+			log.Warnf("Ignoring the additional endpoint %s, the %s format is only supported over HTTP", e.Host, e.Format)
+			continue
+		}
 		newE := NewEndpoint(e.APIKey, e.Host, e.Port, false)
 
 		newE.UseCompression = e.UseCompression
 		newE.CompressionLevel = e.CompressionLevel
+		newE.CompressionKind = e.CompressionKind
 		newE.ProxyAddress = l.socks5ProxyAddress()
 		newE.isReliable = e.IsReliable == nil || *e.IsReliable
 		newE.ConnectionResetInterval = e.ConnectionResetInterval
@@ -148,14 +200,48 @@ func loadTCPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys) []Endpoint {
 }
 
 func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackType IntakeTrackType, intakeProtocol IntakeProtocol, intakeOrigin IntakeOrigin) []Endpoint {
-	additionals := l.getAdditionalEndpoints()
+	return newHTTPAdditionalEndpoints(main, l.getAdditionalEndpoints(), intakeTrackType, intakeProtocol, intakeOrigin)
+}
 
+// newHTTPAdditionalEndpoints returns the endpoints loaded from the
+// configuration, their unset settings default to the ones of the main endpoint.
+func newHTTPAdditionalEndpoints(main Endpoint, additionals []unmarshalEndpoint, intakeTrackType IntakeTrackType, intakeProtocol IntakeProtocol, intakeOrigin IntakeOrigin) []Endpoint {
 	newEndpoints := make([]Endpoint, 0, len(additionals))
 	for _, e := range additionals {
+		if !e.Format.IsValid() {
+			log.Warnf("Ignoring the additional endpoint %s, unknown format %q", e.Host, e.Format)
+			continue
+		}
+		if e.IsFile() {
+			if newE, ok := newFileEndpoint(e); ok {
+				newEndpoints = append(newEndpoints, newE)
+			}
+			continue
+		}
+		if e.Template != "" {
+			if e.IsDatadog() {
+				log.Warnf("Ignoring the template of the additional endpoint %s, it is only used by the endpoints which are not Datadog intakes", e.Host)
+			} else if _, err := NewPayloadTemplate(e.Template); err != nil {
+				log.Warnf("Ignoring the additional endpoint %s, invalid template: %v", e.Host, err)
+				continue
+			}
+		}
 		newE := NewEndpoint(e.APIKey, e.Host, e.Port, false)
 
-		newE.UseCompression = main.UseCompression
-		newE.CompressionLevel = main.CompressionLevel
+		if e.IsDatadog() {
+			newE.UseCompression = main.UseCompression
+			newE.CompressionLevel = main.CompressionLevel
+			newE.CompressionKind = main.CompressionKind
+		} else {
+			// the payloads are serialized and compressed for this endpoint only
+			newE.UseCompression = e.UseCompression
+			newE.CompressionLevel = e.CompressionLevel
+			newE.Format = e.Format
+			newE.Path = e.Path
+			newE.Headers = e.Headers
+			newE.Index = e.Index
+			newE.Template = e.Template
+		}
 		newE.ProxyAddress = e.ProxyAddress
 		newE.isReliable = e.IsReliable == nil || *e.IsReliable
 		newE.ConnectionResetInterval = e.ConnectionResetInterval
@@ -189,6 +275,34 @@ func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackTy
 	return newEndpoints
 }
 
+// newFileEndpoint returns an endpoint writing the logs to a local file, or
+// false if its path is not set.
+func newFileEndpoint(e unmarshalEndpoint) (Endpoint, bool) {
+	if e.Path == "" {
+		log.Warn("Ignoring an additional endpoint of the file format, its path is not set")
+		return Endpoint{}, false
+	}
+	newE := NewEndpoint(e.APIKey, "", 0, false)
+	newE.Format = FileFormat
+	newE.Path = e.Path
+	newE.isReliable = e.IsReliable == nil || *e.IsReliable
+	newE.MaxFileSize = e.MaxFileSize
+	if newE.MaxFileSize <= 0 {
+		newE.MaxFileSize = DefaultFileMaxSize
+	}
+	newE.RotationInterval = e.RotationInterval
+	newE.MaxFiles = e.MaxFiles
+	if newE.MaxFiles <= 0 {
+		newE.MaxFiles = DefaultFileMaxFiles
+	}
+	// the backoff of the retries when the file cannot be written
+	newE.BackoffFactor = pkgconfigsetup.DefaultLogsSenderBackoffFactor
+	newE.BackoffBase = pkgconfigsetup.DefaultLogsSenderBackoffBase
+	newE.BackoffMax = pkgconfigsetup.DefaultLogsSenderBackoffMax
+	newE.RecoveryInterval = pkgconfigsetup.DefaultLogsSenderBackoffRecoveryInterval
+	return newE, true
+}
+
 // GetAPIKey returns the latest API Key for the Endpoint, including when the configuration gets updated at runtime
 func (e *Endpoint) GetAPIKey() string {
 	return e.apiKeyGetter()
@@ -233,9 +347,35 @@ func (e *Endpoint) GetStatus(prefix string, useHTTP bool) string {
 		}
 	}
 
+	if e.IsFile() {
+		return fmt.Sprintf("%sWriting logs to the file %s", prefix, e.Path)
+	}
+	if !e.IsDatadog() {
+		return fmt.Sprintf("%sSending %s logs in %s format in %s to %s on port %d", prefix, compression, e.Format, protocol, host, port)
+	}
 	return fmt.Sprintf("%sSending %s logs in %s to %s on port %d", prefix, compression, protocol, host, port)
 }
 
+// IsDatadog returns true if the endpoint is a Datadog intake.
+func (e *Endpoint) IsDatadog() bool {
+	return e.Format == DatadogFormat
+}
+
+// IsFile returns true if the endpoint writes the logs to a local file.
+func (e *Endpoint) IsFile() bool {
+	return e.Format == FileFormat
+}
+
+// IsValid returns true if the format is known.
+func (f EndpointFormat) IsValid() bool {
+	switch f {
+	case DatadogFormat, ElasticsearchFormat, LokiFormat, LokiProtobufFormat, NDJSONFormat, FileFormat:
+		return true
+	default:
+		return false
+	}
+}
+
 // IsReliable returns true if the endpoint is reliable. Endpoints are reliable by default.
 func (e *Endpoint) IsReliable() bool {
 	return e.isReliable
@@ -252,6 +392,9 @@ type Endpoints struct {
 	BatchMaxSize           int
 	BatchMaxContentSize    int
 	InputChanSize          int
+	// DestinationGroups are the groups of endpoints the logs can be routed to
+	// instead of these endpoints.
+	DestinationGroups []*DestinationGroup
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -263,6 +406,11 @@ func (e *Endpoints) GetStatus() []string {
 	for _, endpoint := range e.GetUnReliableEndpoints() {
 		result = append(result, endpoint.GetStatus("Unreliable: ", e.UseHTTP))
 	}
+	for _, group := range e.DestinationGroups {
+		for _, status := range group.Endpoints.GetStatus() {
+			result = append(result, "Destination group "+group.Name+": "+status)
+		}
+	}
 	return result
 }
 
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Grok field types
+const (
+	GrokString = "string"
+	GrokInt    = "int"
+	GrokFloat  = "float"
+)
+
+// GrokField is an attribute extracted by a grok pattern, it is captured by
+// the group named after its index in the compiled regular expression.
+type GrokField struct {
+	Name  string
+	Type  string
+	Group string
+}
+
+// grokPatterns are the patterns which can be referenced in a grok pattern
+// with `%{NAME}`, they may reference each other.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NONNEGINT":         `\b\d+\b`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][+-]?\d+)?`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}(?:[0-9A-Fa-f]{1,4}|%{IPV4})?`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATHPARAM":      `/[^\s?#]*(?:\?[^\s#]*)?`,
+	"URI":               `[A-Za-z][A-Za-z0-9+.-]*://\S+`,
+	"LOGLEVEL":          `(?i:trace|debug|info(?:rmation)?|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"HTTPDATE":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `\w{3} +\d{1,2} \d{2}:\d{2}:\d{2}`,
+}
+
+// maxGrokDepth bounds the expansion of patterns referencing each other.
+const maxGrokDepth = 16
+
+var grokReferenceRegex = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::(\w+))?\}`)
+
+// CompileGrok compiles a grok pattern into a regular expression. A grok
+// pattern is a regular expression in which `%{NAME}` is replaced by the
+// pattern NAME, `%{NAME:field}` extracts the matched value as the field
+// attribute, and `%{NAME:field:int}` or `%{NAME:field:float}` converts it.
+func CompileGrok(pattern string) (*regexp.Regexp, []GrokField, error) {
+	var fields []GrokField
+	expanded, err := expandGrok(pattern, &fields, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, nil, err
+	}
+	return re, fields, nil
+}
+
+// expandGrok replaces the references of a pattern by their regular
+// expressions, capturing the referenced fields.
+func expandGrok(pattern string, fields *[]GrokField, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok patterns are nested too deeply")
+	}
+	var err error
+	expanded := grokReferenceRegex.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if err != nil {
+			return ""
+		}
+		groups := grokReferenceRegex.FindStringSubmatch(reference)
+		name, field, fieldType := groups[1], groups[2], groups[3]
+		definition, ok := grokPatterns[name]
+		if !ok {
+			err = fmt.Errorf("unknown grok pattern %s", name)
+			return ""
+		}
+		var sub string
+		// the referenced patterns never extract fields
+		sub, err = expandGrok(definition, nil, depth+1)
+		if err != nil {
+			return ""
+		}
+		if field == "" || fields == nil {
+			return "(?:" + sub + ")"
+		}
+		switch fieldType {
+		case "":
+			fieldType = GrokString
+		case GrokString, GrokInt, GrokFloat:
+		default:
+			err = fmt.Errorf("unknown grok type %s for field %s", fieldType, field)
+			return ""
+		}
+		group := fmt.Sprintf("grok%d", len(*fields))
+		*fields = append(*fields, GrokField{Name: field, Type: fieldType, Group: group})
+		return "(?P<" + group + ">" + sub + ")"
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(expanded, "%{") {
+		return "", fmt.Errorf("invalid grok reference in %s", pattern)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileGrok(t *testing.T) {
+	re, fields, err := CompileGrok(`^%{IP:client} - %{USER} \[%{HTTPDATE:date}\] "%{WORD:method} %{URIPATHPARAM:url}" %{INT:status:int}(?: %{NUMBER:duration:float})?$`)
+	require.NoError(t, err)
+	assert.Equal(t, []GrokField{
+		{Name: "client", Type: GrokString, Group: "grok0"},
+		{Name: "date", Type: GrokString, Group: "grok1"},
+		{Name: "method", Type: GrokString, Group: "grok2"},
+		{Name: "url", Type: GrokString, Group: "grok3"},
+		{Name: "status", Type: GrokInt, Group: "grok4"},
+		{Name: "duration", Type: GrokFloat, Group: "grok5"},
+	}, fields)
+
+	match := re.FindStringSubmatch(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?a=1" 200`)
+	require.NotNil(t, match)
+	assert.Equal(t, "127.0.0.1", match[re.SubexpIndex("grok0")])
+	assert.Equal(t, "10/Oct/2000:13:55:36 -0700", match[re.SubexpIndex("grok1")])
+	assert.Equal(t, "/apache_pb.gif?a=1", match[re.SubexpIndex("grok3")])
+	assert.Equal(t, "200", match[re.SubexpIndex("grok4")])
+	assert.Equal(t, "", match[re.SubexpIndex("grok5")])
+
+	re, _, err = CompileGrok(`%{IP:ip}`)
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", re.FindStringSubmatch("from 2001:db8::1")[1])
+	assert.Equal(t, "192.168.0.12", re.FindStringSubmatch("from 192.168.0.12")[1])
+}
+
+func TestCompileGrokShouldFailWithInvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{
+		`%{UNKNOWN:field}`,
+		`%{INT:field:bool}`,
+		`%{INT:field`,
+		`%{WORD:field} (`,
+	} {
+		_, _, err := CompileGrok(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestValidateParsingRules(t *testing.T) {
+	valid := [][]*ProcessingRule{
+		{{Name: "json", Type: JSONParser}},
+		{{Name: "kv", Type: KeyValueParser, Separator: ":", TimestampField: "ts", TimestampFormat: "unix"}},
+		{{Name: "grok", Type: GrokParser, Pattern: "%{WORD:word}"}},
+		{{Name: "filter", Type: ExcludeAtMatch, Pattern: "^2", Field: "status"}},
+	}
+	for _, rules := range valid {
+		assert.NoError(t, ValidateProcessingRules(rules))
+		assert.NoError(t, CompileProcessingRules(rules))
+	}
+	assert.NotNil(t, valid[2][0].Regex)
+	assert.Len(t, valid[2][0].GrokFields, 1)
+
+	invalid := [][]*ProcessingRule{
+		{{Name: "grok", Type: GrokParser}},
+		{{Name: "grok", Type: GrokParser, Pattern: "%{NOPE:word}"}},
+		{{Name: "json", Type: JSONParser, TimestampFormat: "unix"}},
+	}
+	for _, rules := range invalid {
+		assert.Error(t, ValidateProcessingRules(rules))
+	}
+}
@@ -18,6 +18,7 @@ import (
 const (
 	TCPType           = "tcp"
 	UDPType           = "udp"
+	SyslogType        = "syslog"
 	FileType          = "file"
 	DockerType        = "docker"
 	ContainerdType    = "containerd"
@@ -34,6 +35,18 @@ const (
 	SHIFTJIS string = "shift-jis"
 )
 
+// Syslog source protocols
+const (
+	// SyslogTCP receives syslog messages on a TCP port
+	SyslogTCP = "tcp"
+	// SyslogUDP receives syslog messages on a UDP port
+	SyslogUDP = "udp"
+	// SyslogUnix receives syslog messages on a stream unix socket
+	SyslogUnix = "unix"
+	// SyslogUnixgram receives syslog messages on a datagram unix socket
+	SyslogUnixgram = "unixgram"
+)
+
 // LogsConfig represents a log source config, which can be for instance
 // a file to tail or a port to listen to.
 type LogsConfig struct {
@@ -41,13 +54,18 @@ type LogsConfig struct {
 
 	IntegrationName string
 
-	Port        int    // Network
-	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
-	Path        string // File, Journald
+	Port        int    // Network, Syslog
+	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network, Syslog
+	Path        string // File, Journald, Syslog
+	Protocol    string // Syslog
 
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
+	// UnfinishedCompressedFilesOnly only reads the compressed files (`.gz`,
+	// `.zst`) whose content was not fully collected, e.g. when a file was
+	// compressed by its rotation while the agent was stopped.
+	UnfinishedCompressedFilesOnly bool `mapstructure:"unfinished_compressed_files_only" json:"unfinished_compressed_files_only"` // File
 
 	//nolint:revive // TODO(AML) Fix revive linter
 	ConfigId           string   `mapstructure:"config_id" json:"config_id"`                   // Journald
@@ -86,6 +104,9 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	// DestinationGroup is the destination group the logs of the source are
+	// sent to, instead of the main endpoints.
+	DestinationGroup string `mapstructure:"destination_group" json:"destination_group"`
 	// ProcessRawMessage is used to process the raw message instead of only the content part of the message.
 	ProcessRawMessage *bool `mapstructure:"process_raw_message" json:"process_raw_message"`
 
@@ -117,12 +138,18 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
 		fmt.Fprintf(&b, ws("Identifier: %#v,"), c.Identifier)
 		fmt.Fprintf(&b, ws("ExcludePaths: %#v,"), c.ExcludePaths)
 		fmt.Fprintf(&b, ws("TailingMode: %#v,"), c.TailingMode)
+		fmt.Fprintf(&b, ws("UnfinishedCompressedFilesOnly: %t,"), c.UnfinishedCompressedFilesOnly)
 	case DockerType, ContainerdType:
 		fmt.Fprintf(&b, ws("Image: %#v,"), c.Image)
 		fmt.Fprintf(&b, ws("Label: %#v,"), c.Label)
@@ -150,6 +177,9 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	fmt.Fprintf(&b, ws("SourceCategory: %#v,"), c.SourceCategory)
 	fmt.Fprintf(&b, ws("Tags: %#v,"), c.Tags)
 	fmt.Fprintf(&b, ws("ProcessingRules: %#v,"), c.ProcessingRules)
+	if c.DestinationGroup != "" {
+		fmt.Fprintf(&b, ws("DestinationGroup: %#v,"), c.DestinationGroup)
+	}
 	if c.ProcessRawMessage != nil {
 		fmt.Fprintf(&b, ws("ProcessRawMessage: %t,"), *c.ProcessRawMessage)
 	} else {
@@ -172,8 +202,9 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	// Export only fields that are explicitly documented in the public documentation
 	return json.Marshal(&struct {
 		Type            string            `json:"type,omitempty"`
-		Port            int               `json:"port,omitempty"`           // Network
-		Path            string            `json:"path,omitempty"`           // File, Journald
+		Port            int               `json:"port,omitempty"`           // Network, Syslog
+		Path            string            `json:"path,omitempty"`           // File, Journald, Syslog
+		Protocol        string            `json:"protocol,omitempty"`       // Syslog
 		Encoding        string            `json:"encoding,omitempty"`       // File
 		ExcludePaths    []string          `json:"exclude_paths,omitempty"`  // File
 		TailingMode     string            `json:"start_position,omitempty"` // File
@@ -187,6 +218,7 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 		Type:            c.Type,
 		Port:            c.Port,
 		Path:            c.Path,
+		Protocol:        c.Protocol,
 		Encoding:        c.Encoding,
 		ExcludePaths:    c.ExcludePaths,
 		TailingMode:     c.TailingMode,
@@ -260,6 +292,11 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		err := c.validateSyslog()
+		if err != nil {
+			return err
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -279,6 +316,31 @@ func (c *LogsConfig) validateTailingMode() error {
 	return nil
 }
 
+func (c *LogsConfig) validateSyslog() error {
+	switch c.SyslogProtocol() {
+	case SyslogTCP, SyslogUDP:
+		if c.Port == 0 {
+			return fmt.Errorf("syslog source must have a port when using the %s protocol", c.SyslogProtocol())
+		}
+	case SyslogUnix, SyslogUnixgram:
+		if c.Path == "" {
+			return fmt.Errorf("syslog source must have a path when using the %s protocol", c.SyslogProtocol())
+		}
+	default:
+		return fmt.Errorf("invalid syslog protocol '%v', must be one of %s, %s, %s or %s", c.Protocol, SyslogTCP, SyslogUDP, SyslogUnix, SyslogUnixgram)
+	}
+	return nil
+}
+
+// SyslogProtocol returns the protocol a syslog source receives messages with,
+// TCP by default.
+func (c *LogsConfig) SyslogProtocol() string {
+	if c.Protocol == "" {
+		return SyslogTCP
+	}
+	return c.Protocol
+}
+
 // AutoMultiLineEnabled determines whether auto multi line detection is enabled for this config,
 // considering both the agent-wide logs_config.auto_multi_line_detection and any config for this
 // particular log source.
@@ -307,8 +369,8 @@ func (c *LogsConfig) ExperimentalAutoMultiLineEnabled(coreConfig pkgconfigmodel.
 // ShouldProcessRawMessage returns if the raw message should be processed instead
 // of only the message content.
 // This is tightly linked to how messages are transmitted through the pipeline.
-// If returning true, tailers using structured message (journald, windowsevents)
-// will fall back to original behavior of sending the whole message (e.g. JSON
+// If returning true, tailers using structured message (journald, windowsevents,
+// syslog) will fall back to original behavior of sending the whole message (e.g. JSON
 // for journald) for post-processing.
 // Otherwise, the message content is extracted from the structured message and
 // only this part is post-processed and sent to the intake.
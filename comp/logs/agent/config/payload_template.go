@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"encoding/json"
+	"io"
+	"text/template"
+	"time"
+)
+
+// PayloadTemplateLog is the log a payload template renders, for example
+// `{"@timestamp":{{json .Timestamp}},"message":{{json .Message}}}`.
+type PayloadTemplateLog struct {
+	Message   string
+	Status    string
+	Timestamp time.Time
+	Hostname  string
+	Service   string
+	Source    string
+	Tags      []string
+	// Document is the JSON document of the log, as sent to Datadog.
+	Document string
+}
+
+var payloadTemplateFuncs = template.FuncMap{
+	// json writes a value as JSON, e.g. to quote and escape strings
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+// NewPayloadTemplate parses the template rendering each log of the payloads of
+// an endpoint, and checks that it can render a log.
+func NewPayloadTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("payload").Funcs(payloadTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, PayloadTemplateLog{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPayloadTemplate(t *testing.T) {
+	tmpl, err := NewPayloadTemplate(`{"@timestamp":{{json .Timestamp}},"message":{{json .Message}},"tags":{{json .Tags}}}`)
+	require.NoError(t, err)
+
+	var rendered strings.Builder
+	require.NoError(t, tmpl.Execute(&rendered, PayloadTemplateLog{
+		Message:   `say "hello"`,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tags:      []string{"env:prod"},
+	}))
+	assert.Equal(t, `{"@timestamp":"2024-01-02T03:04:05Z","message":"say \"hello\"","tags":["env:prod"]}`, rendered.String())
+
+	_, err = NewPayloadTemplate(`{{.Message`)
+	assert.Error(t, err)
+	_, err = NewPayloadTemplate(`{{.Unknown}}`)
+	assert.Error(t, err)
+	_, err = NewPayloadTemplate(`{{unknown .Message}}`)
+	assert.Error(t, err)
+}
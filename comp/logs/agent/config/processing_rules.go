@@ -7,7 +7,9 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"time"
 )
 
 // Processing rule types
@@ -16,25 +18,138 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	GrokParser     = "grok_parser"
+	JSONParser     = "json_parser"
+	KeyValueParser = "key_value_parser"
+	GenerateMetric = "generate_metric"
+	Deduplicate    = "deduplicate"
+	Throttle       = "throttle"
+	Route          = "route"
 )
 
-// ProcessingRule defines an exclusion or a masking rule to
-// be applied on log lines
+// Metric types of the metric generation rules
+const (
+	CountMetric        = "count"
+	DistributionMetric = "distribution"
+)
+
+// Match modes of the deduplication rules
+const (
+	ExactMatch   = "exact"
+	PatternMatch = "pattern"
+)
+
+// DefaultDeduplicationWindow is the window of the deduplication rules, in
+// seconds, when it is not set.
+const DefaultDeduplicationWindow = 10
+
+// ProcessingRule defines an exclusion, a masking, a parsing, a metric
+// generation, a deduplication, a throttling or a routing rule to be applied on
+// log lines
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// Field is the attribute matched by an exclusion, an inclusion or a metric
+	// generation rule instead of the message, e.g. `http.status_code`.
+	Field string `mapstructure:"field" json:"field"`
+
+	// Parsing rules extract attributes from the message. The attributes are
+	// stored under Target, or at the root of the log if it is empty.
+	Target string `mapstructure:"target" json:"target"`
+	// Separator separates the keys from the values for key=value parsing
+	// rules, `=` by default.
+	Separator string `mapstructure:"separator" json:"separator"`
+	// MessageField is the extracted attribute which replaces the message.
+	MessageField string `mapstructure:"message_field" json:"message_field"`
+	// StatusField is the extracted attribute which sets the status.
+	StatusField string `mapstructure:"status_field" json:"status_field"`
+	// TimestampField is the extracted attribute which sets the timestamp,
+	// parsed with TimestampFormat: a Go time layout, `unix` or `unix_ms`,
+	// RFC 3339 by default.
+	TimestampField  string `mapstructure:"timestamp_field" json:"timestamp_field"`
+	TimestampFormat string `mapstructure:"timestamp_format" json:"timestamp_format"`
+	// TagFields are the extracted attributes added as tags. For metric
+	// generation rules, they are the capture groups or the attributes added
+	// as tags to the metric.
+	TagFields []string `mapstructure:"tag_fields" json:"tag_fields"`
+
+	// Metric generation rules submit a metric for each matching log, the
+	// pattern may be empty to match all logs.
+	MetricName string `mapstructure:"metric_name" json:"metric_name"`
+	// MetricType is `count` (the default) or `distribution`.
+	MetricType string `mapstructure:"metric_type" json:"metric_type"`
+	// ValueField is the capture group or the attribute holding the value of
+	// the metric, counts are incremented by 1 if it is not set.
+	ValueField string `mapstructure:"value_field" json:"value_field"`
+	// DropMessage drops the matching logs once the metric is generated.
+	DropMessage bool `mapstructure:"drop_message" json:"drop_message"`
+
+	// Deduplication and throttling rules are applied on the processed logs,
+	// after the other rules, the pattern may be empty to match all logs.
+	// Match is `exact` (the default) to collapse identical logs, or `pattern`
+	// to collapse the logs with the same structure, e.g. `error 42` and
+	// `error 43`.
+	Match string `mapstructure:"match" json:"match"`
+	// Window is the duration, in seconds, during which duplicated logs are
+	// collapsed.
+	Window int `mapstructure:"window" json:"window"`
+	// RateLimit is the number of logs per second forwarded for each source
+	// by throttling rules, which forward bursts of up to Burst logs.
+	RateLimit float64 `mapstructure:"rate_limit" json:"rate_limit"`
+	Burst     int     `mapstructure:"burst" json:"burst"`
+
+	// DestinationGroup is the destination group the logs matching a routing
+	// rule are sent to, instead of the main endpoints.
+	DestinationGroup string `mapstructure:"destination_group" json:"destination_group"`
+
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	GrokFields  []GrokField
+}
+
+// IsParsingRule returns true if the rule extracts attributes from the message.
+func (r *ProcessingRule) IsParsingRule() bool {
+	switch r.Type {
+	case GrokParser, JSONParser, KeyValueParser:
+		return true
+	default:
+		return false
+	}
+}
+
+// KeyValueSeparator returns the separator of a key=value parsing rule.
+func (r *ProcessingRule) KeyValueSeparator() string {
+	if r.Separator == "" {
+		return "="
+	}
+	return r.Separator
+}
+
+// DeduplicationWindow returns the window of a deduplication rule.
+func (r *ProcessingRule) DeduplicationWindow() time.Duration {
+	if r.Window == 0 {
+		return DefaultDeduplicationWindow * time.Second
+	}
+	return time.Duration(r.Window) * time.Second
+}
+
+// ThrottlingBurst returns the burst of a throttling rule, the number of logs
+// forwarded in one second by default.
+func (r *ProcessingRule) ThrottlingBurst() int {
+	if r.Burst == 0 {
+		return int(math.Max(1, math.Ceil(r.RateLimit)))
+	}
+	return r.Burst
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, unless it is optional for its type
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -44,6 +159,33 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case Route:
+			if rule.DestinationGroup == "" {
+				return fmt.Errorf("no destination_group provided for processing rule: %s", rule.Name)
+			}
+		case JSONParser, KeyValueParser:
+			if err := validateTimestampFormat(rule); err != nil {
+				return err
+			}
+			continue
+		case GenerateMetric, Deduplicate, Throttle:
+			if err := validateOptionalPatternRule(rule); err != nil {
+				return err
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+		case GrokParser:
+			if rule.Pattern == "" {
+				return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+			}
+			if _, _, err := CompileGrok(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+			}
+			if err := validateTimestampFormat(rule); err != nil {
+				return err
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -61,15 +203,85 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 	return nil
 }
 
+// validateOptionalPatternRule validates the rules whose pattern is optional.
+func validateOptionalPatternRule(rule *ProcessingRule) error {
+	switch rule.Type {
+	case GenerateMetric:
+		return validateMetricRule(rule)
+	case Deduplicate:
+		switch rule.Match {
+		case "", ExactMatch, PatternMatch:
+		default:
+			return fmt.Errorf("match %s is not supported for processing rule: %s", rule.Match, rule.Name)
+		}
+		if rule.Window < 0 {
+			return fmt.Errorf("window must be positive for processing rule: %s", rule.Name)
+		}
+	case Throttle:
+		if rule.RateLimit <= 0 {
+			return fmt.Errorf("rate_limit must be positive for processing rule: %s", rule.Name)
+		}
+		if rule.Burst < 0 {
+			return fmt.Errorf("burst must be positive for processing rule: %s", rule.Name)
+		}
+	}
+	return nil
+}
+
+// validateMetricRule returns an error if a metric generation rule has no
+// metric name, or an invalid metric type.
+func validateMetricRule(rule *ProcessingRule) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric_name provided for processing rule: %s", rule.Name)
+	}
+	switch rule.MetricType {
+	case "", CountMetric:
+	case DistributionMetric:
+		if rule.ValueField == "" {
+			return fmt.Errorf("no value_field provided for the distribution of processing rule: %s", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric_type %s is not supported for processing rule: %s", rule.MetricType, rule.Name)
+	}
+	return nil
+}
+
+// validateTimestampFormat returns an error if the timestamp of a parsing rule
+// is extracted without a field.
+func validateTimestampFormat(rule *ProcessingRule) error {
+	if rule.TimestampFormat != "" && rule.TimestampField == "" {
+		return fmt.Errorf("timestamp_format is set without timestamp_field for processing rule: %s", rule.Name)
+	}
+	return nil
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		switch rule.Type {
+		case JSONParser, KeyValueParser:
+			continue
+		case GrokParser:
+			re, fields, err := CompileGrok(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex = re
+			rule.GrokFields = fields
+			continue
+		}
+		switch rule.Type {
+		case GenerateMetric, Deduplicate, Throttle:
+			if rule.Pattern == "" {
+				continue
+			}
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, GenerateMetric, Deduplicate, Throttle, Route:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -9,6 +9,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,3 +34,71 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateMetricRules(t *testing.T) {
+	valid := [][]*ProcessingRule{
+		{{Name: "all", Type: GenerateMetric, MetricName: "logs.count"}},
+		{{Name: "status", Type: GenerateMetric, MetricName: "http.requests", Pattern: `" (?P<status>\d{3}) `, TagFields: []string{"status"}, DropMessage: true}},
+		{{Name: "duration", Type: GenerateMetric, MetricName: "http.duration", MetricType: DistributionMetric, ValueField: "duration"}},
+	}
+	for _, rules := range valid {
+		assert.NoError(t, ValidateProcessingRules(rules))
+		assert.NoError(t, CompileProcessingRules(rules))
+	}
+	assert.Nil(t, valid[0][0].Regex)
+	assert.NotNil(t, valid[1][0].Regex)
+
+	invalid := []*ProcessingRule{
+		{Name: "no_name", Type: GenerateMetric},
+		{Name: "no_value", Type: GenerateMetric, MetricName: "http.duration", MetricType: DistributionMetric},
+		{Name: "gauge", Type: GenerateMetric, MetricName: "http.duration", MetricType: "gauge"},
+		{Name: "pattern", Type: GenerateMetric, MetricName: "http.duration", Pattern: "(?=abf)"},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateDeduplicationAndThrottlingRules(t *testing.T) {
+	valid := [][]*ProcessingRule{
+		{{Name: "dedup", Type: Deduplicate}},
+		{{Name: "dedup_pattern", Type: Deduplicate, Match: PatternMatch, Window: 30, Pattern: "^panic"}},
+		{{Name: "throttle", Type: Throttle, RateLimit: 0.5}},
+		{{Name: "throttle_burst", Type: Throttle, RateLimit: 100, Burst: 500, Pattern: "DEBUG"}},
+	}
+	for _, rules := range valid {
+		assert.NoError(t, ValidateProcessingRules(rules))
+		assert.NoError(t, CompileProcessingRules(rules))
+	}
+	assert.Nil(t, valid[0][0].Regex)
+	assert.NotNil(t, valid[1][0].Regex)
+	assert.Equal(t, 10*time.Second, valid[0][0].DeduplicationWindow())
+	assert.Equal(t, 30*time.Second, valid[1][0].DeduplicationWindow())
+	assert.Equal(t, 1, valid[2][0].ThrottlingBurst())
+	assert.Equal(t, 500, valid[3][0].ThrottlingBurst())
+
+	invalid := []*ProcessingRule{
+		{Name: "match", Type: Deduplicate, Match: "fuzzy"},
+		{Name: "window", Type: Deduplicate, Window: -1},
+		{Name: "no_rate", Type: Throttle},
+		{Name: "burst", Type: Throttle, RateLimit: 10, Burst: -1},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateRoutingRules(t *testing.T) {
+	rules := []*ProcessingRule{{Name: "security", Type: Route, Pattern: "audit", DestinationGroup: "security"}}
+	assert.NoError(t, ValidateProcessingRules(rules))
+	assert.NoError(t, CompileProcessingRules(rules))
+	assert.NotNil(t, rules[0].Regex)
+
+	invalid := []*ProcessingRule{
+		{Name: "no_group", Type: Route, Pattern: "audit"},
+		{Name: "no_pattern", Type: Route, DestinationGroup: "security"},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -19,6 +19,12 @@ const ZlibEncoding = "deflate"
 // ZstdEncoding is the content-encoding value for Zstd
 const ZstdEncoding = "zstd"
 
+// LZ4Encoding is the content-encoding value for LZ4
+const LZ4Encoding = "lz4"
+
+// SnappyEncoding is the content-encoding value for Snappy
+const SnappyEncoding = "snappy"
+
 // Component is the component type.
 type Component interface {
 	Compress(src []byte) ([]byte, error)
@@ -28,7 +34,7 @@ type Component interface {
 	NewStreamCompressor(output *bytes.Buffer) StreamCompressor
 }
 
-// StreamCompressor is the interface that zlib and zstd should implement
+// StreamCompressor is the interface that the compressors should implement
 type StreamCompressor interface {
 	io.WriteCloser
 	Flush() error
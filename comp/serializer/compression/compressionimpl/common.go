@@ -14,3 +14,9 @@ const ZstdKind = "zstd"
 
 // NoneKind defines a const value for disabling compression
 const NoneKind = "none"
+
+// LZ4Kind defines a const value for the LZ4 compressor
+const LZ4Kind = "lz4"
+
+// SnappyKind defines a const value for the Snappy compressor
+const SnappyKind = "snappy"
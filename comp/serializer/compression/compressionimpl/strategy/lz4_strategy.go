@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package strategy
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pierrec/lz4/v4"
+
+	"github.com/DataDog/datadog-agent/comp/serializer/compression"
+)
+
+// lz4BlockSize is the size of the blocks of the LZ4 frames. Small blocks keep
+// the memory used by each stream compressor low.
+const lz4BlockSize = 64 * 1024
+
+// the maximum size of the header of a LZ4 frame, of the size of a block and of the end of a frame
+const (
+	lz4FrameHeaderSize = 19
+	lz4BlockHeaderSize = 4
+	lz4FrameFooterSize = 8
+)
+
+// LZ4Strategy is the strategy for when serializer_compressor_kind is lz4.
+// The payloads are LZ4 frames so that they can be decompressed as a stream.
+type LZ4Strategy struct {
+}
+
+// NewLZ4Strategy returns a new LZ4Strategy
+func NewLZ4Strategy() *LZ4Strategy {
+	return &LZ4Strategy{}
+}
+
+// Compress will compress the data with LZ4
+func (s *LZ4Strategy) Compress(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := s.newWriter(&b)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decompress will decompress the data with LZ4
+func (s *LZ4Strategy) Decompress(src []byte) ([]byte, error) {
+	return io.ReadAll(lz4.NewReader(bytes.NewReader(src)))
+}
+
+// CompressBound returns the worst case size needed for a destination buffer when using LZ4:
+// the blocks which cannot be compressed are stored as is.
+func (s *LZ4Strategy) CompressBound(sourceLen int) int {
+	blocks := sourceLen/lz4BlockSize + 1
+	return lz4FrameHeaderSize + sourceLen + blocks*lz4BlockHeaderSize + lz4FrameFooterSize
+}
+
+// ContentEncoding returns the content encoding value for LZ4
+func (s *LZ4Strategy) ContentEncoding() string {
+	return compression.LZ4Encoding
+}
+
+// NewStreamCompressor returns a new LZ4 writer
+func (s *LZ4Strategy) NewStreamCompressor(output *bytes.Buffer) compression.StreamCompressor {
+	return s.newWriter(output)
+}
+
+func (s *LZ4Strategy) newWriter(output io.Writer) *lz4.Writer {
+	w := lz4.NewWriter(output)
+	// The options are valid, applying them cannot fail
+	_ = w.Apply(lz4.BlockSizeOption(lz4.Block64Kb), lz4.CompressionLevelOption(lz4.Fast))
+	return w
+}
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package strategy
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/golang/snappy"
+
+	"github.com/DataDog/datadog-agent/comp/serializer/compression"
+)
+
+// the size of the stream identifier, the maximum uncompressed size and the
+// header size of the chunks of the Snappy framing format
+const (
+	snappyStreamIdentifierSize = 10
+	snappyChunkSize            = 64 * 1024
+	snappyChunkHeaderSize      = 8
+)
+
+// SnappyStrategy is the strategy for when serializer_compressor_kind is snappy.
+// The payloads use the Snappy framing format so that they can be decompressed as a stream.
+type SnappyStrategy struct {
+}
+
+// NewSnappyStrategy returns a new SnappyStrategy
+func NewSnappyStrategy() *SnappyStrategy {
+	return &SnappyStrategy{}
+}
+
+// Compress will compress the data with Snappy
+func (s *SnappyStrategy) Compress(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := snappy.NewBufferedWriter(&b)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decompress will decompress the data with Snappy
+func (s *SnappyStrategy) Decompress(src []byte) ([]byte, error) {
+	return io.ReadAll(snappy.NewReader(bytes.NewReader(src)))
+}
+
+// CompressBound returns the worst case size needed for a destination buffer when using Snappy:
+// the chunks which cannot be compressed are stored as is.
+func (s *SnappyStrategy) CompressBound(sourceLen int) int {
+	chunks := sourceLen/snappyChunkSize + 1
+	return snappyStreamIdentifierSize + sourceLen + chunks*snappyChunkHeaderSize
+}
+
+// ContentEncoding returns the content encoding value for Snappy
+func (s *SnappyStrategy) ContentEncoding() string {
+	return compression.SnappyEncoding
+}
+
+// NewStreamCompressor returns a new Snappy writer
+func (s *SnappyStrategy) NewStreamCompressor(output *bytes.Buffer) compression.StreamCompressor {
+	return snappy.NewBufferedWriter(output)
+}
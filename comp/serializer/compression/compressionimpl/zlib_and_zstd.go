@@ -34,11 +34,15 @@ func NewCompressor(cfg config.Component) compression.Component {
 	case ZstdKind:
 		level := cfg.GetInt("serializer_zstd_compressor_level")
 		return strategy.NewZstdStrategy(level)
+	case LZ4Kind:
+		return strategy.NewLZ4Strategy()
+	case SnappyKind:
+		return strategy.NewSnappyStrategy()
 	case NoneKind:
-		log.Warn("no serializer_compressor_kind set. use zlib or zstd")
+		log.Warn("no serializer_compressor_kind set. use zlib, zstd, lz4 or snappy")
 		return strategy.NewNoopStrategy()
 	default:
-		log.Warn("invalid serializer_compressor_kind detected. use one of 'zlib', 'zstd'")
+		log.Warn("invalid serializer_compressor_kind detected. use one of 'zlib', 'zstd', 'lz4', 'snappy'")
 		return strategy.NewNoopStrategy()
 	}
 }
@@ -34,11 +34,15 @@ func NewCompressor(cfg config.Component) compression.Component {
 	case ZstdKind:
 		log.Warn("zstd build tag not included. using zlib")
 		return strategy.NewZlibStrategy()
+	case LZ4Kind:
+		return strategy.NewLZ4Strategy()
+	case SnappyKind:
+		return strategy.NewSnappyStrategy()
 	case NoneKind:
-		log.Warn("no serializer_compressor_kind set. use zlib or zstd")
+		log.Warn("no serializer_compressor_kind set. use zlib, zstd, lz4 or snappy")
 		return strategy.NewNoopStrategy()
 	default:
-		log.Warn("invalid serializer_compressor_kind detected. use zlib or zstd")
+		log.Warn("invalid serializer_compressor_kind detected. use one of 'zlib', 'zstd', 'lz4', 'snappy'")
 		return strategy.NewNoopStrategy()
 	}
 }
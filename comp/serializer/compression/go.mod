@@ -38,6 +38,8 @@ require (
 	github.com/DataDog/datadog-agent/pkg/util/fxutil v0.56.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/util/log v0.56.0-rc.3
 	github.com/DataDog/zstd v1.5.5
+	github.com/golang/snappy v0.0.5-0.20220116011046-fa5810519dcb
+	github.com/pierrec/lz4/v4 v4.1.21
 	go.uber.org/fx v1.22.2
 )
 
@@ -684,6 +684,30 @@ func TestFullYamlConfig(t *testing.T) {
 
 	assert.EqualValues(t, []string{"/health", "/500"}, cfg.Ignore["resource"])
 
+	assert.Equal(t, []*traceconfig.SpanRule{
+		{Name: "drop-cache-hits", Operation: `^redis\.`, Tags: map[string]string{"cache.hit": "true"}, Action: "drop_span"},
+		{Service: "^pg-", Action: "rename_service", Value: "postgres"},
+		{Action: "truncate_tag", Key: "sql.query", MaxLength: 1024},
+	}, cfg.SpanRules)
+	assert.Equal(t, []*traceconfig.SpanMetric{
+		{
+			Name:    "checkout.requests",
+			Type:    "count",
+			Filter:  traceconfig.SpanFilter{Service: "^checkout$", Tags: map[string]string{"span.kind": "server"}},
+			GroupBy: []string{"resource", "customer_tier"},
+		},
+		{Name: "checkout.duration", Type: "distribution"},
+	}, cfg.SpanMetrics)
+	assert.True(t, cfg.ZipkinReceiverEnabled)
+	assert.False(t, cfg.JaegerReceiverEnabled)
+	assert.False(t, cfg.TailSampling.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.TailSampling.DecisionWait)
+	assert.EqualValues(t, 1048576, cfg.TailSampling.MaxBufferSize)
+	assert.Equal(t, []*traceconfig.TailSamplingPolicy{
+		{Type: "latency", ThresholdMs: 500},
+		{Type: "probabilistic", SamplingPercentage: 12.5},
+	}, cfg.TailSampling.Policies)
+
 	o := cfg.Obfuscation
 	assert.NotNil(t, o)
 	assert.True(t, o.ES.Enabled)
@@ -1164,6 +1188,75 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_TAIL_SAMPLING_POLICIES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_TAIL_SAMPLING_ENABLED", "true")
+		t.Setenv(env, `[{"type":"error"},{"type":"tag","key":"customer.tier","values":["gold"]},{"type":"probabilistic","sampling_percentage":10,"max_traces_per_second":5}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.TailSampling.Enabled)
+		assert.Equal(t, []*traceconfig.TailSamplingPolicy{
+			{Type: "error"},
+			{Type: "tag", Key: "customer.tier", Values: []string{"gold"}},
+			{Type: "probabilistic", SamplingPercentage: 10, MaxTracesPerSecond: 5},
+		}, cfg.TailSampling.Policies)
+	})
+
+	env = "DD_APM_SPAN_RULES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"name":"drop-health","resource":"^GET /health","action":"drop_span"},{"tags":{"env":"staging"},"action":"set_tag","key":"team","value":"web"}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.SpanRule{
+			{Name: "drop-health", Resource: "^GET /health", Action: "drop_span"},
+			{Tags: map[string]string{"env": "staging"}, Action: "set_tag", Key: "team", Value: "web"},
+		}, cfg.SpanRules)
+	})
+
+	env = "DD_APM_SPAN_METRICS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"name":"cart.size","type":"distribution","metric":"cart.size","filter":{"operation":"^http\\."},"group_by":["service"]}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.SpanMetric{{
+			Name:    "cart.size",
+			Type:    "distribution",
+			Metric:  "cart.size",
+			Filter:  traceconfig.SpanFilter{Operation: `^http\.`},
+			GroupBy: []string{"service"},
+		}}, cfg.SpanMetrics)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
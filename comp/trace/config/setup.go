@@ -274,6 +274,24 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.ProbabilisticSamplerHashSeed = uint32(core.GetInt("apm_config.probabilistic_sampler.hash_seed"))
 	}
 
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSampling.Enabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSampling.DecisionWait = getDuration(core.GetInt("apm_config.tail_sampling.decision_wait"))
+	}
+	if core.IsSet("apm_config.tail_sampling.max_buffer_size") {
+		c.TailSampling.MaxBufferSize = core.GetInt64("apm_config.tail_sampling.max_buffer_size")
+	}
+	if k := "apm_config.tail_sampling.policies"; core.IsSet(k) {
+		policies := make([]*config.TailSamplingPolicy, 0)
+		if err := structure.UnmarshalKey(core, k, &policies); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"type\": \"error\"},{\"type\": \"latency\",\"threshold_ms\": 500}]', error: %v", k, err)
+		} else {
+			c.TailSampling.Policies = policies
+		}
+	}
+
 	if core.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = core.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
@@ -305,6 +323,12 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	} else {
 		c.MaxConnections = 1000
 	}
+	if core.IsSet("apm_config.zipkin_receiver.enabled") {
+		c.ZipkinReceiverEnabled = core.GetBool("apm_config.zipkin_receiver.enabled")
+	}
+	if core.IsSet("apm_config.jaeger_receiver.enabled") {
+		c.JaegerReceiverEnabled = core.GetBool("apm_config.jaeger_receiver.enabled")
+	}
 	if core.IsSet("apm_config.decoder_timeout") {
 		c.DecoderTimeout = core.GetInt("apm_config.decoder_timeout")
 	} else {
@@ -324,6 +348,24 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		}
 	}
 
+	if k := "apm_config.span_rules"; core.IsSet(k) {
+		rules := make([]*config.SpanRule, 0)
+		if err := structure.UnmarshalKey(core, k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"rule_name\",\"service\":\"pattern\",\"action\":\"drop_span\"}]', error: %v", k, err)
+		} else {
+			c.SpanRules = rules
+		}
+	}
+
+	if k := "apm_config.span_metrics"; core.IsSet(k) {
+		metrics := make([]*config.SpanMetric, 0)
+		if err := structure.UnmarshalKey(core, k, &metrics); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"metric_name\",\"type\":\"count\",\"filter\":{\"service\":\"pattern\"},\"group_by\":[\"tag\"]}]', error: %v", k, err)
+		} else {
+			c.SpanMetrics = metrics
+		}
+	}
+
 	if core.IsSet("bind_host") || core.IsSet("apm_config.apm_non_local_traffic") {
 		if core.IsSet("bind_host") {
 			host := core.GetString("bind_host")
@@ -362,6 +404,7 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		BindHost:               c.ReceiverHost,
 		GRPCPort:               grpcPort,
 		MaxRequestBytes:        c.MaxRequestBytes,
+		HTTPEnabled:            core.GetBool("apm_config.otlp_http_receiver.enabled"),
 		SpanNameRemappings:     pkgconfigsetup.Datadog().GetStringMapString("otlp_config.traces.span_name_remappings"),
 		SpanNameAsResourceName: core.GetBool("otlp_config.traces.span_name_as_resource_name"),
 		ProbabilisticSampling:  core.GetFloat64("otlp_config.traces.probabilistic_sampler.sampling_percentage"),
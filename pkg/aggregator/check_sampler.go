@@ -54,7 +54,12 @@ func (cs *CheckSampler) addSample(metricSample *metrics.MetricSample) {
 		return
 	}
 
-	if err := cs.metrics.AddSample(contextKey, metricSample, metricSample.Timestamp, 1, pkgconfigsetup.Datadog()); err != nil {
+	var histogramConfig *metrics.HistogramConfig
+	if metricSample.Mtype == metrics.HistogramType || metricSample.Mtype == metrics.HistorateType {
+		histogramConfig = cs.contextResolver.resolver.histogramConfig(contextKey)
+	}
+
+	if err := cs.metrics.AddSampleWithHistogramConfig(contextKey, metricSample, metricSample.Timestamp, 1, pkgconfigsetup.Datadog(), histogramConfig); err != nil {
 		log.Debugf("Ignoring sample '%s' on host '%s' and tags '%s': %s", metricSample.Name, metricSample.Host, metricSample.Tags, err)
 	}
 }
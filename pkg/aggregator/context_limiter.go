@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// overflowTag replaces the tags of the contexts exceeding their budget
+	overflowTag = "__overflow"
+	// overflowTopTagKeys is the number of tag keys reported for an overflowing metric
+	overflowTopTagKeys = 3
+	// overflowMaxTagValues caps the number of distinct values tracked per tag key
+	overflowMaxTagValues = 1000
+)
+
+// contextBudgetKey identifies a context budget: a metric name, and its origin
+// when budgets are tracked per origin.
+type contextBudgetKey struct {
+	name   string
+	origin ckey.TagsKey
+}
+
+// contextBudget tracks the contexts of a metric name against the limit.
+type contextBudget struct {
+	key contextBudgetKey
+	// count is the number of contexts tracked against this budget
+	count int
+	// overflow is the number of contexts folded since the last flush
+	overflow uint64
+	// tagValues holds the distinct values of the tag keys of the folded contexts
+	// since the last flush, to report which tag keys are exploding
+	tagValues map[string]map[string]struct{}
+}
+
+// contextLimiter limits the number of contexts per metric name (and per origin
+// if configured). Contexts exceeding their budget are folded by the context
+// resolver into a single overflow context.
+//
+// contextLimiter is not thread-safe, it's owned by a single context resolver.
+type contextLimiter struct {
+	id        string
+	limit     int
+	perOrigin bool
+	budgets   map[contextBudgetKey]*contextBudget
+
+	// reported holds the tag sets of the telemetry sent during the last flush
+	reported [][]string
+}
+
+// newContextLimiter returns a new contextLimiter, or nil if the limit is disabled.
+func newContextLimiter(id string, limit int, perOrigin bool) *contextLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &contextLimiter{
+		id:        id,
+		limit:     limit,
+		perOrigin: perOrigin,
+		budgets:   make(map[contextBudgetKey]*contextBudget),
+	}
+}
+
+// track accounts for a new context. It returns the budget the context is
+// accounted against and true, or false if the budget is exhausted in which
+// case the context must be folded into the overflow context.
+func (l *contextLimiter) track(name string, origin ckey.TagsKey, metricTags []string) (*contextBudget, bool) {
+	key := contextBudgetKey{name: name}
+	if l.perOrigin {
+		key.origin = origin
+	}
+
+	budget, found := l.budgets[key]
+	if !found {
+		budget = &contextBudget{key: key}
+		l.budgets[key] = budget
+	}
+
+	if budget.count >= l.limit {
+		budget.overflow++
+		if budget.tagValues == nil {
+			budget.tagValues = make(map[string]map[string]struct{})
+		}
+		for _, tag := range metricTags {
+			k, v, _ := strings.Cut(tag, ":")
+			values, ok := budget.tagValues[k]
+			if !ok {
+				values = make(map[string]struct{})
+				budget.tagValues[k] = values
+			}
+			if len(values) < overflowMaxTagValues {
+				values[v] = struct{}{}
+			}
+		}
+		return nil, false
+	}
+
+	budget.count++
+	return budget, true
+}
+
+// release is called when a context accounted against the budget expires.
+func (l *contextLimiter) release(budget *contextBudget) {
+	budget.count--
+	if budget.count <= 0 && budget.overflow == 0 {
+		delete(l.budgets, budget.key)
+	}
+}
+
+// topTagKeys returns the tag keys with the most distinct values among the
+// folded contexts, and their number of values.
+func (b *contextBudget) topTagKeys() ([]string, []int) {
+	keys := make([]string, 0, len(b.tagValues))
+	for k := range b.tagValues {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := len(b.tagValues[keys[i]]), len(b.tagValues[keys[j]])
+		if ci != cj {
+			return ci > cj
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > overflowTopTagKeys {
+		keys = keys[:overflowTopTagKeys]
+	}
+	counts := make([]int, 0, len(keys))
+	for _, k := range keys {
+		counts = append(counts, len(b.tagValues[k]))
+	}
+	return keys, counts
+}
+
+// updateMetrics sends the telemetry about the metrics which exceeded their
+// budget since the last call, and resets the overflow statistics.
+func (l *contextLimiter) updateMetrics() {
+	for _, tags := range l.reported {
+		tlmDogstatsdContextsOverflowTagKeys.Delete(tags...)
+	}
+	l.reported = l.reported[:0]
+
+	for key, budget := range l.budgets {
+		if budget.overflow == 0 {
+			continue
+		}
+
+		tlmDogstatsdContextsOverflow.Add(float64(budget.overflow), l.id, key.name)
+
+		keys, counts := budget.topTagKeys()
+		described := make([]string, 0, len(keys))
+		for i, k := range keys {
+			tags := []string{l.id, key.name, k}
+			tlmDogstatsdContextsOverflowTagKeys.Set(float64(counts[i]), tags...)
+			l.reported = append(l.reported, tags)
+			described = append(described, fmt.Sprintf("%s (%d values)", k, counts[i]))
+		}
+		log.Warnf("TimeSampler #%s: metric '%s' exceeded its budget of %d contexts, %d new contexts were folded into the '%s' context. Top tag keys: %s",
+			l.id, key.name, l.limit, budget.overflow, overflowTag, strings.Join(described, ", "))
+
+		budget.overflow = 0
+		budget.tagValues = nil
+		if budget.count <= 0 {
+			delete(l.budgets, key)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewContextLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newContextLimiter("test", 0, false))
+	assert.NotNil(t, newContextLimiter("test", 1, false))
+}
+
+func testContextLimiterOverflow(t *testing.T, store *tags.Store) {
+	contextResolver := newTimestampContextResolver(store, "test", 2, 4, newContextLimiter("test", 2, false))
+
+	sample := func(name string, tags ...string) *metrics.MetricSample {
+		return &metrics.MetricSample{Name: name, Value: 1, Mtype: metrics.GaugeType, Tags: tags, SampleRate: 1}
+	}
+
+	key1 := contextResolver.trackContext(sample("my.metric", "request_id:1", "env:prod"), 4)
+	key2 := contextResolver.trackContext(sample("my.metric", "request_id:2", "env:prod"), 4)
+	// other metrics have their own budget
+	contextResolver.trackContext(sample("other.metric", "request_id:3"), 4)
+
+	overflowKey := contextResolver.trackContext(sample("my.metric", "request_id:3", "env:prod"), 4)
+	assert.NotEqual(t, key1, overflowKey)
+	assert.NotEqual(t, key2, overflowKey)
+	// existing contexts are still tracked
+	assert.Equal(t, key1, contextResolver.trackContext(sample("my.metric", "request_id:1", "env:prod"), 4))
+	// all contexts over the budget share the same overflow context
+	for i := 4; i < 10; i++ {
+		assert.Equal(t, overflowKey, contextResolver.trackContext(sample("my.metric", fmt.Sprintf("request_id:%d", i), "env:prod"), 5))
+	}
+	assert.Equal(t, 4, contextResolver.length())
+
+	cx, ok := contextResolver.get(overflowKey)
+	require.True(t, ok)
+	assertContext(t, cx, "my.metric", []string{overflowTag}, "")
+	assert.Nil(t, cx.budget)
+
+	budget := contextResolver.resolver.limiter.budgets[contextBudgetKey{name: "my.metric"}]
+	require.NotNil(t, budget)
+	assert.Equal(t, 2, budget.count)
+	assert.Equal(t, uint64(7), budget.overflow)
+	keys, counts := budget.topTagKeys()
+	assert.Equal(t, []string{"request_id", "env"}, keys)
+	assert.Equal(t, []int{7, 1}, counts)
+
+	contextResolver.resolver.limiter.updateMetrics()
+	assert.Equal(t, uint64(0), budget.overflow)
+	assert.Nil(t, budget.tagValues)
+
+	// once contexts expire, the budget is available again
+	contextResolver.expireContexts(7)
+	assert.Equal(t, 1, contextResolver.length())
+	assert.Equal(t, 0, budget.count)
+	assert.NotContains(t, contextResolver.resolver.limiter.budgets, contextBudgetKey{name: "my.metric"})
+
+	newKey := contextResolver.trackContext(sample("my.metric", "request_id:42", "env:prod"), 8)
+	assert.NotEqual(t, overflowKey, newKey)
+}
+
+func TestContextLimiterOverflow(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterOverflow)
+}
+
+func TestContextLimiterPerOrigin(t *testing.T) {
+	limiter := newContextLimiter("test", 1, true)
+
+	_, ok := limiter.track("my.metric", 1, nil)
+	assert.True(t, ok)
+	_, ok = limiter.track("my.metric", 2, nil)
+	assert.True(t, ok)
+	_, ok = limiter.track("my.metric", 1, nil)
+	assert.False(t, ok)
+
+	limiter = newContextLimiter("test", 1, false)
+	_, ok = limiter.track("my.metric", 1, nil)
+	assert.True(t, ok)
+	_, ok = limiter.track("my.metric", 2, nil)
+	assert.False(t, ok)
+}
@@ -27,6 +27,10 @@ type Context struct {
 	metricTags *tags.Entry
 	noIndex    bool
 	source     metrics.MetricSource
+	budget     *contextBudget
+	// histogramConfig is resolved when the context is created, nil if the
+	// global histogram configuration applies
+	histogramConfig *metrics.HistogramConfig
 }
 
 type resolverEntry struct {
@@ -75,6 +79,9 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	limiter          *contextLimiter
+	// histogramOverrides resolves the histogram configuration of new contexts
+	histogramOverrides *metrics.HistogramOverrides
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -105,7 +112,20 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
-	if entry, ok := cr.contextsByKey[contextKey]; !ok {
+	entry, ok := cr.contextsByKey[contextKey]
+
+	var budget *contextBudget
+	if !ok && cr.limiter != nil {
+		var allowed bool
+		budget, allowed = cr.limiter.track(metricSampleContext.GetName(), taggerKey, cr.metricBuffer.Get())
+		if !allowed {
+			// fold the context into the overflow context of the metric
+			contextKey, taggerKey, metricKey = cr.generateOverflowContextKey(metricSampleContext)
+			entry, ok = cr.contextsByKey[contextKey]
+		}
+	}
+
+	if !ok {
 		mtype := metricSampleContext.GetMetricType()
 		context := &Context{
 			Name:       metricSampleContext.GetName(),
@@ -115,6 +135,10 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 			mtype:      mtype,
 			noIndex:    metricSampleContext.IsNoIndex(),
 			source:     metricSampleContext.GetSource(),
+			budget:     budget,
+		}
+		if mtype == metrics.HistogramType || mtype == metrics.HistorateType {
+			context.histogramConfig = cr.histogramOverrides.Resolve(context.Name)
 		}
 		cr.contextsByKey[contextKey] = resolverEntry{
 			lastSeen: timestamp,
@@ -136,11 +160,36 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 	return contextKey
 }
 
+// generateOverflowContextKey replaces the metric tags by the overflow tag, as
+// well as the tagger tags unless the budgets are tracked per origin, and
+// generates the contextKey of the resulting overflow context.
+func (cr *contextResolver) generateOverflowContextKey(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, ckey.TagsKey, ckey.TagsKey) {
+	cr.metricBuffer.Reset()
+	cr.metricBuffer.Append(overflowTag)
+	if !cr.limiter.perOrigin {
+		cr.taggerBuffer.Reset()
+	}
+	return cr.generateContextKey(metricSampleContext)
+}
+
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
 	ctx, found := cr.contextsByKey[key]
 	return ctx.context, found
 }
 
+// histogramConfig returns the histogram configuration resolved for the
+// context, or nil if the global configuration applies.
+func (cr *contextResolver) histogramConfig(key ckey.ContextKey) *metrics.HistogramConfig {
+	if cr.histogramOverrides == nil {
+		return nil
+	}
+	ctx, found := cr.contextsByKey[key]
+	if !found {
+		return nil
+	}
+	return ctx.context.histogramConfig
+}
+
 func (cr *contextResolver) length() int {
 	return len(cr.contextsByKey)
 }
@@ -153,6 +202,9 @@ func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
 		cr.countsByMtype[context.mtype]--
 		cr.bytesByMtype[context.mtype] -= uint64(context.SizeInBytes())
 		cr.dataBytesByMtype[context.mtype] -= uint64(context.DataSizeInBytes())
+		if context.budget != nil {
+			cr.limiter.release(context.budget)
+		}
 		context.release()
 	}
 }
@@ -172,6 +224,10 @@ func (cr *contextResolver) updateMetrics(countsByMTypeGauge telemetry.Gauge, byt
 		bytesByMTypeGauge.Set(float64(bytes), cr.id, mtype, util.BytesKindStruct)
 		bytesByMTypeGauge.Set(float64(dataBytes), cr.id, mtype, util.BytesKindData)
 	}
+
+	if cr.limiter != nil {
+		cr.limiter.updateMetrics()
+	}
 }
 
 func (cr *contextResolver) release() {
@@ -219,9 +275,11 @@ type timestampContextResolver struct {
 	counterExpireTime int64
 }
 
-func newTimestampContextResolver(cache *tags.Store, id string, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
+func newTimestampContextResolver(cache *tags.Store, id string, contextExpireTime, counterExpireTime int64, limiter *contextLimiter) *timestampContextResolver {
+	resolver := newContextResolver(cache, id)
+	resolver.limiter = limiter
 	return &timestampContextResolver{
-		resolver: newContextResolver(cache, id),
+		resolver: resolver,
 
 		contextExpireTime: contextExpireTime,
 		counterExpireTime: counterExpireTime,
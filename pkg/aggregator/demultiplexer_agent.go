@@ -40,6 +40,7 @@ type DemultiplexerWithAggregator interface {
 	GetEventPlatformForwarder() (eventplatform.Forwarder, error)
 	GetEventsAndServiceChecksChannels() (chan []*event.Event, chan []*servicecheck.ServiceCheck)
 	DumpDogstatsdContexts(io.Writer) error
+	QueryFlushedMetrics(query FlushedMetricsQuery) ([]FlushedMetric, error)
 }
 
 // AgentDemultiplexer is the demultiplexer implementation for the main Agent.
@@ -65,6 +66,9 @@ type AgentDemultiplexer struct {
 
 	// sharded statsd time samplers
 	statsd
+
+	// flushHistory retains the recently flushed series and sketches, nil when disabled
+	flushHistory *flushHistory
 }
 
 // AgentDemultiplexerOptions are the options used to initialize a Demultiplexer.
@@ -77,6 +81,13 @@ type AgentDemultiplexerOptions struct {
 
 	UseDogstatsdContextLimiter bool
 	DogstatsdMaxMetricsTags    int
+
+	// FlushHistoryRetention is how long the flushed series and sketches are
+	// retained to be queried, the flush history is disabled when it is 0.
+	FlushHistoryRetention time.Duration
+	// FlushHistoryMaxSeries caps the number of series and sketches retained
+	// by the flush history, 0 means no limit.
+	FlushHistoryMaxSeries int
 }
 
 // DefaultAgentDemultiplexerOptions returns the default options to initialize an AgentDemultiplexer.
@@ -177,6 +188,11 @@ func initAgentDemultiplexer(
 			bufferSize, metricSamplePool, agg.flushAndSerializeInParallel, tagsStore)
 	}
 
+	var history *flushHistory
+	if options.FlushHistoryRetention > 0 {
+		history = newFlushHistory(options.FlushHistoryRetention, options.FlushHistoryMaxSeries)
+	}
+
 	var noAggWorker *noAggregationStreamWorker
 	var noAggSerializer serializer.MetricSerializer
 	if options.EnableNoAggregationPipeline {
@@ -187,6 +203,7 @@ func initAgentDemultiplexer(
 			noAggSerializer,
 			agg.flushAndSerializeInParallel,
 		)
+		noAggWorker.flushHistory = history
 	}
 
 	// --
@@ -217,6 +234,8 @@ func initAgentDemultiplexer(
 			metricSamplePool:  metricSamplePool,
 			noAggStreamWorker: noAggWorker,
 		},
+
+		flushHistory: history,
 	}
 
 	return demux
@@ -402,10 +421,19 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 	logPayloads := pkgconfigsetup.Datadog().GetBool("log_payloads")
 	series, sketches := createIterableMetrics(d.aggregator.flushAndSerializeInParallel, d.sharedSerializer, logPayloads, false)
 
+	var recorder *flushHistoryRecorder
+	if d.flushHistory != nil {
+		recorder = &flushHistoryRecorder{flushTime: start.Unix()}
+	}
+
 	metrics.Serialize(
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			if recorder != nil {
+				seriesSink, sketchesSink = recorder.wrap(seriesSink, sketchesSink)
+			}
+
 			// flush DogStatsD pipelines (statsd/time samplers)
 			// ------------------------------------------------
 
@@ -455,6 +483,10 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 			}
 		})
 
+	if recorder != nil {
+		d.flushHistory.record(start, recorder.batch)
+	}
+
 	addFlushTime("MainFlushTime", int64(time.Since(start)))
 	aggregatorNumberOfFlush.Add(1)
 }
@@ -557,6 +589,16 @@ func (d *AgentDemultiplexer) DumpDogstatsdContexts(dest io.Writer) error {
 	return nil
 }
 
+// QueryFlushedMetrics returns the series and sketches matching the query among
+// the ones recently flushed to the serializer, from the oldest to the most
+// recent flush.
+func (d *AgentDemultiplexer) QueryFlushedMetrics(query FlushedMetricsQuery) ([]FlushedMetric, error) {
+	if d.flushHistory == nil {
+		return nil, ErrFlushHistoryDisabled
+	}
+	return d.flushHistory.query(query), nil
+}
+
 // GetSender returns a sender.Sender with passed ID, properly registered with the aggregator
 // If no error is returned here, DestroySender must be called with the same ID
 // once the sender is not used anymore
@@ -0,0 +1,243 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+// ErrFlushHistoryDisabled is returned when querying the flushed metrics while
+// the flush history is not enabled.
+var ErrFlushHistoryDisabled = errors.New("the flush history is disabled, set 'aggregator_flush_history.enabled' to true to enable it")
+
+// sketchMetricType is the type reported for the sketches in the flush history
+const sketchMetricType = "sketch"
+
+// FlushedMetric is a serie or a sketch flushed to the serializer, as retained by
+// the flush history.
+type FlushedMetric struct {
+	Name           string               `json:"metric"`
+	Type           string               `json:"type"`
+	Tags           []string             `json:"tags"`
+	Host           string               `json:"host"`
+	Device         string               `json:"device,omitempty"`
+	Interval       int64                `json:"interval"`
+	SourceTypeName string               `json:"source_type_name,omitempty"`
+	Points         []FlushedPoint       `json:"points,omitempty"`
+	Sketches       []FlushedSketchPoint `json:"sketches,omitempty"`
+	FlushTime      int64                `json:"flush_time"`
+}
+
+// FlushedPoint is a point of a flushed serie
+type FlushedPoint struct {
+	Ts    int64   `json:"ts"`
+	Value float64 `json:"value"`
+}
+
+// FlushedSketchPoint is the summary of a point of a flushed sketch
+type FlushedSketchPoint struct {
+	Ts    int64   `json:"ts"`
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+}
+
+// FlushedMetricsQuery selects the flushed metrics returned by the flush history:
+// a metric matches when it has the given name and all the given tags.
+type FlushedMetricsQuery struct {
+	Name string
+	Tags []string
+}
+
+func (q FlushedMetricsQuery) matches(m *FlushedMetric) bool {
+	if m.Name != q.Name {
+		return false
+	}
+	for _, tag := range q.Tags {
+		found := false
+		for _, t := range m.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// copyTags copies the tags as the underlying slices are shared with the tags store
+func copyTags(tags tagset.CompositeTags) []string {
+	copied := make([]string, 0, tags.Len())
+	tags.ForEach(func(tag string) {
+		copied = append(copied, tag)
+	})
+	return copied
+}
+
+func newFlushedSerie(serie *metrics.Serie, flushTime int64) FlushedMetric {
+	m := FlushedMetric{
+		Name:           serie.Name,
+		Type:           serie.MType.String(),
+		Tags:           copyTags(serie.Tags),
+		Host:           serie.Host,
+		Device:         serie.Device,
+		Interval:       serie.Interval,
+		SourceTypeName: serie.SourceTypeName,
+		Points:         make([]FlushedPoint, 0, len(serie.Points)),
+		FlushTime:      flushTime,
+	}
+	for _, p := range serie.Points {
+		m.Points = append(m.Points, FlushedPoint{Ts: int64(p.Ts), Value: p.Value})
+	}
+	return m
+}
+
+func newFlushedSketch(sketch *metrics.SketchSeries, flushTime int64) FlushedMetric {
+	m := FlushedMetric{
+		Name:      sketch.Name,
+		Type:      sketchMetricType,
+		Tags:      copyTags(sketch.Tags),
+		Host:      sketch.Host,
+		Interval:  sketch.Interval,
+		Sketches:  make([]FlushedSketchPoint, 0, len(sketch.Points)),
+		FlushTime: flushTime,
+	}
+	for _, p := range sketch.Points {
+		if p.Sketch == nil {
+			continue
+		}
+		m.Sketches = append(m.Sketches, FlushedSketchPoint{
+			Ts:    p.Ts,
+			Count: p.Sketch.Basic.Cnt,
+			Sum:   p.Sketch.Basic.Sum,
+			Min:   p.Sketch.Basic.Min,
+			Max:   p.Sketch.Basic.Max,
+			Avg:   p.Sketch.Basic.Avg,
+		})
+	}
+	return m
+}
+
+// flushedBatch is the set of metrics recorded by a single flush
+type flushedBatch struct {
+	time    time.Time
+	metrics []FlushedMetric
+}
+
+// flushHistory retains the metrics flushed to the serializer during the
+// last `retention`, keeping at most `maxSeries` of them: the oldest batches are
+// evicted first.
+type flushHistory struct {
+	m sync.RWMutex
+
+	retention time.Duration
+	maxSeries int
+
+	batches []flushedBatch
+	size    int
+}
+
+func newFlushHistory(retention time.Duration, maxSeries int) *flushHistory {
+	return &flushHistory{
+		retention: retention,
+		maxSeries: maxSeries,
+	}
+}
+
+// record adds a batch of flushed metrics to the history, evicting the batches
+// out of the retention.
+func (h *flushHistory) record(t time.Time, flushed []FlushedMetric) {
+	if len(flushed) == 0 {
+		return
+	}
+	if h.maxSeries > 0 && len(flushed) > h.maxSeries {
+		flushed = flushed[:h.maxSeries]
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.batches = append(h.batches, flushedBatch{time: t, metrics: flushed})
+	h.size += len(flushed)
+
+	evicted := 0
+	for evicted < len(h.batches)-1 {
+		oldest := h.batches[evicted]
+		if t.Sub(oldest.time) <= h.retention && (h.maxSeries <= 0 || h.size <= h.maxSeries) {
+			break
+		}
+		h.size -= len(oldest.metrics)
+		evicted++
+	}
+	if evicted > 0 {
+		// don't keep a reference on the evicted batches
+		clear(h.batches[:evicted])
+		h.batches = h.batches[evicted:]
+	}
+}
+
+// query returns the metrics of the history matching the query, from the
+// oldest to the most recent flush.
+func (h *flushHistory) query(q FlushedMetricsQuery) []FlushedMetric {
+	h.m.RLock()
+	defer h.m.RUnlock()
+
+	result := []FlushedMetric{}
+	for _, batch := range h.batches {
+		for i := range batch.metrics {
+			if q.matches(&batch.metrics[i]) {
+				result = append(result, batch.metrics[i])
+			}
+		}
+	}
+	return result
+}
+
+// flushHistoryRecorder records the series and sketches of a flush before
+// forwarding them to the sinks of the serializer. The sinks are used by a
+// single routine at a time, the recorded batch is committed to the history
+// once the flush is done.
+type flushHistoryRecorder struct {
+	flushTime int64
+	batch     []FlushedMetric
+}
+
+func (r *flushHistoryRecorder) wrap(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) (metrics.SerieSink, metrics.SketchesSink) {
+	return &flushHistorySerieSink{SerieSink: seriesSink, recorder: r},
+		&flushHistorySketchesSink{SketchesSink: sketchesSink, recorder: r}
+}
+
+type flushHistorySerieSink struct {
+	metrics.SerieSink
+	recorder *flushHistoryRecorder
+}
+
+// Append records the serie before forwarding it: the serializer owns it afterward.
+func (s *flushHistorySerieSink) Append(serie *metrics.Serie) {
+	s.recorder.batch = append(s.recorder.batch, newFlushedSerie(serie, s.recorder.flushTime))
+	s.SerieSink.Append(serie)
+}
+
+type flushHistorySketchesSink struct {
+	metrics.SketchesSink
+	recorder *flushHistoryRecorder
+}
+
+// Append records the sketch before forwarding it: the serializer owns it afterward.
+func (s *flushHistorySketchesSink) Append(sketch *metrics.SketchSeries) {
+	s.recorder.batch = append(s.recorder.batch, newFlushedSketch(sketch, s.recorder.flushTime))
+	s.SketchesSink.Append(sketch)
+}
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func flushedMetric(name string, tags ...string) FlushedMetric {
+	return FlushedMetric{Name: name, Type: "gauge", Tags: tags}
+}
+
+func TestFlushHistoryQuery(t *testing.T) {
+	h := newFlushHistory(time.Minute, 0)
+	now := time.Now()
+	h.record(now, []FlushedMetric{
+		flushedMetric("my.metric", "env:prod", "service:web"),
+		flushedMetric("my.metric", "env:staging", "service:web"),
+		flushedMetric("other.metric", "env:prod"),
+	})
+	h.record(now.Add(15*time.Second), []FlushedMetric{
+		flushedMetric("my.metric", "env:prod", "service:web"),
+	})
+
+	assert.Len(t, h.query(FlushedMetricsQuery{Name: "my.metric"}), 3)
+	assert.Len(t, h.query(FlushedMetricsQuery{Name: "my.metric", Tags: []string{"env:prod"}}), 2)
+	assert.Len(t, h.query(FlushedMetricsQuery{Name: "my.metric", Tags: []string{"env:staging", "service:web"}}), 1)
+	assert.Empty(t, h.query(FlushedMetricsQuery{Name: "my.metric", Tags: []string{"env:prod", "service:db"}}))
+	assert.Empty(t, h.query(FlushedMetricsQuery{Name: "unknown.metric"}))
+}
+
+func TestFlushHistoryEviction(t *testing.T) {
+	h := newFlushHistory(time.Minute, 0)
+	now := time.Now()
+	h.record(now, []FlushedMetric{flushedMetric("my.metric", "flush:1")})
+	h.record(now.Add(30*time.Second), []FlushedMetric{flushedMetric("my.metric", "flush:2")})
+	h.record(now.Add(75*time.Second), []FlushedMetric{flushedMetric("my.metric", "flush:3")})
+
+	// the first flush is out of the retention
+	flushed := h.query(FlushedMetricsQuery{Name: "my.metric"})
+	require.Len(t, flushed, 2)
+	assert.Equal(t, []string{"flush:2"}, flushed[0].Tags)
+	assert.Equal(t, []string{"flush:3"}, flushed[1].Tags)
+
+	// the oldest flushes are evicted when there are too many series
+	h = newFlushHistory(time.Minute, 3)
+	h.record(now, []FlushedMetric{flushedMetric("my.metric", "flush:1"), flushedMetric("my.metric", "flush:1")})
+	h.record(now, []FlushedMetric{flushedMetric("my.metric", "flush:2"), flushedMetric("my.metric", "flush:2")})
+	flushed = h.query(FlushedMetricsQuery{Name: "my.metric"})
+	require.Len(t, flushed, 2)
+	assert.Equal(t, []string{"flush:2"}, flushed[0].Tags)
+	assert.Equal(t, 2, h.size)
+
+	// the most recent flush is always kept, up to the limit
+	h.record(now, []FlushedMetric{flushedMetric("a"), flushedMetric("b"), flushedMetric("c"), flushedMetric("d")})
+	assert.Len(t, h.batches, 1)
+	assert.Equal(t, 3, h.size)
+}
+
+func TestFlushHistoryRecorder(t *testing.T) {
+	recorder := &flushHistoryRecorder{flushTime: 1700000015}
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	seriesSink, sketchesSink := recorder.wrap(&series, &sketches)
+
+	serie := &metrics.Serie{
+		Name:     "my.metric",
+		Points:   []metrics.Point{{Ts: 1700000000, Value: 12.5}},
+		Tags:     tagset.NewCompositeTags([]string{"env:prod"}, []string{"service:web"}),
+		Host:     "myhost",
+		MType:    metrics.APICountType,
+		Interval: 10,
+	}
+	seriesSink.Append(serie)
+
+	sketch := &quantile.Sketch{}
+	sketch.Insert(quantile.Default(), 1, 2)
+	sketchesSink.Append(&metrics.SketchSeries{
+		Name:     "my.distribution",
+		Tags:     tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Host:     "myhost",
+		Interval: 10,
+		Points:   []metrics.SketchPoint{{Ts: 1700000000, Sketch: sketch}},
+	})
+
+	// the metrics are forwarded to the serializer sinks
+	require.Len(t, series, 1)
+	require.Len(t, sketches, 1)
+
+	require.Len(t, recorder.batch, 2)
+	assert.Equal(t, FlushedMetric{
+		Name:      "my.metric",
+		Type:      "count",
+		Tags:      []string{"env:prod", "service:web"},
+		Host:      "myhost",
+		Interval:  10,
+		Points:    []FlushedPoint{{Ts: 1700000000, Value: 12.5}},
+		FlushTime: 1700000015,
+	}, recorder.batch[0])
+	assert.Equal(t, FlushedMetric{
+		Name:      "my.distribution",
+		Type:      "sketch",
+		Tags:      []string{"env:prod"},
+		Host:      "myhost",
+		Interval:  10,
+		Sketches:  []FlushedSketchPoint{{Ts: 1700000000, Count: 2, Sum: 3, Min: 1, Max: 2, Avg: 1.5}},
+		FlushTime: 1700000015,
+	}, recorder.batch[1])
+}
+
+func TestDemuxFlushHistory(t *testing.T) {
+	noAggWorkerStreamCheckFrequency = 100 * time.Millisecond
+
+	opts := demuxTestOptions()
+	opts.EnableNoAggregationPipeline = true
+	opts.FlushHistoryRetention = time.Minute
+	mockSerializer := &MockSerializerIterableSerie{}
+	mockSerializer.On("AreSeriesEnabled").Return(true)
+	mockSerializer.On("AreSketchesEnabled").Return(true)
+	deps := createDemultiplexerAgentTestDeps(t)
+	demux := initAgentDemultiplexer(deps.Log, NewForwarderTest(deps.Log), deps.OrchestratorFwd, opts, deps.EventPlatform, deps.Compressor, "")
+	demux.statsd.noAggStreamWorker.serializer = mockSerializer
+
+	go demux.run()
+
+	demux.SendSamplesWithoutAggregation(testDemuxSamples(t))
+	time.Sleep(200 * time.Millisecond) // give some time for the automatic flush to trigger
+	demux.Stop(true)
+
+	flushed, err := demux.QueryFlushedMetrics(FlushedMetricsQuery{Name: "second", Tags: []string{"tag:3"}})
+	require.NoError(t, err)
+	require.Len(t, flushed, 1)
+	assert.Equal(t, "rate", flushed[0].Type)
+	assert.Equal(t, []FlushedPoint{{Ts: 1657099125, Value: 2}}, flushed[0].Points)
+
+	demux = initAgentDemultiplexer(deps.Log, NewForwarderTest(deps.Log), deps.OrchestratorFwd, demuxTestOptions(), deps.EventPlatform, deps.Compressor, "")
+	_, err = demux.QueryFlushedMetrics(FlushedMetricsQuery{Name: "second"})
+	assert.ErrorIs(t, err, ErrFlushHistoryDisabled)
+}
@@ -50,6 +50,9 @@ type noAggregationStreamWorker struct {
 	stopChan    chan trigger
 
 	logThrottling util.SimpleThrottler
+
+	// flushHistory retains the streamed series, nil when disabled
+	flushHistory *flushHistory
 }
 
 // noAggWorkerStreamCheckFrequency is the frequency at which the no agg worker
@@ -154,6 +157,7 @@ func (w *noAggregationStreamWorker) run() {
 	for !stopped {
 		start := time.Now()
 		serializedSamples := 0
+		var recorded []FlushedMetric
 
 		metrics.Serialize(
 			w.seriesSink,
@@ -212,6 +216,9 @@ func (w *noAggregationStreamWorker) run() {
 							serie.Host = sample.Host
 							serie.MType = mtype
 							serie.Interval = bucketSize
+							if w.flushHistory != nil {
+								recorded = append(recorded, newFlushedSerie(&serie, start.Unix()))
+							}
 							w.seriesSink.Append(&serie)
 
 							w.taggerBuffer.Reset()
@@ -242,6 +249,10 @@ func (w *noAggregationStreamWorker) run() {
 				// noop: we do not support sketches in the no-agg pipeline.
 			})
 
+		if w.flushHistory != nil {
+			w.flushHistory.record(start, recorded)
+		}
+
 		if stopped {
 			break
 		}
@@ -54,16 +54,20 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostnam
 
 	contextExpireTime := pkgconfigsetup.Datadog().GetInt64("dogstatsd_context_expiry_seconds")
 	counterExpireTime := contextExpireTime + pkgconfigsetup.Datadog().GetInt64("dogstatsd_expiry_seconds")
+	limiter := newContextLimiter(idString,
+		pkgconfigsetup.Datadog().GetInt("dogstatsd_context_limiter.metric_limit"),
+		pkgconfigsetup.Datadog().GetBool("dogstatsd_context_limiter.per_origin"))
 
 	s := &TimeSampler{
 		interval:           interval,
-		contextResolver:    newTimestampContextResolver(cache, idString, contextExpireTime, counterExpireTime),
+		contextResolver:    newTimestampContextResolver(cache, idString, contextExpireTime, counterExpireTime, limiter),
 		metricsByTimestamp: map[int64]metrics.ContextMetrics{},
 		sketchMap:          make(sketchMap),
 		id:                 id,
 		idString:           idString,
 		hostname:           hostname,
 	}
+	s.contextResolver.resolver.histogramOverrides = metrics.NewHistogramOverrides(pkgconfigsetup.Datadog())
 
 	return s
 }
@@ -96,8 +100,12 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 			bucketMetrics = metrics.MakeContextMetrics()
 			s.metricsByTimestamp[bucketStart] = bucketMetrics
 		}
+		var histogramConfig *metrics.HistogramConfig
+		if metricSample.Mtype == metrics.HistogramType || metricSample.Mtype == metrics.HistorateType {
+			histogramConfig = s.contextResolver.resolver.histogramConfig(contextKey)
+		}
 		// Add sample to bucket
-		if err := bucketMetrics.AddSample(contextKey, metricSample, timestamp, s.interval, nil, pkgconfigsetup.Datadog()); err != nil {
+		if err := bucketMetrics.AddSampleWithHistogramConfig(contextKey, metricSample, timestamp, s.interval, nil, pkgconfigsetup.Datadog(), histogramConfig); err != nil {
 			log.Debugf("TimeSampler #%d Ignoring sample '%s' on host '%s' and tags '%s': %s", s.id, metricSample.Name, metricSample.Host, metricSample.Tags, err)
 		}
 	}
@@ -10,6 +10,7 @@ package aggregator
 import (
 	"math"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
 	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
@@ -532,6 +534,34 @@ func TestFlushMissingContext(t *testing.T) {
 	testWithTagsStore(t, testFlushMissingContext)
 }
 
+func testHistogramOverrides(t *testing.T, store *tags.Store) {
+	cfg := pkgconfigmodel.NewConfig("datadog", "DD", strings.NewReplacer(".", "_"))
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "*.latency", "aggregates": []string{"max"}, "percentiles": []string{"0.999"}},
+	})
+
+	sampler := testTimeSampler(store)
+	sampler.contextResolver.resolver.histogramOverrides = metrics.NewHistogramOverrides(cfg)
+
+	for _, name := range []string{"my.latency", "my.size"} {
+		sampler.sample(&metrics.MetricSample{Name: name, Value: 1, Mtype: metrics.HistogramType, SampleRate: 1}, 12345.0)
+	}
+
+	series, _ := flushSerie(sampler, 12360.0)
+	var names []string
+	for _, serie := range series {
+		names = append(names, serie.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"my.latency.max", "my.latency.99_9percentile",
+		"my.size.max", "my.size.median", "my.size.avg", "my.size.count", "my.size.95percentile",
+	}, names)
+}
+
+func TestHistogramOverrides(t *testing.T) {
+	testWithTagsStore(t, testHistogramOverrides)
+}
+
 func benchmarkTimeSampler(b *testing.B, store *tags.Store) {
 	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, "host")
 
@@ -77,7 +77,7 @@ const (
 	// DefaultRuntimePoliciesDir is the default policies directory used by the runtime security module
 	DefaultRuntimePoliciesDir = "/etc/datadog-agent/runtime-security.d"
 
-	// DefaultCompressorKind is the default compressor. Options available are 'zlib' and 'zstd'
+	// DefaultCompressorKind is the default compressor. Options available are 'zlib', 'zstd', 'lz4' and 'snappy'
 	DefaultCompressorKind = "zlib"
 
 	// DefaultZstdCompressionLevel is the default compression level for `zstd`.
@@ -1291,6 +1291,9 @@ func aggregator(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
+	config.BindEnvAndSetDefault("aggregator_flush_history.enabled", false)
+	config.BindEnvAndSetDefault("aggregator_flush_history.retention", 5*time.Minute)
+	config.BindEnvAndSetDefault("aggregator_flush_history.max_series", 100000)
 }
 
 func serverless(config pkgconfigmodel.Setup) {
@@ -1327,6 +1330,7 @@ func forwarder(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("forwarder_outdated_file_in_days", 10)
 	config.BindEnvAndSetDefault("forwarder_flush_to_disk_mem_ratio", 0.5)
 	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0)                // 0 means disabled. This is a BETA feature.
+	config.BindEnvAndSetDefault("forwarder_storage_backend", "file")                     // "file" or "kv"
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
 
@@ -1354,10 +1358,23 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_packet_buffer_size", 32)
 	config.BindEnvAndSetDefault("dogstatsd_packet_buffer_flush_timeout", 100*time.Millisecond)
 	config.BindEnvAndSetDefault("dogstatsd_queue_size", 1024)
+	// When the workers can't keep up with the queue, packets are spilled to disk, up to `max_size` bytes,
+	// in segments of `segment_size` bytes, and drained when the workers recover.
+	config.BindEnvAndSetDefault("dogstatsd_spill.enabled", false)
+	config.BindEnvAndSetDefault("dogstatsd_spill.path", "") // empty means `<run_path>/dogstatsd-spill`
+	config.BindEnvAndSetDefault("dogstatsd_spill.max_size", 256*1024*1024)
+	config.BindEnvAndSetDefault("dogstatsd_spill.segment_size", 4*1024*1024)
 
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
 	config.BindEnvAndSetDefault("dogstatsd_socket", defaultStatsdSocket) // Only enabled on unix systems
 	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "")           // Experimental || Notice: empty means feature disabled
+	// Accept Prometheus/OpenMetrics text payloads over HTTP, on a TCP port and/or on a UDS socket.
+	// Notice: 0 and empty mean feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_port", 0)
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_socket", "")
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_max_body_size", 4*1024*1024)
+	// Control for how long the last value of cumulative OpenMetrics series is kept without being pushed again
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_counter_expiry_seconds", 300)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust_strategy", "max_throughput")
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
@@ -1374,6 +1391,11 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_expiry_seconds", 300)
 	// Control how long we keep dogstatsd contexts in memory.
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 20)
+	// Limit the number of contexts per metric name in each dogstatsd pipeline, contexts exceeding the
+	// limit are folded into a single `__overflow` context. 0 means no limit.
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.metric_limit", 0)
+	// Track the limit per metric name and per origin instead of per metric name only.
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.per_origin", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_optout_enabled", true)
@@ -1431,6 +1453,14 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	config.BindEnv("histogram_overrides")
+	config.ParseEnvAsSlice("histogram_overrides", func(in string) []interface{} {
+		var overrides []interface{}
+		if err := json.Unmarshal([]byte(in), &overrides); err != nil {
+			log.Errorf(`"histogram_overrides" can not be parsed: %v`, err)
+		}
+		return overrides
+	})
 }
 
 func logsagent(config pkgconfigmodel.Setup) {
@@ -1561,6 +1591,12 @@ func logsagent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("logs_config.aggregation_timeout", 1000)
 	// Time in seconds
 	config.BindEnvAndSetDefault("logs_config.file_scan_period", 10.0)
+	// Scan the files to tail when files are created, renamed or removed in the
+	// directories of the file sources, only supported on Linux.
+	config.BindEnvAndSetDefault("logs_config.file_watcher_enabled", false)
+	// Named groups of endpoints the logs can be routed to, by their source or
+	// by routing processing rules, instead of the main endpoints.
+	config.BindEnv("logs_config.destination_groups")
 
 	// Controls how wildcard file log source are prioritized when there are more files
 	// that match wildcard log configurations than the `logs_config.open_files_limit`
@@ -1571,6 +1607,10 @@ func logsagent(config pkgconfigmodel.Setup) {
 	// more disk I/O at the wildcard log paths
 	config.BindEnvAndSetDefault("logs_config.file_wildcard_selection_mode", "by_name")
 
+	// Number of bytes at the beginning of a log file used to identify it independently of its path,
+	// so that its offset is recovered after it is renamed. 0 disables fingerprinting.
+	config.BindEnvAndSetDefault("logs_config.file_fingerprint_size", 1024)
+
 	// Max size in MB an integration logs file can use
 	config.BindEnvAndSetDefault("logs_config.integrations_logs_files_max_size", 10)
 	// Max disk usage in MB all integrations logs files are allowed to use in total
@@ -2360,6 +2400,7 @@ func bindEnvAndSetLogsConfigKeys(config pkgconfigmodel.Setup, prefix string) {
 	config.BindEnv(prefix + "additional_endpoints")
 	config.BindEnvAndSetDefault(prefix+"use_compression", true)
 	config.BindEnvAndSetDefault(prefix+"compression_level", 6) // Default level for the gzip/deflate algorithm
+	config.BindEnvAndSetDefault(prefix+"compression_kind", "gzip")
 	config.BindEnvAndSetDefault(prefix+"batch_wait", DefaultBatchWait)
 	config.BindEnvAndSetDefault(prefix+"connection_reset_interval", 0) // in seconds, 0 means disabled
 	config.BindEnvAndSetDefault(prefix+"logs_no_ssl", false)
@@ -51,14 +51,15 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", "", 0)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 1)
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", "checksum:0123456789abcdef", 1)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
+	suite.Equal("checksum:0123456789abcdef", suite.a.registry[suite.source.Config.Path].Fingerprint)
 }
 
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
@@ -67,15 +68,41 @@ func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
 		Offset:      "42",
 		TailingMode: "end",
+		Fingerprint: "checksum:0123456789abcdef",
 	}
 	suite.NoError(suite.a.flushRegistry())
 	r, err := os.ReadFile(suite.testRegistryPath)
 	suite.NoError(err)
-	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0}}}", string(r))
+	suite.Equal("{\"Version\":3,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0,\"Fingerprint\":\"checksum:0123456789abcdef\"}}}", string(r))
 
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry = suite.a.recoverRegistry()
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
+	suite.Equal("checksum:0123456789abcdef", suite.a.registry[suite.source.Config.Path].Fingerprint)
+}
+
+func (suite *AuditorTestSuite) TestAuditorFindsIdentifierByFingerprint() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry["file:/var/log/app.log"] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		Fingerprint: "checksum:0123456789abcdef",
+	}
+	suite.a.registry["file:/var/log/app.log.1"] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 2, 1, time.UTC),
+		Offset:      "43",
+		Fingerprint: "checksum:0123456789abcdef",
+	}
+	suite.a.registry["file:/var/log/other.log"] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 3, 1, time.UTC),
+		Offset:      "44",
+	}
+
+	suite.Equal("checksum:0123456789abcdef", suite.a.GetFingerprint("file:/var/log/app.log"))
+	suite.Equal("", suite.a.GetFingerprint("file:/var/log/other.log"))
+	suite.Equal("file:/var/log/app.log.1", suite.a.GetIdentifierByFingerprint("checksum:0123456789abcdef"))
+	suite.Equal("", suite.a.GetIdentifierByFingerprint("checksum:fedcba9876543210"))
+	suite.Equal("", suite.a.GetIdentifierByFingerprint(""))
 }
 
 func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForOffset() {
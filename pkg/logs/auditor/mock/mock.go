@@ -10,11 +10,15 @@ package mock
 type Registry struct {
 	offset      string
 	tailingMode string
+	fingerprint string
+	identifiers map[string]string
 }
 
 // NewRegistry returns a new registry.
 func NewRegistry() *Registry {
-	return &Registry{}
+	return &Registry{
+		identifiers: make(map[string]string),
+	}
 }
 
 // GetOffset returns the offset.
@@ -36,3 +40,23 @@ func (r *Registry) GetTailingMode(_ string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetFingerprint returns the fingerprint.
+func (r *Registry) GetFingerprint(_ string) string {
+	return r.fingerprint
+}
+
+// SetFingerprint sets the fingerprint.
+func (r *Registry) SetFingerprint(fingerprint string) {
+	r.fingerprint = fingerprint
+}
+
+// GetIdentifierByFingerprint returns the identifier set for the fingerprint.
+func (r *Registry) GetIdentifierByFingerprint(fingerprint string) string {
+	return r.identifiers[fingerprint]
+}
+
+// SetIdentifierByFingerprint sets the identifier returned for the fingerprint.
+func (r *Registry) SetIdentifierByFingerprint(fingerprint string, identifier string) {
+	r.identifiers[fingerprint] = identifier
+}
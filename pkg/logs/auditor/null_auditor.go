@@ -36,6 +36,12 @@ func (a *NullAuditor) GetOffset(_ string) string { return "" }
 //nolint:revive // TODO(AML) Fix revive linter
 func (a *NullAuditor) GetTailingMode(_ string) string { return "" }
 
+// GetFingerprint returns an empty string.
+func (a *NullAuditor) GetFingerprint(_ string) string { return "" }
+
+// GetIdentifierByFingerprint returns an empty string.
+func (a *NullAuditor) GetIdentifierByFingerprint(_ string) string { return "" }
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
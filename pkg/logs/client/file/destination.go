@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package file implements a destination writing the logs to a local file.
+package file
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Destination writes the messages of the payloads to a file, one JSON
+// document per line. It is only used over HTTP, whose pipeline encodes the
+// messages in JSON. The payloads are acknowledged once written, as they are
+// by the network destinations once sent.
+type Destination struct {
+	path                string
+	file                *rotatingFile
+	destinationsContext *client.DestinationsContext
+
+	// Retry
+	backoff        backoff.Policy
+	nbErrors       int
+	shouldRetry    bool
+	lastRetryError error
+}
+
+// NewDestination returns a new Destination writing to the file of the
+// endpoint. The payloads which cannot be written are retried if shouldRetry is
+// true, and dropped otherwise.
+func NewDestination(endpoint config.Endpoint, destinationsContext *client.DestinationsContext, shouldRetry bool) *Destination {
+	return &Destination{
+		path:                endpoint.Path,
+		file:                newRotatingFile(endpoint.Path, endpoint.MaxFileSize, time.Duration(endpoint.RotationInterval)*time.Second, endpoint.MaxFiles),
+		destinationsContext: destinationsContext,
+		backoff: backoff.NewExpBackoffPolicy(
+			endpoint.BackoffFactor,
+			endpoint.BackoffBase,
+			endpoint.BackoffMax,
+			endpoint.RecoveryInterval,
+			endpoint.RecoveryReset,
+		),
+		shouldRetry: shouldRetry,
+	}
+}
+
+// IsMRF indicates that this destination is a Multi-Region Failover destination.
+func (d *Destination) IsMRF() bool {
+	return false
+}
+
+// Target is the path of the file.
+func (d *Destination) Target() string {
+	return "file://" + d.path
+}
+
+// Start starts reading the input channel
+func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	stop := make(chan struct{})
+	go d.run(input, output, stop, isRetrying)
+	return stop
+}
+
+func (d *Destination) run(input chan *message.Payload, output chan *message.Payload, stopChan chan struct{}, isRetrying chan bool) {
+	for p := range input {
+		d.writeAndRetry(p, output, isRetrying)
+	}
+	if err := d.file.close(); err != nil {
+		log.Warnf("Could not close %s: %v", d.path, err)
+	}
+	d.updateRetryState(nil, isRetrying)
+	stopChan <- struct{}{}
+}
+
+// writeAndRetry writes a payload, and retries with a backoff until it is
+// written or the destinations are stopped.
+func (d *Destination) writeAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	for {
+		if backoffDuration := d.backoff.GetBackoffDuration(d.nbErrors); backoffDuration > 0 {
+			log.Warnf("%s: sleeping %s before retrying due to %d errors", d.Target(), backoffDuration, d.nbErrors)
+			if d.waitForBackoff(backoffDuration) == context.Canceled {
+				d.updateRetryState(nil, isRetrying)
+				return
+			}
+			metrics.RetryCount.Add(1)
+			metrics.TlmRetryCount.Add(1)
+		}
+
+		err := d.write(payload)
+		if err != nil {
+			metrics.DestinationErrors.Add(1)
+			metrics.TlmDestinationErrors.Inc()
+			log.Warnf("Could not write payload: %v", err)
+			if d.shouldRetry {
+				d.updateRetryState(err, isRetrying)
+				continue
+			}
+			return
+		}
+		d.updateRetryState(nil, isRetrying)
+
+		metrics.LogsSent.Add(int64(len(payload.Messages)))
+		metrics.TlmLogsSent.Add(float64(len(payload.Messages)))
+		output <- payload
+		return
+	}
+}
+
+func (d *Destination) write(payload *message.Payload) error {
+	var buffer bytes.Buffer
+	for _, msg := range payload.Messages {
+		buffer.Write(msg.GetContent())
+		buffer.WriteByte('\n')
+	}
+	if err := d.file.write(buffer.Bytes()); err != nil {
+		return err
+	}
+	metrics.BytesSent.Add(int64(buffer.Len()))
+	metrics.TlmBytesSent.Add(float64(buffer.Len()))
+	return nil
+}
+
+// waitForBackoff waits for the backoff duration, it returns
+// context.Canceled if the destinations are stopped in the meantime.
+func (d *Destination) waitForBackoff(backoffDuration time.Duration) error {
+	ctx, cancel := context.WithTimeout(d.destinationsContext.Context(), backoffDuration)
+	defer cancel()
+	<-ctx.Done()
+	if ctx.Err() == context.Canceled {
+		return context.Canceled
+	}
+	return nil
+}
+
+func (d *Destination) updateRetryState(err error, isRetrying chan bool) {
+	if err != nil {
+		d.nbErrors = d.backoff.IncError(d.nbErrors)
+		if isRetrying != nil && d.lastRetryError == nil {
+			isRetrying <- true
+		}
+	} else {
+		d.nbErrors = d.backoff.DecError(d.nbErrors)
+		if isRetrying != nil && d.lastRetryError != nil {
+			isRetrying <- false
+		}
+	}
+	d.lastRetryError = err
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newFileEndpoint(path string) config.Endpoint {
+	return config.Endpoint{
+		Format:        config.FileFormat,
+		Path:          path,
+		MaxFileSize:   config.DefaultFileMaxSize,
+		MaxFiles:      config.DefaultFileMaxFiles,
+		BackoffFactor: 1,
+		BackoffBase:   0.01,
+		BackoffMax:    0.01,
+	}
+}
+
+func newPayload(contents ...string) *message.Payload {
+	var messages []*message.Message
+	for _, content := range contents {
+		messages = append(messages, message.NewMessage([]byte(content), nil, message.StatusInfo, 0))
+	}
+	return &message.Payload{Messages: messages}
+}
+
+func TestDestinationWritesPayloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.log")
+	destinationsContext := client.NewDestinationsContext()
+	destinationsContext.Start()
+	defer destinationsContext.Stop()
+
+	destination := NewDestination(newFileEndpoint(path), destinationsContext, true)
+	assert.Equal(t, "file://"+path, destination.Target())
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload, 2)
+	stop := destination.Start(input, output, nil)
+
+	first := newPayload(`{"message":"hello"}`, `{"message":"world"}`)
+	input <- first
+	second := newPayload(`{"message":"good bye"}`)
+	input <- second
+	close(input)
+	<-stop
+
+	// the payloads are acknowledged once written
+	assert.Equal(t, first, <-output)
+	assert.Equal(t, second, <-output)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"message\":\"hello\"}\n{\"message\":\"world\"}\n{\"message\":\"good bye\"}\n", string(content))
+}
+
+func TestDestinationRetries(t *testing.T) {
+	dir := t.TempDir()
+	// the file cannot be created, its directory is a file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs"), nil, 0640))
+	path := filepath.Join(dir, "logs", "archive.log")
+	destinationsContext := client.NewDestinationsContext()
+	destinationsContext.Start()
+
+	destination := NewDestination(newFileEndpoint(path), destinationsContext, true)
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload, 1)
+	isRetrying := make(chan bool, 1)
+	stop := destination.Start(input, output, isRetrying)
+
+	input <- newPayload("hello")
+	assert.True(t, <-isRetrying)
+
+	// the directory can be created, the payload is written
+	require.NoError(t, os.Remove(filepath.Join(dir, "logs")))
+	<-output
+	assert.False(t, <-isRetrying)
+	close(input)
+	<-stop
+	destinationsContext.Stop()
+
+	// the payloads are dropped without retries
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "logs")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs"), nil, 0640))
+	destination = NewDestination(newFileEndpoint(path), destinationsContext, false)
+	input = make(chan *message.Payload)
+	stop = destination.Start(input, output, nil)
+	input <- newPayload("hello")
+	close(input)
+	<-stop
+	assert.Len(t, output, 0)
+}
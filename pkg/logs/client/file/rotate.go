@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotatedSuffixFormat is the layout of the suffix of the rotated files, they
+// are sorted by their suffix from the oldest to the newest.
+const rotatedSuffixFormat = "20060102T150405.000000000"
+
+// rotatingFile appends to a file, which is renamed with the time of its
+// rotation when it would exceed maxSize bytes, or when it has been written for
+// rotationInterval if it is not zero. The rotated files are removed when there
+// are more than maxFiles of them.
+type rotatingFile struct {
+	path             string
+	maxSize          int64
+	rotationInterval time.Duration
+	maxFiles         int
+
+	file      *os.File
+	size      int64
+	openedAt  time.Time
+	now       func() time.Time
+	writeFile func(file *os.File, data []byte) (int, error)
+}
+
+func newRotatingFile(path string, maxSize int64, rotationInterval time.Duration, maxFiles int) *rotatingFile {
+	return &rotatingFile{
+		path:             path,
+		maxSize:          maxSize,
+		rotationInterval: rotationInterval,
+		maxFiles:         maxFiles,
+		now:              time.Now,
+		writeFile:        (*os.File).Write,
+	}
+}
+
+// write appends the data to the file, rotating it first if needed, and
+// flushes it to the disk. If the data cannot be written, the file is truncated
+// back to its previous size so that retrying does not duplicate lines.
+func (f *rotatingFile) write(data []byte) error {
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.shouldRotate(len(data)) {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	_, err := f.writeFile(f.file, data)
+	if err == nil {
+		err = f.file.Sync()
+	}
+	if err != nil {
+		return f.truncate(err)
+	}
+	f.size += int64(len(data))
+	return nil
+}
+
+// truncate removes the data partially written after the last successful
+// write. The file is closed if it cannot be truncated, and its size is read
+// again when it is reopened.
+func (f *rotatingFile) truncate(writeErr error) error {
+	if err := f.file.Truncate(f.size); err != nil {
+		return errors.Join(writeErr, err, f.close())
+	}
+	return writeErr
+}
+
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+// shouldRotate returns true if the file is not empty, and would exceed its
+// maximum size once the data written, or has been written for the rotation
+// interval.
+func (f *rotatingFile) shouldRotate(dataSize int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.maxSize > 0 && f.size+int64(dataSize) > f.maxSize {
+		return true
+	}
+	return f.rotationInterval > 0 && f.now().Sub(f.openedAt) >= f.rotationInterval
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.close(); err != nil {
+		return err
+	}
+	rotatedPath := f.path + "." + f.now().UTC().Format(rotatedSuffixFormat)
+	if err := os.Rename(f.path, rotatedPath); err != nil {
+		return err
+	}
+	f.removeOldFiles()
+	return f.open()
+}
+
+// removeOldFiles removes the oldest rotated files, keeping maxFiles of them.
+// Only the files whose suffix is a rotation time are considered.
+func (f *rotatingFile) removeOldFiles() {
+	if f.maxFiles <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return
+	}
+	prefix := filepath.Base(f.path) + "."
+	var rotated []string
+	for _, entry := range entries {
+		suffix, found := strings.CutPrefix(entry.Name(), prefix)
+		if !found || entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(rotatedSuffixFormat, suffix); err != nil {
+			continue
+		}
+		rotated = append(rotated, filepath.Join(filepath.Dir(f.path), entry.Name()))
+	}
+	if len(rotated) <= f.maxFiles {
+		return
+	}
+	sort.Strings(rotated)
+	for _, path := range rotated[:len(rotated)-f.maxFiles] {
+		os.Remove(path)
+	}
+}
+
+func (f *rotatingFile) close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "archive.log")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newRotatingFile(path, 10, 0, 2)
+	f.now = func() time.Time { return now }
+	defer f.close()
+
+	require.NoError(t, f.write([]byte("hello\n")))
+	require.NoError(t, f.write([]byte("abc\n")))
+	assert.Equal(t, "hello\nabc\n", readFile(t, path))
+
+	// the file would exceed its maximum size
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		require.NoError(t, f.write([]byte("world\n")))
+	}
+	assert.Equal(t, "world\n", readFile(t, path))
+
+	// the oldest rotated file is removed
+	rotated, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{path + ".20240101T000002.000000000", path + ".20240101T000003.000000000"}, rotated)
+	assert.Equal(t, "world\n", readFile(t, rotated[0]))
+
+	// the files which were not rotated are kept
+	require.NoError(t, os.WriteFile(path+".bak", []byte("backup\n"), 0640))
+	require.NoError(t, os.WriteFile(path+".2024", []byte("backup\n"), 0640))
+	now = now.Add(time.Second)
+	require.NoError(t, f.write([]byte("world\n")))
+	now = now.Add(time.Second)
+	require.NoError(t, f.write([]byte("world\n")))
+	rotated, err = filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{path + ".2024", path + ".20240101T000004.000000000", path + ".20240101T000005.000000000", path + ".bak"}, rotated)
+
+	// a line larger than the maximum size is written to an empty file
+	now = now.Add(time.Second)
+	require.NoError(t, f.write([]byte("a long line\n")))
+	assert.Equal(t, "a long line\n", readFile(t, path))
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.log")
+	require.NoError(t, os.WriteFile(path, []byte("before\n"), 0640))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newRotatingFile(path, 1000, time.Hour, 10)
+	f.now = func() time.Time { return now }
+	defer f.close()
+
+	// the existing file is appended
+	require.NoError(t, f.write([]byte("hello\n")))
+	now = now.Add(59 * time.Minute)
+	require.NoError(t, f.write([]byte("world\n")))
+	assert.Equal(t, "before\nhello\nworld\n", readFile(t, path))
+
+	now = now.Add(time.Minute)
+	require.NoError(t, f.write([]byte("good bye\n")))
+	assert.Equal(t, "good bye\n", readFile(t, path))
+	assert.Equal(t, "before\nhello\nworld\n", readFile(t, path+".20240101T010000.000000000"))
+}
+
+func TestRotatingFilePartialWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.log")
+	f := newRotatingFile(path, 1000, 0, 10)
+	defer f.close()
+
+	require.NoError(t, f.write([]byte("hello\n")))
+
+	// half of the data is written before the error
+	f.writeFile = func(file *os.File, data []byte) (int, error) {
+		n, _ := file.Write(data[:len(data)/2])
+		return n, errors.New("disk full")
+	}
+	assert.Error(t, f.write([]byte("world\nagain\n")))
+	assert.Equal(t, "hello\n", readFile(t, path))
+
+	// the retry does not duplicate the lines
+	f.writeFile = (*os.File).Write
+	require.NoError(t, f.write([]byte("world\nagain\n")))
+	assert.Equal(t, "hello\nworld\nagain\n", readFile(t, path))
+	assert.Equal(t, int64(len("hello\nworld\nagain\n")), f.size)
+}
@@ -287,23 +287,30 @@ func (d *Destination) unconditionalSend(payload *message.Payload) (err error) {
 		// this can happen when the method or the url are valid.
 		return err
 	}
-	req.Header.Set("DD-API-KEY", d.endpoint.GetAPIKey())
 	req.Header.Set("Content-Type", d.contentType)
 	req.Header.Set("User-Agent", fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
 
 	if payload.Encoding != "" {
 		req.Header.Set("Content-Encoding", payload.Encoding)
 	}
-	if d.protocol != "" {
-		req.Header.Set("DD-PROTOCOL", string(d.protocol))
+	then := time.Now()
+	if d.endpoint.IsDatadog() {
+		req.Header.Set("DD-API-KEY", d.endpoint.GetAPIKey())
+		if d.protocol != "" {
+			req.Header.Set("DD-PROTOCOL", string(d.protocol))
+		}
+		if d.origin != "" {
+			req.Header.Set("DD-EVP-ORIGIN", string(d.origin))
+			req.Header.Set("DD-EVP-ORIGIN-VERSION", version.AgentVersion)
+		}
+		req.Header.Set("dd-message-timestamp", strconv.FormatInt(getMessageTimestamp(payload.Messages), 10))
+		req.Header.Set("dd-current-timestamp", strconv.FormatInt(then.UnixMilli(), 10))
 	}
-	if d.origin != "" {
-		req.Header.Set("DD-EVP-ORIGIN", string(d.origin))
-		req.Header.Set("DD-EVP-ORIGIN-VERSION", version.AgentVersion)
+	// the API key is not sent to the endpoints which are not Datadog intakes,
+	// they are authenticated by their headers
+	for name, value := range d.endpoint.Headers {
+		req.Header.Set(name, value)
 	}
-	req.Header.Set("dd-message-timestamp", strconv.FormatInt(getMessageTimestamp(payload.Messages), 10))
-	then := time.Now()
-	req.Header.Set("dd-current-timestamp", strconv.FormatInt(then.UnixMilli(), 10))
 
 	req = req.WithContext(ctx)
 	resp, err := d.client.Do(req)
@@ -347,6 +354,8 @@ func (d *Destination) unconditionalSend(payload *message.Payload) (err error) {
 		// the server could not serve the request, most likely because of an
 		// internal error. We should retry these requests.
 		return client.NewRetryableError(errServer)
+	} else if d.endpoint.Format == config.ElasticsearchFormat {
+		return d.checkBulkResponse(response)
 	} else {
 		return nil
 	}
@@ -403,7 +412,9 @@ func buildURL(endpoint config.Endpoint) string {
 		Scheme: scheme,
 		Host:   address,
 	}
-	if endpoint.Version == config.EPIntakeVersion2 && endpoint.TrackType != "" {
+	if !endpoint.IsDatadog() {
+		url.Path = endpoint.Path
+	} else if endpoint.Version == config.EPIntakeVersion2 && endpoint.TrackType != "" {
 		url.Path = fmt.Sprintf("/api/v2/%s", endpoint.TrackType)
 	} else {
 		url.Path = "/v1/input"
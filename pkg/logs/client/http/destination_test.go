@@ -49,6 +49,14 @@ func TestBuildURLShouldReturnAddressForVersion2(t *testing.T) {
 	assert.Equal(t, "http://foo/api/v2/test-track", url)
 }
 
+func TestBuildURLShouldReturnPathForOtherFormats(t *testing.T) {
+	e := config.NewEndpoint("bar", "foo", 9200, true)
+	e.Format = config.ElasticsearchFormat
+	e.Path = "/_bulk"
+	url := buildURL(e)
+	assert.Equal(t, "https://foo:9200/_bulk", url)
+}
+
 //nolint:revive // TODO(AML) Fix revive linter
 func TestDestinationSend200(_ *testing.T) {
 	cfg := getNewConfig()
@@ -210,6 +218,25 @@ func TestDestinationSendsTimestampHeaders(t *testing.T) {
 	assert.GreaterOrEqual(t, ddCurrentTimestamp, currentTimestamp)
 }
 
+func TestDestinationSendsHeadersOfOtherFormats(t *testing.T) {
+	cfg := getNewConfig()
+	server := NewTestServer(200, cfg)
+	defer server.httpServer.Close()
+
+	server.Destination.endpoint.Format = config.LokiFormat
+	server.Destination.endpoint.Headers = map[string]string{"X-Scope-OrgID": "tenant"}
+	server.Destination.protocol = "test-proto"
+	err := server.Destination.unconditionalSend(&message.Payload{Messages: []*message.Message{{
+		IngestionTimestamp: 1234567890_999_999,
+	}}, Encoded: []byte("payload")})
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant", server.request.Header.Get("X-Scope-OrgID"))
+	// the Datadog headers, and the API key, are only sent to Datadog intakes
+	assert.Empty(t, server.request.Header.Values("dd-api-key"))
+	assert.Empty(t, server.request.Header.Values("dd-protocol"))
+	assert.Empty(t, server.request.Header.Values("dd-message-timestamp"))
+}
+
 func TestDestinationSendsUserAgent(t *testing.T) {
 	cfg := getNewConfig()
 	server := NewTestServer(200, cfg)
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	errBulkItems = errors.New("bulk items failed")
+
+	tlmBulkItemsFailed = telemetry.NewCounter("logs_client_http_destination", "bulk_items_failed", []string{"endpoint_host"}, "Logs which could not be indexed by the Elasticsearch bulk API")
+)
+
+// bulkResponse is the part of a response of the Elasticsearch bulk API which
+// reports the items which could not be indexed.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	// Items have a single key, the action of the item
+	Items []map[string]bulkItem `json:"items"`
+}
+
+type bulkItem struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// checkBulkResponse checks the items of a response of the Elasticsearch bulk
+// API, which answers 200 even when some of them could not be indexed. The
+// request is retried when all its items failed with an error worth retrying,
+// since none of them was indexed. Otherwise, retrying it would index the other
+// items twice, so the failed items are dropped, logged and counted.
+func (d *Destination) checkBulkResponse(response []byte) error {
+	var bulk bulkResponse
+	if err := json.Unmarshal(response, &bulk); err != nil {
+		log.Debugf("Could not parse the bulk response of %s: %v", d.host, err)
+		return nil
+	}
+	if !bulk.Errors {
+		return nil
+	}
+
+	failed, retryable := 0, 0
+	var firstError json.RawMessage
+	for _, actions := range bulk.Items {
+		for _, item := range actions {
+			if item.Status < http.StatusBadRequest {
+				continue
+			}
+			failed++
+			if item.Status == http.StatusTooManyRequests || item.Status >= http.StatusInternalServerError {
+				retryable++
+			}
+			if firstError == nil {
+				firstError = item.Error
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	if retryable == len(bulk.Items) {
+		log.Debugf("All the %d items of the bulk request to %s failed, retrying: %s", failed, d.host, firstError)
+		return client.NewRetryableError(errBulkItems)
+	}
+	log.Warnf("Dropping %d logs which could not be indexed by %s: %s", failed, d.host, firstError)
+	tlmBulkItemsFailed.Add(float64(failed), d.host)
+	return nil
+}
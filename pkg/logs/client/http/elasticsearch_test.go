@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+func TestCheckBulkResponse(t *testing.T) {
+	destination := &Destination{host: "elasticsearch.test"}
+	failed := tlmBulkItemsFailed.WithValues(destination.host)
+
+	// all the items were indexed
+	assert.NoError(t, destination.checkBulkResponse([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`)))
+	// not a bulk response
+	assert.NoError(t, destination.checkBulkResponse([]byte(`not json`)))
+	assert.Equal(t, float64(0), failed.Get())
+
+	// some items failed, the others must not be indexed twice
+	err := destination.checkBulkResponse([]byte(`{"errors":true,"items":[
+		{"index":{"status":201}},
+		{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},
+		{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), failed.Get())
+
+	// all the items failed with a retryable error, none was indexed
+	err = destination.checkBulkResponse([]byte(`{"errors":true,"items":[
+		{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},
+		{"create":{"status":503,"error":{"type":"unavailable_shards_exception"}}}]}`))
+	assert.IsType(t, &client.RetryableError{}, err)
+	assert.Equal(t, float64(2), failed.Get())
+}
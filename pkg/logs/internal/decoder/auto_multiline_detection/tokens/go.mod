@@ -0,0 +1,3 @@
+module github.com/DataDog/datadog-agent/pkg/logs/internal/decoder/auto_multiline_detection/tokens
+
+go 1.22.0
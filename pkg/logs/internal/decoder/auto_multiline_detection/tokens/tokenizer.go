@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tokens
+
+import (
+	"bytes"
+	"unicode"
+)
+
+// maxRun is the maximum run of a char or digit before it is capped.
+// Note: This must not exceed D10 or C10.
+const maxRun = 10
+
+// Tokenizer converts a log message (string of bytes) into a list of tokens that represents
+// the underlying structure of the log. A tokenizer instance is not thread safe as buffers
+// are reused to avoid allocations.
+type Tokenizer struct {
+	strBuf *bytes.Buffer
+}
+
+// NewTokenizer returns a new Tokenizer.
+func NewTokenizer() *Tokenizer {
+	return &Tokenizer{
+		strBuf: bytes.NewBuffer(make([]byte, 0, maxRun)),
+	}
+}
+
+// Tokenize converts a byte slice to a list of tokens.
+// This function return the slice of tokens, and a slice of indices where each token starts.
+func (t *Tokenizer) Tokenize(input []byte) ([]Token, []int) {
+	// len(ts) will always be <= len(input)
+	ts := make([]Token, 0, len(input))
+	indicies := make([]int, 0, len(input))
+	if len(input) == 0 {
+		return ts, indicies
+	}
+
+	idx := 0
+	run := 0
+	lastToken := getToken(input[0])
+	t.strBuf.Reset()
+	t.strBuf.WriteRune(unicode.ToUpper(rune(input[0])))
+
+	insertToken := func() {
+		defer func() {
+			run = 0
+			t.strBuf.Reset()
+		}()
+
+		// Only test for special tokens if the last token was a charcater (Special tokens are currently only A-Z).
+		if lastToken == C1 {
+			if t.strBuf.Len() == 1 {
+				if specialToken := getSpecialShortToken(t.strBuf.Bytes()[0]); specialToken != End {
+					ts = append(ts, specialToken)
+					indicies = append(indicies, idx)
+					return
+				}
+			} else if t.strBuf.Len() > 1 { // Only test special long tokens if buffer is > 1 token
+				if specialToken := getSpecialLongToken(t.strBuf.String()); specialToken != End {
+					ts = append(ts, specialToken)
+					indicies = append(indicies, idx-run)
+					return
+				}
+			}
+		}
+
+		// Check for char or digit runs
+		if lastToken == C1 || lastToken == D1 {
+			indicies = append(indicies, idx-run)
+			// Limit max run size
+			if run >= maxRun {
+				run = maxRun - 1
+			}
+			ts = append(ts, lastToken+Token(run))
+		} else {
+			ts = append(ts, lastToken)
+			indicies = append(indicies, idx-run)
+		}
+	}
+
+	for _, char := range input[1:] {
+		currentToken := getToken(char)
+		if currentToken != lastToken {
+			insertToken()
+		} else {
+			run++
+		}
+		if currentToken == C1 {
+			// Store upper case A-Z characters for matching special tokens
+			t.strBuf.WriteRune(unicode.ToUpper(rune(char)))
+		} else {
+			t.strBuf.WriteByte(char)
+		}
+		lastToken = currentToken
+		idx++
+	}
+
+	// Flush any remaining buffered tokens
+	insertToken()
+
+	return ts, indicies
+}
+
+// getToken returns a single token from a single byte.
+func getToken(char byte) Token {
+	if unicode.IsDigit(rune(char)) {
+		return D1
+	} else if unicode.IsSpace(rune(char)) {
+		return Space
+	}
+
+	switch char {
+	case ':':
+		return Colon
+	case ';':
+		return Semicolon
+	case '-':
+		return Dash
+	case '_':
+		return Underscore
+	case '/':
+		return Fslash
+	case '\\':
+		return Bslash
+	case '.':
+		return Period
+	case ',':
+		return Comma
+	case '\'':
+		return Singlequote
+	case '"':
+		return Doublequote
+	case '`':
+		return Backtick
+	case '~':
+		return Tilda
+	case '*':
+		return Star
+	case '+':
+		return Plus
+	case '=':
+		return Equal
+	case '(':
+		return Parenopen
+	case ')':
+		return Parenclose
+	case '{':
+		return Braceopen
+	case '}':
+		return Braceclose
+	case '[':
+		return Bracketopen
+	case ']':
+		return Bracketclose
+	case '&':
+		return Ampersand
+	case '!':
+		return Exclamation
+	case '@':
+		return At
+	case '#':
+		return Pound
+	case '$':
+		return Dollar
+	case '%':
+		return Percent
+	case '^':
+		return Uparrow
+	}
+
+	return C1
+}
+
+func getSpecialShortToken(char byte) Token {
+	switch char {
+	case 'T':
+		return T
+	case 'Z':
+		return Zone
+	}
+	return End
+}
+
+// getSpecialLongToken returns a special token that is > 1 character.
+// NOTE: This set of tokens is non-exhaustive and can be expanded.
+func getSpecialLongToken(input string) Token {
+	switch input {
+	case "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL",
+		"AUG", "SEP", "OCT", "NOV", "DEC":
+		return Month
+	case "MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN":
+		return Day
+	case "AM", "PM":
+		return Apm
+	case "UTC", "GMT", "EST", "EDT", "CST", "CDT",
+		"MST", "MDT", "PST", "PDT", "JST", "KST",
+		"IST", "MSK", "CEST", "CET", "BST", "NZST",
+		"NZDT", "ACST", "ACDT", "AEST", "AEDT",
+		"AWST", "AWDT", "AKST", "AKDT", "HST",
+		"HDT", "CHST", "CHDT", "NST", "NDT":
+		return Zone
+	}
+
+	return End
+}
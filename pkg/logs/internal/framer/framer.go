@@ -42,6 +42,10 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog messages, either octet-counted or newline-terminated as described
+	// in RFC 6587.  The framing is detected for each message.
+	Syslog
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -61,6 +65,10 @@ type Framer struct {
 	// has already been output as a frame.
 	bytesFramed int
 
+	// bytesDiscarded is the number of bytes discarded by the matcher since
+	// the last frame, they are counted in the raw data length of the next one.
+	bytesDiscarded int
+
 	// The number of raw frames decoded from the input before they are processed.
 	frames *atomic.Int64
 
@@ -101,6 +109,8 @@ func NewFramer(
 		matcher = &dockerStreamMatcher{contentLenLimit}
 	case NoFraming:
 		matcher = &noFramingMatcher{}
+	case Syslog:
+		matcher = &syslogMatcher{contentLenLimit: contentLenLimit}
 	default:
 		panic(fmt.Sprintf("unknown framing %d", framing))
 	}
@@ -158,6 +168,13 @@ func (fr *Framer) Process(input *message.Message) {
 		buf := fr.buffer.Bytes()[framed:]
 
 		content, rawDataLen := fr.matcher.FindFrame(buf, seen-framed)
+		if content == nil && rawDataLen > 0 {
+			// the matcher discarded these bytes
+			fr.bytesDiscarded += rawDataLen
+			framed += rawDataLen
+			seen = framed
+			continue
+		}
 		if content == nil {
 			// if the matcher was asked to match more than contentLenLimit,
 			// chop off contentLenLimit raw bytes and output them
@@ -187,7 +204,8 @@ func (fr *Framer) Process(input *message.Message) {
 		}
 		c.SetContent(owned)
 
-		fr.outputFn(c, rawDataLen)
+		fr.outputFn(c, fr.bytesDiscarded+rawDataLen)
+		fr.bytesDiscarded = 0
 		fr.frames.Inc()
 		framed += rawDataLen
 		seen = framed
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// maxSyslogLengthDigits is the maximum number of digits of the length of an
+// octet-counted syslog message.
+const maxSyslogLengthDigits = 10
+
+// syslogMatcher implements FrameMatcher for syslog streams as described in
+// RFC 6587.  A frame starting with a non-zero digit is octet-counted:
+// `MSG-LEN SP SYSLOG-MSG`, any other frame is terminated by a newline.
+type syslogMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	contentLenLimit int
+
+	// skip is the number of bytes of an octet-counted message longer than
+	// contentLenLimit remaining to be discarded.
+	skip int
+}
+
+// FindFrame implements FrameMatcher#FindFrame.
+func (m *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if m.skip > 0 {
+		// the rest of a truncated message is discarded without producing a frame
+		n := min(m.skip, len(buf))
+		m.skip -= n
+		return nil, n
+	}
+
+	if len(buf) > 0 && buf[0] >= '1' && buf[0] <= '9' {
+		header := buf[:min(len(buf), maxSyslogLengthDigits+1)]
+		sp := bytes.IndexByte(header, ' ')
+		switch {
+		case sp > 0 && isDigits(buf[:sp]):
+			length, err := strconv.Atoi(string(buf[:sp]))
+			if err != nil {
+				break
+			}
+			start := sp + 1
+			// the framer does not buffer more than contentLenLimit bytes: the
+			// content of a longer message, header included, is truncated once
+			// the limit is received, and the rest of the message is discarded
+			if end := max(m.contentLenLimit, start); start+length > end {
+				if len(buf) < end {
+					return nil, 0
+				}
+				m.skip = start + length - end
+				return buf[start:end], end
+			}
+			if len(buf) < start+length {
+				// wait for the rest of the message
+				return nil, 0
+			}
+			return buf[start : start+length], start + length
+		case sp < 0 && len(header) <= maxSyslogLengthDigits && isDigits(header):
+			// wait for the rest of the length
+			return nil, 0
+		}
+	}
+
+	nl := bytes.IndexByte(buf[seen:], '\n')
+	if nl == -1 {
+		return nil, 0
+	}
+	eol := nl + seen
+	if eol > m.contentLenLimit {
+		return buf[:m.contentLenLimit], m.contentLenLimit
+	}
+	return buf[:eol], eol + 1
+}
+
+func isDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
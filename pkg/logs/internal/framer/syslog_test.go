@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func syslogFrames(limit int, chunks ...string) ([]string, []int) {
+	gotContent := []string{}
+	gotLens := []int{}
+	outputFn := func(msg *message.Message, rawDataLen int) {
+		gotContent = append(gotContent, string(msg.GetContent()))
+		gotLens = append(gotLens, rawDataLen)
+	}
+	fr := NewFramer(outputFn, Syslog, limit)
+	for _, chunk := range chunks {
+		fr.Process(message.NewMessage([]byte(chunk), nil, "", 0))
+	}
+	return gotContent, gotLens
+}
+
+func TestSyslogNewlineFraming(t *testing.T) {
+	content, lens := syslogFrames(256000, "<34>1 - host app - - - hello\n<13>Oct 11 22:14:15 host su: bye\n")
+	assert.Equal(t, []string{"<34>1 - host app - - - hello", "<13>Oct 11 22:14:15 host su: bye"}, content)
+	assert.Equal(t, []int{29, 33}, lens)
+}
+
+func TestSyslogOctetCountedFraming(t *testing.T) {
+	// the messages may contain newlines, and are received in several chunks
+	content, lens := syslogFrames(256000, "21 <34>1 - - - - - - a\nb", "5 <34>1", "33 <13>Oct 11 22:14:15 h")
+	assert.Equal(t, []string{"<34>1 - - - - - - a\nb", "<34>1"}, content)
+	assert.Equal(t, []int{24, 7}, lens)
+
+	// the length itself is split across chunks
+	content, lens = syslogFrames(256000, "2", "1 <34>1 - - - - - - a\nb1", "0 <34>1 - -\n")
+	assert.Equal(t, []string{"<34>1 - - - - - - a\nb", "<34>1 - -\n"}, content)
+	assert.Equal(t, []int{24, 13}, lens)
+}
+
+func TestSyslogMixedFraming(t *testing.T) {
+	content, _ := syslogFrames(256000, "5 hello<13>world\n3 bye")
+	assert.Equal(t, []string{"hello", "<13>world", "bye"}, content)
+
+	// a newline-terminated message starting with digits
+	content, _ = syslogFrames(256000, "12345678901234 hello\n")
+	assert.Equal(t, []string{"12345678901234 hello"}, content)
+}
+
+func TestSyslogOctetCountedFramingOverLimit(t *testing.T) {
+	// the message is truncated to the limit, header included, once it is
+	// received, and the rest of it is discarded without producing frames
+	content, lens := syslogFrames(10, "12 0123", "456789", "ab3 xyz")
+	assert.Equal(t, []string{"0123456", "xyz"}, content)
+	assert.Equal(t, []int{10, 10}, lens)
+
+	// a message shorter than the limit, but not with its header
+	content, lens = syslogFrames(10, "9 abcd", "efghi4 ab", "cd")
+	assert.Equal(t, []string{"abcdefgh", "abcd"}, content)
+	assert.Equal(t, []int{10, 7}, lens)
+
+	// a message much longer than the limit, received in many reads
+	chunks := []string{"100 "}
+	for i := 0; i < 10; i++ {
+		chunks = append(chunks, "0123456789")
+	}
+	chunks = append(chunks, "5 hello")
+	content, lens = syslogFrames(10, chunks...)
+	assert.Equal(t, []string{"012345", "hello"}, content)
+	assert.Equal(t, []int{10, 101}, lens)
+}
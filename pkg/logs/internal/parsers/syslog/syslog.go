@@ -0,0 +1,338 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a parser for syslog messages, in the RFC 5424 or
+// in the RFC 3164 format.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// nilValue is the value of the fields of an RFC 5424 message which are not set.
+const nilValue = "-"
+
+// utf8BOM may start the MSG part of an RFC 5424 message.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// severityStatusMapping represents the 1:1 mapping between syslog severities and statuses.
+var severityStatusMapping = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// New creates a new parser that parses syslog messages.
+//
+// The content of a parsed message is its MSG part, and its header is stored
+// in the `syslog` attribute:
+//
+//	<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [ex@32473 a="1"] hello
+//
+// becomes
+//
+//	{
+//	  "message": "hello",
+//	  "syslog": {
+//	    "facility": 20, "severity": 5, "version": 1,
+//	    "timestamp": "2003-10-11T22:14:15.003Z", "hostname": "host",
+//	    "appname": "app", "procid": "1234", "msgid": "ID47",
+//	    "structured_data": {"ex@32473": {"a": "1"}}
+//	  }
+//	}
+//
+// and its status is its severity. When processRawMessage is true, the parsed
+// message is rendered in JSON before being processed, otherwise only its MSG
+// part is processed.
+func New(processRawMessage bool) parsers.Parser {
+	return &syslogFormat{processRawMessage: processRawMessage}
+}
+
+type syslogFormat struct {
+	processRawMessage bool
+}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg *message.Message) (*message.Message, error) {
+	parsed, err := parse(msg.GetContent())
+	if err != nil {
+		return msg, err
+	}
+
+	content := message.BasicStructuredContent{
+		Data: map[string]interface{}{
+			"syslog": parsed.attributes(),
+		},
+	}
+	content.SetContent(parsed.msg)
+	status := severityStatusMapping[parsed.severity]
+
+	if p.processRawMessage {
+		rendered, err := content.Render()
+		if err != nil {
+			return msg, err
+		}
+		msg.SetContent(rendered)
+		msg.Status = status
+		return msg, nil
+	}
+
+	structured := message.NewStructuredMessage(&content, msg.Origin, status, msg.IngestionTimestamp)
+	structured.ParsingExtra = msg.ParsingExtra
+	return structured, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// syslogMessage is a parsed syslog message, an empty field is not set.
+type syslogMessage struct {
+	facility       int
+	severity       int
+	version        int
+	timestamp      string
+	hostname       string
+	appname        string
+	procid         string
+	msgid          string
+	structuredData map[string]map[string]string
+	msg            []byte
+}
+
+// attributes returns the header of the message.
+func (m *syslogMessage) attributes() map[string]interface{} {
+	attributes := map[string]interface{}{
+		"facility": m.facility,
+		"severity": m.severity,
+	}
+	if m.version > 0 {
+		attributes["version"] = m.version
+	}
+	for key, value := range map[string]string{
+		"timestamp": m.timestamp,
+		"hostname":  m.hostname,
+		"appname":   m.appname,
+		"procid":    m.procid,
+		"msgid":     m.msgid,
+	} {
+		if value != "" {
+			attributes[key] = value
+		}
+	}
+	if len(m.structuredData) > 0 {
+		attributes["structured_data"] = m.structuredData
+	}
+	return attributes
+}
+
+// parse parses a syslog message in the RFC 5424 format, or in the RFC 3164
+// format when it has no version.
+func parse(b []byte) (*syslogMessage, error) {
+	// octet-counted messages may still be terminated by a newline
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	priority, rest, err := parsePriority(b)
+	if err != nil {
+		return nil, err
+	}
+	m := &syslogMessage{
+		facility: priority / 8,
+		severity: priority % 8,
+	}
+	if version, after, ok := parseVersion(rest); ok {
+		m.version = version
+		err = parseRFC5424(m, after)
+	} else {
+		parseRFC3164(m, rest)
+	}
+	return m, err
+}
+
+// parsePriority parses the `<PRI>` part of a message.
+func parsePriority(b []byte) (int, []byte, error) {
+	if len(b) < 3 || b[0] != '<' {
+		return 0, nil, errors.New("cannot parse the syslog message: missing priority")
+	}
+	priority := 0
+	i := 1
+	for ; i < len(b) && i <= 4 && b[i] >= '0' && b[i] <= '9'; i++ {
+		priority = priority*10 + int(b[i]-'0')
+	}
+	if i == 1 || i > 4 || i >= len(b) || b[i] != '>' || priority > 191 {
+		return 0, nil, errors.New("cannot parse the syslog message: invalid priority")
+	}
+	return priority, b[i+1:], nil
+}
+
+// parseVersion parses the `VERSION SP` part of an RFC 5424 message.
+func parseVersion(b []byte) (int, []byte, bool) {
+	version := 0
+	i := 0
+	for ; i < len(b) && i < 3 && b[i] >= '0' && b[i] <= '9'; i++ {
+		version = version*10 + int(b[i]-'0')
+	}
+	if i == 0 || version == 0 || i >= len(b) || b[i] != ' ' {
+		return 0, b, false
+	}
+	return version, b[i+1:], true
+}
+
+// parseRFC5424 parses `TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]`.
+func parseRFC5424(m *syslogMessage, b []byte) error {
+	fields := []*string{&m.timestamp, &m.hostname, &m.appname, &m.procid, &m.msgid}
+	for _, field := range fields {
+		var token []byte
+		token, b = nextToken(b)
+		if token == nil {
+			return errors.New("cannot parse the syslog message: missing header fields")
+		}
+		if string(token) != nilValue {
+			*field = string(token)
+		}
+	}
+
+	structuredData, rest, err := parseStructuredData(b)
+	if err != nil {
+		return err
+	}
+	m.structuredData = structuredData
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return errors.New("cannot parse the syslog message: invalid structured data")
+		}
+		rest = rest[1:]
+	}
+	m.msg = bytes.TrimPrefix(rest, utf8BOM)
+	return nil
+}
+
+// parseStructuredData parses the `STRUCTURED-DATA` part of an RFC 5424
+// message: either `-` or a sequence of `[SD-ID *(SP PARAM-NAME="PARAM-VALUE")]`.
+func parseStructuredData(b []byte) (map[string]map[string]string, []byte, error) {
+	if len(b) > 0 && b[0] == '-' {
+		return nil, b[1:], nil
+	}
+	if len(b) == 0 || b[0] != '[' {
+		return nil, nil, errors.New("cannot parse the syslog message: missing structured data")
+	}
+
+	structuredData := make(map[string]map[string]string)
+	for len(b) > 0 && b[0] == '[' {
+		end := bytes.IndexAny(b, " ]")
+		if end <= 1 {
+			return nil, nil, errors.New("cannot parse the syslog message: invalid structured data element")
+		}
+		params := make(map[string]string)
+		structuredData[string(b[1:end])] = params
+		b = b[end:]
+
+		for len(b) > 0 && b[0] == ' ' {
+			eq := bytes.IndexByte(b, '=')
+			if eq <= 1 || eq+1 >= len(b) || b[eq+1] != '"' {
+				return nil, nil, errors.New("cannot parse the syslog message: invalid structured data parameter")
+			}
+			name := string(b[1:eq])
+			value, rest, err := parseParamValue(b[eq+2:])
+			if err != nil {
+				return nil, nil, err
+			}
+			params[name] = value
+			b = rest
+		}
+		if len(b) == 0 || b[0] != ']' {
+			return nil, nil, errors.New("cannot parse the syslog message: unterminated structured data element")
+		}
+		b = b[1:]
+	}
+	return structuredData, b, nil
+}
+
+// parseParamValue parses a PARAM-VALUE up to its closing quote, in which `"`,
+// `\` and `]` are escaped with a `\`.
+func parseParamValue(b []byte) (string, []byte, error) {
+	var value []byte
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			if i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']') {
+				i++
+			}
+			value = append(value, b[i])
+		case '"':
+			return string(value), b[i+1:], nil
+		default:
+			value = append(value, b[i])
+		}
+	}
+	return "", nil, errors.New("cannot parse the syslog message: unterminated structured data parameter value")
+}
+
+// parseRFC3164 parses `TIMESTAMP SP HOSTNAME SP TAG MSG` leniently, as many
+// senders do not follow the RFC: the fields which cannot be found are left
+// unset, and the remainder of the message is its MSG part.
+func parseRFC3164(m *syslogMessage, b []byte) {
+	if len(b) > len(time.Stamp) && b[len(time.Stamp)] == ' ' {
+		if _, err := time.Parse(time.Stamp, string(b[:len(time.Stamp)])); err == nil {
+			m.timestamp = string(b[:len(time.Stamp)])
+			b = b[len(time.Stamp)+1:]
+		}
+	}
+	if m.timestamp == "" {
+		// some senders use an RFC 3339 timestamp instead
+		if token, rest := nextToken(b); token != nil {
+			if _, err := time.Parse(time.RFC3339, string(token)); err == nil {
+				m.timestamp = string(token)
+				b = rest
+			}
+		}
+	}
+	if m.timestamp == "" {
+		m.msg = b
+		return
+	}
+
+	// the hostname is missing when the first token is the tag
+	if token, rest := nextToken(b); token != nil && !isTag(token) {
+		m.hostname = string(token)
+		b = rest
+	}
+
+	if token, rest := nextToken(b); token != nil && isTag(token) {
+		tag := bytes.TrimSuffix(token, []byte(":"))
+		if open := bytes.IndexByte(tag, '['); open >= 0 && tag[len(tag)-1] == ']' {
+			m.procid = string(tag[open+1 : len(tag)-1])
+			tag = tag[:open]
+		}
+		m.appname = string(tag)
+		b = rest
+	}
+	m.msg = b
+}
+
+// isTag returns true if the token is a `TAG:` or a `TAG[PID]:`.
+func isTag(token []byte) bool {
+	return len(token) > 1 && token[len(token)-1] == ':'
+}
+
+// nextToken returns the bytes up to the next space, and the bytes after it.
+// It returns a nil token if there is no space.
+func nextToken(b []byte) ([]byte, []byte) {
+	sp := bytes.IndexByte(b, ' ')
+	if sp <= 0 {
+		return nil, b
+	}
+	return b[:sp], b[sp+1:]
+}
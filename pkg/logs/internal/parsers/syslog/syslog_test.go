@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	m, err := parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] ` + "\xEF\xBB\xBF" + `An application event log entry`))
+	require.NoError(t, err)
+	assert.Equal(t, 20, m.facility)
+	assert.Equal(t, 5, m.severity)
+	assert.Equal(t, 1, m.version)
+	assert.Equal(t, "2003-10-11T22:14:15.003Z", m.timestamp)
+	assert.Equal(t, "mymachine.example.com", m.hostname)
+	assert.Equal(t, "evntslog", m.appname)
+	assert.Equal(t, "", m.procid)
+	assert.Equal(t, "ID47", m.msgid)
+	assert.Equal(t, map[string]map[string]string{
+		"exampleSDID@32473":     {"iut": "3", "eventSource": "Application", "eventID": "1011"},
+		"examplePriority@32473": {"class": "high"},
+	}, m.structuredData)
+	assert.Equal(t, "An application event log entry", string(m.msg))
+}
+
+func TestParseRFC5424WithoutStructuredDataNorMessage(t *testing.T) {
+	m, err := parse([]byte("<34>1 - - su 1234 - -\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, m.facility)
+	assert.Equal(t, 2, m.severity)
+	assert.Equal(t, "", m.timestamp)
+	assert.Equal(t, "", m.hostname)
+	assert.Equal(t, "su", m.appname)
+	assert.Equal(t, "1234", m.procid)
+	assert.Nil(t, m.structuredData)
+	assert.Equal(t, "", string(m.msg))
+}
+
+func TestParseRFC5424EscapedStructuredData(t *testing.T) {
+	m, err := parse([]byte(`<13>1 - host app - - [id@1 a="say \"hi\\" b="[x\]"] msg`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"id@1": {"a": `say "hi\`, "b": "[x]"}}, m.structuredData)
+	assert.Equal(t, "msg", string(m.msg))
+}
+
+func TestParseRFC3164(t *testing.T) {
+	m, err := parse([]byte("<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, m.facility)
+	assert.Equal(t, 2, m.severity)
+	assert.Equal(t, 0, m.version)
+	assert.Equal(t, "Oct 11 22:14:15", m.timestamp)
+	assert.Equal(t, "mymachine", m.hostname)
+	assert.Equal(t, "su", m.appname)
+	assert.Equal(t, "230", m.procid)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(m.msg))
+
+	// without hostname, with a space-padded day
+	m, err = parse([]byte("<13>Feb  5 17:32:18 sshd: Accepted publickey"))
+	require.NoError(t, err)
+	assert.Equal(t, "Feb  5 17:32:18", m.timestamp)
+	assert.Equal(t, "", m.hostname)
+	assert.Equal(t, "sshd", m.appname)
+	assert.Equal(t, "Accepted publickey", string(m.msg))
+
+	// with an RFC 3339 timestamp
+	m, err = parse([]byte("<13>2024-02-05T17:32:18+01:00 host app: hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02-05T17:32:18+01:00", m.timestamp)
+	assert.Equal(t, "host", m.hostname)
+	assert.Equal(t, "app", m.appname)
+	assert.Equal(t, "hello", string(m.msg))
+
+	// without timestamp, the whole message is kept
+	m, err = parse([]byte("<13>hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, "", m.hostname)
+	assert.Equal(t, "hello world", string(m.msg))
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"hello",
+		"<>1 - - - - - -",
+		"<192>1 - - - - - -",
+		"<1234>hello",
+		"<13>1 - - -",
+		"<13>1 - - - - - [unterminated",
+		"<13>1 - - - - - [id a=\"1\"]msg",
+		"<13>1 - - - - - nosd",
+	} {
+		_, err := parse([]byte(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestSyslogParser(t *testing.T) {
+	input := message.NewMessage([]byte(`<11>1 - host app 42 - [meta@1 k="v"] something failed`), nil, "", 12)
+	msg, err := New(false).Parse(input)
+	require.NoError(t, err)
+	assert.Equal(t, message.StateStructured, msg.State)
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, int64(12), msg.IngestionTimestamp)
+	assert.Equal(t, "something failed", string(msg.GetContent()))
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(rendered, &data))
+	assert.Equal(t, map[string]interface{}{
+		"message": "something failed",
+		"syslog": map[string]interface{}{
+			"facility":        1.0,
+			"severity":        3.0,
+			"version":         1.0,
+			"hostname":        "host",
+			"appname":         "app",
+			"procid":          "42",
+			"structured_data": map[string]interface{}{"meta@1": map[string]interface{}{"k": "v"}},
+		},
+	}, data)
+}
+
+func TestSyslogParserProcessRawMessage(t *testing.T) {
+	input := message.NewMessage([]byte("<14>Oct 11 22:14:15 host app: hello"), nil, "", 0)
+	msg, err := New(true).Parse(input)
+	require.NoError(t, err)
+	assert.Equal(t, message.StateUnstructured, msg.State)
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.JSONEq(t, `{"message":"hello","syslog":{"facility":1,"severity":6,"timestamp":"Oct 11 22:14:15","hostname":"host","appname":"app"}}`, string(msg.GetContent()))
+}
+
+func TestSyslogParserInvalidMessage(t *testing.T) {
+	input := message.NewMessage([]byte("not syslog"), nil, "", 0)
+	msg, err := New(true).Parse(input)
+	assert.Error(t, err)
+	assert.Equal(t, "not syslog", string(msg.GetContent()))
+}
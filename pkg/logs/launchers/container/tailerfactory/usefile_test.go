@@ -42,6 +42,20 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// GetFingerprint implements auditor.Registry#GetFingerprint.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (r *fakeRegistry) GetFingerprint(identifier string) string {
+	panic("unused")
+}
+
+// GetIdentifierByFingerprint implements auditor.Registry#GetIdentifierByFingerprint.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (r *fakeRegistry) GetIdentifierByFingerprint(fingerprint string) string {
+	panic("unused")
+}
+
 func TestUseFile(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
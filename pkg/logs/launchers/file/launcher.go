@@ -7,7 +7,10 @@
 package file
 
 import (
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -54,10 +57,18 @@ type Launcher struct {
 	scanPeriod             time.Duration
 	flarecontroller        *flareController.FlareController
 	tagger                 tagger.Component
+	// fullyReadFiles are the compressed files which do not need to be tailed
+	// again, indexed by scan key, as long as they are not replaced.
+	fullyReadFiles map[string]os.FileInfo
+	// set to true to apply the files created, renamed or removed in the
+	// directories of the sources as they happen, and not only periodically.
+	// Only supported on Linux, use `logs_config.file_watcher_enabled`.
+	watchFileEvents bool
+	watcher         *fileWatcher
 }
 
 // NewLauncher returns a new launcher.
-func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePodContainerID bool, scanPeriod time.Duration, wildcardMode string, flarecontroller *flareController.FlareController, tagger tagger.Component) *Launcher {
+func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePodContainerID bool, scanPeriod time.Duration, watchFileEvents bool, wildcardMode string, flarecontroller *flareController.FlareController, tagger tagger.Component) *Launcher {
 
 	var wildcardStrategy fileprovider.WildcardSelectionStrategy
 	switch wildcardMode {
@@ -80,8 +91,10 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		done:                   make(chan struct{}),
 		validatePodContainerID: validatePodContainerID,
 		scanPeriod:             scanPeriod,
+		watchFileEvents:        watchFileEvents,
 		flarecontroller:        flarecontroller,
 		tagger:                 tagger,
+		fullyReadFiles:         make(map[string]os.FileInfo),
 	}
 }
 
@@ -91,6 +104,14 @@ func (s *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvid
 	s.addedSources, s.removedSources = sourceProvider.SubscribeForType(config.FileType)
 	s.registry = registry
 	tracker.Add(s.tailers)
+	if s.watchFileEvents {
+		watcher, err := newFileWatcher()
+		if err != nil {
+			log.Warnf("Could not watch the file events, the files to tail are only scanned periodically: %v", err)
+		} else {
+			s.watcher = watcher
+		}
+	}
 	go s.run()
 }
 
@@ -104,8 +125,15 @@ func (s *Launcher) Stop() {
 // run checks periodically if there are new files to tail and the state of its tailers until stop
 func (s *Launcher) run() {
 	scanTicker := time.NewTicker(s.scanPeriod)
+	var fileEvents chan struct{}
+	if s.watcher != nil {
+		fileEvents = s.watcher.C
+	}
 	defer func() {
 		scanTicker.Stop()
+		if s.watcher != nil {
+			s.watcher.close()
+		}
 		close(s.done)
 	}()
 
@@ -119,6 +147,15 @@ func (s *Launcher) run() {
 			s.cleanUpRotatedTailers()
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
+		case <-fileEvents:
+			// files were created, renamed or removed, the periodic scan still
+			// detects the truncations and the events which were not watched
+			s.cleanUpRotatedTailers()
+			if paths, overflowed := s.watcher.events(); overflowed {
+				s.scan()
+			} else {
+				s.applyFileEvents(paths)
+			}
 		case <-s.stop:
 			// no more file should be tailed
 			s.cleanup()
@@ -148,6 +185,9 @@ func (s *Launcher) cleanup() {
 // For instance, when a file is logrotated, its tailer will keep tailing the rotated file.
 // The Scanner needs to stop that previous tailer, and start a new one for the new file.
 func (s *Launcher) scan() {
+	if s.watcher != nil {
+		s.watcher.update(s.activeSources)
+	}
 	files := s.fileProvider.FilesToTail(s.validatePodContainerID, s.activeSources)
 	filesTailed := make(map[string]bool)
 	var allFiles []string
@@ -171,6 +211,9 @@ func (s *Launcher) scan() {
 		scanKey := file.GetScanKey()
 		tailer, isTailed := s.tailers.Get(scanKey)
 		if isTailed && tailer.IsFinished() {
+			if tailer.IsFullyRead() {
+				s.setFullyRead(file)
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
@@ -200,6 +243,7 @@ func (s *Launcher) scan() {
 	}
 
 	s.flarecontroller.SetAllFiles(allFiles)
+	s.cleanUpFullyReadFiles(files)
 
 	for _, tailer := range s.tailers.All() {
 		// stop all tailers which have not been selected
@@ -215,7 +259,7 @@ func (s *Launcher) scan() {
 	for _, file := range files {
 		scanKey := file.GetScanKey()
 		isTailed := s.tailers.Contains(scanKey)
-		if !isTailed && tailersLen < s.tailingLimit {
+		if !isTailed && tailersLen < s.tailingLimit && !s.isFullyRead(file) {
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
 			succeeded := s.startNewTailer(file, config.Beginning)
 			if !succeeded {
@@ -236,6 +280,107 @@ func (s *Launcher) scan() {
 	}
 }
 
+// applyFileEvents starts, restarts or stops the tailers of the paths created,
+// renamed or removed, without scanning all the files to tail.
+func (s *Launcher) applyFileEvents(paths []string) {
+	dirsChanged := false
+	for _, path := range paths {
+		if s.applyFileEvent(path) {
+			continue
+		}
+		// a directory which may contain files of the sources, its files are
+		// applied as if they were created
+		dirsChanged = true
+		for _, source := range s.activeSources {
+			if !sourceDirMatches(source, path) {
+				continue
+			}
+			matches, _ := filepath.Glob(source.Config.Path)
+			for _, match := range matches {
+				if strings.HasPrefix(match, path+string(filepath.Separator)) {
+					s.applyFileEvent(match)
+				}
+			}
+		}
+	}
+	if dirsChanged {
+		s.watcher.update(s.activeSources)
+	}
+}
+
+// applyFileEvent starts a tailer for a file created, restarts the tailer of a
+// file rotated and stops the tailer of a file removed, as a scan would.
+// It returns false if the path is not a file of a source.
+func (s *Launcher) applyFileEvent(path string) bool {
+	_, statErr := os.Stat(path)
+	matched := false
+	for _, source := range s.activeSources {
+		if !sourceFileMatches(source, path) {
+			continue
+		}
+		matched = true
+		file := tailer.NewFile(path, source, config.ContainsWildcard(source.Config.Path))
+		tailer, isTailed := s.tailers.Get(file.GetScanKey())
+		switch {
+		case statErr != nil:
+			if isTailed {
+				s.stopTailer(tailer)
+			}
+		case isTailed:
+			if didRotate, err := tailer.DidRotate(); err != nil {
+				log.Debugf("failed to detect log rotation: %v", err)
+			} else if didRotate {
+				s.restartTailerAfterFileRotation(tailer, file)
+			}
+		case s.tailers.Count() < s.tailingLimit && !fileprovider.ShouldIgnore(s.validatePodContainerID, file) && !s.isFullyRead(file):
+			s.startNewTailer(file, config.Beginning)
+		}
+	}
+	return matched
+}
+
+// sourceFileMatches returns true if the path matches the path of a file source,
+// and none of its excluded paths.
+func sourceFileMatches(source *sources.LogSource, path string) bool {
+	if source.Config.Path == "" {
+		return false
+	}
+	if matched, err := filepath.Match(source.Config.Path, path); err != nil || !matched {
+		return false
+	}
+	for _, excluded := range source.Config.ExcludePaths {
+		if matched, err := filepath.Match(excluded, path); err == nil && matched {
+			return false
+		}
+	}
+	return true
+}
+
+// sourceDirMatches returns true if the path matches a wildcard directory of
+// the path of a file source.
+func sourceDirMatches(source *sources.LogSource, path string) bool {
+	if source.Config.Path == "" {
+		return false
+	}
+	for dir := filepath.Dir(source.Config.Path); hasMeta(dir) && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if matched, err := filepath.Match(dir, path); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMeta returns true if the path contains wildcards.
+func hasMeta(path string) bool {
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
+
 // cleanUpRotatedTailers removes any rotated tailers that have stopped from the list
 func (s *Launcher) cleanUpRotatedTailers() {
 	pendingTailers := []*tailer.Tailer{}
@@ -250,6 +395,9 @@ func (s *Launcher) cleanUpRotatedTailers() {
 // addSource keeps track of the new source and launch new tailers for this source.
 func (s *Launcher) addSource(source *sources.LogSource) {
 	s.activeSources = append(s.activeSources, source)
+	if s.watcher != nil {
+		s.watcher.update(s.activeSources)
+	}
 	s.launchTailers(source)
 }
 
@@ -281,7 +429,7 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 			return
 		}
 
-		if fileprovider.ShouldIgnore(s.validatePodContainerID, file) {
+		if fileprovider.ShouldIgnore(s.validatePodContainerID, file) || s.isFullyRead(file) {
 			continue
 		}
 		if tailer, isTailed := s.tailers.Get(file.GetScanKey()); isTailed {
@@ -311,10 +459,17 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 
 	tailer := s.createTailer(file, s.pipelineProvider.NextPipelineChan())
 
+	if tailer.IsCompressed() && file.Source.Config().UnfinishedCompressedFilesOnly &&
+		registeredOffset(s.registry, tailer.Identifier(), tailer.Fingerprint()) == "" {
+		log.Debugf("Not tailing %s, no content of this compressed file was collected", file.Path)
+		s.setFullyRead(file)
+		return false
+	}
+
 	var offset int64
 	var whence int
 	mode := s.handleTailingModeChange(tailer.Identifier(), m)
-	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
+	offset, whence, err := Position(s.registry, tailer.Identifier(), tailer.Fingerprint(), mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
@@ -352,6 +507,44 @@ func (s *Launcher) handleTailingModeChange(tailerID string, currentTailingMode c
 	return currentTailingMode
 }
 
+// setFullyRead records that a compressed file does not need to be tailed again.
+func (s *Launcher) setFullyRead(file *tailer.File) {
+	if fi, err := os.Stat(file.Path); err == nil {
+		s.fullyReadFiles[file.GetScanKey()] = fi
+	}
+}
+
+// isFullyRead returns true if the file is a compressed file which does not need
+// to be tailed again, and which has not been replaced since.
+func (s *Launcher) isFullyRead(file *tailer.File) bool {
+	previous, found := s.fullyReadFiles[file.GetScanKey()]
+	if !found {
+		return false
+	}
+	fi, err := os.Stat(file.Path)
+	if err != nil || !os.SameFile(previous, fi) || previous.Size() != fi.Size() {
+		delete(s.fullyReadFiles, file.GetScanKey())
+		return false
+	}
+	return true
+}
+
+// cleanUpFullyReadFiles forgets the fully read files which are not tailed anymore.
+func (s *Launcher) cleanUpFullyReadFiles(files []*tailer.File) {
+	if len(s.fullyReadFiles) == 0 {
+		return
+	}
+	scanKeys := make(map[string]bool, len(files))
+	for _, file := range files {
+		scanKeys[file.GetScanKey()] = true
+	}
+	for scanKey := range s.fullyReadFiles {
+		if !scanKeys[scanKey] {
+			delete(s.fullyReadFiles, scanKey)
+		}
+	}
+}
+
 // stopTailer stops the tailer
 func (s *Launcher) stopTailer(tailer *tailer.Tailer) {
 	go tailer.Stop()
@@ -8,6 +8,8 @@
 package file
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -76,7 +78,7 @@ func (suite *LauncherTestSuite) SetupTest() {
 	suite.source = sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Identifier: suite.configID, Path: suite.testPath})
 	sleepDuration := 20 * time.Millisecond
 	fc := flareController.NewFlareController()
-	suite.s = NewLauncher(suite.openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, suite.tagger)
+	suite.s = NewLauncher(suite.openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, suite.tagger)
 	suite.s.pipelineProvider = suite.pipelineProvider
 	suite.s.registry = auditor.NewRegistry()
 	suite.s.activeSources = append(suite.s.activeSources, suite.source)
@@ -237,7 +239,7 @@ func TestLauncherScanStartNewTailer(t *testing.T) {
 		openFilesLimit := 2
 		sleepDuration := 20 * time.Millisecond
 		fc := flareController.NewFlareController()
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		outputChan := launcher.pipelineProvider.NextPipelineChan()
@@ -278,7 +280,7 @@ func TestLauncherWithConcurrentContainerTailer(t *testing.T) {
 	openFilesLimit := 3
 	sleepDuration := 20 * time.Millisecond
 	fc := flareController.NewFlareController()
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 	outputChan := launcher.pipelineProvider.NextPipelineChan()
@@ -328,7 +330,7 @@ func TestLauncherTailFromTheBeginning(t *testing.T) {
 	openFilesLimit := 3
 	sleepDuration := 20 * time.Millisecond
 	fc := flareController.NewFlareController()
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 	outputChan := launcher.pipelineProvider.NextPipelineChan()
@@ -383,7 +385,7 @@ func TestLauncherSetTail(t *testing.T) {
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
 	fc := flareController.NewFlareController()
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 
@@ -409,7 +411,7 @@ func TestLauncherConfigIdentifier(t *testing.T) {
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
 	fc := flareController.NewFlareController()
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 
@@ -448,7 +450,7 @@ func TestLauncherScanWithTooManyFiles(t *testing.T) {
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
 	fc := flareController.NewFlareController()
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
@@ -479,7 +481,7 @@ func TestLauncherUpdatesSourceForExistingTailer(t *testing.T) {
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
 	fc := flareController.NewFlareController()
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 
@@ -595,7 +597,7 @@ func TestLauncherScanRecentFilesWithNewFiles(t *testing.T) {
 	createLauncher := func() *Launcher {
 		sleepDuration := 20 * time.Millisecond
 		fc := flareController.NewFlareController()
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_modification_time", fc, fakeTagger)
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_modification_time", fc, fakeTagger)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		logDirectory := fmt.Sprintf("%s/*.log", testDir)
@@ -658,7 +660,7 @@ func TestLauncherFileRotation(t *testing.T) {
 	createLauncher := func() *Launcher {
 		sleepDuration := 20 * time.Millisecond
 		fc := flareController.NewFlareController()
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		logDirectory := fmt.Sprintf("%s/*.log", testDir)
@@ -725,7 +727,7 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 	createLauncher := func() *Launcher {
 		sleepDuration := 20 * time.Millisecond
 		fc := flareController.NewFlareController()
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		logDirectory := fmt.Sprintf("%s/*.log", testDir)
@@ -755,6 +757,86 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 	assert.True(t, launcher.tailers.Contains(path("b.log")))
 }
 
+func TestLauncherCompressedFiles(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+
+	path := fmt.Sprintf("%s/app.log.1.gz", testDir)
+	writeGzipFile := func(content string) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(content))
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+		assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+	}
+
+	createLauncher := func(unfinishedOnly bool) (*Launcher, chan *message.Message, *auditor.Registry) {
+		sleepDuration := 20 * time.Millisecond
+		fc := flareController.NewFlareController()
+		launcher := NewLauncher(2, sleepDuration, false, 10*time.Second, false, "by_name", fc, fakeTagger)
+		launcher.pipelineProvider = mock.NewMockProvider()
+		registry := auditor.NewRegistry()
+		launcher.registry = registry
+		source := sources.NewLogSource("", &config.LogsConfig{
+			Type:                          config.FileType,
+			Path:                          fmt.Sprintf("%s/*.gz", testDir),
+			UnfinishedCompressedFilesOnly: unfinishedOnly,
+		})
+		launcher.activeSources = append(launcher.activeSources, source)
+		status.Clear()
+		status.InitStatus(pkgconfigsetup.Datadog(), util.CreateSources([]*sources.LogSource{source}))
+		return launcher, launcher.pipelineProvider.NextPipelineChan(), registry
+	}
+	defer status.Clear()
+
+	writeGzipFile("hello\nworld\n")
+	launcher, outputChan, _ := createLauncher(false)
+	defer launcher.cleanup()
+
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	msg := <-outputChan
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	msg = <-outputChan
+	assert.Equal(t, "world", string(msg.GetContent()))
+	tailer, _ := launcher.tailers.Get(path)
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+
+	// the file was fully read, it is not tailed again
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+
+	// the file was replaced
+	writeGzipFile("hello\nworld\ngood bye\n")
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	for _, content := range []string{"hello", "world", "good bye"} {
+		msg = <-outputChan
+		assert.Equal(t, content, string(msg.GetContent()))
+	}
+
+	// only the compressed files whose content was partially collected are tailed
+	launcher, _, registry := createLauncher(true)
+	defer launcher.cleanup()
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Len(t, launcher.fullyReadFiles, 1)
+
+	launcher, outputChan, registry = createLauncher(true)
+	defer launcher.cleanup()
+	registry.SetOffset("6")
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	for _, content := range []string{"world", "good bye"} {
+		msg = <-outputChan
+		assert.Equal(t, content, string(msg.GetContent()))
+	}
+}
+
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
@@ -11,16 +11,21 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/file"
 )
 
 // Position returns the position from where logs should be collected.
-func Position(registry auditor.Registry, identifier string, mode config.TailingMode) (int64, int, error) {
+//
+// When the file is fingerprinted, the offset registered for its identifier is
+// only used if it was registered for the same file, otherwise the offset
+// registered for this file under another identifier, before it was renamed, is
+// used.
+func Position(registry auditor.Registry, identifier string, fingerprint tailer.Fingerprint, mode config.TailingMode) (int64, int, error) {
 	var offset int64
 	var whence int
 	var err error
 
-	value := registry.GetOffset(identifier)
-
+	value := registeredOffset(registry, identifier, fingerprint)
 	switch {
 	case mode == config.ForceBeginning:
 		offset, whence = 0, io.SeekStart
@@ -47,3 +52,30 @@ func Position(registry auditor.Registry, identifier string, mode config.TailingM
 	}
 	return offset, whence, err
 }
+
+// registeredOffset returns the offset registered for the file, or an empty
+// string if there is none.
+func registeredOffset(registry auditor.Registry, identifier string, fingerprint tailer.Fingerprint) string {
+	value := registry.GetOffset(identifier)
+	if !fingerprint.IsEmpty() {
+		if stored := registry.GetFingerprint(identifier); stored != "" && !fingerprint.Matches(stored) {
+			// another file was tailed on this path, its offset does not apply
+			value = ""
+		}
+		if value == "" {
+			value = offsetByFingerprint(registry, fingerprint)
+		}
+	}
+	return value
+}
+
+// offsetByFingerprint returns the offset registered for the fingerprinted file
+// under any identifier, or an empty string if there is none.
+func offsetByFingerprint(registry auditor.Registry, fingerprint tailer.Fingerprint) string {
+	for _, key := range fingerprint.Keys() {
+		if identifier := registry.GetIdentifierByFingerprint(key); identifier != "" {
+			return registry.GetOffset(identifier)
+		}
+	}
+	return ""
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/file"
 )
 
 func TestPosition(t *testing.T) {
@@ -22,49 +23,105 @@ func TestPosition(t *testing.T) {
 	var offset int64
 	var whence int
 
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.End)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.Beginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("123456789")
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.End)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(123456789), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("987654321")
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.Beginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(987654321), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("foo")
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.End)
 	assert.NotNil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 
 	registry.SetOffset("bar")
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.Beginning)
 	assert.NotNil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("123456789")
-	offset, whence, err = Position(registry, "", config.ForceBeginning)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.ForceBeginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("987654321")
-	offset, whence, err = Position(registry, "", config.ForceEnd)
+	offset, whence, err = Position(registry, "", tailer.Fingerprint{}, config.ForceEnd)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 }
+
+func TestPositionWithFingerprint(t *testing.T) {
+	registry := mock.NewRegistry()
+	fingerprint := tailer.Fingerprint{Checksum: "0123456789abcdef", FileID: "1-2"}
+
+	var err error
+	var offset int64
+	var whence int
+
+	// the offset was registered for this file
+	registry.SetOffset("123456789")
+	registry.SetFingerprint("checksum:0123456789abcdef")
+	offset, whence, err = Position(registry, "file:app.log", fingerprint, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(123456789), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the offset was registered while the file was too short to be checksummed
+	registry.SetFingerprint("fileid:1-2")
+	offset, whence, err = Position(registry, "file:app.log", fingerprint, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(123456789), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the offset was registered before fingerprinting was introduced
+	registry.SetFingerprint("")
+	offset, whence, err = Position(registry, "file:app.log", fingerprint, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(123456789), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the offset was registered for another file on the same path
+	registry.SetFingerprint("checksum:fedcba9876543210")
+	offset, whence, err = Position(registry, "file:app.log", fingerprint, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekEnd, whence)
+
+	offset, whence, err = Position(registry, "file:app.log", fingerprint, config.Beginning)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the file was renamed, its offset is registered under its previous path
+	registry.SetIdentifierByFingerprint("checksum:0123456789abcdef", "file:app.log.1")
+	offset, whence, err = Position(registry, "file:app.log", fingerprint, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(123456789), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the tailing mode is forced
+	offset, whence, err = Position(registry, "file:app.log", fingerprint, config.ForceBeginning)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekStart, whence)
+}
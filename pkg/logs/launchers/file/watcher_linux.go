@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// fileEventsDelay is the time the watcher waits after an event before
+// notifying the launcher, so that the events of a rotation, a file renamed and
+// then created again, are applied at once.
+const fileEventsDelay = 500 * time.Millisecond
+
+// fileWatcher watches the directories of the file sources with inotify, and
+// notifies the launcher when files matching the sources are created, renamed or
+// removed, or when the events overflowed.
+type fileWatcher struct {
+	watcher *fsnotify.Watcher
+	// C is signaled when there are events to apply, see events.
+	C chan struct{}
+
+	mu sync.Mutex
+	// filePatterns are the paths of the sources, dirPatterns the patterns of
+	// their directories and of the parents of their wildcard directories.
+	filePatterns map[string]bool
+	dirPatterns  map[string]bool
+	watchedDirs  map[string]bool
+	// paths are the paths created, renamed or removed since the last events,
+	// overflowed is set if some events were lost.
+	paths      map[string]bool
+	overflowed bool
+	pending    bool
+	delay      time.Duration
+	done       chan struct{}
+}
+
+func newFileWatcher() (*fileWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &fileWatcher{
+		watcher:      watcher,
+		C:            make(chan struct{}, 1),
+		filePatterns: make(map[string]bool),
+		dirPatterns:  make(map[string]bool),
+		watchedDirs:  make(map[string]bool),
+		paths:        make(map[string]bool),
+		delay:        fileEventsDelay,
+		done:         make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// update watches the directories of the sources, and stops watching the
+// directories of the sources which were removed.
+func (w *fileWatcher) update(activeSources []*sources.LogSource) {
+	filePatterns := make(map[string]bool)
+	dirPatterns := make(map[string]bool)
+	for _, source := range activeSources {
+		path := source.Config.Path
+		if path == "" {
+			continue
+		}
+		filePatterns[path] = true
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			dirPatterns[dir] = true
+			if !hasMeta(dir) || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+
+	dirs := make(map[string]bool)
+	for pattern := range dirPatterns {
+		if !hasMeta(pattern) {
+			dirs[pattern] = true
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			if fi, err := os.Stat(match); err == nil && fi.IsDir() {
+				dirs[match] = true
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.filePatterns = filePatterns
+	w.dirPatterns = dirPatterns
+	for dir := range w.watchedDirs {
+		if !dirs[dir] {
+			// the directory may have been removed, and is not watched anymore
+			_ = w.watcher.Remove(dir)
+			delete(w.watchedDirs, dir)
+		}
+	}
+	for dir := range dirs {
+		if w.watchedDirs[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			// the directory does not exist yet, or the inotify watches are
+			// exhausted, its files are found by the periodic scan
+			log.Debugf("Could not watch the directory %s: %v", dir, err)
+			continue
+		}
+		w.watchedDirs[dir] = true
+	}
+}
+
+// run reads the events until the watcher is closed.
+func (w *fileWatcher) run() {
+	defer close(w.done)
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 && w.matches(event.Name) {
+				w.notify(event.Name)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// some events were lost, scan all the files
+				log.Debug("File events overflowed, scanning the files to tail")
+				w.notify("")
+			} else {
+				log.Warnf("Error while watching the files to tail: %v", err)
+			}
+		}
+	}
+}
+
+// matches returns true if the path is a file of a source, or a directory which
+// may contain files of a source.
+func (w *fileWatcher) matches(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, patterns := range []map[string]bool{w.filePatterns, w.dirPatterns} {
+		for pattern := range patterns {
+			if matched, err := filepath.Match(pattern, path); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notify records the path of an event, or that the events overflowed if it is
+// empty, and signals C once the delay has elapsed, the events received in the
+// meantime are merged.
+func (w *fileWatcher) notify(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if path == "" {
+		w.overflowed = true
+	} else {
+		w.paths[path] = true
+	}
+	if w.pending {
+		return
+	}
+	w.pending = true
+	time.AfterFunc(w.delay, func() {
+		w.mu.Lock()
+		w.pending = false
+		w.mu.Unlock()
+		select {
+		case w.C <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// events returns the paths created, renamed or removed since the last call,
+// and whether some events were lost since then.
+func (w *fileWatcher) events() ([]string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	paths := make([]string, 0, len(w.paths))
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	overflowed := w.overflowed
+	w.paths = make(map[string]bool)
+	w.overflowed = false
+	return paths, overflowed
+}
+
+// close stops watching the files.
+func (w *fileWatcher) close() {
+	w.watcher.Close()
+	<-w.done
+}
@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/taggerimpl"
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	flareController "github.com/DataDog/datadog-agent/comp/logs/agent/flare"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/util"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/logs/status"
+)
+
+func newTestFileWatcher(t *testing.T, paths ...string) *fileWatcher {
+	w, err := newFileWatcher()
+	require.NoError(t, err)
+	t.Cleanup(w.close)
+	w.delay = 10 * time.Millisecond
+	var activeSources []*sources.LogSource
+	for _, path := range paths {
+		activeSources = append(activeSources, sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path}))
+	}
+	w.update(activeSources)
+	return w
+}
+
+func assertNotified(t *testing.T, w *fileWatcher) {
+	select {
+	case <-w.C:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the watcher was not notified")
+	}
+}
+
+func assertNotNotified(t *testing.T, w *fileWatcher) {
+	select {
+	case <-w.C:
+		assert.Fail(t, "the watcher should not be notified")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestFileWatcherEvents(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestFileWatcher(t, filepath.Join(dir, "*.log"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.txt"), []byte("hello\n"), 0644))
+	assertNotNotified(t, w)
+
+	// a file created
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), []byte("hello\n"), 0644))
+	assertNotified(t, w)
+	paths, overflowed := w.events()
+	assert.Equal(t, []string{filepath.Join(dir, "app.log")}, paths)
+	assert.False(t, overflowed)
+
+	// a file rotated, the events are merged
+	require.NoError(t, os.Rename(filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.1")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), []byte("hello\n"), 0644))
+	assertNotified(t, w)
+	assertNotNotified(t, w)
+	paths, _ = w.events()
+	assert.ElementsMatch(t, []string{filepath.Join(dir, "app.log")}, paths)
+
+	// a file removed
+	require.NoError(t, os.Remove(filepath.Join(dir, "app.log")))
+	assertNotified(t, w)
+	paths, _ = w.events()
+	assert.Equal(t, []string{filepath.Join(dir, "app.log")}, paths)
+}
+
+func TestFileWatcherOverflow(t *testing.T) {
+	w := newTestFileWatcher(t, filepath.Join(t.TempDir(), "*.log"))
+
+	w.notify("")
+	assertNotified(t, w)
+	paths, overflowed := w.events()
+	assert.Empty(t, paths)
+	assert.True(t, overflowed)
+
+	// the events are drained
+	paths, overflowed = w.events()
+	assert.Empty(t, paths)
+	assert.False(t, overflowed)
+}
+
+func TestFileWatcherWildcardDirectories(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestFileWatcher(t, filepath.Join(dir, "*", "*.log"))
+	assert.Equal(t, map[string]bool{dir: true}, w.watchedDirs)
+
+	// a directory created, which may contain files to tail
+	podDir := filepath.Join(dir, "pod")
+	require.NoError(t, os.Mkdir(podDir, 0755))
+	assertNotified(t, w)
+
+	// the directory is watched once the launcher scans the files to tail
+	w.update([]*sources.LogSource{sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: filepath.Join(dir, "*", "*.log")})})
+	assert.Equal(t, map[string]bool{dir: true, podDir: true}, w.watchedDirs)
+	require.NoError(t, os.WriteFile(filepath.Join(podDir, "app.log"), []byte("hello\n"), 0644))
+	assertNotified(t, w)
+
+	// the directories are not watched anymore once the source is removed
+	w.update(nil)
+	assert.Empty(t, w.watchedDirs)
+	require.NoError(t, os.WriteFile(filepath.Join(podDir, "other.log"), []byte("hello\n"), 0644))
+	assertNotNotified(t, w)
+}
+
+func TestLauncherTailsFilesOnEvents(t *testing.T) {
+	dir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+
+	// the periodic scan never happens during the test
+	launcher := NewLauncher(10, 20*time.Millisecond, false, time.Hour, true, "by_name", flareController.NewFlareController(), fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = auditor.NewRegistry()
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: filepath.Join(dir, "*.log")})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.InitStatus(pkgconfigsetup.Datadog(), util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+
+	watcher, err := newFileWatcher()
+	require.NoError(t, err)
+	watcher.delay = 10 * time.Millisecond
+	launcher.watcher = watcher
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	go launcher.run()
+	defer launcher.Stop()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), []byte("hello world\n"), 0644))
+	select {
+	case msg := <-outputChan:
+		assert.Equal(t, "hello world", string(msg.GetContent()))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the file created was not tailed")
+	}
+}
+
+func TestLauncherAppliesFileEvents(t *testing.T) {
+	dir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, time.Hour, true, "by_name", flareController.NewFlareController(), fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = auditor.NewRegistry()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: filepath.Join(dir, "*", "*.log"), ExcludePaths: []string{filepath.Join(dir, "*", "excluded.log")}})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.InitStatus(pkgconfigsetup.Datadog(), util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+
+	watcher, err := newFileWatcher()
+	require.NoError(t, err)
+	defer watcher.close()
+	launcher.watcher = watcher
+
+	// the files of a directory created are tailed and the directory is watched
+	podDir := filepath.Join(dir, "pod")
+	require.NoError(t, os.Mkdir(podDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(podDir, "app.log"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(podDir, "excluded.log"), nil, 0644))
+	launcher.applyFileEvents([]string{podDir})
+	assert.Equal(t, 1, launcher.tailers.Count())
+	assert.True(t, watcher.watchedDirs[podDir])
+
+	// a file created
+	require.NoError(t, os.WriteFile(filepath.Join(podDir, "other.log"), nil, 0644))
+	launcher.applyFileEvents([]string{filepath.Join(podDir, "other.log"), filepath.Join(podDir, "excluded.log")})
+	assert.Equal(t, 2, launcher.tailers.Count())
+
+	// a file removed
+	require.NoError(t, os.Remove(filepath.Join(podDir, "other.log")))
+	launcher.applyFileEvents([]string{filepath.Join(podDir, "other.log")})
+	assert.Equal(t, 1, launcher.tailers.Count())
+	launcher.cleanup()
+}
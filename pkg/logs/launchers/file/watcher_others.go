@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !linux
+
+package file
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// fileWatcher is only implemented on Linux, the files to tail are found by the
+// periodic scan on the other platforms.
+type fileWatcher struct {
+	C chan struct{}
+}
+
+func newFileWatcher() (*fileWatcher, error) {
+	return nil, errors.New("file events are only supported on Linux")
+}
+
+func (w *fileWatcher) update(_ []*sources.LogSource) {}
+
+func (w *fileWatcher) events() ([]string, bool) {
+	return nil, false
+}
+
+func (w *fileWatcher) close() {}
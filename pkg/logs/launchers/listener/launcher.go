@@ -21,6 +21,7 @@ type Launcher struct {
 	frameSize        int
 	tcpSources       chan *sources.LogSource
 	udpSources       chan *sources.LogSource
+	syslogSources    chan *sources.LogSource
 	listeners        []startstop.StartStoppable
 	stop             chan struct{}
 }
@@ -40,6 +41,7 @@ func (l *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvid
 	l.pipelineProvider = pipelineProvider
 	l.tcpSources = sourceProvider.GetAddedForType(config.TCPType)
 	l.udpSources = sourceProvider.GetAddedForType(config.UDPType)
+	l.syslogSources = sourceProvider.GetAddedForType(config.SyslogType)
 	go l.run()
 }
 
@@ -55,12 +57,30 @@ func (l *Launcher) run() {
 			listener := NewUDPListener(l.pipelineProvider, source, l.frameSize)
 			listener.Start()
 			l.listeners = append(l.listeners, listener)
+		case source := <-l.syslogSources:
+			listener := l.newSyslogListener(source)
+			listener.Start()
+			l.listeners = append(l.listeners, listener)
 		case <-l.stop:
 			return
 		}
 	}
 }
 
+// newSyslogListener returns a listener for the protocol of a syslog source.
+func (l *Launcher) newSyslogListener(source *sources.LogSource) startstop.StartStoppable {
+	switch source.Config.SyslogProtocol() {
+	case config.SyslogUDP:
+		return NewUDPListener(l.pipelineProvider, source, l.frameSize)
+	case config.SyslogUnix:
+		return NewUnixListener(l.pipelineProvider, source, l.frameSize)
+	case config.SyslogUnixgram:
+		return NewUnixgramListener(l.pipelineProvider, source, l.frameSize)
+	default:
+		return NewTCPListener(l.pipelineProvider, source, l.frameSize)
+	}
+}
+
 // Stop stops all listeners
 func (l *Launcher) Stop() {
 	l.stop <- struct{}{}
@@ -20,9 +20,13 @@ import (
 )
 
 // A TCPListener listens and accepts TCP connections and delegates the read operations to a tailer.
+// It also listens on stream-oriented Unix domain sockets.
 type TCPListener struct {
 	pipelineProvider pipeline.Provider
 	source           *sources.LogSource
+	network          string
+	address          string
+	name             string
 	idleTimeout      time.Duration
 	frameSize        int
 	listener         net.Listener
@@ -33,6 +37,19 @@ type TCPListener struct {
 
 // NewTCPListener returns an initialized TCPListener
 func NewTCPListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int) *TCPListener {
+	address := fmt.Sprintf(":%d", source.Config.Port)
+	name := fmt.Sprintf("TCP forwarder on port %d", source.Config.Port)
+	return newStreamListener(pipelineProvider, source, frameSize, "tcp", address, name)
+}
+
+// NewUnixListener returns an initialized TCPListener accepting connections on
+// the Unix domain socket at the path of the source.
+func NewUnixListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int) *TCPListener {
+	name := fmt.Sprintf("Unix forwarder on socket %s", source.Config.Path)
+	return newStreamListener(pipelineProvider, source, frameSize, "unix", source.Config.Path, name)
+}
+
+func newStreamListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int, network, address, name string) *TCPListener {
 	var idleTimeout time.Duration
 	if source.Config.IdleTimeout != "" {
 		var err error
@@ -46,6 +63,9 @@ func NewTCPListener(pipelineProvider pipeline.Provider, source *sources.LogSourc
 	return &TCPListener{
 		pipelineProvider: pipelineProvider,
 		source:           source,
+		network:          network,
+		address:          address,
+		name:             name,
 		idleTimeout:      idleTimeout,
 		frameSize:        frameSize,
 		tailers:          []*tailer.Tailer{},
@@ -55,10 +75,10 @@ func NewTCPListener(pipelineProvider pipeline.Provider, source *sources.LogSourc
 
 // Start starts the listener to accepts new incoming connections.
 func (l *TCPListener) Start() {
-	log.Infof("Starting TCP forwarder on port %d, with read buffer size: %d", l.source.Config.Port, l.frameSize)
+	log.Infof("Starting %s, with read buffer size: %d", l.name, l.frameSize)
 	err := l.startListener()
 	if err != nil {
-		log.Errorf("Can't start TCP forwarder on port %d: %v", l.source.Config.Port, err)
+		log.Errorf("Can't start %s: %v", l.name, err)
 		l.source.Status.Error(err)
 		return
 	}
@@ -68,7 +88,7 @@ func (l *TCPListener) Start() {
 
 // Stop stops the listener from accepting new connections and all the activer tailers.
 func (l *TCPListener) Stop() {
-	log.Infof("Stopping TCP forwarder on port %d", l.source.Config.Port)
+	log.Infof("Stopping %s", l.name)
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.stop <- struct{}{}
@@ -100,11 +120,11 @@ func (l *TCPListener) run() {
 				return
 			case err != nil:
 				// an error occurred, restart the listener.
-				log.Warnf("Can't listen on port %d, restarting a listener: %v", l.source.Config.Port, err)
+				log.Warnf("Can't listen for %s, restarting a listener: %v", l.name, err)
 				l.listener.Close()
 				err := l.startListener()
 				if err != nil {
-					log.Errorf("Can't restart listener on port %d: %v", l.source.Config.Port, err)
+					log.Errorf("Can't restart listener for %s: %v", l.name, err)
 					l.source.Status.Error(err)
 					return
 				}
@@ -120,7 +140,12 @@ func (l *TCPListener) run() {
 
 // startListener starts a new listener, returns an error if it failed.
 func (l *TCPListener) startListener() error {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if l.network == "unix" {
+		if err := removeStaleSocket(l.address); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen(l.network, l.address)
 	if err != nil {
 		return err
 	}
@@ -140,6 +165,10 @@ func (l *TCPListener) read(tailer *tailer.Tailer) ([]byte, string, error) {
 		go l.stopTailer(tailer)
 		return nil, "", err
 	}
+	if l.network == "unix" {
+		// clients of a Unix domain socket have no address
+		return frame[:n], "", nil
+	}
 	return frame[:n], tailer.Conn.RemoteAddr().String(), nil
 }
 
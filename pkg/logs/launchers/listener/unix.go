@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/socket"
+)
+
+// A UnixgramListener opens a datagram-oriented Unix domain socket, keeps it
+// alive and delegates the read operations to a tailer. Like the UDPListener,
+// datagrams bigger than its read buffer are truncated.
+type UnixgramListener struct {
+	pipelineProvider pipeline.Provider
+	source           *sources.LogSource
+	frameSize        int
+	tailer           *tailer.Tailer
+	Conn             *net.UnixConn
+}
+
+// NewUnixgramListener returns an initialized UnixgramListener
+func NewUnixgramListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int) *UnixgramListener {
+	return &UnixgramListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		frameSize:        frameSize,
+	}
+}
+
+// Start opens the socket and starts a tailer.
+func (l *UnixgramListener) Start() {
+	log.Infof("Starting Unix datagram forwarder on socket: %s, with read buffer size: %d", l.source.Config.Path, l.frameSize)
+	err := l.startNewTailer()
+	if err != nil {
+		log.Errorf("Can't start Unix datagram forwarder on socket %s: %v", l.source.Config.Path, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+}
+
+// Stop stops the tailer.
+func (l *UnixgramListener) Stop() {
+	if l.tailer != nil {
+		log.Infof("Stopping Unix datagram forwarder on socket: %s", l.source.Config.Path)
+		l.tailer.Stop()
+	}
+}
+
+// startNewTailer starts a new Tailer
+func (l *UnixgramListener) startNewTailer() error {
+	if err := removeStaleSocket(l.source.Config.Path); err != nil {
+		return err
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: l.source.Config.Path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	l.Conn = conn
+	l.tailer = tailer.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.read)
+	l.tailer.Start()
+	return nil
+}
+
+// read reads a datagram from the socket, returns an error if it failed and reset the tailer.
+func (l *UnixgramListener) read(_ *tailer.Tailer) ([]byte, string, error) {
+	frame := make([]byte, l.frameSize+1)
+	n, err := l.Conn.Read(frame)
+	switch {
+	case err != nil && isClosedConnError(err):
+		return nil, "", err
+	case err != nil:
+		go l.resetTailer()
+		return nil, "", err
+	default:
+		// make sure all logs are separated by line feeds, otherwise they don't get properly split downstream
+		if n > l.frameSize {
+			frame[l.frameSize] = '\n'
+		} else if n > 0 && frame[n-1] != '\n' {
+			frame[n] = '\n'
+			n++
+		}
+		return frame[:n], "", nil
+	}
+}
+
+// resetTailer creates a new tailer.
+func (l *UnixgramListener) resetTailer() {
+	log.Infof("Resetting the Unix datagram socket: %s", l.source.Config.Path)
+	l.tailer.Stop()
+	err := l.startNewTailer()
+	if err != nil {
+		log.Errorf("Could not reset the Unix datagram socket %s: %v", l.source.Config.Path, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+}
+
+// removeStaleSocket removes the socket left at path by a previous run, so that
+// it can be listened on again. It refuses to remove anything but a socket.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestUnixShouldReceiveSyslogMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	processRawMessage := false
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.SyslogUnix, Path: path, ProcessRawMessage: &processRawMessage})
+	listener := NewUnixListener(pp, source, 9000)
+	listener.Start()
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprint(conn, "34 <11>1 - host app - - - hello world")
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Empty(t, msg.Tags())
+
+	listener.Stop()
+}
+
+func TestUnixgramShouldReceiveMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	processRawMessage := false
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.SyslogUnixgram, Path: path, ProcessRawMessage: &processRawMessage})
+	listener := NewUnixgramListener(pp, source, 9000)
+	listener.Start()
+
+	conn, err := net.Dial("unixgram", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprint(conn, "<14>Oct 11 22:14:15 host app: hello world")
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+
+	listener.Stop()
+}
+
+func TestUnixgramShouldReplaceStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	pp := mock.NewMockProvider()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.SyslogUnixgram, Path: path})
+
+	listener := NewUnixgramListener(pp, source, 9000)
+	listener.Start()
+	listener.Stop()
+	_, err := os.Stat(path)
+	require.NoError(t, err)
+
+	listener = NewUnixgramListener(pp, source, 9000)
+	listener.Start()
+	assert.NotNil(t, listener.tailer)
+	listener.Stop()
+}
+
+func TestUnixgramShouldNotRemoveRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+	pp := mock.NewMockProvider()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.SyslogUnixgram, Path: path})
+
+	listener := NewUnixgramListener(pp, source, 9000)
+	listener.Start()
+	assert.Nil(t, listener.tailer)
+	assert.True(t, source.Status.IsError())
+	listener.Stop()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+}
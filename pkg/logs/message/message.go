@@ -55,6 +55,12 @@ type Message struct {
 	RawDataLen int
 	// Tags added on processing
 	ProcessingTags []string
+	// Timestamp is the time the log was emitted, parsed from its content by
+	// the processing rules. The encoders use the current time when it is zero.
+	Timestamp time.Time
+	// DestinationGroup is the name of the destination group the message is
+	// routed to, it is sent to the main endpoints when empty.
+	DestinationGroup string
 	// Extra information from the parsers
 	ParsingExtra
 	// Extra information for Serverless Logs messages
@@ -154,6 +160,22 @@ func (m *MessageContent) SetContent(content []byte) {
 	}
 }
 
+// SetStructured sets the structured content for the MessageContent and sets MessageContent state to structured.
+func (m *MessageContent) SetStructured(content StructuredContent) {
+	m.content = nil
+	m.structuredContent = content
+	m.State = StateStructured
+}
+
+// GetStructured returns the structured content of the MessageContent, nil if
+// it is not in the structured state.
+func (m *MessageContent) GetStructured() StructuredContent {
+	if m.State != StateStructured {
+		return nil
+	}
+	return m.structuredContent
+}
+
 // SetRendered sets the content for the MessageContent and sets MessageContent state to rendered.
 func (m *MessageContent) SetRendered(content []byte) {
 	m.content = content
@@ -81,6 +81,15 @@ var (
 
 	// TlmLogsDiscardedFromSDSBuffer how many messages were dropped when waiting for an SDS configuration because the buffer is full
 	TlmLogsDiscardedFromSDSBuffer = telemetry.NewCounter("logs", "sds__dropped_from_buffer", nil, "Count of messages dropped from the buffer while waiting for an SDS configuration")
+
+	// TlmLogsDeduplicated is the number of logs collapsed by deduplication processing rules, per source
+	TlmLogsDeduplicated = telemetry.NewCounter("logs", "deduplicated", []string{"source"}, "Count of logs collapsed by deduplication processing rules")
+
+	// TlmLogsThrottled is the number of logs dropped by throttling processing rules, per source
+	TlmLogsThrottled = telemetry.NewCounter("logs", "throttled", []string{"source"}, "Count of logs dropped by throttling processing rules")
+
+	// TlmRoutedLogsDropped is the number of logs dropped because their destination group could not keep up, per group
+	TlmRoutedLogsDropped = telemetry.NewCounter("logs", "routed_dropped", []string{"destination_group"}, "Count of logs dropped because their destination group was full")
 )
 
 func init() {
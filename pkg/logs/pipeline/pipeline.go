@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/file"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
@@ -31,6 +32,7 @@ type Pipeline struct {
 	processor  *processor.Processor
 	strategy   sender.Strategy
 	sender     *sender.Sender
+	router     *router
 	serverless bool
 	flushWg    *sync.WaitGroup
 }
@@ -45,7 +47,8 @@ func NewPipeline(outputChan chan *message.Payload,
 	pipelineID int,
 	status statusinterface.Status,
 	hostname hostnameinterface.Component,
-	cfg pkgconfigmodel.Reader) *Pipeline {
+	cfg pkgconfigmodel.Reader,
+	metricSender processor.MetricSender) *Pipeline {
 
 	var senderDoneChan chan *sync.WaitGroup
 	var flushWg *sync.WaitGroup
@@ -54,7 +57,7 @@ func NewPipeline(outputChan chan *message.Payload,
 		flushWg = &sync.WaitGroup{}
 	}
 
-	mainDestinations := getDestinations(endpoints, destinationsContext, pipelineID, serverless, senderDoneChan, status, cfg)
+	mainDestinations := getDestinations(endpoints, destinationsContext, fmt.Sprintf("logs_%d", pipelineID), serverless, senderDoneChan, status, cfg)
 
 	strategyInput := make(chan *message.Message, config.ChanSize)
 	senderInput := make(chan *message.Payload, 1) // Only buffer 1 message since payloads can be large
@@ -78,8 +81,16 @@ func NewPipeline(outputChan chan *message.Payload,
 
 	inputChan := make(chan *message.Message, config.ChanSize)
 
-	processor := processor.New(cfg, inputChan, strategyInput, processingRules,
-		encoder, diagnosticMessageReceiver, hostname, pipelineID)
+	// the messages are routed to their destination group once processed
+	processorOutput := strategyInput
+	var router *router
+	if len(endpoints.DestinationGroups) > 0 && !serverless {
+		processorOutput = make(chan *message.Message, config.ChanSize)
+		router = newRouter(processorOutput, strategyInput, endpoints.DestinationGroups, outputChan, destinationsContext, pipelineID, status, cfg)
+	}
+
+	processor := processor.New(cfg, inputChan, processorOutput, processingRules,
+		encoder, diagnosticMessageReceiver, hostname, metricSender, pipelineID)
 
 	return &Pipeline{
 		InputChan:  inputChan,
@@ -87,6 +98,7 @@ func NewPipeline(outputChan chan *message.Payload,
 		processor:  processor,
 		strategy:   strategy,
 		sender:     logsSender,
+		router:     router,
 		serverless: serverless,
 		flushWg:    flushWg,
 	}
@@ -96,12 +108,18 @@ func NewPipeline(outputChan chan *message.Payload,
 func (p *Pipeline) Start() {
 	p.sender.Start()
 	p.strategy.Start()
+	if p.router != nil {
+		p.router.Start()
+	}
 	p.processor.Start()
 }
 
 // Stop stops the pipeline
 func (p *Pipeline) Stop() {
 	p.processor.Stop()
+	if p.router != nil {
+		p.router.Stop()
+	}
 	p.strategy.Stop()
 	p.sender.Stop()
 }
@@ -109,6 +127,9 @@ func (p *Pipeline) Stop() {
 // Flush flushes synchronously the processor and sender managed by this pipeline.
 func (p *Pipeline) Flush(ctx context.Context) {
 	p.flushChan <- struct{}{}
+	if p.router != nil {
+		p.router.Flush()
+	}
 	p.processor.Flush(ctx) // flush messages in the processor into the sender
 
 	if p.serverless {
@@ -117,26 +138,26 @@ func (p *Pipeline) Flush(ctx context.Context) {
 	}
 }
 
-func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, pipelineID int, serverless bool, senderDoneChan chan *sync.WaitGroup, status statusinterface.Status, cfg pkgconfigmodel.Reader) *client.Destinations {
+func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, telemetryPrefix string, serverless bool, senderDoneChan chan *sync.WaitGroup, status statusinterface.Status, cfg pkgconfigmodel.Reader) *client.Destinations {
 	reliable := []client.Destination{}
 	additionals := []client.Destination{}
 
 	if endpoints.UseHTTP {
 		for i, endpoint := range endpoints.GetReliableEndpoints() {
-			telemetryName := fmt.Sprintf("logs_%d_reliable_%d", pipelineID, i)
-			if serverless {
-				reliable = append(reliable, http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, telemetryName, cfg))
-			} else {
-				reliable = append(reliable, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, true, telemetryName, cfg))
+			if endpoint.IsFile() {
+				reliable = append(reliable, file.NewDestination(endpoint, destinationsContext, !serverless))
+				continue
 			}
+			telemetryName := fmt.Sprintf("%s_reliable_%d", telemetryPrefix, i)
+			reliable = append(reliable, getHTTPDestination(endpoint, endpoints, destinationsContext, serverless, true, senderDoneChan, telemetryName, cfg))
 		}
 		for i, endpoint := range endpoints.GetUnReliableEndpoints() {
-			telemetryName := fmt.Sprintf("logs_%d_unreliable_%d", pipelineID, i)
-			if serverless {
-				additionals = append(additionals, http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, telemetryName, cfg))
-			} else {
-				additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, telemetryName, cfg))
+			if endpoint.IsFile() {
+				additionals = append(additionals, file.NewDestination(endpoint, destinationsContext, false))
+				continue
 			}
+			telemetryName := fmt.Sprintf("%s_unreliable_%d", telemetryPrefix, i)
+			additionals = append(additionals, getHTTPDestination(endpoint, endpoints, destinationsContext, serverless, false, senderDoneChan, telemetryName, cfg))
 		}
 		return client.NewDestinations(reliable, additionals)
 	}
@@ -150,12 +171,32 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 	return client.NewDestinations(reliable, additionals)
 }
 
+// getHTTPDestination returns the destination of an HTTP endpoint, the payloads
+// sent to the endpoints which are not Datadog intakes are serialized in their
+// format.
+func getHTTPDestination(endpoint config.Endpoint, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool, shouldRetry bool, senderDoneChan chan *sync.WaitGroup, telemetryName string, cfg pkgconfigmodel.Reader) client.Destination {
+	contentType := http.JSONContentType
+	if !endpoint.IsDatadog() {
+		contentType = sender.FormatContentType(endpoint.Format)
+	}
+	var destination client.Destination
+	if serverless {
+		destination = http.NewSyncDestination(endpoint, contentType, destinationsContext, senderDoneChan, telemetryName, cfg)
+	} else {
+		destination = http.NewDestination(endpoint, contentType, destinationsContext, endpoints.BatchMaxConcurrentSend, shouldRetry, telemetryName, cfg)
+	}
+	if !endpoint.IsDatadog() {
+		destination = sender.NewFormattedDestination(destination, endpoint)
+	}
+	return destination
+}
+
 //nolint:revive // TODO(AML) Fix revive linter
 func getStrategy(inputChan chan *message.Message, outputChan chan *message.Payload, flushChan chan struct{}, endpoints *config.Endpoints, serverless bool, flushWg *sync.WaitGroup, _ int) sender.Strategy {
 	if endpoints.UseHTTP || serverless {
 		encoder := sender.IdentityContentType
 		if endpoints.Main.UseCompression {
-			encoder = sender.NewGzipContentEncoding(endpoints.Main.CompressionLevel)
+			encoder = sender.NewCompressionContentEncoding(endpoints.Main.CompressionKind, endpoints.Main.CompressionLevel)
 		}
 		return sender.NewBatchStrategy(inputChan, outputChan, flushChan, serverless, flushWg, sender.ArraySerializer, endpoints.BatchWait, endpoints.BatchMaxSize, endpoints.BatchMaxContentSize, "logs", encoder)
 	}
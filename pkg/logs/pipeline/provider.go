@@ -7,6 +7,7 @@ package pipeline
 
 import (
 	"context"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"go.uber.org/atomic"
@@ -18,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -36,6 +38,10 @@ type Provider interface {
 	Flush(ctx context.Context)
 }
 
+// metricsCommitInterval is the interval at which the metrics generated from
+// logs are committed to the aggregator.
+const metricsCommitInterval = 10 * time.Second
+
 // provider implements providing logic
 type provider struct {
 	numberOfPipelines         int
@@ -54,16 +60,21 @@ type provider struct {
 	status   statusinterface.Status
 	hostname hostnameinterface.Component
 	cfg      pkgconfigmodel.Reader
+
+	metricSender processor.MetricSender
+	stopCommit   chan struct{}
+	commitDone   chan struct{}
 }
 
-// NewProvider returns a new Provider
-func NewProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, status statusinterface.Status, hostname hostnameinterface.Component, cfg pkgconfigmodel.Reader) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg)
+// NewProvider returns a new Provider. The metrics generated from logs by the
+// processing rules are submitted with metricSender, which may be nil.
+func NewProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, status statusinterface.Status, hostname hostnameinterface.Component, cfg pkgconfigmodel.Reader, metricSender processor.MetricSender) Provider {
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg, metricSender)
 }
 
 // NewServerlessProvider returns a new Provider in serverless mode
 func NewServerlessProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, status statusinterface.Status, hostname hostnameinterface.Component, cfg pkgconfigmodel.Reader) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg, nil)
 }
 
 // NewMockProvider creates a new provider that will not provide any pipelines.
@@ -71,7 +82,7 @@ func NewMockProvider() Provider {
 	return &provider{}
 }
 
-func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool, status statusinterface.Status, hostname hostnameinterface.Component, cfg pkgconfigmodel.Reader) Provider {
+func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool, status statusinterface.Status, hostname hostnameinterface.Component, cfg pkgconfigmodel.Reader, metricSender processor.MetricSender) Provider {
 	return &provider{
 		numberOfPipelines:         numberOfPipelines,
 		auditor:                   auditor,
@@ -85,6 +96,7 @@ func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessa
 		status:                    status,
 		hostname:                  hostname,
 		cfg:                       cfg,
+		metricSender:              metricSender,
 	}
 }
 
@@ -94,10 +106,33 @@ func (p *provider) Start() {
 	p.outputChan = p.auditor.Channel()
 
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg, p.metricSender)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
+
+	if p.metricSender != nil {
+		p.stopCommit = make(chan struct{})
+		p.commitDone = make(chan struct{})
+		go p.commitMetrics()
+	}
+}
+
+// commitMetrics periodically commits the metrics generated by the pipelines
+// to the aggregator.
+func (p *provider) commitMetrics() {
+	defer close(p.commitDone)
+	ticker := time.NewTicker(metricsCommitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.metricSender.Commit()
+		case <-p.stopCommit:
+			p.metricSender.Commit()
+			return
+		}
+	}
 }
 
 // Stop stops all pipelines in parallel,
@@ -110,6 +145,13 @@ func (p *provider) Stop() {
 	stopper.Stop()
 	p.pipelines = p.pipelines[:0]
 	p.outputChan = nil
+
+	// the pipelines are stopped, commit the last generated metrics
+	if p.stopCommit != nil {
+		close(p.stopCommit)
+		<-p.commitDone
+		p.stopCommit = nil
+	}
 }
 
 // return true if all processor SDS scanners are active.
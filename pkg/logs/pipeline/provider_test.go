@@ -24,7 +24,7 @@ type ProviderTestSuite struct {
 }
 
 func (suite *ProviderTestSuite) SetupTest() {
-	suite.a = auditor.New("", auditor.DefaultRegistryFilename, time.Hour, health.RegisterLiveness("fake"))
+	suite.a = auditor.New(suite.T().TempDir(), auditor.DefaultRegistryFilename, time.Hour, health.RegisterLiveness("fake"))
 	suite.p = &provider{
 		numberOfPipelines:    3,
 		auditor:              suite.a,
@@ -61,6 +61,26 @@ func (suite *ProviderTestSuite) TestProvider() {
 	suite.Nil(suite.p.NextPipelineChan())
 }
 
+type countingMetricSender struct {
+	commits atomic.Int32
+}
+
+func (s *countingMetricSender) Count(string, float64, string, []string)        {}
+func (s *countingMetricSender) Distribution(string, float64, string, []string) {}
+func (s *countingMetricSender) Commit()                                        { s.commits.Inc() }
+
+func (suite *ProviderTestSuite) TestProviderCommitsMetricsOnStop() {
+	sender := &countingMetricSender{}
+	suite.p.metricSender = sender
+	suite.a.Start()
+	suite.p.Start()
+	suite.Equal(int32(0), sender.commits.Load())
+
+	suite.p.Stop()
+	suite.a.Stop()
+	suite.Equal(int32(1), sender.commits.Load())
+}
+
 func TestProviderTestSuite(t *testing.T) {
 	suite.Run(t, new(ProviderTestSuite))
 }
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// router sends the processed messages to the strategy of the main endpoints,
+// or to the one of their destination group.
+type router struct {
+	inputChan  chan *message.Message
+	mainOutput chan *message.Message
+	groups     map[string]*groupSender
+	// unknownGroups are the destination groups of messages which are not
+	// configured, reported once.
+	unknownGroups map[string]bool
+	done          chan struct{}
+}
+
+// groupSender batches and sends the messages of a destination group,
+// independently of the other messages.
+type groupSender struct {
+	inputChan chan *message.Message
+	flushChan chan struct{}
+	strategy  sender.Strategy
+	sender    *sender.Sender
+	// dropping is true while the logs of the group are dropped, reported once
+	// each time the group falls behind.
+	dropping bool
+}
+
+func newRouter(inputChan, mainOutput chan *message.Message, groups []*config.DestinationGroup, outputChan chan *message.Payload, destinationsContext *client.DestinationsContext, pipelineID int, status statusinterface.Status, cfg pkgconfigmodel.Reader) *router {
+	r := &router{
+		inputChan:     inputChan,
+		mainOutput:    mainOutput,
+		groups:        make(map[string]*groupSender, len(groups)),
+		unknownGroups: make(map[string]bool),
+		done:          make(chan struct{}),
+	}
+	for _, group := range groups {
+		telemetryName := fmt.Sprintf("logs_%d_%s", pipelineID, group.Name)
+		destinations := getDestinations(group.Endpoints, destinationsContext, telemetryName, false, nil, status, cfg)
+
+		strategyInput := make(chan *message.Message, group.Endpoints.InputChanSize)
+		senderInput := make(chan *message.Payload, 1) // Only buffer 1 message since payloads can be large
+		flushChan := make(chan struct{})
+		r.groups[group.Name] = &groupSender{
+			inputChan: strategyInput,
+			flushChan: flushChan,
+			strategy:  getStrategy(strategyInput, senderInput, flushChan, group.Endpoints, false, nil, pipelineID),
+			sender:    sender.NewSender(cfg, senderInput, outputChan, destinations, config.DestinationPayloadChanSize, nil, nil),
+		}
+	}
+	return r
+}
+
+// Start starts the destination groups, then the routing.
+func (r *router) Start() {
+	for _, group := range r.groups {
+		group.sender.Start()
+		group.strategy.Start()
+	}
+	go r.run()
+}
+
+// Stop stops the routing, this call blocks until inputChan is flushed and the
+// destination groups are stopped.
+func (r *router) Stop() {
+	close(r.inputChan)
+	<-r.done
+	for _, group := range r.groups {
+		group.strategy.Stop()
+		group.sender.Stop()
+	}
+}
+
+// Flush flushes the batches of the destination groups.
+func (r *router) Flush() {
+	for _, group := range r.groups {
+		group.flushChan <- struct{}{}
+	}
+}
+
+func (r *router) run() {
+	defer close(r.done)
+	for msg := range r.inputChan {
+		r.route(msg)
+	}
+}
+
+func (r *router) route(msg *message.Message) {
+	name := msg.DestinationGroup
+	if name == "" || name == config.DefaultDestinationGroup {
+		r.mainOutput <- msg
+		return
+	}
+	group, exists := r.groups[name]
+	if !exists {
+		if !r.unknownGroups[name] {
+			log.Warnf("Unknown destination group %s, its logs are sent to the main endpoints", name)
+			r.unknownGroups[name] = true
+		}
+		r.mainOutput <- msg
+		return
+	}
+	// the backpressure of a destination group stays within its input buffer:
+	// when the group cannot keep up, its logs are dropped and counted rather
+	// than stalling the main endpoints and the other groups
+	select {
+	case group.inputChan <- msg:
+		group.dropping = false
+	default:
+		if !group.dropping {
+			log.Warnf("Destination group %s cannot keep up, its logs are dropped until it catches up", name)
+			group.dropping = true
+		}
+		metrics.TlmRoutedLogsDropped.Inc(name)
+	}
+}
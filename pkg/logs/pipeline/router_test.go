@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newRoutedMessage(content string, group string) *message.Message {
+	msg := message.NewMessage([]byte(content), nil, message.StatusInfo, 0)
+	msg.DestinationGroup = group
+	return msg
+}
+
+func TestRouter(t *testing.T) {
+	mainOutput := make(chan *message.Message, 10)
+	security := &groupSender{inputChan: make(chan *message.Message, 1)}
+	r := &router{
+		inputChan:     make(chan *message.Message),
+		mainOutput:    mainOutput,
+		groups:        map[string]*groupSender{"security": security},
+		unknownGroups: make(map[string]bool),
+		done:          make(chan struct{}),
+	}
+	go r.run()
+
+	dropped := metrics.TlmRoutedLogsDropped.WithValues("security").Get()
+	r.inputChan <- newRoutedMessage("main", "")
+	r.inputChan <- newRoutedMessage("default", config.DefaultDestinationGroup)
+	r.inputChan <- newRoutedMessage("audit", "security")
+	// the destination group is full, its logs are dropped and counted without
+	// blocking the other logs
+	r.inputChan <- newRoutedMessage("dropped", "security")
+	r.inputChan <- newRoutedMessage("unknown", "debug")
+	close(r.inputChan)
+	<-r.done
+
+	assert.Len(t, mainOutput, 3)
+	for _, content := range []string{"main", "default", "unknown"} {
+		assert.Equal(t, content, string((<-mainOutput).GetContent()))
+	}
+	assert.Len(t, security.inputChan, 1)
+	assert.Equal(t, "audit", string((<-security.inputChan).GetContent()))
+	assert.Equal(t, dropped+1, metrics.TlmRoutedLogsDropped.WithValues("security").Get())
+	assert.True(t, security.dropping)
+	assert.True(t, r.unknownGroups["debug"])
+}
+
+func TestRouterStuckGroup(t *testing.T) {
+	mainOutput := make(chan *message.Message, 100)
+	// the security group never reads its logs
+	security := &groupSender{inputChan: make(chan *message.Message, 1)}
+	audit := &groupSender{inputChan: make(chan *message.Message, 100)}
+	r := &router{
+		inputChan:     make(chan *message.Message),
+		mainOutput:    mainOutput,
+		groups:        map[string]*groupSender{"security": security, "audit": audit},
+		unknownGroups: make(map[string]bool),
+		done:          make(chan struct{}),
+	}
+	go r.run()
+
+	dropped := metrics.TlmRoutedLogsDropped.WithValues("security").Get()
+	for i := 0; i < 50; i++ {
+		select {
+		case r.inputChan <- newRoutedMessage("security", "security"):
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "the router is stalled by the security group")
+		}
+		r.inputChan <- newRoutedMessage("main", "")
+		r.inputChan <- newRoutedMessage("audit", "audit")
+	}
+	close(r.inputChan)
+	<-r.done
+
+	// the main endpoints and the other group receive all their logs
+	assert.Len(t, mainOutput, 50)
+	assert.Len(t, audit.inputChan, 50)
+	assert.Len(t, security.inputChan, 1)
+	assert.Equal(t, dropped+49, metrics.TlmRoutedLogsDropped.WithValues("security").Get())
+
+	// the group is reported again once it caught up
+	<-security.inputChan
+	r.route(newRoutedMessage("security", "security"))
+	assert.False(t, security.dropping)
+}
+
+func TestPipelineWithDestinationGroups(t *testing.T) {
+	cfg := pkgconfigmodel.NewConfig("test", "DD", strings.NewReplacer(".", "_"))
+	respondChan := make(chan int)
+	server := http.NewTestServerWithOptions(200, 0, true, respondChan, cfg)
+	defer server.Stop()
+
+	endpoints := config.NewEndpoints(config.Endpoint{}, nil, false, true)
+	endpoints.DestinationGroups = []*config.DestinationGroup{
+		{Name: "security", Endpoints: config.NewEndpointsWithBatchSettings(server.Endpoint, nil, false, true, 10*time.Millisecond, 0, 10, 1000, 10)},
+	}
+	auditorChan := make(chan *message.Payload, 1)
+	pipeline := NewPipeline(auditorChan, nil, endpoints, server.DestCtx, diagnostic.NewBufferedMessageReceiver(nil, nil), false, 0, nil, nil, cfg, nil)
+	pipeline.Start()
+
+	source := sources.NewLogSource("", &config.LogsConfig{DestinationGroup: "security"})
+	pipeline.InputChan <- message.NewMessageWithSource([]byte("audit"), message.StatusInfo, source, 0)
+
+	// the message is only sent to the endpoints of its destination group
+	assert.Equal(t, 200, <-respondChan)
+	payload := <-auditorChan
+	assert.Contains(t, string(payload.Messages[0].GetContent()), `"message":"audit"`)
+	pipeline.Stop()
+
+	// the logs are not routed in serverless mode
+	pipeline = NewPipeline(auditorChan, nil, endpoints, server.DestCtx, nil, true, 0, nil, nil, cfg, nil)
+	assert.Nil(t, pipeline.router)
+}
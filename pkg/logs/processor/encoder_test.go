@@ -222,6 +222,27 @@ func TestJsonEncoder(t *testing.T) {
 	assert.NotEmpty(t, log.Timestamp)
 }
 
+func TestEncodersUseParsedTimestamp(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	parsed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	msg := newMessage([]byte("message"), source, "")
+	msg.State = message.StateRendered
+	msg.Timestamp = parsed
+	assert.Nil(t, JSONEncoder.Encode(msg, "unknown"))
+	jsonLog := &jsonPayload{}
+	assert.Nil(t, json.Unmarshal(msg.GetContent(), jsonLog))
+	assert.Equal(t, parsed.UnixMilli(), jsonLog.Timestamp)
+
+	msg = newMessage([]byte("message"), source, "")
+	msg.State = message.StateRendered
+	msg.Timestamp = parsed
+	assert.Nil(t, ProtoEncoder.Encode(msg, "unknown"))
+	protoLog := &pb.Log{}
+	assert.Nil(t, protoLog.Unmarshal(msg.GetContent()))
+	assert.Equal(t, parsed.UnixNano(), protoLog.Timestamp)
+}
+
 func TestEncoderToValidUTF8(t *testing.T) {
 	// valid utf-8
 	assert.Equal(t, "", toValidUtf8(nil))
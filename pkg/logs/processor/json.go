@@ -39,7 +39,9 @@ func (j *jsonEncoder) Encode(msg *message.Message, hostname string) error {
 	}
 
 	ts := time.Now().UTC()
-	if !msg.ServerlessExtra.Timestamp.IsZero() {
+	if !msg.Timestamp.IsZero() {
+		ts = msg.Timestamp
+	} else if !msg.ServerlessExtra.Timestamp.IsZero() {
 		ts = msg.ServerlessExtra.Timestamp
 	}
 
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MetricSender submits the metrics generated from logs, it is implemented by
+// the aggregator's sender.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+	Commit()
+}
+
+// generateMetric submits the metric of a metric generation rule if the message
+// matches it, and returns true if it did. No metric is generated, and no
+// message is dropped, by pipelines without a metric sender.
+func (p *Processor) generateMetric(rule *config.ProcessingRule, msg *message.Message, content []byte) bool {
+	if p.metricSender == nil {
+		return false
+	}
+	var groups [][]byte
+	if rule.Regex != nil {
+		groups = rule.Regex.FindSubmatch(matchedContent(rule, msg, content))
+		if groups == nil {
+			return false
+		}
+	}
+	value := 1.0
+	if rule.ValueField != "" {
+		raw, ok := ruleValue(rule, msg, groups, rule.ValueField)
+		if !ok {
+			return false
+		}
+		var err error
+		if value, err = strconv.ParseFloat(string(raw), 64); err != nil {
+			log.Debugf("Processing rule %s cannot parse the metric value %q: %v", rule.Name, raw, err)
+			return false
+		}
+	}
+
+	tags := make([]string, 0, len(rule.TagFields))
+	for _, field := range rule.TagFields {
+		if raw, ok := ruleValue(rule, msg, groups, field); ok {
+			tags = append(tags, field+":"+string(raw))
+		}
+	}
+
+	switch rule.MetricType {
+	case config.DistributionMetric:
+		p.metricSender.Distribution(rule.MetricName, value, "", tags)
+	default:
+		p.metricSender.Count(rule.MetricName, value, "", tags)
+	}
+	return true
+}
+
+// ruleValue returns the value of a capture group of the rule pattern, or of an
+// attribute of the message if there is no such group.
+func ruleValue(rule *config.ProcessingRule, msg *message.Message, groups [][]byte, name string) ([]byte, bool) {
+	if rule.Regex != nil {
+		if i := rule.Regex.SubexpIndex(name); i >= 0 && i < len(groups) {
+			return groups[i], groups[i] != nil
+		}
+	}
+	value := messageAttribute(msg, name)
+	return value, value != nil
+}
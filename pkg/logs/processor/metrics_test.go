@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+)
+
+type submittedMetric struct {
+	metricType string
+	name       string
+	value      float64
+	tags       []string
+}
+
+type mockMetricSender struct {
+	metrics []submittedMetric
+	commits int
+}
+
+func (s *mockMetricSender) Count(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, submittedMetric{config.CountMetric, metric, value, tags})
+}
+
+func (s *mockMetricSender) Distribution(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, submittedMetric{config.DistributionMetric, metric, value, tags})
+}
+
+func (s *mockMetricSender) Commit() {
+	s.commits++
+}
+
+func TestGenerateMetricFromPattern(t *testing.T) {
+	sender := &mockMetricSender{}
+	p := &Processor{metricSender: sender}
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:        config.GenerateMetric,
+		MetricName:  "http.requests",
+		Pattern:     `"(?P<method>[A-Z]+) [^"]*" (?P<status>\d{3})`,
+		TagFields:   []string{"method", "status", "missing"},
+		DropMessage: true,
+	})
+
+	assert.False(t, p.applyRedactingRules(newMessage([]byte(`1.2.3.4 "GET /index.html" 200 512`), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte(`1.2.3.4 "POST /login" 401 12`), source, "")))
+	// logs which do not match are kept
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`starting server`), source, "")))
+
+	assert.Equal(t, []submittedMetric{
+		{config.CountMetric, "http.requests", 1, []string{"method:GET", "status:200"}},
+		{config.CountMetric, "http.requests", 1, []string{"method:POST", "status:401"}},
+	}, sender.metrics)
+}
+
+func TestGenerateMetricFromParsedFields(t *testing.T) {
+	sender := &mockMetricSender{}
+	p := &Processor{metricSender: sender}
+	source := newParsingSource(t,
+		&config.ProcessingRule{Type: config.JSONParser},
+		&config.ProcessingRule{
+			Type:       config.GenerateMetric,
+			MetricName: "http.duration",
+			MetricType: config.DistributionMetric,
+			Field:      "http.status",
+			Pattern:    `^5`,
+			ValueField: "duration",
+			TagFields:  []string{"http.status"},
+		},
+	)
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`{"http":{"status":503},"duration":0.75}`), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`{"http":{"status":200},"duration":0.01}`), source, "")))
+	// the value is missing
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`{"http":{"status":500}}`), source, "")))
+
+	assert.Equal(t, []submittedMetric{
+		{config.DistributionMetric, "http.duration", 0.75, []string{"http.status:503"}},
+	}, sender.metrics)
+}
+
+func TestGenerateMetricWithoutSender(t *testing.T) {
+	p := &Processor{}
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.GenerateMetric, MetricName: "logs", DropMessage: true})
+
+	// the logs are not dropped if no metric can be generated
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`hello`), source, "")))
+}
@@ -0,0 +1,379 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// applyParsingRule extracts attributes from the content of the message with
+// the rule, and stores them in its structured content, converting unstructured
+// messages. It returns false if the content could not be parsed.
+func applyParsingRule(rule *config.ProcessingRule, msg *message.Message) bool {
+	var attributes map[string]interface{}
+	switch rule.Type {
+	case config.GrokParser:
+		attributes = parseGrok(rule, msg.GetContent())
+	case config.JSONParser:
+		attributes = parseJSON(msg.GetContent())
+	case config.KeyValueParser:
+		attributes = parseKeyValue(msg.GetContent(), []byte(rule.KeyValueSeparator()))
+	}
+	if attributes == nil {
+		return false
+	}
+
+	data := structuredData(msg)
+	if data == nil {
+		log.Debugf("Processing rule %s cannot store attributes in this type of structured message", rule.Name)
+		return false
+	}
+
+	if value, ok := getAttribute(attributes, rule.MessageField); ok {
+		deleteAttribute(attributes, rule.MessageField)
+		data["message"] = attributeToString(value)
+	}
+	if value, ok := getAttribute(attributes, rule.StatusField); ok {
+		if status := toStatus(attributeToString(value)); status != "" {
+			msg.Status = status
+		}
+	}
+	if value, ok := getAttribute(attributes, rule.TimestampField); ok {
+		if timestamp, err := parseTimestamp(attributeToString(value), rule.TimestampFormat); err == nil {
+			msg.Timestamp = timestamp.UTC()
+		} else {
+			log.Debugf("Processing rule %s cannot parse the timestamp: %v", rule.Name, err)
+		}
+	}
+	for _, field := range rule.TagFields {
+		if value, ok := getAttribute(attributes, field); ok {
+			msg.ProcessingTags = append(msg.ProcessingTags, field+":"+attributeToString(value))
+		}
+	}
+
+	target := data
+	if rule.Target != "" {
+		target = map[string]interface{}{}
+		if existing, ok := getAttribute(data, rule.Target); ok {
+			if existing, ok := existing.(map[string]interface{}); ok {
+				target = existing
+			}
+		}
+		setAttribute(data, rule.Target, target)
+	}
+	for key, value := range attributes {
+		setAttribute(target, key, value)
+	}
+	// the message of a structured content must be a string
+	if _, ok := data["message"].(string); !ok {
+		if value, exists := data["message"]; exists {
+			data["message"] = attributeToString(value)
+		} else {
+			data["message"] = ""
+		}
+	}
+	return true
+}
+
+// structuredData returns the attributes of the message, converting it into a
+// structured message if it is unstructured. It returns nil if the structured
+// content of the message does not support attributes.
+func structuredData(msg *message.Message) map[string]interface{} {
+	if msg.State == message.StateUnstructured {
+		content := &message.BasicStructuredContent{
+			Data: map[string]interface{}{"message": string(msg.GetContent())},
+		}
+		msg.SetStructured(content)
+		return content.Data
+	}
+	if content, ok := msg.GetStructured().(*message.BasicStructuredContent); ok {
+		return content.Data
+	}
+	return nil
+}
+
+// maskAttributes applies a mask_sequences rule to the attributes of a structured
+// message other than its message, and to the tags extracted from them.
+func maskAttributes(rule *config.ProcessingRule, msg *message.Message) {
+	if content, ok := msg.GetStructured().(*message.BasicStructuredContent); ok {
+		for key, value := range content.Data {
+			if key != "message" {
+				content.Data[key] = maskAttribute(rule, value)
+			}
+		}
+	}
+	for i, tag := range msg.ProcessingTags {
+		msg.ProcessingTags[i] = string(rule.Regex.ReplaceAll([]byte(tag), rule.Placeholder))
+	}
+}
+
+// maskAttribute returns an attribute with the sequences matched by a rule masked.
+// A scalar attribute whose text representation is masked becomes a string.
+func maskAttribute(rule *config.ProcessingRule, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = maskAttribute(rule, nested)
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = maskAttribute(rule, nested)
+		}
+		return v
+	case nil, bool:
+		return v
+	}
+	text := attributeToString(value)
+	masked := rule.Regex.ReplaceAll([]byte(text), rule.Placeholder)
+	if string(masked) == text {
+		return value
+	}
+	return string(masked)
+}
+
+// messageAttribute returns the attribute of a structured message as a string,
+// nil if it does not exist.
+func messageAttribute(msg *message.Message, name string) []byte {
+	content, ok := msg.GetStructured().(*message.BasicStructuredContent)
+	if !ok {
+		return nil
+	}
+	value, ok := getAttribute(content.Data, name)
+	if !ok {
+		return nil
+	}
+	return []byte(attributeToString(value))
+}
+
+// parseGrok returns the fields extracted by a grok pattern, nil if it does not match.
+func parseGrok(rule *config.ProcessingRule, content []byte) map[string]interface{} {
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return nil
+	}
+	attributes := make(map[string]interface{}, len(rule.GrokFields))
+	for _, field := range rule.GrokFields {
+		value := match[rule.Regex.SubexpIndex(field.Group)]
+		if value == nil {
+			// the field is in an optional part of the pattern
+			continue
+		}
+		switch field.Type {
+		case config.GrokInt:
+			if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				attributes[field.Name] = i
+				continue
+			}
+		case config.GrokFloat:
+			if f, err := strconv.ParseFloat(string(value), 64); err == nil {
+				attributes[field.Name] = f
+				continue
+			}
+		}
+		attributes[field.Name] = string(value)
+	}
+	return attributes
+}
+
+// parseJSON returns the attributes of a JSON object, nil if the content is not one.
+func parseJSON(content []byte) map[string]interface{} {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || content[0] != '{' {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// keep the precision of large integers
+	decoder.UseNumber()
+	var attributes map[string]interface{}
+	if err := decoder.Decode(&attributes); err != nil {
+		return nil
+	}
+	return attributes
+}
+
+// parseKeyValue returns the `key<separator>value` pairs separated by spaces,
+// in which values may be double-quoted, nil if there is none.
+func parseKeyValue(content []byte, separator []byte) map[string]interface{} {
+	attributes := make(map[string]interface{})
+	for {
+		content = bytes.TrimLeft(content, " \t")
+		if len(content) == 0 {
+			break
+		}
+		end := bytes.IndexAny(content, " \t")
+		if end < 0 {
+			end = len(content)
+		}
+		sep := bytes.Index(content[:end], separator)
+		if sep <= 0 {
+			// not a pair
+			content = content[end:]
+			continue
+		}
+		key := string(content[:sep])
+		content = content[sep+len(separator):]
+
+		if len(content) > 0 && content[0] == '"' {
+			if value, rest, ok := unquote(content); ok {
+				attributes[key] = value
+				content = rest
+				continue
+			}
+		}
+		end = bytes.IndexAny(content, " \t")
+		if end < 0 {
+			end = len(content)
+		}
+		attributes[key] = string(content[:end])
+		content = content[end:]
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	return attributes
+}
+
+// unquote returns the double-quoted string starting the content and the bytes
+// after it.
+func unquote(content []byte) (string, []byte, bool) {
+	for i := 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(string(content[:i+1]))
+			if err != nil {
+				return string(content[1:i]), content[i+1:], true
+			}
+			return value, content[i+1:], true
+		}
+	}
+	return "", content, false
+}
+
+// getAttribute returns the attribute at path, in which nested attributes are
+// separated with dots.
+func getAttribute(attributes map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	if value, ok := attributes[path]; ok {
+		return value, true
+	}
+	head, tail, found := strings.Cut(path, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := attributes[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return getAttribute(nested, tail)
+}
+
+// setAttribute sets the attribute at path, in which nested attributes are
+// separated with dots.
+func setAttribute(attributes map[string]interface{}, path string, value interface{}) {
+	head, tail, found := strings.Cut(path, ".")
+	if !found || head == "" || tail == "" {
+		attributes[path] = value
+		return
+	}
+	nested, ok := attributes[head].(map[string]interface{})
+	if !ok {
+		nested = make(map[string]interface{})
+		attributes[head] = nested
+	}
+	setAttribute(nested, tail, value)
+}
+
+// deleteAttribute deletes the attribute at path, in which nested attributes
+// are separated with dots.
+func deleteAttribute(attributes map[string]interface{}, path string) {
+	if _, ok := attributes[path]; ok {
+		delete(attributes, path)
+		return
+	}
+	head, tail, found := strings.Cut(path, ".")
+	if !found {
+		return
+	}
+	if nested, ok := attributes[head].(map[string]interface{}); ok {
+		deleteAttribute(nested, tail)
+	}
+}
+
+// attributeToString formats an attribute, objects and arrays are formatted in JSON.
+func attributeToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		if encoded, err := json.Marshal(v); err == nil {
+			return string(encoded)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// toStatus returns the status of a log level, or an empty string if it is unknown.
+func toStatus(level string) string {
+	switch strings.ToLower(level) {
+	case "emerg", "emergency", "panic":
+		return message.StatusEmergency
+	case "alert":
+		return message.StatusAlert
+	case "crit", "critical", "fatal":
+		return message.StatusCritical
+	case "err", "error":
+		return message.StatusError
+	case "warn", "warning":
+		return message.StatusWarning
+	case "notice":
+		return message.StatusNotice
+	case "info", "information", "informational":
+		return message.StatusInfo
+	case "debug", "trace":
+		return message.StatusDebug
+	default:
+		return ""
+	}
+}
+
+// parseTimestamp parses a timestamp with a Go time layout, or as seconds
+// (`unix`) or milliseconds (`unix_ms`) since the epoch. The default format is
+// RFC 3339.
+func parseTimestamp(value string, format string) (time.Time, error) {
+	switch format {
+	case "":
+		return time.Parse(time.RFC3339Nano, value)
+	case "unix", "unix_ms":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if format == "unix_ms" {
+			return time.UnixMilli(int64(f)), nil
+		}
+		return time.Unix(0, int64(f*float64(time.Second))), nil
+	default:
+		return time.Parse(format, value)
+	}
+}
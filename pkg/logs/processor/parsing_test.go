@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newParsingSource(t *testing.T, rules ...*config.ProcessingRule) *sources.LogSource {
+	for _, rule := range rules {
+		rule.Name = "test"
+	}
+	require.NoError(t, config.ValidateProcessingRules(rules))
+	require.NoError(t, config.CompileProcessingRules(rules))
+	return &sources.LogSource{Config: &config.LogsConfig{ProcessingRules: rules}}
+}
+
+func renderAttributes(t *testing.T, msg *message.Message) map[string]interface{} {
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(rendered, &data))
+	return data
+}
+
+func TestParseGrok(t *testing.T) {
+	p := &Processor{}
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:        config.GrokParser,
+		Pattern:     `%{IPORHOST:network.client.ip} %{WORD:http.method} %{URIPATHPARAM:http.url} %{INT:http.status_code:int} %{NUMBER:duration:float}`,
+		TagFields:   []string{"http.method"},
+		StatusField: "level",
+	})
+
+	msg := newMessage([]byte("10.0.0.1 GET /api/v1/users?id=3 503 0.25"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StateStructured, msg.State)
+	assert.Equal(t, "10.0.0.1 GET /api/v1/users?id=3 503 0.25", string(msg.GetContent()))
+	assert.Equal(t, []string{"http.method:GET"}, msg.ProcessingTags)
+	assert.Equal(t, map[string]interface{}{
+		"message":  "10.0.0.1 GET /api/v1/users?id=3 503 0.25",
+		"network":  map[string]interface{}{"client": map[string]interface{}{"ip": "10.0.0.1"}},
+		"http":     map[string]interface{}{"method": "GET", "url": "/api/v1/users?id=3", "status_code": 503.0},
+		"duration": 0.25,
+	}, renderAttributes(t, msg))
+
+	// the message is left untouched when the pattern does not match
+	msg = newMessage([]byte("not an access log"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StateUnstructured, msg.State)
+	assert.Equal(t, "not an access log", string(msg.GetContent()))
+}
+
+func TestParseJSON(t *testing.T) {
+	p := &Processor{}
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:            config.JSONParser,
+		MessageField:    "msg",
+		StatusField:     "level",
+		TimestampField:  "ts",
+		TimestampFormat: "unix_ms",
+		TagFields:       []string{"user.team"},
+	})
+
+	msg := newMessage([]byte(`{"msg":"payment failed","level":"ERR","ts":1700000000123,"user":{"id":12345678901234567,"team":"billing"}}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "payment failed", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, time.UnixMilli(1700000000123).UTC(), msg.Timestamp)
+	assert.Equal(t, []string{"user.team:billing"}, msg.ProcessingTags)
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message":"payment failed","level":"ERR","ts":1700000000123,"user":{"id":12345678901234567,"team":"billing"}}`, string(rendered))
+	assert.Contains(t, string(rendered), "12345678901234567")
+
+	// not a JSON object
+	msg = newMessage([]byte(`[1, 2]`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StateUnstructured, msg.State)
+}
+
+func TestParseKeyValue(t *testing.T) {
+	p := &Processor{}
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:           config.KeyValueParser,
+		Target:         "attrs",
+		TimestampField: "time",
+	})
+
+	msg := newMessage([]byte(`time=2024-01-02T03:04:05.5Z level=info msg="hello \"world\"" ignored empty= path=/tmp`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC), msg.Timestamp)
+	assert.Equal(t, map[string]interface{}{
+		"message": `time=2024-01-02T03:04:05.5Z level=info msg="hello \"world\"" ignored empty= path=/tmp`,
+		"attrs": map[string]interface{}{
+			"time":  "2024-01-02T03:04:05.5Z",
+			"level": "info",
+			"msg":   `hello "world"`,
+			"empty": "",
+			"path":  "/tmp",
+		},
+	}, renderAttributes(t, msg))
+
+	source = newParsingSource(t, &config.ProcessingRule{Type: config.KeyValueParser, Separator: ":"})
+	msg = newMessage([]byte(`user:alice action:login`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, map[string]interface{}{"message": "user:alice action:login", "user": "alice", "action": "login"}, renderAttributes(t, msg))
+}
+
+func TestParseStructuredMessage(t *testing.T) {
+	p := &Processor{}
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.KeyValueParser})
+
+	msg := newStructuredMessage([]byte("a=1 b=2"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, map[string]interface{}{"message": "a=1 b=2", "a": "1", "b": "2"}, renderAttributes(t, msg))
+}
+
+func TestFilterOnParsedField(t *testing.T) {
+	p := &Processor{}
+	rules := []*config.ProcessingRule{
+		{Type: config.MaskSequences, Pattern: `token=\S+`, ReplacePlaceholder: "token=****"},
+		{Type: config.KeyValueParser},
+		{Type: config.ExcludeAtMatch, Field: "status", Pattern: `^2\d\d$`},
+		{Type: config.IncludeAtMatch, Field: "method", Pattern: `^(GET|POST)$`},
+	}
+	source := newParsingSource(t, rules...)
+
+	msg := newMessage([]byte("method=GET status=200 token=secret"), source, "")
+	assert.False(t, p.applyRedactingRules(msg))
+
+	msg = newMessage([]byte("method=PUT status=500"), source, "")
+	assert.False(t, p.applyRedactingRules(msg))
+
+	msg = newMessage([]byte("status=500"), source, "")
+	assert.False(t, p.applyRedactingRules(msg))
+
+	msg = newMessage([]byte("method=POST status=500 token=secret"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "method=POST status=500 token=****", string(msg.GetContent()))
+	assert.Equal(t, "****", renderAttributes(t, msg)["token"])
+}
+
+func TestMaskParsedAttributes(t *testing.T) {
+	p := &Processor{}
+	source := newParsingSource(t,
+		&config.ProcessingRule{Type: config.JSONParser, TagFields: []string{"card"}},
+		&config.ProcessingRule{Type: config.MaskSequences, Pattern: `\d{4}-\d{4}`, ReplacePlaceholder: "[masked]"},
+	)
+
+	msg := newMessage([]byte(`{"message":"paid with 1234-5678","card":"1234-5678","payment":{"cards":["8765-4321"],"amount":12}}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, map[string]interface{}{
+		"message": "paid with [masked]",
+		"card":    "[masked]",
+		"payment": map[string]interface{}{
+			"cards":  []interface{}{"[masked]"},
+			"amount": float64(12),
+		},
+	}, renderAttributes(t, msg))
+	assert.Equal(t, []string{"card:[masked]"}, msg.ProcessingTags)
+}
@@ -8,6 +8,9 @@ package processor
 import (
 	"context"
 	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -38,6 +41,8 @@ type Processor struct {
 	diagnosticMessageReceiver diagnostic.MessageReceiver
 	mu                        sync.Mutex
 	hostname                  hostnameinterface.Component
+	metricSender              MetricSender
+	throttling                throttling
 
 	sds sdsProcessor
 }
@@ -55,10 +60,11 @@ type sdsProcessor struct {
 	scanner *sds.Scanner // configured through RC
 }
 
-// New returns an initialized Processor.
+// New returns an initialized Processor. The metrics generated from logs are
+// submitted with metricSender, which may be nil.
 func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule,
 	encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
-	pipelineID int) *Processor {
+	metricSender MetricSender, pipelineID int) *Processor {
 
 	waitForSDSConfig := sds.ShouldBufferUntilSDSConfiguration(cfg)
 	maxBufferSize := sds.WaitForConfigurationBufferMaxSize(cfg)
@@ -73,6 +79,8 @@ func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
 		hostname:                  hostname,
+		metricSender:              metricSender,
+		throttling:                newThrottling(clock.New()),
 
 		sds: sdsProcessor{
 			// will immediately starts buffering if it has been configured as so
@@ -112,6 +120,8 @@ func (p *Processor) Flush(ctx context.Context) {
 			return
 		default:
 			if len(p.inputChan) == 0 {
+				// send the repeat counts of the deduplicated logs
+				p.flushThrottling(true)
 				return
 			}
 			msg := <-p.inputChan
@@ -126,6 +136,9 @@ func (p *Processor) run() {
 		p.done <- struct{}{}
 	}()
 
+	ticker := time.NewTicker(throttlingFlushInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		// Processing, usual main loop
@@ -133,6 +146,8 @@ func (p *Processor) run() {
 
 		case msg, ok := <-p.inputChan:
 			if !ok { // channel has been closed
+				// send the repeat counts of the deduplicated logs
+				p.flushThrottling(true)
 				return
 			}
 
@@ -157,6 +172,14 @@ func (p *Processor) run() {
 			p.mu.Lock()
 			p.applySDSReconfiguration(order)
 			p.mu.Unlock()
+
+		// Deduplication
+		// -------------
+
+		case <-ticker.C:
+			p.mu.Lock()
+			p.flushThrottling(false)
+			p.mu.Unlock()
 		}
 	}
 }
@@ -224,25 +247,30 @@ func (p *Processor) processMessage(msg *message.Message) {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
 
-		// render the message
-		rendered, err := msg.Render()
-		if err != nil {
-			log.Error("can't render the msg", err)
-			return
-		}
-		msg.SetRendered(rendered)
+		p.sendMessage(msg)
+	}
+}
 
-		// report this message to diagnostic receivers (e.g. `stream-logs` command)
-		p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+// sendMessage renders and encodes a processed message, and sends it.
+func (p *Processor) sendMessage(msg *message.Message) {
+	// render the message
+	rendered, err := msg.Render()
+	if err != nil {
+		log.Error("can't render the msg", err)
+		return
+	}
+	msg.SetRendered(rendered)
 
-		// encode the message to its final format, it is done in-place
-		if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
-			log.Error("unable to encode msg ", err)
-			return
-		}
+	// report this message to diagnostic receivers (e.g. `stream-logs` command)
+	p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
 
-		p.outputChan <- msg
+	// encode the message to its final format, it is done in-place
+	if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
+		log.Error("unable to encode msg ", err)
+		return
 	}
+
+	p.outputChan <- msg
 }
 
 // applyRedactingRules returns given a message if we should process it or not,
@@ -253,21 +281,45 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	// Use the internal scrubbing implementation of the Agent
 	// ---------------------------
 
+	if msg.DestinationGroup == "" {
+		msg.DestinationGroup = msg.Origin.LogSource.Config.DestinationGroup
+	}
+
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	// whether a parsing rule extracted attributes from the message
+	parsed := false
 	for _, rule := range rules {
 		switch rule.Type {
 		case config.ExcludeAtMatch:
 			// if this message matches, we ignore it
-			if rule.Regex.Match(content) {
+			if rule.Regex.Match(matchedContent(rule, msg, content)) {
 				return false
 			}
 		case config.IncludeAtMatch:
 			// if this message doesn't match, we ignore it
-			if !rule.Regex.Match(content) {
+			if !rule.Regex.Match(matchedContent(rule, msg, content)) {
 				return false
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+			if parsed {
+				// the attributes were extracted before being masked
+				maskAttributes(rule, msg)
+			}
+		case config.GrokParser, config.JSONParser, config.KeyValueParser:
+			// parse the masked content, the message may become structured
+			msg.SetContent(content)
+			parsed = applyParsingRule(rule, msg) || parsed
+			content = msg.GetContent()
+		case config.GenerateMetric:
+			if p.generateMetric(rule, msg, content) && rule.DropMessage {
+				return false
+			}
+		case config.Route:
+			// the last matching rule chooses the destination group
+			if rule.Regex.Match(matchedContent(rule, msg, content)) {
+				msg.DestinationGroup = rule.DestinationGroup
+			}
 		}
 	}
 
@@ -285,7 +337,20 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	}
 
 	msg.SetContent(content)
-	return true // we want to send this message
+
+	// Deduplicate and throttle the processed message
+	// ----------------------------------------------
+
+	return p.applyThrottlingRules(rules, msg)
+}
+
+// matchedContent returns the content an exclusion, an inclusion or a metric
+// generation rule is matched against: the message, or one of its attributes.
+func matchedContent(rule *config.ProcessingRule, msg *message.Message, content []byte) []byte {
+	if rule.Field == "" {
+		return content
+	}
+	return messageAttribute(msg, rule.Field)
 }
 
 // GetHostname returns the hostname to applied the given log message
@@ -278,6 +278,34 @@ func TestMask(t *testing.T) {
 	}
 }
 
+func TestRoute(t *testing.T) {
+	securityRule := newProcessingRule(config.Route, "", "audit")
+	securityRule.DestinationGroup = "security"
+	p := &Processor{processingRules: []*config.ProcessingRule{securityRule}}
+
+	// the logs are sent to the destination group of their source by default
+	source := sources.LogSource{Config: &config.LogsConfig{DestinationGroup: "debug"}}
+	msg := newMessage([]byte("hello"), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "debug", msg.DestinationGroup)
+
+	msg = newMessage([]byte("audit: login"), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "security", msg.DestinationGroup)
+
+	// the rules of the source are applied after the global rules
+	defaultRule := newProcessingRule(config.Route, "", "login")
+	defaultRule.DestinationGroup = config.DefaultDestinationGroup
+	source = sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{defaultRule}}}
+	msg = newMessage([]byte("audit: login"), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, config.DefaultDestinationGroup, msg.DestinationGroup)
+
+	msg = newMessage([]byte("hello"), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, "", msg.DestinationGroup)
+}
+
 func TestTruncate(t *testing.T) {
 	p := &Processor{}
 	source := sources.NewLogSource("", &config.LogsConfig{})
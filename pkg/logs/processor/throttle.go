@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder/auto_multiline_detection/tokens"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// RepeatCountAttribute is the attribute holding the number of times a
+// deduplicated log has been repeated after its first occurrence.
+const RepeatCountAttribute = "repeat_count"
+
+// maxDuplicates bounds the number of distinct logs tracked by the
+// deduplication rules of a processor, the logs are not deduplicated once it
+// is reached.
+const maxDuplicates = 10000
+
+// throttlingFlushInterval is the interval at which the repeat counts of the
+// deduplicated logs are sent once their window is over.
+const throttlingFlushInterval = time.Second
+
+// throttling holds the state of the deduplication and throttling rules of a
+// processor, it is only accessed by the processor goroutine.
+type throttling struct {
+	clock      clock.Clock
+	tokenizer  *tokens.Tokenizer
+	duplicates map[duplicateKey]*duplicates
+	buckets    map[bucketKey]*tokenBucket
+}
+
+type duplicateKey struct {
+	rule    *config.ProcessingRule
+	source  *sources.LogSource
+	content string
+}
+
+// duplicates are the repetitions of a log during the window of a
+// deduplication rule. The first occurrence is sent, the repetitions are
+// dropped and the last one is sent with their count once the window is over.
+// Its offset is the one of its own position, and is older than the offsets
+// of the logs sent in the meantime, so the auditor ignores it.
+type duplicates struct {
+	windowEnd time.Time
+	repeated  int
+	last      *message.Message
+}
+
+type bucketKey struct {
+	rule   *config.ProcessingRule
+	source *sources.LogSource
+}
+
+// tokenBucket forwards up to the burst of its rule, and is refilled at its
+// rate limit.
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newThrottling(clock clock.Clock) throttling {
+	return throttling{
+		clock:      clock,
+		tokenizer:  tokens.NewTokenizer(),
+		duplicates: make(map[duplicateKey]*duplicates),
+		buckets:    make(map[bucketKey]*tokenBucket),
+	}
+}
+
+// applyThrottlingRules applies the deduplication and throttling rules on a
+// processed message, and returns true if it should be sent.
+func (p *Processor) applyThrottlingRules(rules []*config.ProcessingRule, msg *message.Message) bool {
+	for _, rule := range rules {
+		switch rule.Type {
+		case config.Deduplicate:
+			if rule.Regex != nil && !rule.Regex.Match(matchedContent(rule, msg, msg.GetContent())) {
+				continue
+			}
+			if !p.deduplicate(rule, msg) {
+				return false
+			}
+		case config.Throttle:
+			if rule.Regex != nil && !rule.Regex.Match(matchedContent(rule, msg, msg.GetContent())) {
+				continue
+			}
+			if !p.throttle(rule, msg) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// deduplicate returns true for the first occurrence of a log during the
+// window of the rule, and counts its repetitions, which are dropped.
+func (p *Processor) deduplicate(rule *config.ProcessingRule, msg *message.Message) bool {
+	key := duplicateKey{rule: rule, source: msg.Origin.LogSource, content: p.deduplicationKey(rule, msg)}
+	now := p.throttling.clock.Now()
+
+	if d, exists := p.throttling.duplicates[key]; exists {
+		if now.Before(d.windowEnd) {
+			d.repeated++
+			d.last = msg
+			metrics.TlmLogsDeduplicated.Inc(msg.Origin.LogSource.Name)
+			return false
+		}
+		p.sendDuplicates(key, d)
+	}
+	if len(p.throttling.duplicates) < maxDuplicates {
+		p.throttling.duplicates[key] = &duplicates{windowEnd: now.Add(rule.DeduplicationWindow())}
+	}
+	return true
+}
+
+// deduplicationKey returns the content of the message, or its structure for
+// rules matching patterns.
+func (p *Processor) deduplicationKey(rule *config.ProcessingRule, msg *message.Message) string {
+	if rule.Match != config.PatternMatch {
+		return string(msg.GetContent())
+	}
+	ts, _ := p.throttling.tokenizer.Tokenize(msg.GetContent())
+	key := make([]byte, len(ts))
+	for i, token := range ts {
+		key[i] = byte(token)
+	}
+	return string(key)
+}
+
+// flushThrottling sends the repeat counts of the logs whose window is over,
+// or of all the deduplicated logs if force is true, and forgets the token
+// buckets which are full again.
+func (p *Processor) flushThrottling(force bool) {
+	now := p.throttling.clock.Now()
+	for key, d := range p.throttling.duplicates {
+		if force || !now.Before(d.windowEnd) {
+			p.sendDuplicates(key, d)
+		}
+	}
+	for key, bucket := range p.throttling.buckets {
+		refill := time.Duration(float64(key.rule.ThrottlingBurst()) / key.rule.RateLimit * float64(time.Second))
+		if now.Sub(bucket.lastRefill) >= refill {
+			delete(p.throttling.buckets, key)
+		}
+	}
+}
+
+// sendDuplicates sends the last repetition of a deduplicated log with the
+// number of times it has been repeated during the window, if it has been.
+func (p *Processor) sendDuplicates(key duplicateKey, d *duplicates) {
+	delete(p.throttling.duplicates, key)
+	if d.repeated == 0 {
+		return
+	}
+	if data := structuredData(d.last); data != nil {
+		data[RepeatCountAttribute] = d.repeated
+	} else {
+		d.last.ProcessingTags = append(d.last.ProcessingTags, RepeatCountAttribute+":"+strconv.Itoa(d.repeated))
+	}
+	p.sendMessage(d.last)
+}
+
+// throttle returns true if the token bucket of the rule for the source of the
+// message is not empty, and takes a token from it.
+func (p *Processor) throttle(rule *config.ProcessingRule, msg *message.Message) bool {
+	key := bucketKey{rule: rule, source: msg.Origin.LogSource}
+	now := p.throttling.clock.Now()
+	burst := float64(rule.ThrottlingBurst())
+
+	bucket, exists := p.throttling.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: burst, lastRefill: now}
+		p.throttling.buckets[key] = bucket
+	}
+	elapsed := now.Sub(bucket.lastRefill).Seconds()
+	bucket.tokens = math.Min(burst, bucket.tokens+elapsed*rule.RateLimit)
+	bucket.lastRefill = now
+
+	if bucket.tokens < 1 {
+		metrics.TlmLogsThrottled.Inc(msg.Origin.LogSource.Name)
+		return false
+	}
+	bucket.tokens--
+	return true
+}
@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newThrottlingProcessor() (*Processor, *clock.Mock) {
+	hostnameComponent, _ := hostnameinterface.NewMock("testHostname")
+	clk := clock.NewMock()
+	return &Processor{
+		encoder:                   RawEncoder,
+		outputChan:                make(chan *message.Message, 10),
+		diagnosticMessageReceiver: diagnostic.NewBufferedMessageReceiver(nil, hostnameComponent),
+		throttling:                newThrottling(clk),
+	}, clk
+}
+
+func TestDeduplicate(t *testing.T) {
+	p, clk := newThrottlingProcessor()
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Deduplicate, Window: 5})
+
+	// the first occurrence is sent, the repetitions are dropped
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("connection refused"), source, "")))
+	for i := 0; i < 3; i++ {
+		assert.False(t, p.applyRedactingRules(newMessage([]byte("connection refused"), source, "")))
+	}
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("connection refused again"), source, "")))
+
+	// the repeat count is sent once the window is over, for the repeated logs
+	clk.Add(4 * time.Second)
+	p.flushThrottling(false)
+	assert.Len(t, p.outputChan, 0)
+	clk.Add(time.Second)
+	p.flushThrottling(false)
+	require.Len(t, p.outputChan, 1)
+	assert.Equal(t, []string{`{"message":"connection refused","repeat_count":3}`}, trimContents([]string{string((<-p.outputChan).GetContent())}))
+	assert.Empty(t, p.throttling.duplicates)
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("connection refused"), source, "")))
+}
+
+func TestDeduplicatePatterns(t *testing.T) {
+	p, clk := newThrottlingProcessor()
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Deduplicate, Match: config.PatternMatch, Pattern: "^request"})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("request 12 failed after 250ms"), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("request 13 failed after 251ms"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("request 14 failed: timeout"), source, "")))
+	// logs which do not match the pattern are not deduplicated
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("starting server"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("starting server"), source, "")))
+
+	// the repeat counts are sent when the processor stops
+	clk.Add(time.Second)
+	p.flushThrottling(true)
+	require.Len(t, p.outputChan, 1)
+	assert.Equal(t, []string{`{"message":"request 13 failed after 251ms","repeat_count":1}`}, trimContents([]string{string((<-p.outputChan).GetContent())}))
+}
+
+func TestDeduplicateOffsets(t *testing.T) {
+	p, clk := newThrottlingProcessor()
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Deduplicate, Pattern: "^boom"})
+	newFileMessage := func(content string, offset string) *message.Message {
+		msg := newMessage([]byte(content), source, "")
+		msg.Origin.Identifier = "file:/var/log/app.log"
+		msg.Origin.Offset = offset
+		return msg
+	}
+
+	// no log is held, the offsets are sent in order
+	assert.True(t, p.applyRedactingRules(newFileMessage("boom", "10")))
+	assert.True(t, p.applyRedactingRules(newFileMessage("ok", "20")))
+	assert.False(t, p.applyRedactingRules(newFileMessage("boom", "30")))
+	assert.True(t, p.applyRedactingRules(newFileMessage("ok", "40")))
+
+	// the repeat count keeps the offset of the last repetition, which is
+	// older than the offsets already sent and ignored by the auditor
+	clk.Add(10 * time.Second)
+	p.flushThrottling(false)
+	require.Len(t, p.outputChan, 1)
+	msg := <-p.outputChan
+	assert.Equal(t, "30", msg.Origin.Offset)
+	assert.Contains(t, string(msg.GetContent()), `"repeat_count":1`)
+}
+
+func TestFlushSendsRepeatCounts(t *testing.T) {
+	p, _ := newThrottlingProcessor()
+	p.inputChan = make(chan *message.Message, 10)
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Deduplicate})
+
+	for i := 0; i < 3; i++ {
+		p.inputChan <- newMessage([]byte("connection refused"), source, "")
+	}
+	p.Flush(context.Background())
+
+	require.Len(t, p.outputChan, 2)
+	contents := []string{string((<-p.outputChan).GetContent()), string((<-p.outputChan).GetContent())}
+	assert.Equal(t, []string{"connection refused", `{"message":"connection refused","repeat_count":2}`}, trimContents(contents))
+	assert.Empty(t, p.throttling.duplicates)
+}
+
+func TestThrottle(t *testing.T) {
+	p, clk := newThrottlingProcessor()
+	rule := &config.ProcessingRule{Type: config.Throttle, RateLimit: 2, Burst: 3}
+	source := newParsingSource(t, rule)
+	other := newParsingSource(t, rule)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, p.applyRedactingRules(newMessage([]byte("log"), source, "")))
+	}
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("log"), source, "")))
+	// the sources are throttled separately
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("log"), other, "")))
+
+	clk.Add(500 * time.Millisecond)
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("log"), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("log"), source, "")))
+
+	// full buckets are forgotten
+	clk.Add(2 * time.Second)
+	p.flushThrottling(false)
+	assert.Empty(t, p.throttling.buckets)
+}
+
+// trimContents removes the RFC 5424 header the raw encoder adds before the
+// contents of the messages.
+func trimContents(contents []string) []string {
+	trimmed := make([]string, 0, len(contents))
+	for _, content := range contents {
+		trimmed = append(trimmed, content[strings.LastIndex(content, " - ")+3:])
+	}
+	return trimmed
+}
@@ -8,6 +8,10 @@ package sender
 import (
 	"bytes"
 	"compress/gzip"
+
+	"github.com/golang/snappy"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // ContentEncoding encodes the payload
@@ -29,6 +33,19 @@ func (c *identityContentType) encode(payload []byte) ([]byte, error) {
 	return payload, nil
 }
 
+// NewCompressionContentEncoding creates the content type compressing the payload
+// with the algorithm of `kind`, either "gzip" or "snappy". `level` is only used by gzip.
+func NewCompressionContentEncoding(kind string, level int) ContentEncoding {
+	switch kind {
+	case "snappy":
+		return NewSnappyContentEncoding()
+	case "gzip", "":
+	default:
+		log.Warnf("invalid compression_kind %q detected, use 'gzip' or 'snappy'. Falling back to 'gzip'", kind)
+	}
+	return NewGzipContentEncoding(level)
+}
+
 // GzipContentEncoding encodes the payload using gzip algorithm
 type GzipContentEncoding struct {
 	level int
@@ -71,3 +88,29 @@ func (c *GzipContentEncoding) encode(payload []byte) ([]byte, error) {
 	}
 	return compressedPayload.Bytes(), nil
 }
+
+// SnappyContentEncoding encodes the payload using the framing format of snappy
+type SnappyContentEncoding struct{}
+
+// NewSnappyContentEncoding creates a new Snappy content type
+func NewSnappyContentEncoding() *SnappyContentEncoding {
+	return &SnappyContentEncoding{}
+}
+
+func (c *SnappyContentEncoding) name() string {
+	return "snappy"
+}
+
+func (c *SnappyContentEncoding) encode(payload []byte) ([]byte, error) {
+	var compressedPayload bytes.Buffer
+	snappyWriter := snappy.NewBufferedWriter(&compressedPayload)
+	_, err := snappyWriter.Write(payload)
+	if err != nil {
+		return nil, err
+	}
+	err = snappyWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	return compressedPayload.Bytes(), nil
+}
@@ -8,8 +8,10 @@ package sender
 import (
 	"bytes"
 	"compress/gzip"
+	"io"
 	"testing"
 
+	"github.com/golang/snappy"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +44,28 @@ func TestGzipContentEncodingName(t *testing.T) {
 	assert.Equal(t, NewGzipContentEncoding(gzip.BestCompression).name(), "gzip")
 }
 
+func TestSnappyContentEncoding(t *testing.T) {
+	payload := []byte("my payload")
+
+	encodedPayload, err := NewSnappyContentEncoding().encode(payload)
+	assert.Nil(t, err)
+
+	decompressedPayload, err := io.ReadAll(snappy.NewReader(bytes.NewReader(encodedPayload)))
+	assert.Nil(t, err)
+
+	assert.Equal(t, payload, decompressedPayload)
+}
+
+func TestSnappyContentEncodingName(t *testing.T) {
+	assert.Equal(t, NewSnappyContentEncoding().name(), "snappy")
+}
+
+func TestCompressionContentEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", NewCompressionContentEncoding("gzip", gzip.BestCompression).name())
+	assert.Equal(t, "snappy", NewCompressionContentEncoding("snappy", gzip.BestCompression).name())
+	assert.Equal(t, "gzip", NewCompressionContentEncoding("unknown", gzip.BestCompression).name())
+}
+
 func decompress(payload []byte) ([]byte, error) {
 	reader, err := gzip.NewReader(bytes.NewReader(payload))
 	if err != nil {
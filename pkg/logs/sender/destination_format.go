@@ -0,0 +1,346 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Content types of the payloads of the endpoints which are not Datadog intakes.
+const (
+	ndjsonContentType   = "application/x-ndjson"
+	jsonContentType     = "application/json"
+	protobufContentType = "application/x-protobuf"
+)
+
+// formattedDestination sends the payloads to an endpoint which is not a
+// Datadog intake. The batches of messages are serialized in the format of the
+// endpoint, and compressed for it only, before being sent by the wrapped
+// destination, which retries and backs off as it does for Datadog intakes.
+type formattedDestination struct {
+	client.Destination
+	serializer      Serializer
+	contentEncoding ContentEncoding
+}
+
+// NewFormattedDestination returns a destination sending the payloads to an
+// endpoint in its format.
+func NewFormattedDestination(destination client.Destination, endpoint config.Endpoint) client.Destination {
+	contentEncoding := IdentityContentType
+	if endpoint.UseCompression && endpoint.Format != config.LokiProtobufFormat {
+		contentEncoding = NewGzipContentEncoding(endpoint.CompressionLevel)
+	}
+	return &formattedDestination{
+		Destination:     destination,
+		serializer:      newFormatSerializer(endpoint),
+		contentEncoding: contentEncoding,
+	}
+}
+
+// FormatContentType returns the content type of the payloads of a format.
+func FormatContentType(format config.EndpointFormat) string {
+	switch format {
+	case config.ElasticsearchFormat, config.NDJSONFormat:
+		return ndjsonContentType
+	case config.LokiProtobufFormat:
+		return protobufContentType
+	default:
+		return jsonContentType
+	}
+}
+
+func newFormatSerializer(endpoint config.Endpoint) Serializer {
+	render := newLogRenderer(endpoint)
+	switch endpoint.Format {
+	case config.ElasticsearchFormat:
+		return newElasticsearchSerializer(endpoint.Index, render)
+	case config.LokiFormat:
+		return &lokiSerializer{render: render}
+	case config.LokiProtobufFormat:
+		return &lokiSerializer{render: render, protobuf: true}
+	default:
+		return &ndjsonSerializer{render: render}
+	}
+}
+
+// logRenderer returns the document of a log in the payloads of an endpoint.
+type logRenderer func(msg *message.Message) []byte
+
+// newLogRenderer returns the renderer of the logs of an endpoint, which
+// renders them with the template of the endpoint if it has one, or returns
+// their JSON document otherwise.
+func newLogRenderer(endpoint config.Endpoint) logRenderer {
+	if endpoint.Template == "" {
+		return (*message.Message).GetContent
+	}
+	tmpl, err := config.NewPayloadTemplate(endpoint.Template)
+	if err != nil {
+		// the templates are checked when the endpoints are loaded
+		log.Warnf("Ignoring the invalid template of the endpoint %s: %v", endpoint.Host, err)
+		return (*message.Message).GetContent
+	}
+	return func(msg *message.Message) []byte {
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, newPayloadTemplateLog(msg)); err != nil {
+			log.Debugf("Could not render a log with the template of the endpoint %s: %v", endpoint.Host, err)
+			return msg.GetContent()
+		}
+		return buffer.Bytes()
+	}
+}
+
+// newPayloadTemplateLog returns the log rendered by the templates, read from
+// the JSON document of the message.
+func newPayloadTemplateLog(msg *message.Message) config.PayloadTemplateLog {
+	var document struct {
+		Message   string `json:"message"`
+		Status    string `json:"status"`
+		Timestamp int64  `json:"timestamp"`
+		Hostname  string `json:"hostname"`
+		Service   string `json:"service"`
+		Source    string `json:"ddsource"`
+		Tags      string `json:"ddtags"`
+	}
+	content := msg.GetContent()
+	if err := json.Unmarshal(content, &document); err != nil {
+		return config.PayloadTemplateLog{
+			Message:   string(content),
+			Status:    msg.GetStatus(),
+			Timestamp: time.Unix(0, lokiTimestamp(msg)).UTC(),
+			Hostname:  msg.Hostname,
+			Tags:      msg.Tags(),
+			Document:  string(content),
+		}
+	}
+	var tags []string
+	if document.Tags != "" {
+		tags = strings.Split(document.Tags, ",")
+	}
+	return config.PayloadTemplateLog{
+		Message:   document.Message,
+		Status:    document.Status,
+		Timestamp: time.UnixMilli(document.Timestamp).UTC(),
+		Hostname:  document.Hostname,
+		Service:   document.Service,
+		Source:    document.Source,
+		Tags:      tags,
+		Document:  string(content),
+	}
+}
+
+// Start starts formatting the payloads of the input channel, and the wrapped
+// destination.
+func (d *formattedDestination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	// the formatted payloads are not buffered, so that the input of the
+	// destination is full as long as the wrapped destination is blocked
+	formatted := make(chan *message.Payload)
+	stopChan = d.Destination.Start(formatted, output, isRetrying)
+	go func() {
+		defer close(formatted)
+		for payload := range input {
+			serialized := d.serializer.Serialize(payload.Messages)
+			encoded, err := d.contentEncoding.encode(serialized)
+			if err != nil {
+				log.Warn("Encoding failed - dropping payload", err)
+				continue
+			}
+			formatted <- &message.Payload{
+				Messages:      payload.Messages,
+				Encoded:       encoded,
+				Encoding:      d.contentEncoding.name(),
+				UnencodedSize: len(serialized),
+			}
+		}
+	}()
+	return stopChan
+}
+
+// ndjsonSerializer serializes the messages as newline delimited JSON
+// documents, for webhooks.
+type ndjsonSerializer struct {
+	render logRenderer
+}
+
+// Serialize writes each message on its own line.
+func (s *ndjsonSerializer) Serialize(messages []*message.Message) []byte {
+	var buffer bytes.Buffer
+	for _, message := range messages {
+		buffer.Write(s.render(message))
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes()
+}
+
+// elasticsearchSerializer serializes the messages as the body of a request to
+// the Elasticsearch bulk API, each message is indexed as a document.
+type elasticsearchSerializer struct {
+	action []byte
+	render logRenderer
+}
+
+func newElasticsearchSerializer(index string, render logRenderer) *elasticsearchSerializer {
+	action := []byte(`{"index":{}}`)
+	if index != "" {
+		action, _ = json.Marshal(map[string]map[string]string{"index": {"_index": index}})
+	}
+	return &elasticsearchSerializer{action: action, render: render}
+}
+
+// Serialize writes an index action followed by the document of each message,
+// for example:
+// "{"message":"content1"}", "{"message":"content2"}"
+// returns, "{"index":{}}\n{"message":"content1"}\n{"index":{}}\n{"message":"content2"}\n"
+func (s *elasticsearchSerializer) Serialize(messages []*message.Message) []byte {
+	var buffer bytes.Buffer
+	for _, message := range messages {
+		buffer.Write(s.action)
+		buffer.WriteByte('\n')
+		buffer.Write(s.render(message))
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes()
+}
+
+// lokiSerializer serializes the messages as the body of a request to the Loki
+// push API, in JSON, or in snappy compressed protobuf. The messages are
+// grouped in streams by their source, service and status.
+type lokiSerializer struct {
+	render   logRenderer
+	protobuf bool
+}
+
+type lokiStream struct {
+	labels  map[string]string
+	entries []*message.Message
+}
+
+// Serialize groups the messages in streams and writes them.
+func (s *lokiSerializer) Serialize(messages []*message.Message) []byte {
+	var streams []*lokiStream
+	streamsByLabels := make(map[string]*lokiStream)
+	for _, msg := range messages {
+		labels := lokiLabels(msg)
+		key := lokiLabelsString(labels)
+		stream, exists := streamsByLabels[key]
+		if !exists {
+			stream = &lokiStream{labels: labels}
+			streamsByLabels[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, msg)
+	}
+	if s.protobuf {
+		return lokiProtobuf(streams, s.render)
+	}
+	return lokiJSON(streams, s.render)
+}
+
+func lokiLabels(msg *message.Message) map[string]string {
+	labels := map[string]string{"status": msg.GetStatus()}
+	if msg.Origin != nil {
+		if source := msg.Origin.Source(); source != "" {
+			labels["source"] = source
+		}
+		if service := msg.Origin.Service(); service != "" {
+			labels["service"] = service
+		}
+	}
+	return labels
+}
+
+// lokiLabelsString returns the labels in the Prometheus format, for example
+// `{service="web", status="info"}`.
+func lokiLabelsString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+strconv.Quote(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// lokiTimestamp returns the timestamp of a message in nanoseconds.
+func lokiTimestamp(msg *message.Message) int64 {
+	if msg.IngestionTimestamp > 0 {
+		return msg.IngestionTimestamp
+	}
+	return time.Now().UnixNano()
+}
+
+func lokiJSON(streams []*lokiStream, render logRenderer) []byte {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	payload := struct {
+		Streams []jsonStream `json:"streams"`
+	}{Streams: make([]jsonStream, 0, len(streams))}
+	for _, stream := range streams {
+		values := make([][2]string, 0, len(stream.entries))
+		for _, msg := range stream.entries {
+			values = append(values, [2]string{strconv.FormatInt(lokiTimestamp(msg), 10), string(render(msg))})
+		}
+		payload.Streams = append(payload.Streams, jsonStream{Stream: stream.labels, Values: values})
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		log.Warnf("Could not serialize the Loki payload: %v", err)
+	}
+	return encoded
+}
+
+// lokiProtobuf writes the streams as a PushRequest message of the Loki
+// protobuf API:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func lokiProtobuf(streams []*lokiStream, render logRenderer) []byte {
+	var request []byte
+	for _, stream := range streams {
+		var adapter []byte
+		adapter = protowire.AppendTag(adapter, 1, protowire.BytesType)
+		adapter = protowire.AppendString(adapter, lokiLabelsString(stream.labels))
+		for _, msg := range stream.entries {
+			ts := lokiTimestamp(msg)
+			var timestamp []byte
+			timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(ts/int64(time.Second)))
+			timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(ts%int64(time.Second)))
+
+			var entry []byte
+			entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, timestamp)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, render(msg))
+
+			adapter = protowire.AppendTag(adapter, 2, protowire.BytesType)
+			adapter = protowire.AppendBytes(adapter, entry)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, adapter)
+	}
+	// Loki expects the block format of snappy, not its framing format
+	return snappy.Encode(nil, request)
+}
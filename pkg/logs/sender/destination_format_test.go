@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newFormatMessages() []*message.Message {
+	web := message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Source: "nginx", Service: "web"}))
+	db := message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Source: "postgres"}))
+	return []*message.Message{
+		message.NewMessage([]byte(`{"message":"a"}`), web, message.StatusInfo, 1700000000123456789),
+		message.NewMessage([]byte(`{"message":"b"}`), db, message.StatusError, 1700000001000000000),
+		message.NewMessage([]byte(`{"message":"c"}`), web, message.StatusInfo, 1700000002000000000),
+	}
+}
+
+func TestNDJSONSerializer(t *testing.T) {
+	payload := newFormatSerializer(config.Endpoint{Format: config.NDJSONFormat}).Serialize(newFormatMessages())
+	assert.Equal(t, "{\"message\":\"a\"}\n{\"message\":\"b\"}\n{\"message\":\"c\"}\n", string(payload))
+}
+
+func TestElasticsearchSerializer(t *testing.T) {
+	messages := newFormatMessages()[:2]
+
+	payload := newFormatSerializer(config.Endpoint{Format: config.ElasticsearchFormat}).Serialize(messages)
+	assert.Equal(t, "{\"index\":{}}\n{\"message\":\"a\"}\n{\"index\":{}}\n{\"message\":\"b\"}\n", string(payload))
+
+	payload = newFormatSerializer(config.Endpoint{Format: config.ElasticsearchFormat, Index: "logs"}).Serialize(messages)
+	assert.Equal(t, "{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"a\"}\n{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"b\"}\n", string(payload))
+}
+
+func TestTemplateSerializer(t *testing.T) {
+	origin := message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Source: "nginx"}))
+	messages := []*message.Message{
+		message.NewMessage([]byte(`{"message":"GET /","status":"info","timestamp":1700000000123,"hostname":"web-1","service":"web","ddsource":"nginx","ddtags":"env:prod,team:a"}`), origin, message.StatusInfo, 0),
+		// not a JSON document, the log is read from the message
+		message.NewMessage([]byte(`raw line`), origin, message.StatusError, 1700000001000000000),
+	}
+
+	endpoint := config.Endpoint{
+		Format:   config.ElasticsearchFormat,
+		Template: `{"@timestamp":{{json .Timestamp}},"message":{{json .Message}},"host":{{json .Hostname}},"level":{{json .Status}},"tags":{{json .Tags}}}`,
+	}
+	payload := newFormatSerializer(endpoint).Serialize(messages)
+	assert.Equal(t, `{"index":{}}
+{"@timestamp":"2023-11-14T22:13:20.123Z","message":"GET /","host":"web-1","level":"info","tags":["env:prod","team:a"]}
+{"index":{}}
+{"@timestamp":"2023-11-14T22:13:21Z","message":"raw line","host":"","level":"error","tags":null}
+`, string(payload))
+
+	endpoint = config.Endpoint{Format: config.NDJSONFormat, Template: `{{.Service}}: {{.Message}}`}
+	payload = newFormatSerializer(endpoint).Serialize(messages)
+	assert.Equal(t, "web: GET /\n: raw line\n", string(payload))
+}
+
+func TestLokiJSONSerializer(t *testing.T) {
+	payload := newFormatSerializer(config.Endpoint{Format: config.LokiFormat}).Serialize(newFormatMessages())
+	assert.JSONEq(t, `{"streams":[
+		{"stream":{"source":"nginx","service":"web","status":"info"},"values":[
+			["1700000000123456789","{\"message\":\"a\"}"],
+			["1700000002000000000","{\"message\":\"c\"}"]]},
+		{"stream":{"source":"postgres","status":"error"},"values":[
+			["1700000001000000000","{\"message\":\"b\"}"]]}]}`, string(payload))
+}
+
+// protoFields returns the values of the fields of a protobuf message, by field
+// number, varints are returned as their value.
+func protoFields(t *testing.T, b []byte) map[protowire.Number][]interface{} {
+	fields := make(map[protowire.Number][]interface{})
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		default:
+			require.Fail(t, "unexpected wire type", typ)
+		}
+	}
+	return fields
+}
+
+func TestLokiProtobufSerializer(t *testing.T) {
+	payload := newFormatSerializer(config.Endpoint{Format: config.LokiProtobufFormat}).Serialize(newFormatMessages())
+	request, err := snappy.Decode(nil, payload)
+	require.NoError(t, err)
+
+	streams := protoFields(t, request)[1]
+	require.Len(t, streams, 2)
+
+	web := protoFields(t, streams[0].([]byte))
+	assert.Equal(t, `{service="web", source="nginx", status="info"}`, string(web[1][0].([]byte)))
+	require.Len(t, web[2], 2)
+	entry := protoFields(t, web[2][0].([]byte))
+	assert.Equal(t, `{"message":"a"}`, string(entry[2][0].([]byte)))
+	timestamp := protoFields(t, entry[1][0].([]byte))
+	assert.Equal(t, uint64(1700000000), timestamp[1][0])
+	assert.Equal(t, uint64(123456789), timestamp[2][0])
+
+	db := protoFields(t, streams[1].([]byte))
+	assert.Equal(t, `{source="postgres", status="error"}`, string(db[1][0].([]byte)))
+	require.Len(t, db[2], 1)
+}
+
+type captureDestination struct {
+	input chan *message.Payload
+}
+
+func (d *captureDestination) IsMRF() bool    { return false }
+func (d *captureDestination) Target() string { return "capture" }
+func (d *captureDestination) Start(input chan *message.Payload, _ chan *message.Payload, _ chan bool) <-chan struct{} {
+	d.input = input
+	return make(chan struct{})
+}
+
+func TestFormattedDestination(t *testing.T) {
+	captured := &captureDestination{}
+	endpoint := config.Endpoint{Format: config.NDJSONFormat, UseCompression: true, CompressionLevel: 6}
+	destination := NewFormattedDestination(captured, endpoint)
+
+	input := make(chan *message.Payload, 1)
+	destination.Start(input, nil, nil)
+	messages := newFormatMessages()
+	input <- &message.Payload{Messages: messages, Encoded: []byte("[datadog payload]"), Encoding: "identity"}
+
+	payload := <-captured.input
+	assert.Equal(t, messages, payload.Messages)
+	assert.Equal(t, "gzip", payload.Encoding)
+	reader, err := gzip.NewReader(bytes.NewReader(payload.Encoded))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"message\":\"a\"}\n{\"message\":\"b\"}\n{\"message\":\"c\"}\n", string(decoded))
+	assert.Equal(t, len(decoded), payload.UnencodedSize)
+
+	// the formatted payloads are closed with the input
+	close(input)
+	_, isOpen := <-captured.input
+	assert.False(t, isOpen)
+}
+
+func TestFormatContentType(t *testing.T) {
+	assert.Equal(t, "application/x-ndjson", FormatContentType(config.ElasticsearchFormat))
+	assert.Equal(t, "application/x-ndjson", FormatContentType(config.NDJSONFormat))
+	assert.Equal(t, "application/json", FormatContentType(config.LokiFormat))
+	assert.Equal(t, "application/x-protobuf", FormatContentType(config.LokiProtobufFormat))
+}
@@ -173,6 +173,12 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Protocol"] = c.SyslogProtocol()
+		if c.Port != 0 {
+			dictionary["Port"] = c.Port
+		}
+		dictionary["Path"] = c.Path
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Compression formats of the compressed log files
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// CompressionFromPath returns the compression format of a file from its
+// extension, or an empty string if it is not compressed.
+func CompressionFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return Gzip
+	case ".zst":
+		return Zstd
+	default:
+		return ""
+	}
+}
+
+// newDecompressor returns a reader of the decompressed content of r.
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		return zstd.NewReader(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+}
+
+// IsCompressed returns true if the tailed file is compressed.
+func (t *Tailer) IsCompressed() bool {
+	return t.compression != ""
+}
+
+// IsFullyRead returns true if the tailer has read its compressed file until
+// its end, or was started at its end. Compressed files are not written
+// anymore, they do not need to be tailed again.
+func (t *Tailer) IsFullyRead() bool {
+	return t.isFullyRead.Load()
+}
+
+// setupCompressed opens the compressed file. Offsets are in the uncompressed
+// content, the decompressed bytes before the offset are skipped by the first
+// read, since compressed streams cannot be seeked.
+func (t *Tailer) setupCompressed(offset int64, whence int) error {
+	log.Info("Opening compressed file", t.file.Path, "for tailer key", t.file.GetScanKey())
+	f, err := filesystem.OpenShared(t.fullpath)
+	if err != nil {
+		return err
+	}
+	t.setupFingerprint(f)
+	decompressor, err := newDecompressor(f, t.compression)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("could not decompress %q: %w", t.file.Path, err)
+	}
+	t.osFile = f
+	t.decompressor = decompressor
+
+	if whence == io.SeekStart {
+		t.skippedBytes = offset
+	} else {
+		// tailing from the end of a compressed file reads nothing
+		t.startedAtEnd = true
+		offset = 0
+	}
+	t.lastReadOffset.Store(offset)
+	t.decodedOffset.Store(offset)
+	return nil
+}
+
+// readCompressed reads the decompressed content of the file, it returns
+// io.EOF once the whole file has been read.
+func (t *Tailer) readCompressed() (int, error) {
+	if t.startedAtEnd {
+		t.isFullyRead.Store(true)
+		return 0, io.EOF
+	}
+	if t.skippedBytes > 0 {
+		_, err := io.CopyN(io.Discard, t.decompressor, t.skippedBytes)
+		t.skippedBytes = 0
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Debugf("Compressed file %s ends unexpectedly: %v", t.file.Path, err)
+			return 0, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.file.Source.Status().Error(err)
+			return 0, log.Error("Unexpected error occurred while decompressing file: ", err)
+		}
+	}
+
+	inBuf := make([]byte, 4096)
+	n, err := t.decompressor.Read(inBuf)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// the file may still be being compressed, the tailer is restarted
+		// from the last offset by the next scan
+		log.Debugf("Compressed file %s ends unexpectedly: %v", t.file.Path, err)
+		return 0, err
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		t.file.Source.Status().Error(err)
+		return 0, log.Error("Unexpected error occurred while decompressing file: ", err)
+	}
+	if n > 0 {
+		t.lastReadOffset.Add(int64(n))
+		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+		return n, nil
+	}
+	if err != nil {
+		log.Debugf("Read compressed file %s until its end", t.file.Path)
+		t.isFullyRead.Store(true)
+		return 0, err
+	}
+	return 0, nil
+}
+
+// compressedChecksum returns the checksum of the first `size` decompressed
+// bytes of the file, so that a compressed file is identified as the file it
+// was compressed from.
+func compressedChecksum(f *os.File, size int, compression string) (string, error) {
+	decompressor, err := newDecompressor(io.NewSectionReader(f, 0, math.MaxInt64), compression)
+	if err != nil {
+		return "", fmt.Errorf("could not decompress %q: %w", f.Name(), err)
+	}
+	defer decompressor.Close()
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(decompressor, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", nil
+		}
+		return "", fmt.Errorf("could not decompress %q: %w", f.Name(), err)
+	}
+	return checksum(buf), nil
+}
@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+var compressedLines = []string{"hello world\n", "hello again\n", "good bye\n"}
+
+func writeCompressedFile(t *testing.T, path string, content []byte) {
+	var compressed []byte
+	switch CompressionFromPath(path) {
+	case Gzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		compressed = buf.Bytes()
+	case Zstd:
+		var err error
+		compressed, err = zstd.Compress(nil, content)
+		require.NoError(t, err)
+	default:
+		compressed = content
+	}
+	require.NoError(t, os.WriteFile(path, compressed, 0644))
+}
+
+func newCompressedTailer(path string, outputChan chan *message.Message) *Tailer {
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	tailer := NewTailer(&TailerOptions{
+		OutputChan:    outputChan,
+		File:          NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration: 10 * time.Millisecond,
+		Decoder:       decoder.NewDecoderFromSource(source, info),
+		Info:          info,
+	})
+	tailer.closeTimeout = closeTimeout
+	return tailer
+}
+
+func TestCompressionFromPath(t *testing.T) {
+	assert.Equal(t, Gzip, CompressionFromPath("/var/log/app.log.1.gz"))
+	assert.Equal(t, Gzip, CompressionFromPath("/var/log/app.log.GZ"))
+	assert.Equal(t, Zstd, CompressionFromPath("/var/log/app.log.zst"))
+	assert.Equal(t, "", CompressionFromPath("/var/log/app.log"))
+	assert.Equal(t, "", CompressionFromPath("/var/log/app.gz.log"))
+}
+
+func TestTailCompressedFiles(t *testing.T) {
+	for _, name := range []string{"app.log.1.gz", "app.log.1.zst"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			writeCompressedFile(t, path, []byte(compressedLines[0]+compressedLines[1]+compressedLines[2]))
+
+			outputChan := make(chan *message.Message, chanSize)
+			tailer := newCompressedTailer(path, outputChan)
+			assert.True(t, tailer.IsCompressed())
+			require.NoError(t, tailer.StartFromBeginning())
+
+			offset := 0
+			for _, line := range compressedLines {
+				msg := <-outputChan
+				offset += len(line)
+				assert.Equal(t, line[:len(line)-1], string(msg.GetContent()))
+				assert.Equal(t, offset, toInt(msg.Origin.Offset))
+			}
+
+			// the tailer stops at the end of the file
+			assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+			assert.True(t, tailer.IsFullyRead())
+			tailer.Stop()
+		})
+	}
+}
+
+func TestTailCompressedFileFromOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeCompressedFile(t, path, []byte(compressedLines[0]+compressedLines[1]+compressedLines[2]))
+
+	outputChan := make(chan *message.Message, chanSize)
+	tailer := newCompressedTailer(path, outputChan)
+	require.NoError(t, tailer.Start(int64(len(compressedLines[0])), io.SeekStart))
+	defer tailer.Stop()
+
+	msg := <-outputChan
+	assert.Equal(t, "hello again", string(msg.GetContent()))
+	assert.Equal(t, len(compressedLines[0])+len(compressedLines[1]), toInt(msg.Origin.Offset))
+	msg = <-outputChan
+	assert.Equal(t, "good bye", string(msg.GetContent()))
+	assert.Eventually(t, tailer.IsFullyRead, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTailCompressedFileFromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.zst")
+	writeCompressedFile(t, path, []byte(compressedLines[0]))
+
+	outputChan := make(chan *message.Message, chanSize)
+	tailer := newCompressedTailer(path, outputChan)
+	require.NoError(t, tailer.Start(0, io.SeekEnd))
+	defer tailer.Stop()
+
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, tailer.IsFullyRead())
+	assert.Len(t, outputChan, 0)
+}
+
+func TestTailTruncatedCompressedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log.1.gz")
+	writeCompressedFile(t, path, []byte(compressedLines[0]+compressedLines[1]))
+	compressed, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// the file is still being compressed, its trailer is missing
+	require.NoError(t, os.WriteFile(path, compressed[:len(compressed)-4], 0644))
+
+	outputChan := make(chan *message.Message, chanSize)
+	tailer := newCompressedTailer(path, outputChan)
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, tailer.IsFullyRead())
+}
+
+func TestCompressedFileFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte(compressedLines[0] + compressedLines[1])
+	plain := filepath.Join(dir, "app.log.1")
+	require.NoError(t, os.WriteFile(plain, content, 0644))
+	plainFingerprint, err := ComputeFingerprint(plain, 16)
+	require.NoError(t, err)
+
+	// compressed files are identified as the file they were compressed from
+	for _, name := range []string{"app.log.1.gz", "app.log.1.zst"} {
+		path := filepath.Join(dir, name)
+		writeCompressedFile(t, path, content)
+		fingerprint, err := ComputeFingerprint(path, 16)
+		require.NoError(t, err)
+		assert.Equal(t, plainFingerprint.Checksum, fingerprint.Checksum, name)
+		assert.True(t, fingerprint.Matches(plainFingerprint.String()), name)
+	}
+
+	// compressed files too short to be checksummed
+	path := filepath.Join(dir, "short.log.gz")
+	writeCompressedFile(t, path, []byte("hello\n"))
+	fingerprint, err := ComputeFingerprint(path, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "", fingerprint.Checksum)
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// Fingerprint identifies a file independently of its path, so that its offset
+// can be recovered after it has been renamed, and so that a new file written
+// at the same path is not mistaken for the previous one.
+//
+// The file is identified by a checksum of its first bytes. Files too short to
+// be checksummed are identified by their device and inode (or volume and file
+// index on Windows) instead.
+type Fingerprint struct {
+	// Checksum is the checksum of the first bytes of the file, empty if the
+	// file is shorter than the fingerprint size.
+	Checksum string
+	// FileID identifies the file on its filesystem, empty if it could not be
+	// retrieved.
+	FileID string
+}
+
+// IsEmpty returns true if the file could not be fingerprinted, or if
+// fingerprinting is disabled.
+func (f Fingerprint) IsEmpty() bool {
+	return f.Checksum == "" && f.FileID == ""
+}
+
+// String returns the fingerprint stored in the registry, the checksum if there is
+// one, the file ID otherwise.
+func (f Fingerprint) String() string {
+	if f.Checksum != "" {
+		return f.checksumKey()
+	}
+	return f.fileIDKey()
+}
+
+// Keys returns the fingerprints the file may have been stored in the registry
+// with, from the most to the least reliable.
+func (f Fingerprint) Keys() []string {
+	var keys []string
+	if f.Checksum != "" {
+		keys = append(keys, f.checksumKey())
+	}
+	if f.FileID != "" {
+		keys = append(keys, f.fileIDKey())
+	}
+	return keys
+}
+
+// Matches returns true if a fingerprint stored in the registry identifies this
+// file. A file may have been stored with its file ID while it was too short to
+// be checksummed.
+func (f Fingerprint) Matches(stored string) bool {
+	for _, key := range f.Keys() {
+		if stored == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Fingerprint) checksumKey() string {
+	return "checksum:" + f.Checksum
+}
+
+func (f Fingerprint) fileIDKey() string {
+	if f.FileID == "" {
+		return ""
+	}
+	return "fileid:" + f.FileID
+}
+
+// ComputeFingerprint returns the fingerprint of the file at path, using a
+// checksum of its first `size` bytes. Fingerprinting is disabled when size is 0.
+func ComputeFingerprint(path string, size int) (Fingerprint, error) {
+	if size <= 0 {
+		return Fingerprint{}, nil
+	}
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer f.Close()
+	return fingerprintFile(f, size)
+}
+
+// fingerprintFile returns the fingerprint of an open file, without changing its
+// read offset.
+func fingerprintFile(f *os.File, size int) (Fingerprint, error) {
+	if size <= 0 {
+		return Fingerprint{}, nil
+	}
+	fileID, err := fileIdentity(f)
+	if err != nil {
+		return Fingerprint{}, fmt.Errorf("could not identify %q: %w", f.Name(), err)
+	}
+	checksum, err := fileChecksum(f, size)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{Checksum: checksum, FileID: fileID}, nil
+}
+
+// fileChecksum returns the checksum of the first `size` bytes of the file, or an
+// empty string if the file is shorter.
+func fileChecksum(f *os.File, size int) (string, error) {
+	if compression := CompressionFromPath(f.Name()); compression != "" {
+		return compressedChecksum(f, size, compression)
+	}
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, 0)
+	if n < size {
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("could not read %q: %w", f.Name(), err)
+		}
+		return "", nil
+	}
+	return checksum(buf), nil
+}
+
+// checksum returns the checksum of the first bytes of a file.
+func checksum(buf []byte) string {
+	return fmt.Sprintf("%016x", crc64.Checksum(buf, crc64Table))
+}
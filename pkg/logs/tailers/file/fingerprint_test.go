@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("hello\n"), 0644))
+
+	// too short to be checksummed
+	short, err := ComputeFingerprint(path, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "", short.Checksum)
+	assert.NotEqual(t, "", short.FileID)
+	assert.Equal(t, "fileid:"+short.FileID, short.String())
+
+	require.NoError(t, os.WriteFile(path, []byte("hello world\nhello again\n"), 0644))
+	fingerprint, err := ComputeFingerprint(path, 16)
+	require.NoError(t, err)
+	assert.NotEqual(t, "", fingerprint.Checksum)
+	assert.Equal(t, short.FileID, fingerprint.FileID)
+	assert.Equal(t, "checksum:"+fingerprint.Checksum, fingerprint.String())
+	assert.True(t, fingerprint.Matches(short.String()))
+
+	// the fingerprint does not depend on the path, nor on the bytes past its size
+	renamed := filepath.Join(dir, "app.log.1")
+	require.NoError(t, os.Rename(path, renamed))
+	require.NoError(t, os.WriteFile(path, []byte("hello world\nhello"), 0644))
+	renamedFingerprint, err := ComputeFingerprint(renamed, 16)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, renamedFingerprint)
+	recreated, err := ComputeFingerprint(path, 16)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint.Checksum, recreated.Checksum)
+	assert.NotEqual(t, fingerprint.FileID, recreated.FileID)
+
+	// the checksum differs when the beginning of the file differs
+	require.NoError(t, os.WriteFile(path, []byte("good bye world\ngood bye again\n"), 0644))
+	other, err := ComputeFingerprint(path, 16)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint.Checksum, other.Checksum)
+	assert.False(t, fingerprint.Matches(other.String()))
+
+	// fingerprinting is disabled
+	disabled, err := ComputeFingerprint(path, 0)
+	require.NoError(t, err)
+	assert.True(t, disabled.IsEmpty())
+	assert.Equal(t, "", disabled.String())
+	assert.False(t, disabled.Matches(""))
+}
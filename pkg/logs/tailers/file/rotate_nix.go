@@ -21,6 +21,7 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
+// - truncated and written again past the last read offset
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
@@ -42,13 +43,17 @@ func (t *Tailer) DidRotate() (bool, error) {
 	fileSize := fi1.Size()
 
 	recreated := !os.SameFile(fi1, fi2)
-	truncated := fileSize < lastReadOffset
+	// the offsets of compressed files are in their uncompressed content
+	truncated := !t.IsCompressed() && fileSize < lastReadOffset
+	rewritten := !recreated && !truncated && t.didContentChange(f)
 
 	if recreated {
 		log.Debugf("File rotation detected due to recreation, f1: %+v, f2: %+v", fi1, fi2)
 	} else if truncated {
 		log.Debugf("File rotation detected due to size change, lastReadOffset=%d, fileSize=%d", lastReadOffset, fileSize)
+	} else if rewritten {
+		log.Debugf("File rotation detected due to content change, lastReadOffset=%d, fileSize=%d", lastReadOffset, fileSize)
 	}
 
-	return recreated || truncated, nil
+	return recreated || truncated || rewritten, nil
 }
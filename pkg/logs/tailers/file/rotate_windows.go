@@ -17,7 +17,8 @@ import (
 // DidRotate returns true if the file has been log-rotated.
 //
 // On Windows, log rotation is identified by the file size being smaller
-// than the last offset read.
+// than the last offset read, or by the beginning of the file being different
+// from the beginning of the tailed file.
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
@@ -38,10 +39,16 @@ func (t *Tailer) DidRotate() (bool, error) {
 	// polled before the offset.
 	sz := st.Size()
 
-	if sz < offset {
+	// the offsets of compressed files are in their uncompressed content
+	if !t.IsCompressed() && sz < offset {
 		log.Debugf("File rotation detected due to size change, lastReadOffset=%d, fileSize=%d", offset, sz)
 		return true, nil
 	}
 
+	if t.didContentChange(f) {
+		log.Debugf("File rotation detected due to content change, lastReadOffset=%d, fileSize=%d", offset, sz)
+		return true, nil
+	}
+
 	return false, nil
 }
@@ -58,10 +58,31 @@ type Tailer struct {
 	// fullpath is the absolute path to file.Path.
 	fullpath string
 
+	// fingerprintSize is the number of bytes at the beginning of the file used to
+	// fingerprint it, 0 if fingerprinting is disabled.
+	fingerprintSize int
+
+	// fingerprint identifies the tailed file in the registry independently of its
+	// path. It is nil until it is computed.
+	fingerprint *atomic.Pointer[Fingerprint]
+
 	// osFile is the os.File object from which log data is read.  The read implementation
 	// is platform-specific.
 	osFile *os.File
 
+	// compression is the compression format of the file, empty if it is not
+	// compressed. Compressed files are read through decompressor, which is
+	// not platform-specific, and their offsets are in the uncompressed content.
+	compression  string
+	decompressor io.ReadCloser
+	// skippedBytes is the number of decompressed bytes to skip to reach the
+	// start offset, startedAtEnd is true if the tailer starts at the end of
+	// the compressed file.
+	skippedBytes int64
+	startedAtEnd bool
+	// isFullyRead is true when the compressed file has been read until its end.
+	isFullyRead *atomic.Bool
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -152,6 +173,7 @@ func NewTailer(opts *TailerOptions) *Tailer {
 	forwardContext, stopForward := context.WithCancel(context.Background())
 	closeTimeout := pkgconfigsetup.Datadog().GetDuration("logs_config.close_timeout") * time.Second
 	windowsOpenFileTimeout := pkgconfigsetup.Datadog().GetDuration("logs_config.windows_open_file_timeout") * time.Second
+	fingerprintSize := pkgconfigsetup.Datadog().GetInt("logs_config.file_fingerprint_size")
 
 	bytesRead := status.NewCountInfo("Bytes Read")
 	fileRotated := opts.Rotated
@@ -173,6 +195,10 @@ func NewTailer(opts *TailerOptions) *Tailer {
 		sleepDuration:          opts.SleepDuration,
 		closeTimeout:           closeTimeout,
 		windowsOpenFileTimeout: windowsOpenFileTimeout,
+		fingerprintSize:        fingerprintSize,
+		fingerprint:            atomic.NewPointer[Fingerprint](nil),
+		compression:            CompressionFromPath(opts.File.Path),
+		isFullyRead:            atomic.NewBool(false),
 		stop:                   make(chan struct{}, 1),
 		done:                   make(chan struct{}, 1),
 		forwardContext:         forwardContext,
@@ -226,6 +252,63 @@ func (t *Tailer) Identifier() string {
 	return fmt.Sprintf("file:%s", t.file.Path)
 }
 
+// Fingerprint returns the fingerprint of the tailed file. Before the tailer is
+// started, it is computed from the file found at its path.
+func (t *Tailer) Fingerprint() Fingerprint {
+	if fingerprint := t.fingerprint.Load(); fingerprint != nil {
+		return *fingerprint
+	}
+	fingerprint, err := ComputeFingerprint(t.file.Path, t.fingerprintSize)
+	if err != nil {
+		log.Debugf("Could not fingerprint %s: %v", t.file.Path, err)
+		return Fingerprint{}
+	}
+	t.fingerprint.Store(&fingerprint)
+	return fingerprint
+}
+
+// setupFingerprint fingerprints the opened file, unless its fingerprint was
+// already computed.
+func (t *Tailer) setupFingerprint(f *os.File) {
+	if t.fingerprint.Load() != nil {
+		return
+	}
+	fingerprint, err := fingerprintFile(f, t.fingerprintSize)
+	if err != nil {
+		log.Debugf("Could not fingerprint %s: %v", t.file.Path, err)
+	}
+	t.fingerprint.Store(&fingerprint)
+}
+
+// didContentChange returns true if the file found at the tailed path does not
+// start with the same bytes as the tailed file, which happens when a file is
+// truncated and written again past the last read offset between two scans.
+//
+// A file too short to be checksummed when the tailer started is checksummed
+// once it is long enough.
+func (t *Tailer) didContentChange(f *os.File) bool {
+	current := t.fingerprint.Load()
+	if current == nil || t.fingerprintSize <= 0 {
+		return false
+	}
+	if current.Checksum == "" {
+		fileID, err := fileIdentity(f)
+		if err != nil || fileID == "" || fileID != current.FileID {
+			return false
+		}
+		checksum, err := fileChecksum(f, t.fingerprintSize)
+		if err == nil && checksum != "" {
+			t.fingerprint.Store(&Fingerprint{Checksum: checksum, FileID: fileID})
+		}
+		return false
+	}
+	checksum, err := fileChecksum(f, t.fingerprintSize)
+	if err != nil {
+		return false
+	}
+	return checksum != current.Checksum
+}
+
 // Start begins the tailer's operation in a dedicated goroutine.
 func (t *Tailer) Start(offset int64, whence int) error {
 	err := t.setup(offset, whence)
@@ -286,6 +369,9 @@ func (t *Tailer) StopAfterFileRotation() {
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
 	defer func() {
+		if t.decompressor != nil {
+			t.decompressor.Close()
+		}
 		t.osFile.Close()
 		t.decoder.Stop()
 		log.Info("Closed", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.Source().BytesRead.Get(), "bytes and", t.decoder.GetLineCount(), "lines")
@@ -349,6 +435,9 @@ func (t *Tailer) forwardMessages() {
 		origin := message.NewOrigin(t.file.Source.UnderlyingSource())
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		if fingerprint := t.fingerprint.Load(); fingerprint != nil && identifier != "" {
+			origin.Fingerprint = fingerprint.String()
+		}
 
 		tags := make([]string, len(t.tags))
 		copy(tags, t.tags)
@@ -450,6 +450,64 @@ func (suite *TailerTestSuite) TestDidRotateNilFullpath() {
 	}, "Agent should not have panicked due to empty file path")
 }
 
+func (suite *TailerTestSuite) TestOriginFingerprint() {
+	suite.tailer.fingerprintSize = 16
+	_, err := suite.testFile.WriteString("hello world\nhello again\n")
+	suite.Nil(err)
+
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	fingerprint := suite.tailer.Fingerprint()
+	suite.NotEqual("", fingerprint.Checksum)
+	msg := <-suite.outputChan
+	suite.Equal("checksum:"+fingerprint.Checksum, msg.Origin.Fingerprint)
+}
+
+func (suite *TailerTestSuite) TestDidRotateAfterRewrite() {
+	suite.tailer.fingerprintSize = 16
+	_, err := suite.testFile.WriteString("hello world\nhello again\n")
+	suite.Nil(err)
+
+	suite.Nil(suite.tailer.StartFromBeginning())
+	<-suite.outputChan
+	<-suite.outputChan
+
+	didRotate, err := suite.tailer.DidRotate()
+	suite.Nil(err)
+	suite.False(didRotate)
+
+	// copytruncate, then more logs than were read are written before the next scan
+	suite.Nil(suite.testFile.Truncate(0))
+	_, err = suite.testFile.WriteAt([]byte("good bye world\ngood bye again\ngood bye\n"), 0)
+	suite.Nil(err)
+
+	didRotate, err = suite.tailer.DidRotate()
+	suite.Nil(err)
+	suite.True(didRotate)
+}
+
+func (suite *TailerTestSuite) TestFingerprintOfShortFile() {
+	suite.tailer.fingerprintSize = 16
+	_, err := suite.testFile.WriteString("hello\n")
+	suite.Nil(err)
+
+	suite.Nil(suite.tailer.StartFromBeginning())
+	<-suite.outputChan
+
+	fingerprint := suite.tailer.Fingerprint()
+	suite.Equal("", fingerprint.Checksum)
+	suite.NotEqual("", fingerprint.FileID)
+
+	// the file is checksummed once it is long enough
+	_, err = suite.testFile.WriteString("hello world\nhello again\n")
+	suite.Nil(err)
+	didRotate, err := suite.tailer.DidRotate()
+	suite.Nil(err)
+	suite.False(didRotate)
+	suite.NotEqual("", suite.tailer.Fingerprint().Checksum)
+	suite.Equal(fingerprint.FileID, suite.tailer.Fingerprint().FileID)
+}
+
 func toInt(str string) int {
 	if value, err := strconv.ParseInt(str, 10, 64); err == nil {
 		return int(value)
@@ -29,11 +29,16 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	// adds metadata to enable users to filter logs by filename
 	t.tags = t.buildTailerTags()
 
+	if t.IsCompressed() {
+		return t.setupCompressed(offset, whence)
+	}
+
 	log.Info("Opening ", t.fullpath)
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return err
 	}
+	t.setupFingerprint(f)
 	filePos, _ := f.Seek(offset, whence)
 	f.Close()
 
@@ -123,6 +128,10 @@ func (t *Tailer) readAvailable() (int, error) {
 // windows version open and close the file between each call to 'read'. This is
 // needed in order not to block the file and prevent the user from renaming it.
 func (t *Tailer) read() (int, error) {
+	if t.IsCompressed() {
+		// compressed files are not written anymore, they are kept open
+		return t.readCompressed()
+	}
 	n, err := t.readAvailable()
 	if err == io.EOF || os.IsNotExist(err) {
 		return n, nil
@@ -41,6 +41,35 @@ func TestReadAndForwardShouldSucceedWithSuccessfulRead(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestReadAndForwardSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	processRawMessage := false
+	logsConfig := &config.LogsConfig{Type: config.SyslogType, ProcessRawMessage: &processRawMessage}
+	tailer := NewTailer(sources.NewLogSource("", logsConfig), r, msgChan, read)
+	tailer.Start()
+
+	var msg *message.Message
+
+	// should receive and parse octet-counted and newline-delimited messages
+	w.Write([]byte("26 <11>1 - host app - - - foo<12>Oct 11 22:14:15 host app: bar\n"))
+	msg = <-msgChan
+	assert.Equal(t, "foo", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, message.StateStructured, msg.State)
+	msg = <-msgChan
+	assert.Equal(t, "bar", string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.Status)
+
+	// should forward messages which are not syslog as-is
+	w.Write([]byte("boo\n"))
+	msg = <-msgChan
+	assert.Equal(t, "boo", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+
+	tailer.Stop()
+}
+
 func TestReadShouldFailWithError(t *testing.T) {
 	msgChan := make(chan *message.Message)
 	r, w := net.Pipe()
@@ -68,10 +68,16 @@ func NewCheckMetrics(expireMetrics bool, statefulTimeout time.Duration) CheckMet
 //
 // See also ContextMetrics.AddSample().
 func (cm *CheckMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, config pkgconfigmodel.Config) error {
+	return cm.AddSampleWithHistogramConfig(contextKey, sample, timestamp, interval, config, nil)
+}
+
+// AddSampleWithHistogramConfig is like AddSample, the histograms it initializes
+// use histogramConfig instead of the global configuration when it's not nil.
+func (cm *CheckMetrics) AddSampleWithHistogramConfig(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, config pkgconfigmodel.Config, histogramConfig *HistogramConfig) error {
 	if cm.deadlines != nil {
 		delete(cm.deadlines, contextKey)
 	}
-	return cm.metrics.AddSample(contextKey, sample, timestamp, interval, checkMetricsAddSampleTelemetry, config)
+	return cm.metrics.AddSampleWithHistogramConfig(contextKey, sample, timestamp, interval, checkMetricsAddSampleTelemetry, config, histogramConfig)
 }
 
 // Expire enables metric data for given context keys to be removed.
@@ -44,6 +44,12 @@ func (a *AddSampleTelemetry) Inc(isStateful bool) {
 
 // AddSample add a sample to the current ContextMetrics and initialize a new metrics if needed.
 func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry, config pkgconfigmodel.Config) error {
+	return m.AddSampleWithHistogramConfig(contextKey, sample, timestamp, interval, t, config, nil)
+}
+
+// AddSampleWithHistogramConfig is like AddSample, the histograms it initializes
+// use histogramConfig instead of the global configuration when it's not nil.
+func (m ContextMetrics) AddSampleWithHistogramConfig(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry, config pkgconfigmodel.Config, histogramConfig *HistogramConfig) error {
 	if math.IsInf(sample.Value, 0) || math.IsNaN(sample.Value) {
 		return fmt.Errorf("sample with value '%v'", sample.Value)
 	}
@@ -58,9 +64,9 @@ func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSamp
 		case MonotonicCountType:
 			m[contextKey] = &MonotonicCount{}
 		case HistogramType:
-			m[contextKey] = NewHistogram(interval, config) // default histogram configuration (no call to `configure`) for now
+			m[contextKey] = NewHistogramWithConfig(interval, config, histogramConfig)
 		case HistorateType:
-			m[contextKey] = NewHistorate(interval, config) // internal histogram has the configuration for now
+			m[contextKey] = NewHistorateWithConfig(interval, config, histogramConfig)
 		case SetType:
 			m[contextKey] = NewSet()
 		case CounterType:
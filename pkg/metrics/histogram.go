@@ -6,9 +6,9 @@
 package metrics
 
 import (
-	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -28,9 +28,9 @@ func (w weightSamples) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
 
 // Histogram tracks the distribution of samples added over one flush period
 type Histogram struct {
-	aggregates  []string // aggregates configured on this histogram
-	percentiles []int    // percentiles configured on this histogram, each in the 1-100 range
-	interval    int64    // interval over which the `count` value is normalized (bucket interval for Dogstatsd, 1 otherwise)
+	aggregates  []string  // aggregates configured on this histogram
+	percentiles []float64 // percentiles configured on this histogram, each in the 0-100 range
+	interval    int64     // interval over which the `count` value is normalized (bucket interval for Dogstatsd, 1 otherwise)
 	samples     weightSamples
 	sum         float64
 	count       int64
@@ -47,7 +47,7 @@ const (
 
 var (
 	defaultAggregates  = []string(nil)
-	defaultPercentiles = []int(nil)
+	defaultPercentiles = []float64(nil)
 )
 
 type histogramPercentilesConfig struct {
@@ -86,8 +86,12 @@ func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
 		if err != nil {
 			log.Errorf("Could not Unmarshal histogram configuration: %s", err)
 		} else {
-			defaultPercentiles = c.percentiles()
-			sort.Ints(defaultPercentiles)
+			percentiles := c.percentiles()
+			sort.Ints(percentiles)
+			defaultPercentiles = make([]float64, 0, len(percentiles))
+			for _, p := range percentiles {
+				defaultPercentiles = append(defaultPercentiles, float64(p))
+			}
 		}
 	}
 
@@ -98,9 +102,27 @@ func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
 	}
 }
 
-func (h *Histogram) configure(aggregates []string, percentiles []int) {
+// NewHistogramWithConfig returns a newly initialized histogram using the
+// aggregates and percentiles set in histogramConfig instead of the global ones.
+// A nil histogramConfig, or a nil field, falls back to the global configuration.
+func NewHistogramWithConfig(interval int64, config pkgconfigmodel.Config, histogramConfig *HistogramConfig) *Histogram {
+	h := NewHistogram(interval, config)
+	if histogramConfig != nil {
+		// the configuration is shared by every histogram of the context, its
+		// percentiles are sorted once when it is built
+		if histogramConfig.Aggregates != nil {
+			h.aggregates = histogramConfig.Aggregates
+		}
+		if histogramConfig.Percentiles != nil {
+			h.percentiles = histogramConfig.Percentiles
+		}
+	}
+	return h
+}
+
+func (h *Histogram) configure(aggregates []string, percentiles []float64) {
 	h.aggregates = aggregates
-	sort.Ints(percentiles)
+	sort.Float64s(percentiles)
 	h.percentiles = percentiles
 }
 
@@ -166,7 +188,7 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 	// Compute percentiles
 	target := make([]int64, 0, len(h.percentiles))
 	for _, percentile := range h.percentiles {
-		target = append(target, (int64(percentile)*h.count-1)/100)
+		target = append(target, int64((percentile*float64(h.count)-1)/100))
 	}
 
 	if len(target) > 0 {
@@ -178,7 +200,7 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 				series = append(series, &Serie{
 					Points:     []Point{{Ts: timestamp, Value: s.value}},
 					MType:      APIGaugeType,
-					NameSuffix: fmt.Sprintf(".%dpercentile", h.percentiles[idx]),
+					NameSuffix: "." + percentileName(h.percentiles[idx]),
 				})
 				idx++
 			}
@@ -196,6 +218,12 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 	return series, nil
 }
 
+// percentileName returns the name of the series of a percentile: `95percentile`
+// for whole percentiles, `99_9percentile` for fractional ones.
+func percentileName(percentile float64) string {
+	return strings.ReplaceAll(strconv.FormatFloat(percentile, 'f', -1, 64), ".", "_") + "percentile"
+}
+
 func (h *Histogram) isStateful() bool {
 	return false
 }
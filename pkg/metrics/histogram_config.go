@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	histogramOverrideMatchTypeWildcard = "wildcard"
+	histogramOverrideMatchTypeRegex    = "regex"
+)
+
+// HistogramConfig holds the aggregates and percentiles computed by the
+// histograms of a context. A nil field falls back to the global
+// `histogram_aggregates` or `histogram_percentiles` setting.
+type HistogramConfig struct {
+	Aggregates  []string
+	Percentiles []float64 // sorted, each in the 0-100 range
+}
+
+// histogramOverrideConfig is one entry of the `histogram_overrides` setting
+type histogramOverrideConfig struct {
+	Match       string   `mapstructure:"match"`
+	MatchType   string   `mapstructure:"match_type"`
+	Aggregates  []string `mapstructure:"aggregates"`
+	Percentiles []string `mapstructure:"percentiles"`
+}
+
+type histogramOverride struct {
+	regex  *regexp.Regexp
+	config *HistogramConfig
+}
+
+// HistogramOverrides resolves the histogram configuration of the metrics
+// matching the patterns of the `histogram_overrides` setting.
+type HistogramOverrides struct {
+	overrides []histogramOverride
+}
+
+// NewHistogramOverrides returns the HistogramOverrides built from the
+// `histogram_overrides` setting, or nil if no override is configured. Invalid
+// overrides are logged and skipped.
+func NewHistogramOverrides(config pkgconfigmodel.Config) *HistogramOverrides {
+	if !config.IsSet("histogram_overrides") {
+		return nil
+	}
+
+	var configs []histogramOverrideConfig
+	if err := config.UnmarshalKey("histogram_overrides", &configs); err != nil {
+		log.Errorf("Could not parse 'histogram_overrides': %s", err)
+		return nil
+	}
+
+	overrides := &HistogramOverrides{}
+	for i, c := range configs {
+		override, err := newHistogramOverride(c)
+		if err != nil {
+			log.Errorf("Invalid 'histogram_overrides' entry %d (skipping): %s", i, err)
+			continue
+		}
+		overrides.overrides = append(overrides.overrides, override)
+	}
+
+	if len(overrides.overrides) == 0 {
+		return nil
+	}
+	return overrides
+}
+
+func newHistogramOverride(c histogramOverrideConfig) (histogramOverride, error) {
+	if c.Match == "" {
+		return histogramOverride{}, errors.New("match is required")
+	}
+
+	pattern := c.Match
+	switch c.MatchType {
+	case "", histogramOverrideMatchTypeWildcard:
+		pattern = strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	case histogramOverrideMatchTypeRegex:
+	default:
+		return histogramOverride{}, fmt.Errorf("invalid match type '%s', must be '%s' or '%s'", c.MatchType, histogramOverrideMatchTypeWildcard, histogramOverrideMatchTypeRegex)
+	}
+	regex, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return histogramOverride{}, err
+	}
+
+	config := &HistogramConfig{Aggregates: c.Aggregates}
+	if c.Percentiles != nil {
+		config.Percentiles = make([]float64, 0, len(c.Percentiles))
+		for _, p := range c.Percentiles {
+			i, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				log.Errorf("Could not parse '%s' from 'histogram_overrides' percentiles (skipping): %s", p, err)
+				continue
+			}
+			if i < 0 || i > 1 {
+				log.Errorf("histogram_overrides percentiles must be between 0 and 1: skipping %f", i)
+				continue
+			}
+			// keep up to two decimals (0.9999 is the 99.99th percentile), rounding
+			// away the imprecision of the '*100'
+			config.Percentiles = append(config.Percentiles, math.Round(i*10000)/100)
+		}
+		sort.Float64s(config.Percentiles)
+	}
+
+	return histogramOverride{regex: regex, config: config}, nil
+}
+
+// Resolve returns the histogram configuration of the first override matching
+// the metric name, or nil if the global configuration applies.
+func (o *HistogramOverrides) Resolve(name string) *HistogramConfig {
+	if o == nil {
+		return nil
+	}
+	for _, override := range o.overrides {
+		if override.regex.MatchString(name) {
+			return override.config
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+func TestHistogramOverridesNotConfigured(t *testing.T) {
+	mockConfig := pkgconfigmodel.NewConfig("datadog", "DD", strings.NewReplacer(".", "_"))
+	assert.Nil(t, NewHistogramOverrides(mockConfig))
+
+	var overrides *HistogramOverrides
+	assert.Nil(t, overrides.Resolve("my.metric"))
+}
+
+func TestHistogramOverrides(t *testing.T) {
+	mockConfig := pkgconfigmodel.NewConfig("datadog", "DD", strings.NewReplacer(".", "_"))
+	mockConfig.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "*.latency", "percentiles": []string{"0.999", "0.5", "0.99", "0.9999"}},
+		{"match": `^size\.(in|out)$`, "match_type": "regex", "aggregates": []string{"max", "avg"}},
+		{"match": "invalid.*", "match_type": "glob", "aggregates": []string{"max"}},
+		{"aggregates": []string{"max"}},
+		{"match": "*", "percentiles": []string{"0.95", "test", "2"}},
+	})
+
+	overrides := NewHistogramOverrides(mockConfig)
+	require.NotNil(t, overrides)
+	require.Len(t, overrides.overrides, 3)
+
+	latency := overrides.Resolve("http.request.latency")
+	require.NotNil(t, latency)
+	assert.Nil(t, latency.Aggregates)
+	assert.Equal(t, []float64{50, 99, 99.9, 99.99}, latency.Percentiles)
+
+	size := overrides.Resolve("size.in")
+	require.NotNil(t, size)
+	assert.Equal(t, []string{"max", "avg"}, size.Aggregates)
+	assert.Nil(t, size.Percentiles)
+
+	// the first matching override wins, invalid entries are skipped
+	other := overrides.Resolve("invalid.metric")
+	require.NotNil(t, other)
+	assert.Nil(t, other.Aggregates)
+	assert.Equal(t, []float64{95}, other.Percentiles)
+}
+
+func TestHistogramWithConfig(t *testing.T) {
+	cfg := setupConfig()
+	defaultAggregates = nil
+	defaultPercentiles = nil
+
+	hist := NewHistogramWithConfig(10, cfg, nil)
+	assert.Equal(t, []string{"max", "median", "avg", "count"}, hist.aggregates)
+	assert.Equal(t, []float64{95}, hist.percentiles)
+
+	// percentiles fall back to the global configuration
+	hist = NewHistogramWithConfig(10, cfg, &HistogramConfig{Aggregates: []string{"max"}})
+	assert.Equal(t, []string{"max"}, hist.aggregates)
+	assert.Equal(t, []float64{95}, hist.percentiles)
+
+	hist = NewHistogramWithConfig(10, cfg, &HistogramConfig{Aggregates: []string{}, Percentiles: []float64{50, 99.9}})
+	for i := 1; i <= 1000; i++ {
+		hist.addSample(&MetricSample{Value: float64(i)}, 50)
+	}
+	series, err := hist.flush(60)
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, ".50percentile", series[0].NameSuffix)
+	assert.InEpsilon(t, 500, series[0].Points[0].Value, epsilon)
+	assert.Equal(t, ".99_9percentile", series[1].NameSuffix)
+	assert.InEpsilon(t, 999, series[1].Points[0].Value, epsilon)
+}
+
+func TestHistorateWithConfig(t *testing.T) {
+	hist := NewHistorateWithConfig(10, setupConfig(), &HistogramConfig{Aggregates: []string{"max"}, Percentiles: []float64{}})
+	assert.Equal(t, []string{"max"}, hist.histogram.aggregates)
+	assert.Empty(t, hist.histogram.percentiles)
+}
@@ -35,7 +35,7 @@ func TestConfigureDefault(t *testing.T) {
 	_, err := hist.flush(60)
 	require.Nil(t, err)
 	assert.Equal(t, []string{"max", "median", "avg", "count"}, hist.aggregates)
-	assert.Equal(t, []int{95}, hist.percentiles)
+	assert.Equal(t, []float64{95}, hist.percentiles)
 }
 
 func TestConfigure(t *testing.T) {
@@ -49,7 +49,7 @@ func TestConfigure(t *testing.T) {
 
 	hist := NewHistogram(10, mockConfig)
 	assert.Equal(t, aggregates, hist.aggregates)
-	assert.Equal(t, []int{30, 50, 98}, hist.percentiles)
+	assert.Equal(t, []float64{30, 50, 98}, hist.percentiles)
 }
 
 func TestDefaultHistogramSampling(t *testing.T) {
@@ -99,7 +99,7 @@ func TestCustomHistogramSampling(t *testing.T) {
 	// Initialize custom histogram, with an invalid aggregate
 	cfg := setupConfig()
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"min", "sum", "invalid"}, []int{})
+	mHistogram.configure([]string{"min", "sum", "invalid"}, []float64{})
 
 	// Empty flush
 	_, err := mHistogram.flush(50)
@@ -145,7 +145,7 @@ func TestHistogramPercentiles(t *testing.T) {
 	// Initialize custom histogram
 	cfg := setupConfig()
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "median", "avg", "count", "min"}, []int{95, 80})
+	mHistogram.configure([]string{"max", "median", "avg", "count", "min"}, []float64{95, 80})
 
 	// Empty flush
 	_, err := mHistogram.flush(50)
@@ -194,7 +194,7 @@ func TestHistogramPercentiles(t *testing.T) {
 func TestHistogramSampleRate(t *testing.T) {
 	cfg := setupConfig()
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 
 	mHistogram.addSample(&MetricSample{Value: 1}, 50)
 	mHistogram.addSample(&MetricSample{Value: 2, SampleRate: 0.5}, 50)
@@ -235,7 +235,7 @@ func TestHistogramSampleRate(t *testing.T) {
 func TestHistogramReset(t *testing.T) {
 	cfg := setupConfig()
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 
 	mHistogram.addSample(&MetricSample{Value: 1}, 50)
 	mHistogram.addSample(&MetricSample{Value: 2, SampleRate: 0.5}, 50)
@@ -282,7 +282,7 @@ func benchHistogram(b *testing.B, number int, sampleRate float64) {
 	cfg := setupConfig()
 	for n := 0; n < b.N; n++ {
 		h := NewHistogram(1, cfg)
-		h.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+		h.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 		m := MetricSample{Value: 21, SampleRate: sampleRate}
 
 		for i := 0; i < number; i++ {
@@ -26,6 +26,14 @@ func NewHistorate(interval int64, config pkgconfigmodel.Config) *Historate {
 	}
 }
 
+// NewHistorateWithConfig returns a newly-initialized historate whose internal
+// histogram uses histogramConfig, see NewHistogramWithConfig.
+func NewHistorateWithConfig(interval int64, config pkgconfigmodel.Config, histogramConfig *HistogramConfig) *Historate {
+	return &Historate{
+		histogram: *NewHistogramWithConfig(interval, config, histogramConfig),
+	}
+}
+
 func (h *Historate) addSample(sample *MetricSample, timestamp float64) {
 	if h.previousTimestamp != 0 {
 		v := (sample.Value - h.previousSample) / (timestamp - h.previousTimestamp)
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+)
+
+// protoRepeatedFieldOverhead is the maximum size of the tag and of the length
+// prefixing each sketch in the repeated field of a gogen.SketchPayload.
+const protoRepeatedFieldOverhead = 1 + 5
+
+// SeriesChunks reads the series of an IterableSeries once, grouping them into
+// chunks while they are read so that the source is never held in memory as a whole.
+type SeriesChunks struct {
+	series       *IterableSeries
+	stream       *jsoniter.Stream
+	envelopeSize int
+	pending      *metrics.Serie
+	pendingSize  int
+	chunkCount   int
+}
+
+// NewSeriesChunks creates a new instance of *SeriesChunks
+func NewSeriesChunks(series *IterableSeries) *SeriesChunks {
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, nil, 4096)
+	_ = writeHeader(stream)
+	_ = writeFooter(stream)
+	return &SeriesChunks{
+		series: series,
+		stream: stream,
+		// the header, the footer and the trailing newline of a payload
+		envelopeSize: stream.Buffered() + 1,
+	}
+}
+
+// PayloadType returns the type of the series payloads
+func (c *SeriesChunks) PayloadType() string {
+	return "series"
+}
+
+// NextChunk returns the next series whose JSON representation fits in maxSize bytes.
+// A serie bigger than maxSize is returned alone. It returns nil once the source is read.
+// An empty source gives a single empty chunk.
+func (c *SeriesChunks) NextChunk(maxSize int) (marshaler.AbstractMarshaler, error) {
+	chunk := Series{}
+	size := c.envelopeSize
+	for {
+		if c.pending == nil {
+			if !c.series.MoveNext() {
+				break
+			}
+			serie := c.series.source.Current()
+			if serie == nil {
+				continue
+			}
+			c.stream.Reset(nil)
+			if err := writeItem(c.stream, serie); err != nil {
+				return nil, err
+			}
+			// the series are separated by a comma
+			c.pending, c.pendingSize = serie, c.stream.Buffered()+1
+		}
+		if len(chunk) > 0 && size+c.pendingSize > maxSize {
+			break
+		}
+		chunk = append(chunk, c.pending)
+		size += c.pendingSize
+		c.pending = nil
+	}
+
+	// an empty source still gives an empty payload
+	if len(chunk) == 0 && c.chunkCount > 0 {
+		return nil, nil
+	}
+	c.chunkCount++
+	return chunk, nil
+}
+
+// SketchChunks reads the sketches of a SketchSeriesList once, grouping them into
+// chunks while they are read so that the source is never held in memory as a whole.
+type SketchChunks struct {
+	sketches    SketchSeriesList
+	pending     *metrics.SketchSeries
+	pendingSize int
+	chunkCount  int
+}
+
+// NewSketchChunks creates a new instance of *SketchChunks
+func NewSketchChunks(sketches SketchSeriesList) *SketchChunks {
+	return &SketchChunks{
+		sketches: sketches,
+	}
+}
+
+// PayloadType returns the type of the sketches payloads
+func (c *SketchChunks) PayloadType() string {
+	return "sketches"
+}
+
+// NextChunk returns the next sketches whose protobuf representation fits in maxSize bytes.
+// A sketch bigger than maxSize is returned alone. It returns nil once the source is read.
+// An empty source gives a single empty chunk.
+func (c *SketchChunks) NextChunk(maxSize int) (marshaler.AbstractMarshaler, error) {
+	chunk := SketchSeriesSlice{}
+	size := 0
+	for {
+		if c.pending == nil {
+			if !c.sketches.MoveNext() {
+				break
+			}
+			ss := c.sketches.Current()
+			if ss == nil {
+				continue
+			}
+			sketch := sketchToProto(ss)
+			c.pending, c.pendingSize = ss, sketch.Size()+protoRepeatedFieldOverhead
+		}
+		if len(chunk) > 0 && size+c.pendingSize > maxSize {
+			break
+		}
+		chunk = append(chunk, c.pending)
+		size += c.pendingSize
+		c.pending = nil
+	}
+
+	// an empty source still gives an empty payload
+	if len(chunk) == 0 && c.chunkCount > 0 {
+		return nil, nil
+	}
+	c.chunkCount++
+	return chunk, nil
+}
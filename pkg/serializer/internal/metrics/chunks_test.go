@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DataDog/agent-payload/v5/gogen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestSeriesChunks(t *testing.T) {
+	series := metrics.Series{}
+	for i := 0; i < 50; i++ {
+		series = append(series, &metrics.Serie{
+			Name:   fmt.Sprintf("test.metrics%d", i),
+			Points: []metrics.Point{{Ts: 12345, Value: float64(i)}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"tag1", fmt.Sprintf("tag2:%d", i)}),
+		})
+	}
+	series[10].NoIndex = true
+
+	chunks := NewSeriesChunks(CreateIterableSeries(CreateSerieSource(series)))
+	names := map[string]bool{}
+	chunkCount := 0
+	for {
+		chunk, err := chunks.NextChunk(1024)
+		require.NoError(t, err)
+		if chunk == nil {
+			break
+		}
+		chunkCount++
+
+		payload, err := chunk.(marshaler.JSONMarshaler).MarshalJSON()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(payload), 1024)
+		for _, serie := range chunk.(Series) {
+			names[serie.Name] = true
+		}
+	}
+
+	// The series are read once, skipping the ones with NoIndex set
+	assert.Greater(t, chunkCount, 1)
+	assert.Len(t, names, 49)
+	assert.NotContains(t, names, "test.metrics10")
+}
+
+func TestSeriesChunksItemBiggerThanMaxSize(t *testing.T) {
+	series := metrics.Series{
+		&metrics.Serie{Name: "serie1"},
+		&metrics.Serie{Name: "serie2"},
+	}
+
+	chunks := NewSeriesChunks(CreateIterableSeries(CreateSerieSource(series)))
+	for _, name := range []string{"serie1", "serie2"} {
+		chunk, err := chunks.NextChunk(1)
+		require.NoError(t, err)
+		require.Len(t, chunk, 1)
+		assert.Equal(t, name, chunk.(Series)[0].Name)
+	}
+	chunk, err := chunks.NextChunk(1)
+	require.NoError(t, err)
+	assert.Nil(t, chunk)
+}
+
+func TestSketchChunks(t *testing.T) {
+	sl := metrics.NewSketchesSourceTest()
+	for i := 0; i < 20; i++ {
+		sl.Append(Makeseries(i))
+	}
+
+	chunks := NewSketchChunks(SketchSeriesList{SketchesSource: sl})
+	sketchCount := 0
+	chunkCount := 0
+	for {
+		chunk, err := chunks.NextChunk(2048)
+		require.NoError(t, err)
+		if chunk == nil {
+			break
+		}
+		chunkCount++
+
+		payload, err := chunk.(marshaler.ProtoMarshaler).Marshal()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(payload), 2048)
+		pl := new(gogen.SketchPayload)
+		require.NoError(t, pl.Unmarshal(payload))
+		sketchCount += len(pl.Sketches)
+	}
+
+	assert.Greater(t, chunkCount, 1)
+	assert.Equal(t, 20, sketchCount)
+}
+
+func TestSeriesChunksEmptySource(t *testing.T) {
+	chunks := NewSeriesChunks(CreateIterableSeries(CreateSerieSource(metrics.Series{})))
+
+	chunk, err := chunks.NextChunk(1024)
+	require.NoError(t, err)
+	payload, err := chunk.(marshaler.JSONMarshaler).MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"series":[]}`, string(payload))
+
+	chunk, err = chunks.NextChunk(1024)
+	require.NoError(t, err)
+	assert.Nil(t, chunk)
+}
@@ -66,6 +66,11 @@ func (events Events) Marshal() ([]byte, error) {
 	return proto.Marshal(payload)
 }
 
+// PayloadType returns the type of the events payloads
+func (events Events) PayloadType() string {
+	return "events"
+}
+
 func (events Events) getEventsBySourceType() map[string][]*event.Event {
 	eventsBySourceType := make(map[string][]*event.Event)
 	for _, e := range events.EventsArr {
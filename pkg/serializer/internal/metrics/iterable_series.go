@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	"github.com/DataDog/datadog-agent/comp/serializer/compression"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 )
@@ -496,6 +497,7 @@ func (pb *PayloadsBuilder) finishPayload() error {
 	}
 
 	if pb.seriesThisPayload > 0 {
+		sizing.ObserveFill("series", pb.compressor.UncompressedSize(), len(payload), pb.maxUncompressedSize, pb.maxPayloadSize)
 		pb.payloads = append(pb.payloads, transaction.NewBytesPayload(payload, pb.pointsThisPayload))
 	}
 
@@ -524,6 +526,11 @@ func (series *IterableSeries) MarshalJSON() ([]byte, error) {
 	return reqBody.Bytes(), err
 }
 
+// PayloadType returns the type of the series payloads
+func (series *IterableSeries) PayloadType() string {
+	return "series"
+}
+
 // SplitPayload breaks the payload into, at least, "times" number of pieces
 func (series *IterableSeries) SplitPayload(times int) ([]marshaler.AbstractMarshaler, error) {
 	seriesExpvar.Add("TimesSplit", 1)
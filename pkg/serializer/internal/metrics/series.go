@@ -44,6 +44,11 @@ func (series Series) MarshalJSON() ([]byte, error) {
 	return reqBody.Bytes(), err
 }
 
+// PayloadType returns the type of the series payloads
+func (series Series) PayloadType() string {
+	return "series"
+}
+
 // SplitPayload breaks the payload into, at least, "times" number of pieces
 func (series Series) SplitPayload(times int) ([]marshaler.AbstractMarshaler, error) {
 	seriesExpvar.Add("TimesSplit", 1)
@@ -41,6 +41,11 @@ func (sc ServiceChecks) MarshalJSON() ([]byte, error) {
 	return reqBody.Bytes(), err
 }
 
+// PayloadType returns the type of the service checks payloads
+func (sc ServiceChecks) PayloadType() string {
+	return "service_checks"
+}
+
 // SplitPayload breaks the payload into times number of pieces
 func (sc ServiceChecks) SplitPayload(times int) ([]marshaler.AbstractMarshaler, error) {
 	serviceCheckExpvar.Add("TimesSplit", 1)
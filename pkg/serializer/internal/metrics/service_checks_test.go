@@ -19,6 +19,7 @@ import (
 	mock "github.com/DataDog/datadog-agent/pkg/config/mock"
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
@@ -202,7 +203,7 @@ func benchmarkPayloadsServiceCheck(b *testing.B, numberOfItem int) {
 	mockConfig := mock.New(b)
 	strategy := compressionimpl.NewCompressor(mockConfig)
 	for n := 0; n < b.N; n++ {
-		split.Payloads(serviceChecks, true, split.JSONMarshalFct, strategy)
+		split.AdaptivePayloads(serviceChecks, true, split.JSONMarshalFct, strategy, sizing.NewEstimator())
 	}
 }
 
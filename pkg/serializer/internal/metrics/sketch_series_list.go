@@ -16,6 +16,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	"github.com/DataDog/datadog-agent/comp/serializer/compression"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
@@ -415,6 +416,7 @@ func (pb *payloadsBuilder) finishPayload() error {
 		return err
 	}
 
+	sizing.ObserveFill("sketches", pb.compressor.UncompressedSize(), len(payload), pb.maxUncompressedSize, pb.maxPayloadSize)
 	pb.payloads = append(pb.payloads, transaction.NewBytesPayload(payload, pb.pointCount))
 
 	return nil
@@ -422,46 +424,26 @@ func (pb *payloadsBuilder) finishPayload() error {
 
 // Marshal encodes this series list.
 func (sl SketchSeriesList) Marshal() ([]byte, error) {
-	pb := &gogen.SketchPayload{
-		Sketches: make([]gogen.SketchPayload_Sketch, 0),
-	}
-
-	for sl.MoveNext() {
-		ss := sl.Current()
-		dsl := make([]gogen.SketchPayload_Sketch_Dogsketch, 0, len(ss.Points))
+	return sl.Collect().Marshal()
+}
 
-		for _, p := range ss.Points {
-			b := p.Sketch.Basic
-			k, n := p.Sketch.Cols()
-			dsl = append(dsl, gogen.SketchPayload_Sketch_Dogsketch{
-				Ts:  p.Ts,
-				Cnt: b.Cnt,
-				Min: b.Min,
-				Max: b.Max,
-				Avg: b.Avg,
-				Sum: b.Sum,
-				K:   k,
-				N:   n,
-			})
-		}
+// PayloadType returns the type of the sketches payloads
+func (sl SketchSeriesList) PayloadType() string {
+	return "sketches"
+}
 
-		pb.Sketches = append(pb.Sketches, gogen.SketchPayload_Sketch{
-			Metric:      ss.Name,
-			Host:        ss.Host,
-			Tags:        ss.Tags.UnsafeToReadOnlySliceString(),
-			Dogsketches: dsl,
-		})
+// Collect reads all the sketches of the source, to serialize them several times.
+func (sl SketchSeriesList) Collect() SketchSeriesSlice {
+	sketches := SketchSeriesSlice{}
+	for sl.MoveNext() {
+		sketches = append(sketches, sl.Current())
 	}
-	return pb.Marshal()
+	return sketches
 }
 
 // SplitPayload breaks the payload into times number of pieces
 func (sl SketchSeriesList) SplitPayload(times int) ([]marshaler.AbstractMarshaler, error) {
-	var sketches SketchSeriesSlice
-	for sl.MoveNext() {
-		ss := sl.Current()
-		sketches = append(sketches, ss)
-	}
+	sketches := sl.Collect()
 	if len(sketches) == 0 {
 		return []marshaler.AbstractMarshaler{}, nil
 	}
@@ -471,6 +453,49 @@ func (sl SketchSeriesList) SplitPayload(times int) ([]marshaler.AbstractMarshale
 //nolint:revive // TODO(AML) Fix revive linter
 type SketchSeriesSlice []*metrics.SketchSeries
 
+// Marshal encodes this series slice.
+func (sl SketchSeriesSlice) Marshal() ([]byte, error) {
+	pb := &gogen.SketchPayload{
+		Sketches: make([]gogen.SketchPayload_Sketch, 0, len(sl)),
+	}
+
+	for _, ss := range sl {
+		pb.Sketches = append(pb.Sketches, sketchToProto(ss))
+	}
+	return pb.Marshal()
+}
+
+func sketchToProto(ss *metrics.SketchSeries) gogen.SketchPayload_Sketch {
+	dsl := make([]gogen.SketchPayload_Sketch_Dogsketch, 0, len(ss.Points))
+
+	for _, p := range ss.Points {
+		b := p.Sketch.Basic
+		k, n := p.Sketch.Cols()
+		dsl = append(dsl, gogen.SketchPayload_Sketch_Dogsketch{
+			Ts:  p.Ts,
+			Cnt: b.Cnt,
+			Min: b.Min,
+			Max: b.Max,
+			Avg: b.Avg,
+			Sum: b.Sum,
+			K:   k,
+			N:   n,
+		})
+	}
+
+	return gogen.SketchPayload_Sketch{
+		Metric:      ss.Name,
+		Host:        ss.Host,
+		Tags:        ss.Tags.UnsafeToReadOnlySliceString(),
+		Dogsketches: dsl,
+	}
+}
+
+// PayloadType returns the type of the sketches payloads
+func (sl SketchSeriesSlice) PayloadType() string {
+	return "sketches"
+}
+
 // SplitPayload breaks the payload into times number of pieces
 func (sl SketchSeriesSlice) SplitPayload(times int) ([]marshaler.AbstractMarshaler, error) {
 	// Only break it down as much as possible
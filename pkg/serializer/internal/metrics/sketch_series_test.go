@@ -88,6 +88,29 @@ func TestSketchSeriesSplitEmptyPayload(t *testing.T) {
 	require.Nil(t, err)
 }
 
+func TestSketchSeriesSplitPayloadMarshal(t *testing.T) {
+	sl := metrics.NewSketchesSourceTest()
+	for i := 0; i < 5; i++ {
+		sl.Append(Makeseries(i))
+	}
+
+	pieces, err := SketchSeriesList{SketchesSource: sl}.SplitPayload(2)
+	require.NoError(t, err)
+	require.Len(t, pieces, 2)
+
+	// The pieces can be serialized as the whole list
+	sketchCount := 0
+	for _, piece := range pieces {
+		b, err := piece.(marshaler.ProtoMarshaler).Marshal()
+		require.NoError(t, err)
+		pl := new(gogen.SketchPayload)
+		require.NoError(t, pl.Unmarshal(b))
+		sketchCount += len(pl.Sketches)
+	}
+	assert.Equal(t, 5, sketchCount)
+	assert.Equal(t, "sketches", marshaler.PayloadTypeOf(pieces[0]))
+}
+
 func TestSketchSeriesMarshalSplitCompressEmpty(t *testing.T) {
 	tests := map[string]struct {
 		kind string
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package sizing learns the compression ratio of each type of payload from the
+// payloads already compressed, to estimate the compressed size of a payload
+// before compressing it.
+package sizing
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// smoothingFactor is the weight of the last payload in the moving average of
+// the compression ratio of its type.
+const smoothingFactor = 0.2
+
+var (
+	tlmPayloadFill = telemetry.NewHistogram("serializer", "payload_fill_ratio",
+		[]string{"payload_type", "size"}, "Size of the payloads relative to the maximum size of a payload, compressed or uncompressed",
+		[]float64{0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 1})
+	tlmCompressionRatio = telemetry.NewGauge("serializer", "compression_ratio",
+		[]string{"payload_type"}, "Compressed size of the payloads relative to their uncompressed size, averaged over the last payloads")
+)
+
+// Estimator estimates the compressed size of the payloads of each type. It is
+// safe for concurrent use.
+type Estimator struct {
+	mu     sync.Mutex
+	ratios map[string]float64
+}
+
+// NewEstimator returns a new Estimator which did not observe any payload yet.
+func NewEstimator() *Estimator {
+	return &Estimator{
+		ratios: make(map[string]float64),
+	}
+}
+
+// Observe learns the compression ratio of a payload of the given type, and
+// records how close its sizes landed to the limits.
+func (e *Estimator) Observe(payloadType string, uncompressedSize, compressedSize, maxUncompressedSize, maxCompressedSize int) {
+	ObserveFill(payloadType, uncompressedSize, compressedSize, maxUncompressedSize, maxCompressedSize)
+	if uncompressedSize <= 0 || compressedSize <= 0 {
+		return
+	}
+
+	ratio := float64(compressedSize) / float64(uncompressedSize)
+	e.mu.Lock()
+	if previous, found := e.ratios[payloadType]; found {
+		ratio = previous + smoothingFactor*(ratio-previous)
+	}
+	e.ratios[payloadType] = ratio
+	e.mu.Unlock()
+
+	tlmCompressionRatio.Set(ratio, payloadType)
+}
+
+// Ratio returns the compressed size of the payloads of the given type relative
+// to their uncompressed size. The payloads are assumed to be incompressible
+// until one of them was observed.
+func (e *Estimator) Ratio(payloadType string) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if ratio, found := e.ratios[payloadType]; found {
+		return ratio
+	}
+	return 1
+}
+
+// CompressedSize estimates the compressed size of a payload of the given type.
+func (e *Estimator) CompressedSize(payloadType string, uncompressedSize int) int {
+	return int(float64(uncompressedSize) * e.Ratio(payloadType))
+}
+
+// ObserveFill records how close the sizes of a payload landed to the limits,
+// without learning its compression ratio.
+func ObserveFill(payloadType string, uncompressedSize, compressedSize, maxUncompressedSize, maxCompressedSize int) {
+	if maxCompressedSize > 0 {
+		tlmPayloadFill.Observe(float64(compressedSize)/float64(maxCompressedSize), payloadType, "compressed")
+	}
+	if maxUncompressedSize > 0 {
+		tlmPayloadFill.Observe(float64(uncompressedSize)/float64(maxUncompressedSize), payloadType, "uncompressed")
+	}
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sizing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimator(t *testing.T) {
+	e := NewEstimator()
+
+	// The payloads are assumed incompressible until one was observed
+	assert.Equal(t, 1.0, e.Ratio("series"))
+	assert.Equal(t, 1000, e.CompressedSize("series", 1000))
+
+	e.Observe("series", 1000, 100, 4000, 2000)
+	assert.InDelta(t, 0.1, e.Ratio("series"), 0.0001)
+	assert.Equal(t, 100, e.CompressedSize("series", 1000))
+
+	// The ratio follows the last payloads without jumping to them
+	e.Observe("series", 1000, 600, 4000, 2000)
+	assert.InDelta(t, 0.2, e.Ratio("series"), 0.0001)
+
+	// The types of payloads are learned separately
+	assert.Equal(t, 1.0, e.Ratio("sketches"))
+
+	// Empty payloads are ignored
+	e.Observe("sketches", 0, 10, 4000, 2000)
+	assert.Equal(t, 1.0, e.Ratio("sketches"))
+}
@@ -67,6 +67,7 @@ type Compressor struct {
 	maxZippedItemSize   int
 	maxPayloadSize      int
 	maxUncompressedSize int
+	headerZippedSize    int // compressed bytes written with the header, e.g. the lz4 frame header
 	separator           []byte
 }
 
@@ -92,6 +93,7 @@ func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedS
 	c.zipper = zipper
 	n, err := c.zipper.Write(header)
 	c.maxZippedItemSize = maxUncompressedSize - c.strategy.CompressBound(len(footer)+len(header))
+	c.headerZippedSize = max(len(header), c.compressed.Len())
 	c.uncompressedWritten += n
 
 	return c, err
@@ -102,7 +104,7 @@ func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedS
 // that could actually fit after compression. That said it is probably impossible
 // to have a 2MB+ item that is valid for the backend.
 func (c *Compressor) checkItemSize(data []byte) bool {
-	maxEffectivePayloadSize := c.maxPayloadSize - len(c.footer) - len(c.header)
+	maxEffectivePayloadSize := c.maxPayloadSize - len(c.footer) - c.headerZippedSize
 	compressedWillFit := c.strategy.CompressBound(len(data)) < c.maxZippedItemSize && c.strategy.CompressBound(len(data)) < maxEffectivePayloadSize
 
 	return len(data) < c.maxUnzippedItemSize && compressedWillFit
@@ -207,6 +209,11 @@ func (c *Compressor) Close() ([]byte, error) {
 	return payload, nil
 }
 
+// UncompressedSize returns the number of uncompressed bytes written to the payload
+func (c *Compressor) UncompressedSize() int {
+	return c.uncompressedWritten
+}
+
 func (c *Compressor) remainingSpace() int {
 	return c.maxPayloadSize - c.compressed.Len() - len(c.footer)
 }
@@ -35,8 +35,10 @@ func TestCompressorSimple(t *testing.T) {
 	tests := map[string]struct {
 		kind string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind},
-		"zstd": {kind: compressionimpl.ZstdKind},
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -69,8 +71,10 @@ func TestCompressorAddItemErrCodeWithEmptyCompressor(t *testing.T) {
 	tests := map[string]struct {
 		kind string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind},
-		"zstd": {kind: compressionimpl.ZstdKind},
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -114,8 +118,10 @@ func TestOnePayloadSimple(t *testing.T) {
 	tests := map[string]struct {
 		kind string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind},
-		"zstd": {kind: compressionimpl.ZstdKind},
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -142,8 +148,10 @@ func TestMaxCompressedSizePayload(t *testing.T) {
 		kind           string
 		maxPayloadSize int
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind, maxPayloadSize: 22},
-		"zstd": {kind: compressionimpl.ZstdKind, maxPayloadSize: 90},
+		"zlib":   {kind: compressionimpl.ZlibKind, maxPayloadSize: 22},
+		"zstd":   {kind: compressionimpl.ZstdKind, maxPayloadSize: 90},
+		"lz4":    {kind: compressionimpl.LZ4Kind, maxPayloadSize: 45},
+		"snappy": {kind: compressionimpl.SnappyKind, maxPayloadSize: 25},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -195,8 +203,10 @@ func TestTwoPayload(t *testing.T) {
 		kind           string
 		maxPayloadSize int
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind, maxPayloadSize: 22},
-		"zstd": {kind: compressionimpl.ZstdKind, maxPayloadSize: 70},
+		"zlib":   {kind: compressionimpl.ZlibKind, maxPayloadSize: 22},
+		"zstd":   {kind: compressionimpl.ZstdKind, maxPayloadSize: 70},
+		"lz4":    {kind: compressionimpl.LZ4Kind, maxPayloadSize: 45},
+		"snappy": {kind: compressionimpl.SnappyKind, maxPayloadSize: 25},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -224,8 +234,10 @@ func TestLockedCompressorProducesSamePayloads(t *testing.T) {
 	tests := map[string]struct {
 		kind string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind},
-		"zstd": {kind: compressionimpl.ZstdKind},
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -254,8 +266,10 @@ func TestBuildWithOnErrItemTooBigPolicyMetadata(t *testing.T) {
 		kind                       string
 		maxUncompressedPayloadSize int
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind, maxUncompressedPayloadSize: 40},
-		"zstd": {kind: compressionimpl.ZstdKind, maxUncompressedPayloadSize: 170},
+		"zlib":   {kind: compressionimpl.ZlibKind, maxUncompressedPayloadSize: 40},
+		"zstd":   {kind: compressionimpl.ZstdKind, maxUncompressedPayloadSize: 170},
+		"lz4":    {kind: compressionimpl.LZ4Kind, maxUncompressedPayloadSize: 71},
+		"snappy": {kind: compressionimpl.SnappyKind, maxUncompressedPayloadSize: 45},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
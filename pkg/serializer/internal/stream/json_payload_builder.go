@@ -17,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	"github.com/DataDog/datadog-agent/comp/serializer/compression"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -69,10 +70,17 @@ type JSONPayloadBuilder struct {
 	mu                            sync.Mutex
 	config                        config.Component
 	compressor                    compression.Component
+	estimator                     *sizing.Estimator
 }
 
 // NewJSONPayloadBuilder returns a new JSONPayloadBuilder
 func NewJSONPayloadBuilder(shareAndLockBuffers bool, config config.Component, compressor compression.Component) *JSONPayloadBuilder {
+	return NewJSONPayloadBuilderWithEstimator(shareAndLockBuffers, config, compressor, sizing.NewEstimator())
+}
+
+// NewJSONPayloadBuilderWithEstimator returns a new JSONPayloadBuilder teaching
+// the compression ratio of the payloads it builds to `estimator`.
+func NewJSONPayloadBuilderWithEstimator(shareAndLockBuffers bool, config config.Component, compressor compression.Component, estimator *sizing.Estimator) *JSONPayloadBuilder {
 	if shareAndLockBuffers {
 		return &JSONPayloadBuilder{
 			inputSizeHint:       4096,
@@ -82,6 +90,7 @@ func NewJSONPayloadBuilder(shareAndLockBuffers bool, config config.Component, co
 			output:              bytes.NewBuffer(make([]byte, 0, 4096)),
 			config:              config,
 			compressor:          compressor,
+			estimator:           estimator,
 		}
 	}
 	return &JSONPayloadBuilder{
@@ -90,6 +99,7 @@ func NewJSONPayloadBuilder(shareAndLockBuffers bool, config config.Component, co
 		shareAndLockBuffers: false,
 		config:              config,
 		compressor:          compressor,
+		estimator:           estimator,
 	}
 }
 
@@ -138,6 +148,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 	}
 
 	var payloads transaction.BytesPayloads
+	payloadType := marshaler.PayloadTypeOf(m)
 	expvarsTotalCalls.Add(1)
 	tlmTotalCalls.Inc()
 	start := time.Now()
@@ -190,6 +201,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 			if err != nil {
 				return payloads, err
 			}
+			b.estimator.Observe(payloadType, compressor.UncompressedSize(), len(payload), maxUncompressedSize, maxPayloadSize)
 			payloads = append(payloads, transaction.NewBytesPayload(payload, pointCount))
 			pointCount = 0
 			input.Reset()
@@ -228,6 +240,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 	if err != nil {
 		return payloads, err
 	}
+	b.estimator.Observe(payloadType, compressor.UncompressedSize(), len(payload), maxUncompressedSize, maxPayloadSize)
 	payloads = append(payloads, transaction.NewBytesPayload(payload, pointCount))
 
 	if !b.shareAndLockBuffers {
@@ -33,6 +33,21 @@ type AbstractMarshaler interface {
 	SplitPayload(int) ([]AbstractMarshaler, error)
 }
 
+// PayloadTyper is implemented by the marshalers giving the type of their payloads,
+// for instance "series". The payloads of a type share the same compression ratio.
+type PayloadTyper interface {
+	PayloadType() string
+}
+
+// PayloadTypeOf returns the type of the payloads of a marshaler, or "unknown"
+// when it does not implement PayloadTyper.
+func PayloadTypeOf(m interface{}) string {
+	if typer, ok := m.(PayloadTyper); ok {
+		return typer.PayloadType()
+	}
+	return "unknown"
+}
+
 // StreamJSONMarshaler is an interface for metrics that are able to serialize themselves in a stream
 type StreamJSONMarshaler interface {
 	WriteHeader(*jsoniter.Stream) error
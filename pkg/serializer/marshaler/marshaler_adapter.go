@@ -56,3 +56,8 @@ func (a *IterableStreamJSONMarshalerAdapter) MoveNext() bool {
 func (a *IterableStreamJSONMarshalerAdapter) GetCurrentItemPointCount() int {
 	return 0
 }
+
+// PayloadType returns the payload type of the adapted marshaler
+func (a *IterableStreamJSONMarshalerAdapter) PayloadType() string {
+	return PayloadTypeOf(a.marshaler)
+}
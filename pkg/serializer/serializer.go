@@ -27,6 +27,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	"github.com/DataDog/datadog-agent/pkg/process/util/api/headers"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
@@ -108,6 +109,7 @@ type Serializer struct {
 	config                config.Component
 
 	Strategy                            compression.Component
+	payloadSizes                        *sizing.Estimator
 	seriesJSONPayloadBuilder            *stream.JSONPayloadBuilder
 	jsonExtraHeaders                    http.Header
 	protobufExtraHeaders                http.Header
@@ -136,12 +138,15 @@ type Serializer struct {
 func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestratorForwarder.Component, compressor compression.Component, config config.Component, hostName string) *Serializer {
 
 	streamAvailable := compressor.NewStreamCompressor(&bytes.Buffer{}) != nil
+	// the compression ratios learned from the payloads are shared by all the payload builders
+	payloadSizes := sizing.NewEstimator()
 
 	s := &Serializer{
 		Forwarder:                           forwarder,
 		orchestratorForwarder:               orchestratorForwarder,
 		config:                              config,
-		seriesJSONPayloadBuilder:            stream.NewJSONPayloadBuilder(config.GetBool("enable_json_stream_shared_compressor_buffers"), config, compressor),
+		payloadSizes:                        payloadSizes,
+		seriesJSONPayloadBuilder:            stream.NewJSONPayloadBuilderWithEstimator(config.GetBool("enable_json_stream_shared_compressor_buffers"), config, compressor, payloadSizes),
 		enableEvents:                        config.GetBool("enable_payloads.events"),
 		enableSeries:                        config.GetBool("enable_payloads.series"),
 		enableServiceChecks:                 config.GetBool("enable_payloads.service_checks"),
@@ -219,7 +224,17 @@ func (s Serializer) serializePayloadProto(payload marshaler.ProtoMarshaler, comp
 }
 
 func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler, compress bool, extraHeaders http.Header, marshalFct split.MarshalFct) (transaction.BytesPayloads, http.Header, error) {
-	payloads, err := split.Payloads(payload, compress, marshalFct, s.Strategy)
+	payloads, err := split.AdaptivePayloads(payload, compress, marshalFct, s.Strategy, s.payloadSizes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not split payload into small enough chunks: %s", err)
+	}
+
+	return payloads, extraHeaders, nil
+}
+
+// serializeChunkedPayload compresses a payload read once from an iterator, chunk by chunk.
+func (s Serializer) serializeChunkedPayload(source split.ChunkSource, extraHeaders http.Header, marshalFct split.MarshalFct) (transaction.BytesPayloads, http.Header, error) {
+	payloads, err := split.StreamPayloads(source, true, marshalFct, s.Strategy, s.payloadSizes)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not split payload into small enough chunks: %s", err)
 	}
@@ -348,7 +363,7 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 	if useV1API && s.enableJSONStream {
 		seriesBytesPayloads, extraHeaders, err = s.serializeIterableStreamablePayload(seriesSerializer, stream.DropItemOnErrItemTooBig)
 	} else if useV1API && !s.enableJSONStream {
-		seriesBytesPayloads, extraHeaders, err = s.serializePayloadJSON(seriesSerializer, true)
+		seriesBytesPayloads, extraHeaders, err = s.serializeChunkedPayload(metricsserializer.NewSeriesChunks(seriesSerializer), s.jsonExtraHeadersWithCompression, split.JSONMarshalFct)
 	} else {
 		failoverActive, allowlist := s.getFailoverAllowlist()
 
@@ -438,9 +453,7 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 			return s.Forwarder.SubmitSketchSeries(payloads, s.protobufExtraHeadersWithCompression)
 		}
 	} else {
-		//nolint:revive // TODO(AML) Fix revive linter
-		compress := true
-		splitSketches, extraHeaders, err := s.serializePayloadProto(sketchesSerializer, compress)
+		splitSketches, extraHeaders, err := s.serializeChunkedPayload(metricsserializer.NewSketchChunks(sketchesSerializer), s.protobufExtraHeadersWithCompression, split.ProtoMarshalFct)
 		if err != nil {
 			return fmt.Errorf("dropping sketch payload: %s", err)
 		}
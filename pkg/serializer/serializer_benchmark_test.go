@@ -16,6 +16,7 @@ import (
 	mock "github.com/DataDog/datadog-agent/pkg/config/mock"
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
 )
@@ -60,7 +61,7 @@ func benchmarkSplit(b *testing.B, numberOfEvents int) {
 	mockConfig := mock.New(b)
 	strategy := compressionimpl.NewCompressor(mockConfig)
 	for n := 0; n < b.N; n++ {
-		results, _ = split.Payloads(events, true, split.JSONMarshalFct, strategy)
+		results, _ = split.AdaptivePayloads(events, true, split.JSONMarshalFct, strategy, sizing.NewEstimator())
 	}
 }
 
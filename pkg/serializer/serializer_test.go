@@ -63,8 +63,10 @@ func TestInitExtraHeadersWithCompression(t *testing.T) {
 		kind             string
 		expectedEncoding string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind, expectedEncoding: compression.ZlibEncoding},
-		"zstd": {kind: compressionimpl.ZstdKind, expectedEncoding: compression.ZstdEncoding},
+		"zlib":   {kind: compressionimpl.ZlibKind, expectedEncoding: compression.ZlibEncoding},
+		"zstd":   {kind: compressionimpl.ZstdKind, expectedEncoding: compression.ZstdEncoding},
+		"lz4":    {kind: compressionimpl.LZ4Kind, expectedEncoding: compression.LZ4Encoding},
+		"snappy": {kind: compressionimpl.SnappyKind, expectedEncoding: compression.SnappyEncoding},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
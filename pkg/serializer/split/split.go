@@ -11,6 +11,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	"github.com/DataDog/datadog-agent/comp/serializer/compression"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 
@@ -21,6 +22,13 @@ import (
 var maxPayloadSizeCompressed = 2 * 1024 * 1024
 var maxPayloadSizeUnCompressed = 64 * 1024 * 1024
 
+// chunkTargetFill is the size targeted for the chunks of a payload, relative to
+// the limits: the compression ratio of a chunk differs from the average one.
+const chunkTargetFill = 0.9
+
+// maxChunkHalvings bounds how many times a chunk still too big once compressed is halved.
+const maxChunkHalvings = 3
+
 // MarshalFct marshal m. Must be either JSONMarshalFct or ProtoMarshalFct.
 type MarshalFct func(m marshaler.AbstractMarshaler) ([]byte, error)
 
@@ -37,26 +45,22 @@ func ProtoMarshalFct(m marshaler.AbstractMarshaler) ([]byte, error) {
 var (
 	// TODO(remy): could probably be removed as not used in the status page
 	splitterExpvars      = expvar.NewMap("splitter")
-	splitterNotTooBig    = expvar.Int{}
-	splitterTooBig       = expvar.Int{}
-	splitterTotalLoops   = expvar.Int{}
 	splitterPayloadDrops = expvar.Int{}
+	splitterChunked      = expvar.Int{}
+	splitterChunksMissed = expvar.Int{}
 
-	tlmSplitterNotTooBig = telemetry.NewCounter("splitter", "not_too_big",
-		nil, "Splitter 'not too big' occurrences")
-	tlmSplitterTooBig = telemetry.NewCounter("splitter", "too_big",
-		nil, "Splitter 'too big' occurrences")
-	tlmSplitterTotalLoops = telemetry.NewCounter("splitter", "total_loops",
-		nil, "Splitter total loops run")
 	tlmSplitterPayloadDrops = telemetry.NewCounter("splitter", "payload_drops",
 		nil, "Splitter payload drops")
+	tlmSplitterChunked = telemetry.NewCounter("splitter", "chunked",
+		[]string{"payload_type"}, "Payloads split into chunks before being compressed")
+	tlmSplitterChunksMissed = telemetry.NewCounter("splitter", "chunks_missed",
+		[]string{"payload_type"}, "Chunks still too big once compressed, split again")
 )
 
 func init() {
-	splitterExpvars.Set("NotTooBig", &splitterNotTooBig)
-	splitterExpvars.Set("TooBig", &splitterTooBig)
-	splitterExpvars.Set("TotalLoops", &splitterTotalLoops)
 	splitterExpvars.Set("PayloadDrops", &splitterPayloadDrops)
+	splitterExpvars.Set("Chunked", &splitterChunked)
+	splitterExpvars.Set("ChunksMissed", &splitterChunksMissed)
 
 }
 
@@ -73,90 +77,162 @@ func CheckSizeAndSerialize(m marshaler.AbstractMarshaler, compress bool, marshal
 	return mustBeSplit, compressedPayload, payload, nil
 }
 
-// Payloads serializes a metadata payload and sends it to the forwarder
-func Payloads(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFct, strategy compression.Component) (transaction.BytesPayloads, error) {
-	marshallers := []marshaler.AbstractMarshaler{m}
-	smallEnoughPayloads := transaction.BytesPayloads{}
-	tooBig, compressedPayload, _, err := CheckSizeAndSerialize(m, compress, marshalFct, strategy)
+// AdaptivePayloads serializes a payload into payloads small enough to be sent,
+// compressing them only once in most cases: the number of chunks needed is
+// estimated from the uncompressed size of the payload and from the compression
+// ratio learned for its type, and the payload is split before being compressed.
+// The chunks still too big once compressed are halved, see compressChunk.
+// The marshaler must be able to serialize itself several times.
+func AdaptivePayloads(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFct, strategy compression.Component, estimator *sizing.Estimator) (transaction.BytesPayloads, error) {
+	payloadType := marshaler.PayloadTypeOf(m)
+	payload, err := marshalFct(m)
 	if err != nil {
-		return smallEnoughPayloads, err
-	}
-	// If the payload's size is fine, just return it
-	if !tooBig {
-		log.Debug("The payload was not too big, returning the full payload")
-		splitterNotTooBig.Add(1)
-		tlmSplitterNotTooBig.Inc()
-		smallEnoughPayloads = append(smallEnoughPayloads, transaction.NewBytesPayloadWithoutMetaData(compressedPayload))
-		return smallEnoughPayloads, nil
-	}
-	splitterTooBig.Add(1)
-	tlmSplitterTooBig.Inc()
-	loops := 0
-	// Do not attempt to split payloads forever, if a payload cannot be split then abandon the task
-	// the function will return all the payloads that were able to be split
-	for tooBig && loops < 3 {
-		splitterTotalLoops.Add(1)
-		tlmSplitterTotalLoops.Inc()
-		// create a temporary slice, the other array will be reused to keep track of the payloads that have yet to be split
-		tempSlice := make([]marshaler.AbstractMarshaler, len(marshallers))
-		copy(tempSlice, marshallers)
-		marshallers = []marshaler.AbstractMarshaler{}
-		for _, toSplit := range tempSlice {
-			var e error
-			// we have to do this every time to get the proper payload
-			compressedPayload, payload, e := serializeMarshaller(toSplit, compress, marshalFct, strategy)
-			if e != nil {
-				return smallEnoughPayloads, e
-			}
-			payloadSize := len(payload)
-			compressedSize := len(compressedPayload)
-			// Attempt to account for the compression when estimating the number of chunks that will be needed
-			// This is the same function used in dd-agent
-			compressionRatio := float64(payloadSize) / float64(compressedSize)
-			numChunks := compressedSize/maxPayloadSizeCompressed + 1 + int(compressionRatio/2)
-			log.Debugf("split the payload into into %d chunks", numChunks)
-			chunks, err := toSplit.SplitPayload(numChunks)
-			log.Debugf("payload was split into %d chunks", len(chunks))
+		return nil, err
+	}
+
+	estimatedSize := len(payload)
+	if compress {
+		estimatedSize = estimator.CompressedSize(payloadType, len(payload))
+	}
+	numChunks := max(chunksFor(estimatedSize, maxPayloadSizeCompressed), chunksFor(len(payload), maxPayloadSizeUnCompressed))
+
+	chunks := []marshaler.AbstractMarshaler{m}
+	if numChunks > 1 {
+		log.Debugf("split the %s payload into %d chunks before compressing it", payloadType, numChunks)
+		splitterChunked.Add(1)
+		tlmSplitterChunked.Inc(payloadType)
+		if splitChunks, err := m.SplitPayload(numChunks); err == nil {
+			chunks = splitChunks
+		} else {
+			// the estimation may be wrong, the whole payload may fit once compressed
+			log.Debugf("could not split the %s payload before compressing it: %s", payloadType, err)
+			numChunks = 1
+		}
+	}
+
+	payloads := transaction.BytesPayloads{}
+	for _, chunk := range chunks {
+		// the payload of a single chunk is already serialized
+		chunkPayload := payload
+		if numChunks > 1 {
+			chunkPayload, err = marshalFct(chunk)
 			if err != nil {
-				log.Warnf("Some payloads could not be split, dropping them")
-				splitterPayloadDrops.Add(1)
-				tlmSplitterPayloadDrops.Inc()
-				return smallEnoughPayloads, err
-			}
-			// after the payload has been split, loop through the chunks
-			for _, chunk := range chunks {
-				// serialize the payload
-				tooBigChunk, compressedPayload, _, err := CheckSizeAndSerialize(chunk, compress, marshalFct, strategy)
-				if err != nil {
-					log.Debugf("Error serializing a chunk: %s", err)
-					continue
-				}
-				if !tooBigChunk {
-					// if the payload is small enough, return it straight away
-					smallEnoughPayloads = append(smallEnoughPayloads, transaction.NewBytesPayloadWithoutMetaData(compressedPayload))
-					log.Debugf("chunk was small enough: %v, smallEnoughPayloads are of length: %v", len(compressedPayload), len(smallEnoughPayloads))
-				} else {
-					// if it is not small enough, append it to the list of payloads
-					marshallers = append(marshallers, chunk)
-					log.Debugf("chunk was not small enough: %v, marshallers are of length: %v", len(compressedPayload), len(marshallers))
-				}
+				return payloads, err
 			}
 		}
-		if len(marshallers) == 0 {
-			log.Debug("marshallers was empty, breaking out of the loop")
-			tooBig = false
-		} else {
-			log.Debug("marshallers was not empty, running around the loop again")
-			loops++
+		chunkPayloads, err := compressChunk(chunk, chunkPayload, payloadType, compress, marshalFct, strategy, estimator, 0)
+		payloads = append(payloads, chunkPayloads...)
+		if err != nil {
+			return payloads, err
 		}
 	}
-	if len(marshallers) != 0 {
-		log.Warnf("Some payloads could not be split, dropping them")
+	return payloads, nil
+}
+
+// ChunkSource is a payload whose items are read once, from an iterator.
+type ChunkSource interface {
+	// NextChunk reads the next items whose serialized size fits in maxSize bytes
+	// and returns them as a chunk. It returns nil once all the items are read.
+	NextChunk(maxSize int) (marshaler.AbstractMarshaler, error)
+}
+
+// StreamPayloads serializes a payload read once from an iterator into payloads
+// small enough to be sent. Its items are grouped into chunks while being read,
+// the size of a chunk being estimated from the compression ratio learned for its
+// type, so that a single chunk is held in memory at once.
+func StreamPayloads(source ChunkSource, compress bool, marshalFct MarshalFct, strategy compression.Component, estimator *sizing.Estimator) (transaction.BytesPayloads, error) {
+	payloadType := marshaler.PayloadTypeOf(source)
+	payloads := transaction.BytesPayloads{}
+	for chunkCount := 0; ; chunkCount++ {
+		chunk, err := source.NextChunk(chunkTargetSize(payloadType, compress, estimator))
+		if err != nil {
+			return payloads, err
+		}
+		if chunk == nil {
+			return payloads, nil
+		}
+		if chunkCount == 1 {
+			splitterChunked.Add(1)
+			tlmSplitterChunked.Inc(payloadType)
+		}
+
+		chunkPayloads, err := compressChunk(chunk, nil, payloadType, compress, marshalFct, strategy, estimator, 0)
+		payloads = append(payloads, chunkPayloads...)
+		if err != nil {
+			return payloads, err
+		}
+	}
+}
+
+// chunkTargetSize returns the uncompressed size targeted for the chunks of a
+// payload type, from the compression ratio learned for this type.
+func chunkTargetSize(payloadType string, compress bool, estimator *sizing.Estimator) int {
+	target := float64(maxPayloadSizeCompressed)
+	if compress {
+		target /= estimator.Ratio(payloadType)
+	}
+	return int(min(target, float64(maxPayloadSizeUnCompressed)) * chunkTargetFill)
+}
+
+// compressChunk compresses a chunk of a payload, serializing it first when its
+// payload is nil, and learns the compression ratio of its type.
+// A chunk compressing worse than the learned ratio can still be too big: it is
+// then halved, serializing again the items of this chunk only, and dropped when
+// it cannot be split in maxChunkHalvings halvings.
+func compressChunk(chunk marshaler.AbstractMarshaler, payload []byte, payloadType string, compress bool, marshalFct MarshalFct, strategy compression.Component, estimator *sizing.Estimator, halvings int) (transaction.BytesPayloads, error) {
+	var err error
+	if payload == nil {
+		if payload, err = marshalFct(chunk); err != nil {
+			return nil, err
+		}
+	}
+	compressedPayload := payload
+	if compress {
+		compressedPayload, err = strategy.Compress(payload)
+		if err != nil {
+			return nil, err
+		}
+		estimator.Observe(payloadType, len(payload), len(compressedPayload), maxPayloadSizeUnCompressed, maxPayloadSizeCompressed)
+	} else {
+		sizing.ObserveFill(payloadType, len(payload), len(compressedPayload), maxPayloadSizeUnCompressed, maxPayloadSizeCompressed)
+	}
+
+	if !tooBigCompressed(compressedPayload) && !tooBigUnCompressed(payload) {
+		return transaction.BytesPayloads{transaction.NewBytesPayloadWithoutMetaData(compressedPayload)}, nil
+	}
+
+	log.Debugf("a chunk of the %s payload is too big (%d bytes compressed), halving it", payloadType, len(compressedPayload))
+	splitterChunksMissed.Add(1)
+	tlmSplitterChunksMissed.Inc(payloadType)
+	var halves []marshaler.AbstractMarshaler
+	if halvings < maxChunkHalvings {
+		halves, err = chunk.SplitPayload(2)
+	}
+	if halvings >= maxChunkHalvings || err != nil || len(halves) < 2 {
+		log.Warnf("A chunk of the %s payload could not be split, dropping it", payloadType)
 		splitterPayloadDrops.Add(1)
 		tlmSplitterPayloadDrops.Inc()
+		return nil, nil
 	}
 
-	return smallEnoughPayloads, nil
+	payloads := transaction.BytesPayloads{}
+	for _, half := range halves {
+		halfPayloads, err := compressChunk(half, nil, payloadType, compress, marshalFct, strategy, estimator, halvings+1)
+		payloads = append(payloads, halfPayloads...)
+		if err != nil {
+			return payloads, err
+		}
+	}
+	return payloads, nil
+}
+
+// chunksFor returns the number of chunks of chunkTargetFill * limit bytes needed for size bytes
+func chunksFor(size int, limit int) int {
+	target := int(float64(limit) * chunkTargetFill)
+	if size <= limit || target <= 0 {
+		return 1
+	}
+	return (size + target - 1) / target
 }
 
 // serializeMarshaller serializes the marshaller and returns both the compressed and uncompressed payloads
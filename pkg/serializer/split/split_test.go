@@ -15,11 +15,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/comp/serializer/compression"
 	"github.com/DataDog/datadog-agent/comp/serializer/compression/compressionimpl"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sizing"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
 
 	mock "github.com/DataDog/datadog-agent/pkg/config/mock"
@@ -54,8 +56,10 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 	tests := map[string]struct {
 		kind string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind},
-		"zstd": {kind: compressionimpl.ZstdKind},
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -88,7 +92,7 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 			strategy := compressionimpl.NewCompressor(mockConfig)
 
-			payloads, err := Payloads(testSeries, compress, JSONMarshalFct, strategy)
+			payloads, err := AdaptivePayloads(testSeries, compress, JSONMarshalFct, strategy, sizing.NewEstimator())
 			require.Nil(t, err)
 
 			originalLength := len(testSeries)
@@ -139,9 +143,9 @@ func BenchmarkSplitPayloadsSeries(b *testing.B) {
 	strategy := compressionimpl.NewCompressor(mockConfig)
 	var r transaction.BytesPayloads
 	for n := 0; n < b.N; n++ {
-		// always record the result of Payloads to prevent
+		// always record the result of AdaptivePayloads to prevent
 		// the compiler eliminating the function call.
-		r, _ = Payloads(testSeries, true, JSONMarshalFct, strategy)
+		r, _ = AdaptivePayloads(testSeries, true, JSONMarshalFct, strategy, sizing.NewEstimator())
 
 	}
 	// ensure we actually had to split
@@ -193,8 +197,10 @@ func testSplitPayloadsEvents(t *testing.T, numPoints int, compress bool) {
 	tests := map[string]struct {
 		kind string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind},
-		"zstd": {kind: compressionimpl.ZstdKind},
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -216,7 +222,7 @@ func testSplitPayloadsEvents(t *testing.T, numPoints int, compress bool) {
 			mockConfig := mock.New(t)
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 			strategy := compressionimpl.NewCompressor(mockConfig)
-			payloads, err := Payloads(testEvent, compress, JSONMarshalFct, strategy)
+			payloads, err := AdaptivePayloads(testEvent, compress, JSONMarshalFct, strategy, sizing.NewEstimator())
 			require.Nil(t, err)
 
 			originalLength := len(testEvent.EventsArr)
@@ -274,8 +280,10 @@ func testSplitPayloadsServiceChecks(t *testing.T, numPoints int, compress bool)
 	tests := map[string]struct {
 		kind string
 	}{
-		"zlib": {kind: compressionimpl.ZlibKind},
-		"zstd": {kind: compressionimpl.ZstdKind},
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -295,7 +303,7 @@ func testSplitPayloadsServiceChecks(t *testing.T, numPoints int, compress bool)
 			mockConfig := mock.New(t)
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 			strategy := compressionimpl.NewCompressor(mockConfig)
-			payloads, err := Payloads(testServiceChecks, compress, JSONMarshalFct, strategy)
+			payloads, err := AdaptivePayloads(testServiceChecks, compress, JSONMarshalFct, strategy, sizing.NewEstimator())
 			require.Nil(t, err)
 
 			originalLength := len(testServiceChecks)
@@ -322,3 +330,165 @@ func testSplitPayloadsServiceChecks(t *testing.T, numPoints int, compress bool)
 
 	}
 }
+
+func TestAdaptivePayloadsSeries(t *testing.T) {
+	prevMaxPayloadSizeCompressed := maxPayloadSizeCompressed
+	maxPayloadSizeCompressed = 1024
+	defer func() { maxPayloadSizeCompressed = prevMaxPayloadSizeCompressed }()
+
+	prevMaxPayloadSizeUnCompressed := maxPayloadSizeUnCompressed
+	maxPayloadSizeUnCompressed = 16 * 1024
+	defer func() { maxPayloadSizeUnCompressed = prevMaxPayloadSizeUnCompressed }()
+
+	tests := map[string]struct {
+		kind string
+	}{
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockConfig := mock.New(t)
+			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
+			strategy := compressionimpl.NewCompressor(mockConfig)
+			estimator := sizing.NewEstimator()
+
+			testSeries := metricsserializer.Series{}
+			for i := 0; i < 200; i++ {
+				testSeries = append(testSeries, &metrics.Serie{
+					Points:   []metrics.Point{{Ts: 12345.0, Value: float64(i)}, {Ts: 67890.0, Value: float64(i * 2)}},
+					MType:    metrics.APIGaugeType,
+					Name:     fmt.Sprintf("test.metrics%d", i),
+					Interval: 1,
+					Host:     "localHost",
+					Tags:     tagset.CompositeTagsFromSlice([]string{"tag1", fmt.Sprintf("tag2:%d", i)}),
+				})
+			}
+
+			missesBefore := splitterChunksMissed.Value()
+			var payloadCounts []int
+			for run := 0; run < 3; run++ {
+				payloads, err := AdaptivePayloads(testSeries, true, JSONMarshalFct, strategy, estimator)
+				require.NoError(t, err)
+
+				seriesCount := 0
+				for _, payload := range payloads {
+					require.LessOrEqual(t, len(payload.GetContent()), maxPayloadSizeCompressed)
+					decompressed, err := strategy.Decompress(payload.GetContent())
+					require.NoError(t, err)
+					var s map[string]metricsserializer.Series
+					require.NoError(t, json.Unmarshal(decompressed, &s))
+					seriesCount += len(s["series"])
+				}
+				require.Equal(t, len(testSeries), seriesCount)
+				payloadCounts = append(payloadCounts, len(payloads))
+			}
+
+			// Once the compression ratio is learned, the payloads are larger
+			require.Less(t, estimator.Ratio("series"), 1.0)
+			require.Less(t, payloadCounts[2], payloadCounts[0])
+			// and the chunks are not split again
+			require.Equal(t, missesBefore, splitterChunksMissed.Value())
+		})
+	}
+}
+
+func TestStreamPayloadsSeries(t *testing.T) {
+	prevMaxPayloadSizeCompressed := maxPayloadSizeCompressed
+	maxPayloadSizeCompressed = 1024
+	defer func() { maxPayloadSizeCompressed = prevMaxPayloadSizeCompressed }()
+
+	prevMaxPayloadSizeUnCompressed := maxPayloadSizeUnCompressed
+	maxPayloadSizeUnCompressed = 16 * 1024
+	defer func() { maxPayloadSizeUnCompressed = prevMaxPayloadSizeUnCompressed }()
+
+	tests := map[string]struct {
+		kind string
+	}{
+		"zlib":   {kind: compressionimpl.ZlibKind},
+		"zstd":   {kind: compressionimpl.ZstdKind},
+		"lz4":    {kind: compressionimpl.LZ4Kind},
+		"snappy": {kind: compressionimpl.SnappyKind},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockConfig := mock.New(t)
+			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
+			strategy := compressionimpl.NewCompressor(mockConfig)
+			estimator := sizing.NewEstimator()
+
+			missesBefore := splitterChunksMissed.Value()
+			var payloadCounts []int
+			for run := 0; run < 3; run++ {
+				source := metricsserializer.NewSeriesChunks(metricsserializer.CreateIterableSeries(metricsserializer.CreateSerieSource(makeStreamTestSeries(200))))
+				payloads, err := StreamPayloads(source, true, JSONMarshalFct, strategy, estimator)
+				require.NoError(t, err)
+				require.Equal(t, 200, countCompressedSeries(t, strategy, payloads))
+				payloadCounts = append(payloadCounts, len(payloads))
+			}
+
+			// Once the compression ratio is learned, the payloads are larger
+			require.Less(t, estimator.Ratio("series"), 1.0)
+			require.Less(t, payloadCounts[2], payloadCounts[0])
+			// and the chunks are not split again
+			require.Equal(t, missesBefore, splitterChunksMissed.Value())
+		})
+	}
+}
+
+func TestStreamPayloadsHalvesMissedChunks(t *testing.T) {
+	prevMaxPayloadSizeCompressed := maxPayloadSizeCompressed
+	maxPayloadSizeCompressed = 1024
+	defer func() { maxPayloadSizeCompressed = prevMaxPayloadSizeCompressed }()
+
+	prevMaxPayloadSizeUnCompressed := maxPayloadSizeUnCompressed
+	maxPayloadSizeUnCompressed = 16 * 1024
+	defer func() { maxPayloadSizeUnCompressed = prevMaxPayloadSizeUnCompressed }()
+
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("serializer_compressor_kind", compressionimpl.ZlibKind)
+	strategy := compressionimpl.NewCompressor(mockConfig)
+	estimator := sizing.NewEstimator()
+	// The series compress worse than the learned ratio
+	estimator.Observe("series", 100000, 100, 0, 0)
+
+	missesBefore := splitterChunksMissed.Value()
+	dropsBefore := GetPayloadDrops()
+	source := metricsserializer.NewSeriesChunks(metricsserializer.CreateIterableSeries(metricsserializer.CreateSerieSource(makeStreamTestSeries(200))))
+	payloads, err := StreamPayloads(source, true, JSONMarshalFct, strategy, estimator)
+	require.NoError(t, err)
+
+	require.Equal(t, 200, countCompressedSeries(t, strategy, payloads))
+	require.Greater(t, splitterChunksMissed.Value(), missesBefore)
+	require.Equal(t, dropsBefore, GetPayloadDrops())
+}
+
+func makeStreamTestSeries(count int) metrics.Series {
+	series := metrics.Series{}
+	for i := 0; i < count; i++ {
+		series = append(series, &metrics.Serie{
+			Points:   []metrics.Point{{Ts: 12345.0, Value: float64(i)}, {Ts: 67890.0, Value: float64(i * 2)}},
+			MType:    metrics.APIGaugeType,
+			Name:     fmt.Sprintf("test.metrics%d", i),
+			Interval: 1,
+			Host:     "localHost",
+			Tags:     tagset.CompositeTagsFromSlice([]string{"tag1", fmt.Sprintf("tag2:%d", i)}),
+		})
+	}
+	return series
+}
+
+func countCompressedSeries(t *testing.T, strategy compression.Component, payloads transaction.BytesPayloads) int {
+	seriesCount := 0
+	for _, payload := range payloads {
+		require.LessOrEqual(t, len(payload.GetContent()), maxPayloadSizeCompressed)
+		decompressed, err := strategy.Decompress(payload.GetContent())
+		require.NoError(t, err)
+		var s map[string]metricsserializer.Series
+		require.NoError(t, json.Unmarshal(decompressed, &s))
+		seriesCount += len(s["series"])
+	}
+	return seriesCount
+}
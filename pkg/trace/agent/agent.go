@@ -86,13 +86,16 @@ type Agent struct {
 	OTLPReceiver          *api.OTLPReceiver
 	Concentrator          Concentrator
 	ClientStatsAggregator *stats.ClientStatsAggregator
+	SpanMetrics           *stats.SpanMetrics
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	SpanRules             *filters.SpanRules
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
 	ProbabilisticSampler  *sampler.ProbabilisticSampler
+	TailSampler           *sampler.TailSampler
 	EventProcessor        *event.Processor
 	TraceWriter           TraceWriter
 	StatsWriter           *writer.DatadogStatsWriter
@@ -147,8 +150,10 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	agnt := &Agent{
 		Concentrator:          stats.NewConcentrator(conf, statsWriter, time.Now(), statsd),
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsWriter, statsd),
+		SpanMetrics:           stats.NewSpanMetrics(conf, statsd),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		SpanRules:             filters.NewSpanRules(conf.SpanRules, statsd),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf, statsd),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf, statsd),
 		RareSampler:           sampler.NewRareSampler(conf, statsd),
@@ -168,6 +173,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
 	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector, statsd, timing, comp)
+	agnt.TailSampler = sampler.NewTailSampler(conf, statsd, agnt.writeTailSampledChunks)
 	return agnt
 }
 
@@ -183,6 +189,7 @@ func (a *Agent) Run() {
 		a.ErrorsSampler,
 		a.NoPrioritySampler,
 		a.ProbabilisticSampler,
+		a.TailSampler,
 		a.EventProcessor,
 		a.OTLPReceiver,
 		a.RemoteConfigHandler,
@@ -260,6 +267,7 @@ func (a *Agent) loop() {
 		log.Error(err)
 	}
 	for _, stopper := range []interface{ Stop() }{
+		a.TailSampler, // the traces buffered by the TailSampler are written to the TraceWriter
 		a.Concentrator,
 		a.ClientStatsAggregator,
 		a.TraceWriter,
@@ -315,6 +323,8 @@ func (a *Agent) Process(p *api.Payload) {
 	defer a.Timing.Since("datadog.trace_agent.internal.process_payload_ms", now)
 	ts := p.Source
 	sampledChunks := new(writer.SampledChunks)
+	// tailPayload holds the metadata of the payload for the chunks buffered by the TailSampler
+	var tailPayload *pb.TracerPayload
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
 
 	p.TracerPayload.Env = traceutil.NormalizeTag(p.TracerPayload.Env)
@@ -338,6 +348,11 @@ func (a *Agent) Process(p *api.Payload) {
 			p.RemoveChunk(i)
 			continue
 		}
+		chunk.Spans = a.SpanRules.Apply(chunk.Spans)
+		if dropped := tracen - int64(len(chunk.Spans)); dropped > 0 {
+			ts.SpansFiltered.Add(dropped)
+			tracen -= dropped
+		}
 
 		// Root span is used to carry some trace-level metadata, such as sampling rate and priority.
 		root := traceutil.GetRoot(chunk.Spans)
@@ -388,10 +403,22 @@ func (a *Agent) Process(p *api.Payload) {
 		a.setPayloadAttributes(p, root, chunk)
 
 		pt := processedTrace(p, chunk, root, p.TracerPayload.ContainerID, a.conf)
+		a.SpanMetrics.Add(pt)
 		if !p.ClientComputedStats {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
+		if a.TailSampler.Enabled() {
+			if tailPayload == nil {
+				tailPayload = payloadMetadata(p.TracerPayload)
+			}
+			if a.TailSampler.Add(sampler.TailChunk{Payload: tailPayload, Trace: pt, Source: ts}) {
+				// The chunk is sampled with the rest of its trace once it is complete.
+				p.RemoveChunk(i)
+				continue
+			}
+		}
+
 		keep, numEvents := a.sample(now, ts, pt)
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
@@ -431,6 +458,50 @@ func (a *Agent) Process(p *api.Payload) {
 	}
 }
 
+// writeTailSampledChunks writes the chunks of the traces sampled by the TailSampler,
+// after applying its decision like Process does for the other chunks. The
+// consecutive chunks received in the same payload are written together.
+func (a *Agent) writeTailSampledChunks(chunks []sampler.TailChunk) {
+	var sampledChunks *writer.SampledChunks
+	for i, c := range chunks {
+		pt := c.Trace
+		keep := !pt.TraceChunk.DroppedTrace
+		numEvents := a.applySamplingDecision(c.Source.(*info.TagStats), pt, keep, !a.isUserDrop(pt))
+		if keep || len(pt.TraceChunk.Spans) > 0 {
+			if sampledChunks == nil {
+				sampledChunks = new(writer.SampledChunks)
+				sampledChunks.TracerPayload = payloadMetadata(c.Payload)
+			}
+			if !pt.TraceChunk.DroppedTrace {
+				a.setFirstTraceTags(pt.Root)
+				sampledChunks.SpanCount += int64(len(pt.TraceChunk.Spans))
+			}
+			sampledChunks.TracerPayload.Chunks = append(sampledChunks.TracerPayload.Chunks, pt.TraceChunk)
+			sampledChunks.EventCount += int64(numEvents)
+			sampledChunks.Size += pt.TraceChunk.Msgsize()
+		}
+		if sampledChunks != nil && (i == len(chunks)-1 || chunks[i+1].Payload != c.Payload || sampledChunks.Size > writer.MaxPayloadSize) {
+			a.TraceWriter.WriteChunks(sampledChunks)
+			sampledChunks = nil
+		}
+	}
+}
+
+// payloadMetadata returns a copy of the tracer payload without its chunks.
+func payloadMetadata(p *pb.TracerPayload) *pb.TracerPayload {
+	return &pb.TracerPayload{
+		ContainerID:     p.GetContainerID(),
+		LanguageName:    p.GetLanguageName(),
+		LanguageVersion: p.GetLanguageVersion(),
+		TracerVersion:   p.GetTracerVersion(),
+		RuntimeID:       p.GetRuntimeID(),
+		Env:             p.GetEnv(),
+		Hostname:        p.GetHostname(),
+		AppVersion:      p.GetAppVersion(),
+		Tags:            p.GetTags(),
+	}
+}
+
 func (a *Agent) setPayloadAttributes(p *api.Payload, root *pb.Span, chunk *pb.TraceChunk) {
 	if p.TracerPayload.Hostname == "" {
 		// Older tracers set tracer hostname in the root span.
@@ -567,7 +638,12 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt *traceutil.Processed
 	// For example: We want to maintain the overall trace level sampling decision for a trace with Analytics Events
 	// where a trace might be marked as DroppedTrace true, but we still sent analytics events in that ProcessedTrace.
 	keep, checkAnalyticsEvents := a.traceSampling(now, ts, pt)
+	return keep, a.applySamplingDecision(ts, pt, keep, checkAnalyticsEvents)
+}
 
+// applySamplingDecision extracts the analytics events of the trace, and samples
+// its single spans if it is not kept. It returns the number of events.
+func (a *Agent) applySamplingDecision(ts *info.TagStats, pt *traceutil.ProcessedTrace, keep bool, checkAnalyticsEvents bool) int {
 	var events []*pb.Span
 	if checkAnalyticsEvents {
 		events = a.getAnalyzedEvents(pt, ts)
@@ -584,7 +660,21 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt *traceutil.Processed
 		}
 	}
 
-	return keep, len(events)
+	return len(events)
+}
+
+// isUserDrop reports whether the trace is dropped by the user, in which case
+// its analytics events are not extracted.
+func (a *Agent) isUserDrop(pt *traceutil.ProcessedTrace) bool {
+	if a.conf.HasFeature("error_rare_sample_tracer_drop") {
+		// We skip analytics events when a trace is marked as manual drop (aka priority -1)
+		// Note that we DON'T skip single span sampling. We only do this for historical
+		// reasons and analytics events are deprecated so hopefully this can all go away someday.
+		return isManualUserDrop(pt)
+	}
+	// This path to be deleted once manualUserDrop detection is available on all tracers for P < 1.
+	priority, _ := sampler.GetSamplingPriority(pt.TraceChunk)
+	return priority < 0
 }
 
 // isManualUserDrop returns true if and only if the ProcessedTrace is marked as Priority User Drop
@@ -652,17 +742,8 @@ func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.Proce
 	} else {
 		ts.TracesPriorityNone.Inc()
 	}
-	if a.conf.HasFeature("error_rare_sample_tracer_drop") {
-		// We skip analytics events when a trace is marked as manual drop (aka priority -1)
-		// Note that we DON'T skip single span sampling. We only do this for historical
-		// reasons and analytics events are deprecated so hopefully this can all go away someday.
-		if isManualUserDrop(&pt) {
-			return false, false
-		}
-	} else { // This path to be deleted once manualUserDrop detection is available on all tracers for P < 1.
-		if priority < 0 {
-			return false, false
-		}
+	if a.isUserDrop(&pt) {
+		return false, false
 	}
 
 	if rare {
@@ -38,6 +38,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats"
 	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/teststatsd"
 	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
@@ -205,6 +206,91 @@ func TestProcess(t *testing.T) {
 		assert.Equal(t, 42.0, span.Metrics["safe.data"])
 	})
 
+	t.Run("SpanRules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanRules = []*config.SpanRule{
+			{Operation: "^cache\\.", Action: config.SpanRuleDropSpan},
+			{Service: "^pg-", Action: config.SpanRuleRenameService, Value: "postgres"},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+
+		now := time.Now()
+		span := func(spanID, parentID uint64, service, name string) *pb.Span {
+			return &pb.Span{
+				TraceID:  1,
+				SpanID:   spanID,
+				ParentID: parentID,
+				Service:  service,
+				Name:     name,
+				Resource: "resource",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+			}
+		}
+		root, query := span(1, 0, "web", "http.request"), span(3, 2, "pg-primary", "pg.query")
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpans([]*pb.Span{
+				root,
+				span(2, 1, "web", "cache.get"),
+				query,
+			})),
+			Source: want,
+		})
+
+		// the rules are applied before the stats are computed
+		inputs := agnt.Concentrator.(*mockConcentrator).Reset()
+		require.Len(t, inputs, 1)
+		assert.Len(t, inputs[0].Traces[0].TraceChunk.Spans, 2)
+		assert.Equal(t, "postgres", query.Service)
+		assert.Equal(t, uint64(1), query.ParentID)
+		assert.EqualValues(t, 1, want.SpansFiltered.Load())
+		assert.EqualValues(t, 0, want.TracesFiltered.Load())
+	})
+
+	t.Run("SpanMetrics", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanMetrics = []*config.SpanMetric{{
+			Name:    "requests",
+			Type:    config.SpanMetricCount,
+			GroupBy: []string{"customer_tier"},
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+		client := &teststatsd.Client{}
+		agnt.SpanMetrics = stats.NewSpanMetrics(cfg, client)
+
+		now := time.Now()
+		span := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Service:  "web",
+			Name:     "http.request",
+			Resource: "resource",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"customer_tier": "gold"},
+			Metrics:  map[string]float64{"_sampling_priority_v1": -1},
+		}
+		agnt.Process(&api.Payload{
+			TracerPayload:       testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span)),
+			Source:              info.NewReceiverStats().GetTagStats(info.Tags{}),
+			ClientComputedStats: true,
+		})
+
+		// the metrics are generated before the traces are sampled, even when
+		// their stats are computed by the client
+		assert.Empty(t, agnt.TraceWriter.(*mockTraceWriter).payloads)
+		require.Len(t, client.CountCalls, 1)
+		assert.Equal(t, "requests", client.CountCalls[0].Name)
+		assert.Equal(t, []string{"customer_tier:gold"}, client.CountCalls[0].Tags)
+	})
+
 	t.Run("Blacklister", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -1386,6 +1472,89 @@ func TestSampleManualUserDropNoAnalyticsEvents(t *testing.T) {
 	assert.Empty(t, pt.Root.Metrics["_dd.analyzed"])
 }
 
+func TestTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.Policies = []*config.TailSamplingPolicy{{Type: config.TailSamplingError}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	agnt.TailSampler.Start()
+
+	now := time.Now()
+	span := func(traceID, spanID uint64, errored int32) *pb.Span {
+		return &pb.Span{
+			TraceID:  traceID,
+			SpanID:   spanID,
+			Service:  "s",
+			Name:     "n",
+			Resource: "r",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Error:    errored,
+			Metrics:  map[string]float64{"_sampling_priority_v1": 0.0},
+		}
+	}
+	first := testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span(1, 1, 0)))
+	first.Hostname = "first"
+	first.Chunks = append(first.Chunks, testutil.TraceChunkWithSpan(span(2, 2, 0)))
+	// the error is in the second chunk of the trace
+	second := testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span(1, 3, 1)))
+	second.Hostname = "second"
+	for _, tp := range []*pb.TracerPayload{first, second} {
+		agnt.Process(&api.Payload{
+			TracerPayload: tp,
+			Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+		})
+	}
+	// the chunks are buffered, and the stats computed on all of them
+	assert.Empty(t, agnt.TraceWriter.(*mockTraceWriter).payloads)
+	assert.Len(t, agnt.Concentrator.(*mockConcentrator).Reset(), 2)
+
+	agnt.TailSampler.Stop()
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	assert.Len(t, payloads, 2)
+	for i, hostname := range []string{"first", "second"} {
+		tp := payloads[i].TracerPayload
+		assert.Equal(t, hostname, tp.Hostname)
+		assert.Len(t, tp.Chunks, 1)
+		assert.False(t, tp.Chunks[0].DroppedTrace)
+		assert.Equal(t, uint64(1), tp.Chunks[0].Spans[0].TraceID)
+		assert.EqualValues(t, 1, payloads[i].SpanCount)
+	}
+}
+
+func TestTailSamplingSingleSpanSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.Policies = []*config.TailSamplingPolicy{{Type: config.TailSamplingError}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	agnt.TailSampler.Start()
+
+	now := time.Now()
+	root := &pb.Span{TraceID: 1, SpanID: 1, Service: "s", Name: "n", Resource: "r", Start: now.Add(-time.Second).UnixNano(), Duration: (500 * time.Millisecond).Nanoseconds(), Metrics: map[string]float64{"_sampling_priority_v1": 0.0}}
+	sss := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "s", Name: "n", Resource: "r", Start: now.Add(-time.Second).UnixNano(), Duration: (100 * time.Millisecond).Nanoseconds(), Metrics: map[string]float64{sampler.KeySpanSamplingMechanism: 8}}
+	agnt.Process(&api.Payload{
+		TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpans([]*pb.Span{root, sss})),
+		Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+	})
+
+	// the trace has no error and is dropped, only its single span sampled span is kept
+	agnt.TailSampler.Stop()
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	require.Len(t, payloads, 1)
+	chunks := payloads[0].TracerPayload.Chunks
+	require.Len(t, chunks, 1)
+	assert.False(t, chunks[0].DroppedTrace)
+	assert.EqualValues(t, sampler.PriorityUserKeep, chunks[0].Priority)
+	require.Len(t, chunks[0].Spans, 1)
+	assert.Equal(t, uint64(2), chunks[0].Spans[0].SpanID)
+}
+
 func TestPartialSamplingFree(t *testing.T) {
 	cfg := &config.AgentConfig{RareSamplerEnabled: false, BucketInterval: 10 * time.Second}
 	dynConf := sampler.NewDynamicConfig()
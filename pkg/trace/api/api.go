@@ -8,6 +8,7 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/tinylib/msgp/msgp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/atomic"
 
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
@@ -100,6 +102,10 @@ type HTTPReceiver struct {
 	telemetryCollector telemetry.TelemetryCollector
 	telemetryForwarder *TelemetryForwarder
 
+	// otlp converts the spans received in other formats, such as Zipkin or
+	// Jaeger, once they are translated to OTLP.
+	otlp *OTLPReceiver
+
 	rateLimiterResponse int // HTTP status code when refusing
 
 	wg   sync.WaitGroup // waits for all requests to be processed
@@ -155,6 +161,8 @@ func NewHTTPReceiver(
 		telemetryCollector: telemetryCollector,
 		telemetryForwarder: telemetryForwarder,
 
+		otlp: NewOTLPReceiver(out, conf, statsd, timing),
+
 		rateLimiterResponse: rateLimiterResponse,
 
 		exit: make(chan struct{}),
@@ -644,6 +652,66 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 	r.out <- payload
 }
 
+// receiveOTelTraces receives the traces of the endpoints accepting spans in other
+// formats than Datadog's, such as OTLP, Zipkin or Jaeger: unmarshaler translates
+// them to OTLP, then they are processed like the traces of the OTLP receiver.
+// It reports whether the traces were received, otherwise the error is written
+// to w. The caller writes the response of the received traces.
+func (r *HTTPReceiver) receiveOTelTraces(endpointVersion string, w http.ResponseWriter, req *http.Request, unmarshaler ptrace.Unmarshaler) bool {
+	defer req.Body.Close()
+	if req.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		http.Error(w, "cross-site request rejected", http.StatusForbidden)
+		return false
+	}
+
+	select {
+	case r.recvsem <- struct{}{}:
+	case <-time.After(time.Duration(r.conf.DecoderTimeout) * time.Millisecond):
+		// this payload can not be accepted
+		io.Copy(io.Discard, req.Body) //nolint:errcheck
+		w.WriteHeader(http.StatusTooManyRequests)
+		return false
+	}
+	defer func() {
+		<-r.recvsem
+	}()
+
+	start := time.Now()
+	tags := []string{"handler:traces", "v:" + endpointVersion}
+	body, err := readRequestBody(req, r.conf.MaxRequestBytes)
+	var traces ptrace.Traces
+	if err == nil {
+		traces, err = unmarshaler.UnmarshalTraces(body)
+	}
+	_ = r.statsd.Histogram("datadog.trace_agent.receiver.serve_traces_ms", float64(time.Since(start))/float64(time.Millisecond), append(tags, fmt.Sprintf("success:%v", err == nil)), 1)
+	if err != nil {
+		httpDecodingError(err, tags, w, r.statsd)
+		log.Errorf("Cannot decode %s traces payload: %v", endpointVersion, err)
+		return false
+	}
+
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		r.otlp.receiveResourceSpans(req.Context(), traces.ResourceSpans().At(i), req.Header, endpointVersion)
+	}
+	return true
+}
+
+// readRequestBody reads the body of the request, decompressing it if it is
+// gzip encoded. Both the body and its decompressed content are limited to
+// limit bytes.
+func readRequestBody(req *http.Request, limit int64) ([]byte, error) {
+	var rd io.ReadCloser = apiutil.NewLimitedReader(req.Body, limit)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(rd)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		rd = apiutil.NewLimitedReader(gz, limit)
+	}
+	return io.ReadAll(rd)
+}
+
 func droppedTracesFromHeader(h http.Header, ts *info.TagStats) int64 {
 	var dropped int64
 	if v := h.Get(header.DroppedP0Traces); v != "" {
@@ -94,6 +94,21 @@ var endpoints = []Endpoint{
 		Pattern: "/v0.7/traces",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.handleWithVersion(V07, r.handleTraces) },
 	},
+	{
+		Pattern:   "/v1/traces",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleOTLPTraces) },
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.OTLPReceiver != nil && cfg.OTLPReceiver.HTTPEnabled },
+	},
+	{
+		Pattern:   "/api/v2/spans",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleZipkin) },
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.ZipkinReceiverEnabled },
+	},
+	{
+		Pattern:   "/api/traces",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleJaeger) },
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.JaegerReceiverEnabled },
+	},
 	{
 		Pattern: "/profiling/v1/input",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.profileProxyHandler() },
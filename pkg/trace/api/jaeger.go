@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	jaegerthrift "github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/jaeger"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// jaegerThrift is the endpoint version of the Jaeger Thrift batches.
+const jaegerThrift = "jaeger_thrift"
+
+// handleJaeger handles the Jaeger batches encoded with the Thrift binary
+// protocol, as sent over HTTP to the Jaeger collector. The Jaeger process is
+// the service of the spans, and their span.kind tag is translated to their
+// span kind.
+func (r *HTTPReceiver) handleJaeger(w http.ResponseWriter, req *http.Request) {
+	switch mediaType := getMediaType(req); mediaType {
+	case "application/x-thrift", "application/vnd.apache.thrift.binary":
+	default:
+		httpFormatError(w, jaegerThrift, fmt.Errorf("unsupported media type: %q", mediaType), r.statsd)
+		return
+	}
+	if r.receiveOTelTraces(jaegerThrift, w, req, jaegerThriftUnmarshaler{}) {
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// jaegerThriftUnmarshaler translates a Jaeger Thrift batch to OTLP.
+type jaegerThriftUnmarshaler struct{}
+
+// UnmarshalTraces implements ptrace.Unmarshaler.
+func (jaegerThriftUnmarshaler) UnmarshalTraces(buf []byte) (ptrace.Traces, error) {
+	batch := &jaegerthrift.Batch{}
+	if err := thrift.NewTDeserializer().Read(context.Background(), batch, buf); err != nil {
+		return ptrace.NewTraces(), err
+	}
+	return jaeger.ThriftToTraces(batch)
+}
@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	jaegerthrift "github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jaegerBatch(t *testing.T) []byte {
+	kind, method := "client", "GET"
+	batch := &jaegerthrift.Batch{
+		Process: &jaegerthrift.Process{ServiceName: "checkout"},
+		Spans: []*jaegerthrift.Span{{
+			TraceIdHigh:   1,
+			TraceIdLow:    2,
+			SpanId:        3,
+			ParentSpanId:  4,
+			OperationName: "get /cart",
+			StartTime:     1700000000000000,
+			Duration:      2500,
+			Tags: []*jaegerthrift.Tag{
+				{Key: "span.kind", VType: jaegerthrift.TagType_STRING, VStr: &kind},
+				{Key: "http.method", VType: jaegerthrift.TagType_STRING, VStr: &method},
+			},
+		}},
+	}
+	body, err := thrift.NewTSerializer().Write(context.Background(), batch)
+	require.NoError(t, err)
+	return body
+}
+
+func TestJaeger(t *testing.T) {
+	conf := NewTestConfig(t)
+	conf.DecoderTimeout = 10000
+	conf.JaegerReceiverEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+
+	req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader(jaegerBatch(t)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-thrift")
+	rr := httptest.NewRecorder()
+	rcv.buildMux().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+
+	select {
+	case p := <-rcv.out:
+		assert.Equal(t, jaegerThrift, p.Source.EndpointVersion)
+		require.Len(t, p.TracerPayload.Chunks, 1)
+		require.Len(t, p.TracerPayload.Chunks[0].Spans, 1)
+		span := p.TracerPayload.Chunks[0].Spans[0]
+		assert.Equal(t, "checkout", span.Service)
+		assert.Equal(t, uint64(2), span.TraceID)
+		assert.Equal(t, uint64(3), span.SpanID)
+		assert.Equal(t, uint64(4), span.ParentID)
+		assert.Equal(t, int64(2500*time.Microsecond), span.Duration)
+		assert.Equal(t, "client", span.Meta["span.kind"])
+		assert.Equal(t, "GET", span.Meta["http.method"])
+		assert.Equal(t, "00000000000000010000000000000002", span.Meta["otel.trace_id"])
+	case <-time.After(time.Second):
+		t.Fatal("no payload received")
+	}
+}
+
+func TestJaegerErrors(t *testing.T) {
+	conf := NewTestConfig(t)
+	conf.DecoderTimeout = 10000
+	conf.JaegerReceiverEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+	mux := rcv.buildMux()
+
+	for contentType, code := range map[string]int{
+		"application/json":     http.StatusUnsupportedMediaType,
+		"application/x-thrift": http.StatusBadRequest,
+	} {
+		req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader([]byte("not thrift")))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, code, rr.Code, contentType)
+	}
+	assert.Empty(t, rcv.out)
+}
@@ -42,6 +42,13 @@ import (
 // computed for the resource spans.
 const keyStatsComputed = "_dd.stats_computed"
 
+const (
+	// otlpGRPCVersion is the endpoint version of the traces received over OTLP/gRPC.
+	otlpGRPCVersion = "opentelemetry_grpc_v1"
+	// otlpHTTPVersion is the endpoint version of the traces received over OTLP/HTTP.
+	otlpHTTPVersion = "opentelemetry_http_v1"
+)
+
 var _ (ptraceotlp.GRPCServer) = (*OTLPReceiver)(nil)
 
 // OTLPReceiver implements an OpenTelemetry Collector receiver which accepts incoming
@@ -104,13 +111,13 @@ func (o *OTLPReceiver) Stop() {
 func (o *OTLPReceiver) Export(ctx context.Context, in ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
 	defer o.timing.Since("datadog.trace_agent.otlp.process_grpc_request_ms", time.Now())
 	md, _ := metadata.FromIncomingContext(ctx)
-	_ = o.statsd.Count("datadog.trace_agent.otlp.payload", 1, tagsFromHeaders(http.Header(md)), 1)
+	_ = o.statsd.Count("datadog.trace_agent.otlp.payload", 1, tagsFromHeaders(http.Header(md), otlpGRPCVersion), 1)
 	o.processRequest(ctx, http.Header(md), in)
 	return ptraceotlp.NewExportResponse(), nil
 }
 
-func tagsFromHeaders(h http.Header) []string {
-	tags := []string{"endpoint_version:opentelemetry_grpc_v1"}
+func tagsFromHeaders(h http.Header, endpointVersion string) []string {
+	tags := []string{"endpoint_version:" + endpointVersion}
 	if v := fastHeaderGet(h, header.Lang); v != "" {
 		tags = append(tags, "lang:"+v)
 	}
@@ -187,6 +194,11 @@ func (o *OTLPReceiver) SetOTelAttributeTranslator(attrstrans *attributes.Transla
 
 // ReceiveResourceSpans processes the given rspans and returns the source that it identified from processing them.
 func (o *OTLPReceiver) ReceiveResourceSpans(ctx context.Context, rspans ptrace.ResourceSpans, httpHeader http.Header) source.Source {
+	return o.receiveResourceSpans(ctx, rspans, httpHeader, otlpGRPCVersion)
+}
+
+// receiveResourceSpans processes the given rspans received on the endpoint of the given version.
+func (o *OTLPReceiver) receiveResourceSpans(ctx context.Context, rspans ptrace.ResourceSpans, httpHeader http.Header, endpointVersion string) source.Source {
 	// each rspans is coming from a different resource and should be considered
 	// a separate payload; typically there is only one item in this slice
 	src, srcok := o.conf.OTLPReceiver.AttributesTranslator.ResourceToSource(ctx, rspans.Resource(), traceutil.SignalTypeSet)
@@ -224,7 +236,7 @@ func (o *OTLPReceiver) ReceiveResourceSpans(ctx context.Context, rspans ptrace.R
 			Interpreter:     fastHeaderGet(httpHeader, header.LangInterpreter),
 			LangVendor:      fastHeaderGet(httpHeader, header.LangInterpreterVendor),
 			TracerVersion:   fmt.Sprintf("otlp-%s", rattr[string(semconv.AttributeTelemetrySDKVersion)]),
-			EndpointVersion: "opentelemetry_grpc_v1",
+			EndpointVersion: endpointVersion,
 		},
 		Stats: info.NewStats(),
 	}
@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// handleOTLPTraces handles the OTLP/HTTP traces, encoded in Protobuf or JSON,
+// for the exporters which can not use gRPC. They are processed like the traces
+// received over OTLP/gRPC, and the response is encoded like the request.
+func (r *HTTPReceiver) handleOTLPTraces(w http.ResponseWriter, req *http.Request) {
+	defer r.timing.Since("datadog.trace_agent.otlp.process_http_request_ms", time.Now())
+	var (
+		unmarshaler ptrace.Unmarshaler
+		marshal     func(ptraceotlp.ExportResponse) ([]byte, error)
+	)
+	mediaType := getMediaType(req)
+	switch mediaType {
+	case "application/x-protobuf":
+		unmarshaler = &ptrace.ProtoUnmarshaler{}
+		marshal = ptraceotlp.ExportResponse.MarshalProto
+	case "application/json":
+		unmarshaler = &ptrace.JSONUnmarshaler{}
+		marshal = ptraceotlp.ExportResponse.MarshalJSON
+	default:
+		httpFormatError(w, otlpHTTPVersion, fmt.Errorf("unsupported media type: %q", mediaType), r.statsd)
+		return
+	}
+	_ = r.statsd.Count("datadog.trace_agent.otlp.payload", 1, tagsFromHeaders(req.Header, otlpHTTPVersion), 1)
+	if !r.receiveOTelTraces(otlpHTTPVersion, w, req, unmarshaler) {
+		return
+	}
+
+	resp, err := marshal(ptraceotlp.NewExportResponse())
+	if err != nil {
+		log.Errorf("Cannot encode OTLP traces response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp) //nolint:errcheck
+}
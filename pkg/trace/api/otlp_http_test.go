@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+)
+
+func TestOTLPHTTP(t *testing.T) {
+	conf := NewTestConfig(t)
+	conf.DecoderTimeout = 10000
+	conf.OTLPReceiver.HTTPEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+	mux := rcv.buildMux()
+
+	in := testutil.NewOTLPTracesRequest([]testutil.OTLPResourceSpan{{
+		LibName:    "libname",
+		LibVersion: "1.2",
+		Attributes: map[string]interface{}{"service.name": "web"},
+		Spans: []*testutil.OTLPSpan{{
+			TraceID: testutil.OTLPFixedTraceID,
+			SpanID:  testutil.OTLPFixedSpanID,
+			Name:    "GET /users",
+			Kind:    ptrace.SpanKindServer,
+			Start:   uint64(time.Now().UnixNano()),
+			End:     uint64(time.Now().Add(time.Second).UnixNano()),
+		}},
+	}})
+	protoBody, err := in.MarshalProto()
+	require.NoError(t, err)
+	jsonBody, err := in.MarshalJSON()
+	require.NoError(t, err)
+	var gzipBody bytes.Buffer
+	gz := gzip.NewWriter(&gzipBody)
+	_, err = gz.Write(protoBody)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	for _, tt := range []struct {
+		name, contentType, encoding string
+		body                        []byte
+	}{
+		{name: "protobuf", contentType: "application/x-protobuf", body: protoBody},
+		{name: "json", contentType: "application/json", body: jsonBody},
+		{name: "gzip", contentType: "application/x-protobuf", encoding: "gzip", body: gzipBody.Bytes()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/v1/traces", bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Content-Encoding", tt.encoding)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			resp := ptraceotlp.NewExportResponse()
+			if tt.contentType == "application/json" {
+				assert.NoError(t, resp.UnmarshalJSON(rr.Body.Bytes()))
+			} else {
+				assert.NoError(t, resp.UnmarshalProto(rr.Body.Bytes()))
+			}
+
+			select {
+			case p := <-rcv.out:
+				assert.Equal(t, otlpHTTPVersion, p.Source.EndpointVersion)
+				require.Len(t, p.TracerPayload.Chunks, 1)
+				require.Len(t, p.TracerPayload.Chunks[0].Spans, 1)
+				span := p.TracerPayload.Chunks[0].Spans[0]
+				assert.Equal(t, "web", span.Service)
+				assert.Equal(t, uint64(0x240031ead750e5f3), span.TraceID)
+				assert.Equal(t, uint64(0x240031ead750e5f3), span.SpanID)
+				assert.Equal(t, "server", span.Meta["span.kind"])
+			case <-time.After(time.Second):
+				t.Fatal("no payload received")
+			}
+		})
+	}
+}
+
+func TestOTLPHTTPErrors(t *testing.T) {
+	conf := NewTestConfig(t)
+	conf.DecoderTimeout = 10000
+	conf.OTLPReceiver.HTTPEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+	mux := rcv.buildMux()
+
+	for contentType, code := range map[string]int{
+		"text/plain":             http.StatusUnsupportedMediaType,
+		"application/x-protobuf": http.StatusBadRequest,
+		"application/json":       http.StatusBadRequest,
+	} {
+		req, err := http.NewRequest("POST", "/v1/traces", bytes.NewReader([]byte("{not otlp")))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, code, rr.Code, contentType)
+	}
+	assert.Empty(t, rcv.out)
+}
+
+func TestOTLPHTTPDisabled(t *testing.T) {
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	req, err := http.NewRequest("POST", "/v1/traces", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	rcv.buildMux().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
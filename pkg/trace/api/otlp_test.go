@@ -1028,7 +1028,7 @@ func TestOTLPHelpers(t *testing.T) {
 			header.LangVersion:           {"1.14"},
 			header.LangInterpreter:       {"x"},
 			header.LangInterpreterVendor: {"y"},
-		}))
+		}), otlpGRPCVersion)
 		assert.Equal(t, []string{"endpoint_version:opentelemetry_grpc_v1", "lang:go", "lang_version:1.14", "interpreter:x", "lang_vendor:y"}, out)
 	})
 }
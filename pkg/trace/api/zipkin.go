@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/zipkin/zipkinv2"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// zipkinV2 is the endpoint version of the Zipkin v2 spans.
+const zipkinV2 = "zipkin_v2"
+
+// handleZipkin handles the Zipkin v2 spans, encoded in JSON or Protobuf. The
+// Zipkin local endpoint is the service of the spans, and the Zipkin span kind
+// is translated to the span kind of the span.
+func (r *HTTPReceiver) handleZipkin(w http.ResponseWriter, req *http.Request) {
+	var unmarshaler ptrace.Unmarshaler
+	switch mediaType := getMediaType(req); mediaType {
+	case "application/json":
+		unmarshaler = zipkinv2.NewJSONTracesUnmarshaler(false)
+	case "application/x-protobuf", "application/protobuf":
+		unmarshaler = zipkinv2.NewProtobufTracesUnmarshaler(false, false)
+	default:
+		httpFormatError(w, zipkinV2, fmt.Errorf("unsupported media type: %q", mediaType), r.statsd)
+		return
+	}
+	if r.receiveOTelTraces(zipkinV2, w, req, unmarshaler) {
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/zipkin/zipkinv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const zipkinSpans = `[{
+	"traceId": "00000000000000010000000000000002",
+	"id": "0000000000000003",
+	"name": "get /users",
+	"kind": "SERVER",
+	"timestamp": 1700000000000000,
+	"duration": 1500,
+	"localEndpoint": {"serviceName": "frontend"},
+	"tags": {"http.method": "GET"}
+}]`
+
+func newZipkinRequest(t *testing.T, contentType string, body []byte) *http.Request {
+	req, err := http.NewRequest("POST", "/api/v2/spans", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestZipkin(t *testing.T) {
+	conf := NewTestConfig(t)
+	conf.DecoderTimeout = 10000
+	conf.ZipkinReceiverEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+	mux := rcv.buildMux()
+
+	protoBody := func() []byte {
+		traces, err := zipkinv2.NewJSONTracesUnmarshaler(false).UnmarshalTraces([]byte(zipkinSpans))
+		require.NoError(t, err)
+		body, err := zipkinv2.NewProtobufTracesMarshaler().MarshalTraces(traces)
+		require.NoError(t, err)
+		return body
+	}
+	gzipBody := func() []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write([]byte(zipkinSpans))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		return buf.Bytes()
+	}
+
+	for name, req := range map[string]*http.Request{
+		"json":     newZipkinRequest(t, "application/json", []byte(zipkinSpans)),
+		"protobuf": newZipkinRequest(t, "application/x-protobuf", protoBody()),
+		"gzip": func() *http.Request {
+			req := newZipkinRequest(t, "application/json", gzipBody())
+			req.Header.Set("Content-Encoding", "gzip")
+			return req
+		}(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusAccepted, rr.Code)
+
+			select {
+			case p := <-rcv.out:
+				assert.Equal(t, zipkinV2, p.Source.EndpointVersion)
+				require.Len(t, p.TracerPayload.Chunks, 1)
+				require.Len(t, p.TracerPayload.Chunks[0].Spans, 1)
+				span := p.TracerPayload.Chunks[0].Spans[0]
+				assert.Equal(t, "frontend", span.Service)
+				assert.Equal(t, uint64(2), span.TraceID)
+				assert.Equal(t, uint64(3), span.SpanID)
+				assert.Equal(t, int64(1500*time.Microsecond), span.Duration)
+				assert.Equal(t, "server", span.Meta["span.kind"])
+				assert.Equal(t, "GET", span.Meta["http.method"])
+				assert.Equal(t, "00000000000000010000000000000002", span.Meta["otel.trace_id"])
+			case <-time.After(time.Second):
+				t.Fatal("no payload received")
+			}
+		})
+	}
+}
+
+func TestZipkinErrors(t *testing.T) {
+	conf := NewTestConfig(t)
+	conf.DecoderTimeout = 10000
+	conf.ZipkinReceiverEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+	mux := rcv.buildMux()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, newZipkinRequest(t, "text/plain", []byte(zipkinSpans)))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, newZipkinRequest(t, "application/json", []byte("[{")))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rcv.out)
+}
+
+func TestZipkinDisabled(t *testing.T) {
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	rr := httptest.NewRecorder()
+	rcv.buildMux().ServeHTTP(rr, newZipkinRequest(t, "application/json", []byte(zipkinSpans)))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
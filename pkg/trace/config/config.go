@@ -69,6 +69,10 @@ type OTLP struct {
 	// from an incoming HTTP request.
 	MaxRequestBytes int64 `mapstructure:"-"`
 
+	// HTTPEnabled enables the intake of OTLP/HTTP traces at /v1/traces on the
+	// trace-agent receiver.
+	HTTPEnabled bool `mapstructure:"-"`
+
 	// ProbabilisticSampling specifies the percentage of traces to ingest. Exceptions are made for errors
 	// and rare traces (outliers) if "RareSamplerEnabled" is true. Invalid values are equivalent to 100.
 	// If spans have the "sampling.priority" attribute set, probabilistic sampling is skipped and the user's
@@ -183,6 +187,144 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// TailSamplingConfig specifies the tail-based sampling of the traces: the chunks
+// of each trace are buffered for DecisionWait, then the complete trace is kept
+// if any of the policies matches it.
+type TailSamplingConfig struct {
+	// Enabled reports whether the traces are tail sampled instead of being
+	// sampled chunk by chunk.
+	Enabled bool
+
+	// DecisionWait is the time the chunks of a trace are buffered for, from the
+	// arrival of its first chunk.
+	DecisionWait time.Duration
+
+	// MaxBufferSize is the maximum size in bytes of the buffered chunks, the
+	// oldest traces are sampled early when it is exceeded.
+	MaxBufferSize int64
+
+	// Policies are evaluated in order, the first one matching keeps the trace.
+	Policies []*TailSamplingPolicy
+}
+
+// Tail sampling policy types.
+const (
+	// TailSamplingError keeps the traces containing an error.
+	TailSamplingError = "error"
+	// TailSamplingLatency keeps the traces lasting longer than a threshold.
+	TailSamplingLatency = "latency"
+	// TailSamplingTag keeps the traces with a span having a tag.
+	TailSamplingTag = "tag"
+	// TailSamplingProbabilistic keeps a percentage of the traces, up to a rate.
+	TailSamplingProbabilistic = "probabilistic"
+)
+
+// TailSamplingPolicy specifies a policy of the tail-based sampling.
+type TailSamplingPolicy struct {
+	// Type is the type of the policy, one of the TailSampling* constants.
+	Type string `mapstructure:"type"`
+
+	// ThresholdMs is the minimum duration of the traces kept by latency policies.
+	ThresholdMs int64 `mapstructure:"threshold_ms"`
+
+	// Key is the tag of the spans looked for by tag policies, Values optionally
+	// lists the values the tag must have.
+	Key    string   `mapstructure:"key"`
+	Values []string `mapstructure:"values"`
+
+	// SamplingPercentage is the percentage of the traces kept by probabilistic
+	// policies, at most MaxTracesPerSecond of them are kept if it is set.
+	SamplingPercentage float64 `mapstructure:"sampling_percentage"`
+	MaxTracesPerSecond float64 `mapstructure:"max_traces_per_second"`
+}
+
+// SpanFilter specifies the conditions a span must match, the empty conditions
+// match all the spans.
+type SpanFilter struct {
+	// Service, Operation and Resource are regular expressions which must match
+	// the service, the operation name and the resource of the span.
+	Service   string `mapstructure:"service"`
+	Operation string `mapstructure:"operation"`
+	Resource  string `mapstructure:"resource"`
+
+	// Tags maps the tags the span must have to the regular expressions their
+	// values must match.
+	Tags map[string]string `mapstructure:"tags"`
+}
+
+// Span rule actions.
+const (
+	// SpanRuleDropSpan drops the span, its children are re-parented to its parent.
+	SpanRuleDropSpan = "drop_span"
+	// SpanRuleSetTag sets the meta Key to Value.
+	SpanRuleSetTag = "set_tag"
+	// SpanRuleSetMetric sets the metric Key to Value, which must be a number.
+	SpanRuleSetMetric = "set_metric"
+	// SpanRuleDeleteTag deletes the meta or metric Key.
+	SpanRuleDeleteTag = "delete_tag"
+	// SpanRuleRenameTag renames the meta or metric Key to Value.
+	SpanRuleRenameTag = "rename_tag"
+	// SpanRuleRenameService renames the service of the span to Value.
+	SpanRuleRenameService = "rename_service"
+	// SpanRuleRenameOperation renames the operation of the span to Value.
+	SpanRuleRenameOperation = "rename_operation"
+	// SpanRuleTruncateTag truncates the meta Key, or all of them if Key is empty,
+	// to MaxLength bytes.
+	SpanRuleTruncateTag = "truncate_tag"
+)
+
+// SpanRule specifies a span processing rule: its action is applied to the spans
+// matching all of its conditions.
+type SpanRule struct {
+	// Name identifies the rule in its telemetry.
+	Name string `mapstructure:"name"`
+
+	// Service, Operation, Resource and Tags are the conditions of the rule, as
+	// in SpanFilter. The empty conditions match all the spans.
+	Service   string            `mapstructure:"service"`
+	Operation string            `mapstructure:"operation"`
+	Resource  string            `mapstructure:"resource"`
+	Tags      map[string]string `mapstructure:"tags"`
+
+	// Action is the action of the rule, one of the SpanRule* constants.
+	Action string `mapstructure:"action"`
+
+	// Key, Value and MaxLength are the arguments of the action.
+	Key       string `mapstructure:"key"`
+	Value     string `mapstructure:"value"`
+	MaxLength int    `mapstructure:"max_length"`
+}
+
+// Span metric types.
+const (
+	// SpanMetricCount counts the spans.
+	SpanMetricCount = "count"
+	// SpanMetricDistribution is the distribution of the duration of the spans,
+	// or of one of their metrics.
+	SpanMetricDistribution = "distribution"
+)
+
+// SpanMetric specifies a metric generated from the spans matching its filter,
+// before they are sampled.
+type SpanMetric struct {
+	// Name is the name of the metric.
+	Name string `mapstructure:"name"`
+
+	// Type is the type of the metric, one of the SpanMetric* constants.
+	Type string `mapstructure:"type"`
+
+	// Metric is the metric of the spans whose distribution is computed, the
+	// distribution of the duration of the spans in seconds is computed if empty.
+	Metric string `mapstructure:"metric"`
+
+	// Filter selects the spans the metric is generated from.
+	Filter SpanFilter `mapstructure:"filter"`
+
+	// GroupBy lists the tags of the spans the metric is tagged with. The
+	// service, operation and resource tags are the ones of the spans.
+	GroupBy []string `mapstructure:"group_by"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -317,6 +459,9 @@ type AgentConfig struct {
 	ProbabilisticSamplerHashSeed           uint32
 	ProbabilisticSamplerSamplingPercentage float32
 
+	// TailSampling configures the tail-based sampling of the traces.
+	TailSampling TailSamplingConfig
+
 	// Receiver
 	ReceiverEnabled bool // specifies whether Receiver listeners are enabled. Unless OTLPReceiver is used, this should always be true.
 	ReceiverHost    string
@@ -330,6 +475,11 @@ type AgentConfig struct {
 	MaxConnections  int   // specifies the maximum number of concurrent incoming connections allowed.
 	DecoderTimeout  int   // specifies the maximum time in milliseconds that the decoders will wait for a turn to accept a payload before returning 429
 
+	// ZipkinReceiverEnabled enables the intake of Zipkin v2 spans, in JSON or Protobuf, at /api/v2/spans.
+	ZipkinReceiverEnabled bool
+	// JaegerReceiverEnabled enables the intake of Jaeger Thrift batches over HTTP at /api/traces.
+	JaegerReceiverEnabled bool
+
 	WindowsPipeName        string
 	PipeBufferSize         int
 	PipeSecurityDescriptor string
@@ -376,6 +526,12 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// SpanRules are applied in order to the spans once they are normalized.
+	SpanRules []*SpanRule
+
+	// SpanMetrics are generated from the spans before they are sampled.
+	SpanMetrics []*SpanMetric
+
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
 
@@ -495,6 +651,11 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSampling: TailSamplingConfig{
+			DecisionWait:  10 * time.Second,
+			MaxBufferSize: 64 * 1024 * 1024, // 64MB
+		},
+
 		ReceiverEnabled:        true,
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// SpanRules is a filter which applies the span processing rules to the spans
+// of the traces: it modifies them, or drops them.
+type SpanRules struct {
+	rules  []*spanRule
+	statsd statsd.ClientInterface
+}
+
+// spanRule is a compiled config.SpanRule.
+type spanRule struct {
+	statsTags []string
+	filter    *traceutil.SpanFilter
+
+	action    string
+	key       string
+	value     string
+	metric    float64
+	maxLength int
+}
+
+// NewSpanRules returns a new SpanRules applying the given rules, the invalid
+// rules are skipped.
+func NewSpanRules(rules []*config.SpanRule, statsd statsd.ClientInterface) *SpanRules {
+	sr := &SpanRules{statsd: statsd}
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = "rule_" + strconv.Itoa(i)
+		}
+		rule, err := newSpanRule(r)
+		if err != nil {
+			log.Errorf("Invalid span rule %q: %v", name, err)
+			continue
+		}
+		rule.statsTags = []string{"rule:" + name}
+		sr.rules = append(sr.rules, rule)
+	}
+	return sr
+}
+
+func newSpanRule(r *config.SpanRule) (*spanRule, error) {
+	rule := &spanRule{
+		action:    r.Action,
+		key:       r.Key,
+		value:     r.Value,
+		maxLength: r.MaxLength,
+	}
+	var err error
+	if rule.filter, err = traceutil.NewSpanFilter(r.Service, r.Operation, r.Resource, r.Tags); err != nil {
+		return nil, err
+	}
+
+	switch r.Action {
+	case config.SpanRuleDropSpan:
+	case config.SpanRuleSetTag, config.SpanRuleRenameTag:
+		if r.Key == "" || r.Value == "" {
+			return nil, fmt.Errorf("%s requires a key and a value", r.Action)
+		}
+	case config.SpanRuleSetMetric:
+		if r.Key == "" {
+			return nil, fmt.Errorf("%s requires a key", r.Action)
+		}
+		if rule.metric, err = strconv.ParseFloat(r.Value, 64); err != nil {
+			return nil, fmt.Errorf("%s requires a numeric value: %v", r.Action, err)
+		}
+	case config.SpanRuleDeleteTag:
+		if r.Key == "" {
+			return nil, fmt.Errorf("%s requires a key", r.Action)
+		}
+	case config.SpanRuleRenameService:
+		if rule.value, err = traceutil.NormalizeService(r.Value, ""); err != nil {
+			return nil, fmt.Errorf("%s requires a valid service: %v", r.Action, err)
+		}
+	case config.SpanRuleRenameOperation:
+		if rule.value, err = traceutil.NormalizeName(r.Value); err != nil {
+			return nil, fmt.Errorf("%s requires a valid operation name: %v", r.Action, err)
+		}
+	case config.SpanRuleTruncateTag:
+		if r.MaxLength <= 0 {
+			return nil, fmt.Errorf("%s requires a positive max_length", r.Action)
+		}
+	case "":
+		return nil, errors.New("no action")
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+	return rule, nil
+}
+
+// Apply applies the rules in order to the spans of the trace and returns the
+// remaining spans, the children of the dropped spans are re-parented to their
+// closest remaining ancestor. The root span is never dropped, as it holds the
+// metadata of the trace.
+func (sr *SpanRules) Apply(trace pb.Trace) pb.Trace {
+	if sr == nil || len(sr.rules) == 0 {
+		return trace
+	}
+	root := traceutil.GetRoot(trace)
+	matched := make([]int64, len(sr.rules))
+	dropped := make([]int64, len(sr.rules))
+	var parents map[uint64]uint64 // the parents of the dropped spans
+	kept := trace[:0]
+	for _, span := range trace {
+		drop := false
+		for i, rule := range sr.rules {
+			if !rule.filter.Matches(span) {
+				continue
+			}
+			matched[i]++
+			if rule.action != config.SpanRuleDropSpan {
+				rule.apply(span)
+				continue
+			}
+			if span != root {
+				dropped[i]++
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, span)
+			continue
+		}
+		if parents == nil {
+			parents = make(map[uint64]uint64)
+		}
+		parents[span.SpanID] = span.ParentID
+	}
+	if len(parents) > 0 {
+		for _, span := range kept {
+			// the number of iterations is bounded in case the dropped spans form a cycle
+			for n := 0; n < len(parents); n++ {
+				parent, ok := parents[span.ParentID]
+				if !ok {
+					break
+				}
+				span.ParentID = parent
+			}
+		}
+	}
+	for i, rule := range sr.rules {
+		if matched[i] > 0 {
+			_ = sr.statsd.Count("datadog.trace_agent.span_rules.matched", matched[i], rule.statsTags, 1)
+		}
+		if dropped[i] > 0 {
+			_ = sr.statsd.Count("datadog.trace_agent.span_rules.dropped", dropped[i], rule.statsTags, 1)
+		}
+	}
+	return kept
+}
+
+// apply applies the action of the rule to the span, except drop_span which is
+// handled by Apply.
+func (r *spanRule) apply(s *pb.Span) {
+	switch r.action {
+	case config.SpanRuleSetTag:
+		delete(s.Metrics, r.key)
+		traceutil.SetMeta(s, r.key, r.value)
+	case config.SpanRuleSetMetric:
+		delete(s.Meta, r.key)
+		traceutil.SetMetric(s, r.key, r.metric)
+	case config.SpanRuleDeleteTag:
+		delete(s.Meta, r.key)
+		delete(s.Metrics, r.key)
+	case config.SpanRuleRenameTag:
+		if v, ok := s.Meta[r.key]; ok {
+			delete(s.Meta, r.key)
+			s.Meta[r.value] = v
+		}
+		if v, ok := s.Metrics[r.key]; ok {
+			delete(s.Metrics, r.key)
+			s.Metrics[r.value] = v
+		}
+	case config.SpanRuleRenameService:
+		s.Service = r.value
+	case config.SpanRuleRenameOperation:
+		s.Name = r.value
+	case config.SpanRuleTruncateTag:
+		if r.key != "" {
+			if v, ok := s.Meta[r.key]; ok {
+				s.Meta[r.key] = traceutil.TruncateUTF8(v, r.maxLength)
+			}
+			return
+		}
+		for k, v := range s.Meta {
+			if !strings.HasPrefix(k, hiddenTagPrefix) {
+				s.Meta[k] = traceutil.TruncateUTF8(v, r.maxLength)
+			}
+		}
+	}
+}
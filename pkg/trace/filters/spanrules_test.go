@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+func TestSpanRulesActions(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		rule       config.SpanRule
+		got, want  *pb.Span
+		matchesAll bool
+	}{
+		{
+			name: "set_tag",
+			rule: config.SpanRule{Action: config.SpanRuleSetTag, Key: "team", Value: "payments"},
+			got:  &pb.Span{Metrics: map[string]float64{"team": 1}},
+			want: &pb.Span{Meta: map[string]string{"team": "payments"}, Metrics: map[string]float64{}},
+		},
+		{
+			name: "set_metric",
+			rule: config.SpanRule{Action: config.SpanRuleSetMetric, Key: "weight", Value: "2.5"},
+			got:  &pb.Span{Meta: map[string]string{"weight": "heavy"}},
+			want: &pb.Span{Meta: map[string]string{}, Metrics: map[string]float64{"weight": 2.5}},
+		},
+		{
+			name: "delete_tag",
+			rule: config.SpanRule{Action: config.SpanRuleDeleteTag, Key: "user.email"},
+			got:  &pb.Span{Meta: map[string]string{"user.email": "a@b.c", "user.id": "1"}},
+			want: &pb.Span{Meta: map[string]string{"user.id": "1"}},
+		},
+		{
+			name: "rename_tag",
+			rule: config.SpanRule{Action: config.SpanRuleRenameTag, Key: "retries", Value: "http.retries"},
+			got:  &pb.Span{Meta: map[string]string{}, Metrics: map[string]float64{"retries": 2}},
+			want: &pb.Span{Meta: map[string]string{}, Metrics: map[string]float64{"http.retries": 2}},
+		},
+		{
+			name: "rename_service",
+			rule: config.SpanRule{Service: "^pg-", Action: config.SpanRuleRenameService, Value: "Postgres"},
+			got:  &pb.Span{Service: "pg-primary"},
+			want: &pb.Span{Service: "postgres"},
+		},
+		{
+			name: "rename_service-no-match",
+			rule: config.SpanRule{Service: "^pg-", Action: config.SpanRuleRenameService, Value: "postgres"},
+			got:  &pb.Span{Service: "mysql"},
+			want: &pb.Span{Service: "mysql"},
+		},
+		{
+			name: "rename_operation",
+			rule: config.SpanRule{Operation: "^http$", Tags: map[string]string{"span.kind": "server"}, Action: config.SpanRuleRenameOperation, Value: "http.request"},
+			got:  &pb.Span{Name: "http", Meta: map[string]string{"span.kind": "server"}},
+			want: &pb.Span{Name: "http.request", Meta: map[string]string{"span.kind": "server"}},
+		},
+		{
+			name: "rename_operation-no-tag",
+			rule: config.SpanRule{Operation: "^http$", Tags: map[string]string{"span.kind": "server"}, Action: config.SpanRuleRenameOperation, Value: "http.request"},
+			got:  &pb.Span{Name: "http"},
+			want: &pb.Span{Name: "http"},
+		},
+		{
+			name: "truncate_tag",
+			rule: config.SpanRule{Action: config.SpanRuleTruncateTag, Key: "sql.query", MaxLength: 6},
+			got:  &pb.Span{Meta: map[string]string{"sql.query": "SELECT * FROM users", "other": "untouched"}},
+			want: &pb.Span{Meta: map[string]string{"sql.query": "SELECT", "other": "untouched"}},
+		},
+		{
+			name: "truncate_tag-all",
+			rule: config.SpanRule{Resource: "users", Action: config.SpanRuleTruncateTag, MaxLength: 3},
+			got:  &pb.Span{Resource: "GET /users", Meta: map[string]string{"a": "abcdef", "_dd.hidden": "abcdef"}},
+			want: &pb.Span{Resource: "GET /users", Meta: map[string]string{"a": "abc", "_dd.hidden": "abcdef"}},
+		},
+		{
+			name: "metric-condition",
+			rule: config.SpanRule{Tags: map[string]string{"http.status_code": "^5"}, Action: config.SpanRuleSetTag, Key: "alert", Value: "true"},
+			got:  &pb.Span{Metrics: map[string]float64{"http.status_code": 503}},
+			want: &pb.Span{Meta: map[string]string{"alert": "true"}, Metrics: map[string]float64{"http.status_code": 503}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := NewSpanRules([]*config.SpanRule{&tt.rule}, &statsd.NoOpClient{})
+			require.Len(t, sr.rules, 1)
+			trace := sr.Apply(pb.Trace{tt.got})
+			assert.Equal(t, pb.Trace{tt.want}, trace)
+		})
+	}
+}
+
+func TestSpanRulesDropSpan(t *testing.T) {
+	sr := NewSpanRules([]*config.SpanRule{
+		{Name: "drop-cache", Operation: "^cache\\.", Action: config.SpanRuleDropSpan},
+		{Name: "tag-kept", Action: config.SpanRuleSetTag, Key: "kept", Value: "true"},
+	}, &statsd.NoOpClient{})
+	root := &pb.Span{SpanID: 1, Name: "cache.root"}
+	trace := pb.Trace{
+		root,
+		{SpanID: 2, ParentID: 1, Name: "cache.get"},
+		{SpanID: 3, ParentID: 2, Name: "cache.decode"},
+		{SpanID: 4, ParentID: 3, Name: "db.query"},
+		{SpanID: 5, ParentID: 1, Name: "http.request"},
+	}
+	trace = sr.Apply(trace)
+
+	// the root span is never dropped
+	require.Len(t, trace, 3)
+	assert.Equal(t, root, trace[0])
+	assert.Equal(t, uint64(4), trace[1].SpanID)
+	assert.Equal(t, uint64(1), trace[1].ParentID)
+	assert.Equal(t, uint64(5), trace[2].SpanID)
+	assert.Equal(t, uint64(1), trace[2].ParentID)
+	for _, span := range trace {
+		assert.Equal(t, "true", span.Meta["kept"])
+	}
+}
+
+func TestSpanRulesInvalid(t *testing.T) {
+	sr := NewSpanRules([]*config.SpanRule{
+		{},
+		{Action: "unknown"},
+		{Service: "(", Action: config.SpanRuleDropSpan},
+		{Tags: map[string]string{"a": "("}, Action: config.SpanRuleDropSpan},
+		{Action: config.SpanRuleSetTag, Key: "a"},
+		{Action: config.SpanRuleSetMetric, Key: "a", Value: "b"},
+		{Action: config.SpanRuleDeleteTag},
+		{Action: config.SpanRuleRenameService},
+		{Action: config.SpanRuleTruncateTag, Key: "a"},
+		{Action: config.SpanRuleDropSpan},
+	}, &statsd.NoOpClient{})
+	require.Len(t, sr.rules, 1)
+	assert.Equal(t, []string{"rule:rule_9"}, sr.rules[0].statsTags)
+
+	var nilRules *SpanRules
+	trace := pb.Trace{{SpanID: 1}}
+	assert.Equal(t, trace, nilRules.Apply(trace))
+}
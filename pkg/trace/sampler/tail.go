@@ -0,0 +1,498 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+const (
+	// tailDecisionCacheSize is the number of trace decisions remembered to
+	// sample the chunks arriving after the decision was made.
+	tailDecisionCacheSize = 100_000
+	// tailTickInterval is the interval the buffered traces are checked at.
+	tailTickInterval = time.Second
+)
+
+// TailChunk is a chunk buffered by the TailSampler.
+type TailChunk struct {
+	// Payload holds the metadata of the tracer payload the chunk was received
+	// in, its chunks are not set.
+	Payload *pb.TracerPayload
+	Trace   *traceutil.ProcessedTrace
+	// Source is set by the caller of Add and passed back with the chunk once
+	// its trace is sampled, e.g. the stats of the source of the payload.
+	Source interface{}
+}
+
+// tailTraceID is the 128-bit ID of a trace.
+type tailTraceID [16]byte
+
+// newTailTraceID returns the 128-bit ID of the trace of a chunk, built from
+// its root span like the ProbabilisticSampler does.
+func newTailTraceID(root *pb.Span) tailTraceID {
+	var id tailTraceID
+	tid, err := get128BitTraceID(root)
+	if err != nil || len(tid) != len(id) {
+		// the upper bits are unknown, the trace is identified by the others
+		binary.BigEndian.PutUint64(id[8:], root.TraceID)
+		return id
+	}
+	copy(id[:], tid)
+	return id
+}
+
+// tailTrace holds the buffered chunks of a trace.
+type tailTrace struct {
+	id       tailTraceID
+	chunks   []TailChunk
+	size     int64
+	deadline time.Time
+	elem     *list.Element
+}
+
+// TailSampler buffers the chunks of the traces for a decision window, then
+// keeps the complete traces matched by any of its policies, or marked as kept
+// by the user. The buffer is bounded by a maximum size, and by the maximum
+// memory of the agent: the oldest traces are sampled early when either is
+// exceeded.
+type TailSampler struct {
+	enabled       bool
+	decisionWait  time.Duration
+	maxBufferSize int64
+	maxMemory     float64
+	policies      []tailPolicy
+	tickInterval  time.Duration
+
+	// write is called with the chunks of the sampled traces.
+	write func([]TailChunk)
+	// memory returns the memory allocated by the agent.
+	memory func() uint64
+
+	mu        sync.Mutex
+	traces    map[tailTraceID]*tailTrace
+	queue     *list.List // traces ordered by deadline
+	size      int64
+	decisions *decisionCache
+	done      bool
+
+	statsd        statsd.ClientInterface
+	tracesKept    *atomic.Int64
+	tracesDropped *atomic.Int64
+	tracesEarly   *atomic.Int64
+	lateChunks    *atomic.Int64
+
+	// start/stop synchronization
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewTailSampler returns a TailSampler calling write with the chunks of the
+// traces it samples, the DroppedTrace of the chunks is set to its decision.
+// The invalid policies are ignored, the sampler is disabled if no policy is
+// valid.
+func NewTailSampler(conf *config.AgentConfig, statsd statsd.ClientInterface, write func([]TailChunk)) *TailSampler {
+	enabled := conf.TailSampling.Enabled
+	var policies []tailPolicy
+	if enabled {
+		_, fullTraceIDMode := conf.Features["probabilistic_sampler_full_trace_id"]
+		for _, p := range conf.TailSampling.Policies {
+			policy, err := newTailPolicy(p, conf.ProbabilisticSamplerHashSeed, fullTraceIDMode)
+			if err != nil {
+				log.Errorf("Ignoring tail sampling policy: %v", err)
+				continue
+			}
+			policies = append(policies, policy)
+		}
+		if len(policies) == 0 {
+			log.Error("Tail sampling is disabled, no valid policy is configured")
+			enabled = false
+		}
+	}
+	info := watchdog.NewCurrentInfo()
+	return &TailSampler{
+		enabled:       enabled,
+		decisionWait:  conf.TailSampling.DecisionWait,
+		maxBufferSize: conf.TailSampling.MaxBufferSize,
+		maxMemory:     conf.MaxMemory,
+		policies:      policies,
+		tickInterval:  tailTickInterval,
+		write:         write,
+		memory:        func() uint64 { return info.Mem().Alloc },
+		traces:        make(map[tailTraceID]*tailTrace),
+		queue:         list.New(),
+		decisions:     newDecisionCache(tailDecisionCacheSize),
+		statsd:        statsd,
+		tracesKept:    atomic.NewInt64(0),
+		tracesDropped: atomic.NewInt64(0),
+		tracesEarly:   atomic.NewInt64(0),
+		lateChunks:    atomic.NewInt64(0),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// Enabled reports whether the traces are tail sampled, it is false for a nil
+// TailSampler.
+func (s *TailSampler) Enabled() bool {
+	return s != nil && s.enabled
+}
+
+// Start starts the routine sampling the traces at the end of their decision
+// window, and periodically sending stats.
+func (s *TailSampler) Start() {
+	if !s.enabled {
+		close(s.stopped)
+		return
+	}
+	go func() {
+		defer watchdog.LogOnPanic(s.statsd)
+		ticker := time.NewTicker(s.tickInterval)
+		defer ticker.Stop()
+		statsTicker := time.NewTicker(10 * time.Second)
+		defer statsTicker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.flushExpired(now)
+			case <-statsTicker.C:
+				s.report()
+			case <-s.stop:
+				s.flushAll()
+				s.report()
+				close(s.stopped)
+				return
+			}
+		}
+	}()
+}
+
+// Stop samples all the buffered traces and shuts down the TailSampler's routine.
+func (s *TailSampler) Stop() {
+	if !s.enabled {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.stopped
+	})
+}
+
+// Add buffers a chunk. It returns false if the chunk is not handled by the
+// sampler, because it is disabled or stopped, in which case the caller is
+// responsible for sampling it.
+func (s *TailSampler) Add(c TailChunk) bool {
+	if !s.enabled || len(c.Trace.TraceChunk.Spans) == 0 {
+		return false
+	}
+	id := newTailTraceID(c.Trace.Root)
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return false
+	}
+	if keep, ok := s.decisions.get(id); ok {
+		s.mu.Unlock()
+		// the trace was already sampled, its late chunks follow its decision
+		s.lateChunks.Inc()
+		c.Trace.TraceChunk.DroppedTrace = !keep
+		s.write([]TailChunk{c})
+		return true
+	}
+	t, ok := s.traces[id]
+	if !ok {
+		t = &tailTrace{id: id, deadline: time.Now().Add(s.decisionWait)}
+		t.elem = s.queue.PushBack(t)
+		s.traces[id] = t
+	}
+	size := int64(c.Trace.TraceChunk.Msgsize())
+	t.chunks = append(t.chunks, c)
+	t.size += size
+	s.size += size
+	var sampled []TailChunk
+	for s.size > s.maxBufferSize && s.queue.Len() > 0 {
+		s.tracesEarly.Inc()
+		sampled = append(sampled, s.decide(s.queue.Front().Value.(*tailTrace))...)
+	}
+	s.mu.Unlock()
+	if len(sampled) > 0 {
+		s.write(sampled)
+	}
+	return true
+}
+
+// flushExpired samples the traces whose decision window is over, and the
+// oldest half of the traces if the agent uses more than its maximum memory.
+func (s *TailSampler) flushExpired(now time.Time) {
+	overMemory := s.maxMemory > 0 && float64(s.memory()) > s.maxMemory
+	var sampled []TailChunk
+	s.mu.Lock()
+	for s.queue.Len() > 0 {
+		t := s.queue.Front().Value.(*tailTrace)
+		if t.deadline.After(now) {
+			break
+		}
+		sampled = append(sampled, s.decide(t)...)
+	}
+	if overMemory {
+		for n := s.queue.Len() / 2; n > 0; n-- {
+			s.tracesEarly.Inc()
+			sampled = append(sampled, s.decide(s.queue.Front().Value.(*tailTrace))...)
+		}
+	}
+	s.mu.Unlock()
+	if len(sampled) > 0 {
+		s.write(sampled)
+	}
+}
+
+// flushAll samples all the buffered traces, the chunks added afterwards are
+// not handled by the sampler.
+func (s *TailSampler) flushAll() {
+	var sampled []TailChunk
+	s.mu.Lock()
+	s.done = true
+	for s.queue.Len() > 0 {
+		sampled = append(sampled, s.decide(s.queue.Front().Value.(*tailTrace))...)
+	}
+	s.mu.Unlock()
+	if len(sampled) > 0 {
+		s.write(sampled)
+	}
+}
+
+// decide removes the trace from the buffer and returns its chunks, with their
+// DroppedTrace set to the decision. It must be called with the lock held.
+func (s *TailSampler) decide(t *tailTrace) []TailChunk {
+	s.queue.Remove(t.elem)
+	delete(s.traces, t.id)
+	s.size -= t.size
+
+	keep := s.sample(t)
+	s.decisions.add(t.id, keep)
+	if keep {
+		s.tracesKept.Inc()
+	} else {
+		s.tracesDropped.Inc()
+	}
+	for _, c := range t.chunks {
+		c.Trace.TraceChunk.DroppedTrace = !keep
+	}
+	return t.chunks
+}
+
+// sample reports whether the trace is kept by the user or by a policy.
+func (s *TailSampler) sample(t *tailTrace) bool {
+	for _, c := range t.chunks {
+		if priority, ok := GetSamplingPriority(c.Trace.TraceChunk); ok && priority == PriorityUserKeep {
+			return true
+		}
+	}
+	for _, p := range s.policies {
+		if p.keep(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	buffered, size := s.queue.Len(), s.size
+	s.mu.Unlock()
+	_ = s.statsd.Gauge("datadog.trace_agent.tail_sampler.buffered_traces", float64(buffered), nil, 1)
+	_ = s.statsd.Gauge("datadog.trace_agent.tail_sampler.buffer_size", float64(size), nil, 1)
+	_ = s.statsd.Count("datadog.trace_agent.tail_sampler.kept", s.tracesKept.Swap(0), nil, 1)
+	_ = s.statsd.Count("datadog.trace_agent.tail_sampler.dropped", s.tracesDropped.Swap(0), nil, 1)
+	_ = s.statsd.Count("datadog.trace_agent.tail_sampler.early_decisions", s.tracesEarly.Swap(0), nil, 1)
+	_ = s.statsd.Count("datadog.trace_agent.tail_sampler.late_chunks", s.lateChunks.Swap(0), nil, 1)
+}
+
+// decisionCache remembers the decisions of the most recently sampled traces.
+type decisionCache struct {
+	decisions map[tailTraceID]bool
+	ids       []tailTraceID
+	next      int
+}
+
+func newDecisionCache(size int) *decisionCache {
+	return &decisionCache{
+		decisions: make(map[tailTraceID]bool, size),
+		ids:       make([]tailTraceID, 0, size),
+	}
+}
+
+func (c *decisionCache) get(id tailTraceID) (keep bool, ok bool) {
+	keep, ok = c.decisions[id]
+	return keep, ok
+}
+
+func (c *decisionCache) add(id tailTraceID, keep bool) {
+	if _, ok := c.decisions[id]; ok {
+		c.decisions[id] = keep
+		return
+	}
+	if len(c.ids) < cap(c.ids) {
+		c.ids = append(c.ids, id)
+	} else {
+		// the oldest decision is forgotten
+		delete(c.decisions, c.ids[c.next])
+		c.ids[c.next] = id
+		c.next = (c.next + 1) % len(c.ids)
+	}
+	c.decisions[id] = keep
+}
+
+// tailPolicy decides whether a complete trace is kept.
+type tailPolicy interface {
+	keep(t *tailTrace) bool
+}
+
+func newTailPolicy(p *config.TailSamplingPolicy, hashSeed uint32, fullTraceIDMode bool) (tailPolicy, error) {
+	switch p.Type {
+	case config.TailSamplingError:
+		return errorPolicy{}, nil
+	case config.TailSamplingLatency:
+		if p.ThresholdMs <= 0 {
+			return nil, fmt.Errorf("the threshold_ms of a latency policy must be positive")
+		}
+		return latencyPolicy{threshold: p.ThresholdMs * int64(time.Millisecond)}, nil
+	case config.TailSamplingTag:
+		if p.Key == "" {
+			return nil, fmt.Errorf("the key of a tag policy must be set")
+		}
+		policy := tagPolicy{key: p.Key}
+		if len(p.Values) > 0 {
+			policy.values = make(map[string]struct{}, len(p.Values))
+			for _, v := range p.Values {
+				policy.values[v] = struct{}{}
+			}
+		}
+		return policy, nil
+	case config.TailSamplingProbabilistic:
+		if p.SamplingPercentage <= 0 || p.SamplingPercentage > 100 {
+			return nil, fmt.Errorf("the sampling_percentage of a probabilistic policy must be in (0, 100]")
+		}
+		policy := &probabilisticPolicy{
+			hashSeed:                 make([]byte, 4),
+			scaledSamplingPercentage: uint32(p.SamplingPercentage * percentageScaleFactor),
+			fullTraceIDMode:          fullTraceIDMode,
+		}
+		binary.LittleEndian.PutUint32(policy.hashSeed, hashSeed)
+		if p.MaxTracesPerSecond > 0 {
+			policy.limiter = rate.NewLimiter(rate.Limit(p.MaxTracesPerSecond), int(p.MaxTracesPerSecond)+1)
+		}
+		return policy, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", p.Type)
+	}
+}
+
+// errorPolicy keeps the traces with an error span.
+type errorPolicy struct{}
+
+func (errorPolicy) keep(t *tailTrace) bool {
+	for _, c := range t.chunks {
+		for _, span := range c.Trace.TraceChunk.Spans {
+			if span.Error != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// latencyPolicy keeps the traces lasting at least threshold nanoseconds, from
+// the start of their first span to the end of their last one.
+type latencyPolicy struct {
+	threshold int64
+}
+
+func (p latencyPolicy) keep(t *tailTrace) bool {
+	var start, end int64
+	for _, c := range t.chunks {
+		for _, span := range c.Trace.TraceChunk.Spans {
+			if start == 0 || span.Start < start {
+				start = span.Start
+			}
+			if span.Start+span.Duration > end {
+				end = span.Start + span.Duration
+			}
+		}
+	}
+	return end-start >= p.threshold
+}
+
+// tagPolicy keeps the traces with a span having the key tag, with one of the
+// given values if any.
+type tagPolicy struct {
+	key    string
+	values map[string]struct{}
+}
+
+func (p tagPolicy) keep(t *tailTrace) bool {
+	for _, c := range t.chunks {
+		for _, span := range c.Trace.TraceChunk.Spans {
+			v, ok := span.Meta[p.key]
+			if !ok {
+				if _, ok := span.Metrics[p.key]; ok && p.values == nil {
+					return true
+				}
+				continue
+			}
+			if p.values == nil {
+				return true
+			}
+			if _, ok := p.values[v]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// probabilisticPolicy deterministically keeps a percentage of the traces by
+// a hash of their trace ID, like the ProbabilisticSampler, rate limited by
+// limiter if it is set.
+type probabilisticPolicy struct {
+	hashSeed                 []byte
+	scaledSamplingPercentage uint32
+	fullTraceIDMode          bool
+	limiter                  *rate.Limiter
+}
+
+func (p *probabilisticPolicy) keep(t *tailTrace) bool {
+	tid := t.id[:]
+	if !p.fullTraceIDMode {
+		// like the ProbabilisticSampler, only the lower 64 bits are hashed
+		tid = make([]byte, 16)
+		copy(tid, t.id[8:])
+	}
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(p.hashSeed)
+	_, _ = hasher.Write(tid)
+	if hasher.Sum32()&bitMaskHashBuckets >= p.scaledSamplingPercentage {
+		return false
+	}
+	return p.limiter == nil || p.limiter.Allow()
+}
@@ -0,0 +1,358 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+type tailWriter struct {
+	mu     sync.Mutex
+	chunks []TailChunk
+}
+
+func (w *tailWriter) write(chunks []TailChunk) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.chunks = append(w.chunks, chunks...)
+}
+
+// written returns the chunks of the kept traces written since the last call.
+func (w *tailWriter) written() []*pb.TraceChunk {
+	kept, _ := w.sampled()
+	return kept
+}
+
+// sampled returns the chunks of the kept traces, and the number of chunks of
+// the dropped traces, written since the last call.
+func (w *tailWriter) sampled() (kept []*pb.TraceChunk, dropped int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, c := range w.chunks {
+		if c.Trace.TraceChunk.DroppedTrace {
+			dropped++
+		} else {
+			kept = append(kept, c.Trace.TraceChunk)
+		}
+	}
+	w.chunks = nil
+	return kept, dropped
+}
+
+func newTailSampler(policies ...*config.TailSamplingPolicy) (*TailSampler, *tailWriter) {
+	conf := config.New()
+	conf.TailSampling.Enabled = true
+	conf.TailSampling.DecisionWait = time.Hour
+	conf.TailSampling.Policies = policies
+	w := &tailWriter{}
+	return NewTailSampler(conf, &statsd.NoOpClient{}, w.write), w
+}
+
+func tailChunk(traceID uint64, spans ...*pb.Span) *pb.TraceChunk {
+	for _, span := range spans {
+		span.TraceID = traceID
+	}
+	return &pb.TraceChunk{Spans: spans, DroppedTrace: true}
+}
+
+func newTailChunk(payload *pb.TracerPayload, chunk *pb.TraceChunk) TailChunk {
+	return TailChunk{Payload: payload, Trace: &traceutil.ProcessedTrace{TraceChunk: chunk, Root: traceutil.GetRoot(chunk.Spans)}}
+}
+
+func tailID(traceID uint64) tailTraceID {
+	return newTailTraceID(&pb.Span{TraceID: traceID})
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		policy config.TailSamplingPolicy
+		chunks []*pb.TraceChunk
+		keep   bool
+	}{
+		{
+			name:   "error",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingError},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{}), tailChunk(1, &pb.Span{Error: 1})},
+			keep:   true,
+		},
+		{
+			name:   "no-error",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingError},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{}, &pb.Span{})},
+			keep:   false,
+		},
+		{
+			name:   "latency",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingLatency, ThresholdMs: 500},
+			chunks: []*pb.TraceChunk{
+				tailChunk(1, &pb.Span{Start: int64(time.Second), Duration: int64(100 * time.Millisecond)}),
+				tailChunk(1, &pb.Span{Start: int64(1200 * time.Millisecond), Duration: int64(300 * time.Millisecond)}),
+			},
+			keep: true,
+		},
+		{
+			name:   "fast",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingLatency, ThresholdMs: 500},
+			chunks: []*pb.TraceChunk{
+				tailChunk(1, &pb.Span{Start: int64(time.Second), Duration: int64(100 * time.Millisecond)}),
+				tailChunk(1, &pb.Span{Start: int64(1200 * time.Millisecond), Duration: int64(200 * time.Millisecond)}),
+			},
+			keep: false,
+		},
+		{
+			name:   "tag",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingTag, Key: "customer.tier"},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{}, &pb.Span{Meta: map[string]string{"customer.tier": "free"}})},
+			keep:   true,
+		},
+		{
+			name:   "tag-metric",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingTag, Key: "retries"},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{Metrics: map[string]float64{"retries": 3}})},
+			keep:   true,
+		},
+		{
+			name:   "tag-value",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingTag, Key: "customer.tier", Values: []string{"gold", "platinum"}},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{Meta: map[string]string{"customer.tier": "gold"}})},
+			keep:   true,
+		},
+		{
+			name:   "tag-other-value",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingTag, Key: "customer.tier", Values: []string{"gold", "platinum"}},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{Meta: map[string]string{"customer.tier": "free"}})},
+			keep:   false,
+		},
+		{
+			name:   "probabilistic-all",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingProbabilistic, SamplingPercentage: 100},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{})},
+			keep:   true,
+		},
+		{
+			name:   "probabilistic-none",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingProbabilistic, SamplingPercentage: 0.001},
+			chunks: []*pb.TraceChunk{tailChunk(1, &pb.Span{})},
+			keep:   false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newTailPolicy(&tt.policy, 0, false)
+			require.NoError(t, err)
+			trace := &tailTrace{id: tailID(1)}
+			for _, chunk := range tt.chunks {
+				trace.chunks = append(trace.chunks, newTailChunk(nil, chunk))
+			}
+			assert.Equal(t, tt.keep, policy.keep(trace))
+		})
+	}
+}
+
+func TestTailSamplerInvalidPolicies(t *testing.T) {
+	for _, p := range []*config.TailSamplingPolicy{
+		{Type: "unknown"},
+		{Type: config.TailSamplingLatency},
+		{Type: config.TailSamplingTag},
+		{Type: config.TailSamplingProbabilistic, SamplingPercentage: 120},
+	} {
+		_, err := newTailPolicy(p, 0, false)
+		assert.Error(t, err, p.Type)
+	}
+
+	s, _ := newTailSampler(&config.TailSamplingPolicy{Type: "unknown"})
+	assert.False(t, s.Enabled())
+	assert.False(t, s.Add(newTailChunk(nil, tailChunk(1, &pb.Span{}))))
+}
+
+func TestTailSamplerProbabilisticRateLimit(t *testing.T) {
+	policy, err := newTailPolicy(&config.TailSamplingPolicy{Type: config.TailSamplingProbabilistic, SamplingPercentage: 100, MaxTracesPerSecond: 2}, 0, false)
+	require.NoError(t, err)
+	kept := 0
+	for i := 0; i < 10; i++ {
+		if policy.keep(&tailTrace{id: tailID(uint64(i))}) {
+			kept++
+		}
+	}
+	assert.Equal(t, 3, kept)
+}
+
+func TestTailSamplerBuffersTraces(t *testing.T) {
+	s, w := newTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingError})
+	payload := &pb.TracerPayload{Hostname: "host"}
+
+	first := tailChunk(1, &pb.Span{SpanID: 1})
+	assert.True(t, s.Add(newTailChunk(payload, first)))
+	assert.True(t, s.Add(newTailChunk(payload, tailChunk(2, &pb.Span{SpanID: 2}))))
+	// the error is in the second chunk of the trace
+	second := tailChunk(1, &pb.Span{SpanID: 3, Error: 1})
+	assert.True(t, s.Add(newTailChunk(payload, second)))
+	assert.True(t, s.Add(newTailChunk(payload, tailChunk(3, &pb.Span{SpanID: 4, Error: 1}))))
+	assert.Empty(t, w.written())
+
+	// the decision window of the traces is not over
+	s.flushExpired(time.Now())
+	assert.Empty(t, w.written())
+
+	s.flushExpired(time.Now().Add(2 * time.Hour))
+	chunks, dropped := w.sampled()
+	// the chunks of the dropped trace are written for their events and single spans
+	assert.Equal(t, 1, dropped)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, []*pb.TraceChunk{first, second}, chunks[:2])
+	assert.Equal(t, uint64(3), chunks[2].Spans[0].TraceID)
+	for _, chunk := range chunks {
+		assert.False(t, chunk.DroppedTrace)
+	}
+	assert.Equal(t, 0, s.queue.Len())
+	assert.Zero(t, s.size)
+
+	// the late chunks follow the decision of their trace
+	late := tailChunk(1, &pb.Span{SpanID: 5})
+	assert.True(t, s.Add(newTailChunk(payload, late)))
+	assert.True(t, s.Add(newTailChunk(payload, tailChunk(2, &pb.Span{SpanID: 6}))))
+	chunks, dropped = w.sampled()
+	assert.Equal(t, []*pb.TraceChunk{late}, chunks)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, 0, s.queue.Len())
+}
+
+func TestTailSamplerFullTraceID(t *testing.T) {
+	s, w := newTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingError})
+	withUpper := func(chunk *pb.TraceChunk, upper string) *pb.TraceChunk {
+		chunk.Spans[0].Meta = map[string]string{"_dd.p.tid": upper}
+		return chunk
+	}
+
+	// the traces sharing the lower 64 bits of their ID are buffered separately
+	errored := withUpper(tailChunk(1, &pb.Span{Error: 1}), "00000000000000aa")
+	assert.True(t, s.Add(newTailChunk(nil, errored)))
+	other := withUpper(tailChunk(1, &pb.Span{}), "00000000000000bb")
+	assert.True(t, s.Add(newTailChunk(nil, other)))
+	// the full trace ID of the OTel spans is in otel.trace_id
+	otel := tailChunk(1, &pb.Span{Meta: map[string]string{"otel.trace_id": "00000000000000aa0000000000000001"}})
+	assert.True(t, s.Add(newTailChunk(nil, otel)))
+	assert.Equal(t, 2, s.queue.Len())
+
+	s.flushExpired(time.Now().Add(2 * time.Hour))
+	chunks, dropped := w.sampled()
+	assert.Equal(t, []*pb.TraceChunk{errored, otel}, chunks)
+	assert.Equal(t, 1, dropped)
+}
+
+func TestTailSamplerProbabilisticLikeProbabilisticSampler(t *testing.T) {
+	for _, fullTraceIDMode := range []bool{false, true} {
+		conf := &config.AgentConfig{
+			ProbabilisticSamplerEnabled:            true,
+			ProbabilisticSamplerHashSeed:           22,
+			ProbabilisticSamplerSamplingPercentage: 50,
+			Features:                               map[string]struct{}{},
+		}
+		if fullTraceIDMode {
+			conf.Features["probabilistic_sampler_full_trace_id"] = struct{}{}
+		}
+		ps := NewProbabilisticSampler(conf, &statsd.NoOpClient{})
+		policy, err := newTailPolicy(&config.TailSamplingPolicy{Type: config.TailSamplingProbabilistic, SamplingPercentage: 50}, 22, fullTraceIDMode)
+		require.NoError(t, err)
+
+		kept := 0
+		for i := uint64(1); i <= 200; i++ {
+			root := &pb.Span{TraceID: i, Meta: map[string]string{"_dd.p.tid": fmt.Sprintf("%016x", i*7919)}}
+			keep := policy.keep(&tailTrace{id: newTailTraceID(root)})
+			assert.Equal(t, ps.Sample(root), keep, "trace %d, full trace ID mode %v", i, fullTraceIDMode)
+			if keep {
+				kept++
+			}
+		}
+		assert.InDelta(t, 100, kept, 30)
+	}
+}
+
+func TestTailSamplerUserKeep(t *testing.T) {
+	s, w := newTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingError})
+	chunk := tailChunk(1, &pb.Span{})
+	chunk.Priority = int32(PriorityUserKeep)
+	assert.True(t, s.Add(newTailChunk(nil, chunk)))
+	s.flushExpired(time.Now().Add(2 * time.Hour))
+	assert.Equal(t, []*pb.TraceChunk{chunk}, w.written())
+}
+
+func TestTailSamplerMaxBufferSize(t *testing.T) {
+	s, w := newTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingProbabilistic, SamplingPercentage: 100})
+	first := tailChunk(1, &pb.Span{})
+	s.maxBufferSize = int64(2 * first.Msgsize())
+
+	assert.True(t, s.Add(newTailChunk(nil, first)))
+	assert.True(t, s.Add(newTailChunk(nil, tailChunk(2, &pb.Span{}))))
+	assert.Empty(t, w.written())
+	// the oldest trace is sampled early
+	assert.True(t, s.Add(newTailChunk(nil, tailChunk(3, &pb.Span{}))))
+	assert.Equal(t, []*pb.TraceChunk{first}, w.written())
+	assert.Equal(t, 2, s.queue.Len())
+}
+
+func TestTailSamplerMaxMemory(t *testing.T) {
+	s, w := newTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingProbabilistic, SamplingPercentage: 100})
+	var memory uint64
+	s.memory = func() uint64 { return memory }
+	s.maxMemory = 100
+	for i := 1; i <= 4; i++ {
+		assert.True(t, s.Add(newTailChunk(nil, tailChunk(uint64(i), &pb.Span{}))))
+	}
+
+	memory = 50
+	s.flushExpired(time.Now())
+	assert.Empty(t, w.written())
+
+	// the oldest half of the traces is sampled early
+	memory = 150
+	s.flushExpired(time.Now())
+	chunks := w.written()
+	require.Len(t, chunks, 2)
+	assert.Equal(t, uint64(1), chunks[0].Spans[0].TraceID)
+	assert.Equal(t, uint64(2), chunks[1].Spans[0].TraceID)
+	assert.Equal(t, 2, s.queue.Len())
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	s, w := newTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingError})
+	s.Start()
+	chunk := tailChunk(1, &pb.Span{Error: 1})
+	assert.True(t, s.Add(newTailChunk(nil, chunk)))
+	assert.True(t, s.Add(newTailChunk(nil, tailChunk(2, &pb.Span{}))))
+
+	// the buffered traces are sampled when the sampler stops
+	s.Stop()
+	assert.Equal(t, []*pb.TraceChunk{chunk}, w.written())
+	assert.False(t, s.Add(newTailChunk(nil, tailChunk(3, &pb.Span{}))))
+}
+
+func TestDecisionCache(t *testing.T) {
+	c := newDecisionCache(2)
+	c.add(tailID(1), true)
+	c.add(tailID(2), false)
+	c.add(tailID(3), true)
+
+	_, ok := c.get(tailID(1))
+	assert.False(t, ok)
+	keep, ok := c.get(tailID(2))
+	assert.True(t, ok)
+	assert.False(t, keep)
+	keep, ok = c.get(tailID(3))
+	assert.True(t, ok)
+	assert.True(t, keep)
+}
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// SpanMetrics generates the user-defined span metrics from the traces, before
+// they are sampled, and sends them through DogStatsD.
+type SpanMetrics struct {
+	metrics []*spanMetric
+	statsd  statsd.ClientInterface
+}
+
+// spanMetric is a compiled config.SpanMetric.
+type spanMetric struct {
+	name         string
+	distribution bool
+	metric       string
+	filter       *traceutil.SpanFilter
+	groupBy      []string
+}
+
+// NewSpanMetrics returns a new SpanMetrics generating the span metrics of the
+// configuration, the invalid ones are skipped.
+func NewSpanMetrics(conf *config.AgentConfig, statsd statsd.ClientInterface) *SpanMetrics {
+	sm := &SpanMetrics{statsd: statsd}
+	for _, m := range conf.SpanMetrics {
+		metric, err := newSpanMetric(m)
+		if err != nil {
+			log.Errorf("Invalid span metric %q: %v", m.Name, err)
+			continue
+		}
+		sm.metrics = append(sm.metrics, metric)
+	}
+	return sm
+}
+
+func newSpanMetric(m *config.SpanMetric) (*spanMetric, error) {
+	if m.Name == "" {
+		return nil, errors.New("no name")
+	}
+	metric := &spanMetric{
+		name:    m.Name,
+		metric:  m.Metric,
+		groupBy: m.GroupBy,
+	}
+	switch m.Type {
+	case config.SpanMetricCount:
+		if m.Metric != "" {
+			return nil, fmt.Errorf("a %s can not have a metric", m.Type)
+		}
+	case config.SpanMetricDistribution:
+		metric.distribution = true
+	default:
+		return nil, fmt.Errorf("unknown type %q", m.Type)
+	}
+	var err error
+	f := m.Filter
+	if metric.filter, err = traceutil.NewSpanFilter(f.Service, f.Operation, f.Resource, f.Tags); err != nil {
+		return nil, err
+	}
+	return metric, nil
+}
+
+// Add generates the span metrics of the spans of the trace. The counts are
+// weighted by the sampling rate the tracer applied to the trace, each span is
+// counted weight times. The distributions get a single value per span, so that
+// their cost does not grow with the weight: their percentiles are the ones of
+// the spans received, use a count to get the number of spans.
+// The spans of the P0 traces the tracers drop when they compute the stats
+// themselves (Datadog-Client-Computed-Stats) never reach the agent, and are
+// not counted.
+func (sm *SpanMetrics) Add(pt *traceutil.ProcessedTrace) {
+	if sm == nil || len(sm.metrics) == 0 {
+		return
+	}
+	n := weightedCount(weight(pt.Root))
+	for _, s := range pt.TraceChunk.Spans {
+		for _, m := range sm.metrics {
+			if !m.filter.Matches(s) {
+				continue
+			}
+			if !m.distribution {
+				_ = sm.statsd.Count(m.name, n, m.tags(s), 1)
+				continue
+			}
+			v := float64(s.Duration) / 1e9
+			if m.metric != "" {
+				var ok bool
+				if v, ok = s.Metrics[m.metric]; !ok {
+					continue
+				}
+			}
+			_ = sm.statsd.Distribution(m.name, v, m.tags(s), 1)
+		}
+	}
+}
+
+// weightedCount returns the number of times a span of weight w is counted: the
+// weight, rounded down or up at random so that its expected value is w.
+func weightedCount(w float64) int64 {
+	n := math.Floor(w)
+	if rand.Float64() < w-n {
+		n++
+	}
+	return int64(n)
+}
+
+// tags returns the group by tags of the metric for the span, the tags missing
+// from the span are omitted.
+func (m *spanMetric) tags(s *pb.Span) []string {
+	tags := make([]string, 0, len(m.groupBy))
+	for _, k := range m.groupBy {
+		var (
+			v  string
+			ok = true
+		)
+		switch k {
+		case "service":
+			v = s.Service
+		case "operation":
+			v = s.Name
+		case "resource":
+			v = s.Resource
+		default:
+			v, ok = traceutil.GetTag(s, k)
+		}
+		if ok {
+			tags = append(tags, traceutil.NormalizeTag(k+":"+v))
+		}
+	}
+	return tags
+}
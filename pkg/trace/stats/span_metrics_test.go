@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/teststatsd"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+func TestSpanMetrics(t *testing.T) {
+	conf := config.New()
+	conf.SpanMetrics = []*config.SpanMetric{
+		{
+			Name:    "checkout.requests",
+			Type:    config.SpanMetricCount,
+			Filter:  config.SpanFilter{Service: "^checkout$", Tags: map[string]string{"span.kind": "server"}},
+			GroupBy: []string{"resource", "customer_tier", "http.status_code"},
+		},
+		{
+			Name:    "checkout.duration",
+			Type:    config.SpanMetricDistribution,
+			Filter:  config.SpanFilter{Operation: "^http\\."},
+			GroupBy: []string{"service"},
+		},
+		{
+			Name:   "checkout.cart_size",
+			Type:   config.SpanMetricDistribution,
+			Metric: "cart.size",
+		},
+	}
+	statsd := &teststatsd.Client{}
+	sm := NewSpanMetrics(conf, statsd)
+	require.Len(t, sm.metrics, 3)
+
+	sm.Add(&traceutil.ProcessedTrace{TraceChunk: &pb.TraceChunk{Spans: []*pb.Span{
+		{
+			Service:  "checkout",
+			Name:     "http.request",
+			Resource: "POST /cart",
+			Duration: (250 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"span.kind": "server", "customer_tier": "Gold"},
+			Metrics:  map[string]float64{"http.status_code": 200, "cart.size": 3},
+		},
+		{
+			Service:  "checkout",
+			Name:     "db.query",
+			Duration: (10 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"span.kind": "client"},
+		},
+	}}})
+
+	require.Len(t, statsd.CountCalls, 1)
+	assert.Equal(t, "checkout.requests", statsd.CountCalls[0].Name)
+	assert.Equal(t, 1.0, statsd.CountCalls[0].Value)
+	assert.Equal(t, []string{"resource:post_/cart", "customer_tier:gold", "http.status_code:200"}, statsd.CountCalls[0].Tags)
+
+	require.Len(t, statsd.DistributionCalls, 2)
+	assert.Equal(t, "checkout.duration", statsd.DistributionCalls[0].Name)
+	assert.Equal(t, 0.25, statsd.DistributionCalls[0].Value)
+	assert.Equal(t, []string{"service:checkout"}, statsd.DistributionCalls[0].Tags)
+	assert.Equal(t, "checkout.cart_size", statsd.DistributionCalls[1].Name)
+	assert.Equal(t, 3.0, statsd.DistributionCalls[1].Value)
+	assert.Empty(t, statsd.DistributionCalls[1].Tags)
+}
+
+func TestSpanMetricsWeighted(t *testing.T) {
+	conf := config.New()
+	conf.SpanMetrics = []*config.SpanMetric{
+		{Name: "requests", Type: config.SpanMetricCount},
+		{Name: "duration", Type: config.SpanMetricDistribution},
+	}
+	statsd := &teststatsd.Client{}
+	sm := NewSpanMetrics(conf, statsd)
+
+	for _, tc := range []struct {
+		sampleRate float64
+		count      float64
+	}{
+		// the tracer kept one trace out of four
+		{0.25, 4},
+		// a large weight does not make the distributions more expensive
+		{0.0001, 10000},
+	} {
+		statsd.Reset()
+		root := &pb.Span{Duration: (time.Second).Nanoseconds(), Metrics: map[string]float64{"_sample_rate": tc.sampleRate}}
+		sm.Add(&traceutil.ProcessedTrace{Root: root, TraceChunk: &pb.TraceChunk{Spans: []*pb.Span{root}}})
+
+		require.Len(t, statsd.CountCalls, 1)
+		assert.InDelta(t, tc.count, statsd.CountCalls[0].Value, 1)
+		require.Len(t, statsd.DistributionCalls, 1)
+		assert.Equal(t, 1.0, statsd.DistributionCalls[0].Value)
+		assert.Equal(t, 1.0, statsd.DistributionCalls[0].Rate)
+	}
+}
+
+func TestWeightedCount(t *testing.T) {
+	assert.Equal(t, int64(1), weightedCount(1))
+	assert.Equal(t, int64(10), weightedCount(10))
+
+	// the fractional weights are counted on average
+	var total int64
+	for i := 0; i < 10000; i++ {
+		n := weightedCount(2.5)
+		assert.Contains(t, []int64{2, 3}, n)
+		total += n
+	}
+	assert.InDelta(t, 25000, total, 1000)
+}
+
+func TestSpanMetricsInvalid(t *testing.T) {
+	conf := config.New()
+	conf.SpanMetrics = []*config.SpanMetric{
+		{Type: config.SpanMetricCount},
+		{Name: "unknown", Type: "gauge"},
+		{Name: "count-metric", Type: config.SpanMetricCount, Metric: "cart.size"},
+		{Name: "bad-filter", Type: config.SpanMetricCount, Filter: config.SpanFilter{Resource: "("}},
+	}
+	sm := NewSpanMetrics(conf, &teststatsd.Client{})
+	assert.Empty(t, sm.metrics)
+
+	var nilMetrics *SpanMetrics
+	nilMetrics.Add(&traceutil.ProcessedTrace{TraceChunk: &pb.TraceChunk{Spans: []*pb.Span{{}}}})
+}
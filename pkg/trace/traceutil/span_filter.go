@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package traceutil
+
+import (
+	"regexp"
+	"strconv"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+// SpanFilter matches the spans against regular expressions.
+type SpanFilter struct {
+	service, operation, resource *regexp.Regexp
+	tags                         map[string]*regexp.Regexp
+}
+
+// NewSpanFilter returns a SpanFilter matching the spans whose service, operation
+// name and resource match the given regular expressions, and whose tags match
+// the regular expressions of tags. The empty expressions match all the spans.
+func NewSpanFilter(service, operation, resource string, tags map[string]string) (*SpanFilter, error) {
+	sf := &SpanFilter{}
+	var err error
+	if sf.service, err = compileCondition(service); err != nil {
+		return nil, err
+	}
+	if sf.operation, err = compileCondition(operation); err != nil {
+		return nil, err
+	}
+	if sf.resource, err = compileCondition(resource); err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		sf.tags = make(map[string]*regexp.Regexp, len(tags))
+		for k, v := range tags {
+			if sf.tags[k], err = regexp.Compile(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sf, nil
+}
+
+// compileCondition compiles the regular expression of a condition, the empty
+// conditions are nil.
+func compileCondition(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+// Matches reports whether the span matches all the conditions of the filter.
+func (f *SpanFilter) Matches(s *pb.Span) bool {
+	if f.service != nil && !f.service.MatchString(s.Service) {
+		return false
+	}
+	if f.operation != nil && !f.operation.MatchString(s.Name) {
+		return false
+	}
+	if f.resource != nil && !f.resource.MatchString(s.Resource) {
+		return false
+	}
+	for k, re := range f.tags {
+		v, ok := GetTag(s, k)
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTag returns the value of the meta or of the metric key of the span.
+func GetTag(s *pb.Span, key string) (string, bool) {
+	if v, ok := s.Meta[key]; ok {
+		return v, true
+	}
+	if v, ok := s.Metrics[key]; ok {
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
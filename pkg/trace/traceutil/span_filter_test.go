@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package traceutil
+
+import (
+	"testing"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanFilter(t *testing.T) {
+	span := &pb.Span{
+		Service:  "checkout",
+		Name:     "http.request",
+		Resource: "POST /cart",
+		Meta:     map[string]string{"span.kind": "server"},
+		Metrics:  map[string]float64{"http.status_code": 503},
+	}
+	for _, tt := range []struct {
+		service, operation, resource string
+		tags                         map[string]string
+		match                        bool
+	}{
+		{match: true},
+		{service: "^check", operation: `^http\.`, resource: "cart$", match: true},
+		{service: "^web$", match: false},
+		{operation: "^db", match: false},
+		{resource: "^GET", match: false},
+		{tags: map[string]string{"span.kind": "server", "http.status_code": "^5"}, match: true},
+		{tags: map[string]string{"http.status_code": "^2"}, match: false},
+		{tags: map[string]string{"missing": ".*"}, match: false},
+	} {
+		f, err := NewSpanFilter(tt.service, tt.operation, tt.resource, tt.tags)
+		require.NoError(t, err)
+		assert.Equal(t, tt.match, f.Matches(span), tt)
+	}
+
+	_, err := NewSpanFilter("(", "", "", nil)
+	assert.Error(t, err)
+	_, err = NewSpanFilter("", "", "", map[string]string{"a": "("})
+	assert.Error(t, err)
+}
@@ -229,6 +229,9 @@ DEFAULT_MODULES = {
     "pkg/logs/auditor": GoModule("pkg/logs/auditor", independent=True, used_by_otel=True),
     "pkg/logs/client": GoModule("pkg/logs/client", independent=True, used_by_otel=True),
     "pkg/logs/diagnostic": GoModule("pkg/logs/diagnostic", independent=True, used_by_otel=True),
+    "pkg/logs/internal/decoder/auto_multiline_detection/tokens": GoModule(
+        "pkg/logs/internal/decoder/auto_multiline_detection/tokens", independent=True, used_by_otel=True
+    ),
     "pkg/logs/message": GoModule("pkg/logs/message", independent=True, used_by_otel=True),
     "pkg/logs/metrics": GoModule("pkg/logs/metrics", independent=True, used_by_otel=True),
     "pkg/logs/pipeline": GoModule("pkg/logs/pipeline", independent=True, used_by_otel=True),